@@ -0,0 +1,450 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Action lifecycle: pending -> queued -> running -> executed/failed, and
+// executed -> rolling_back -> rolled_back (or back to executed) on a rollback
+const (
+	actionStatusPending     = "pending"
+	actionStatusQueued      = "queued"
+	actionStatusRunning     = "running"
+	actionStatusExecuted    = "executed"
+	actionStatusFailed      = "failed"
+	actionStatusRollingBack = "rolling_back"
+	actionStatusRolledBack  = "rolled_back"
+)
+
+// actionQueueSize bounds how many actions can wait for the worker
+const actionQueueSize = 100
+
+// errDryRun is recorded on actions the worker picks up in dry-run mode
+var errDryRun = errors.New("not executed: optimkube is running in dry-run mode")
+
+// ActionChange describes the change an action makes, or would make in a dry
+// run
+type ActionChange struct {
+	Kind            string `json:"kind"`
+	Namespace       string `json:"namespace"`
+	Name            string `json:"name"`
+	CurrentReplicas *int32 `json:"current_replicas,omitempty"` // unknown in demo mode
+	NewReplicas     int32  `json:"new_replicas"`
+	Patch           string `json:"patch"` // equivalent merge patch of the Deployment, for kubectl patch
+}
+
+// demoActions is the example action seeded in demo mode, where executing it
+// only logs what would change
+func (co *CostOptimizer) demoActions() []OptimizationAction {
+	return []OptimizationAction{
+		{
+			ID:        uuid.NewString(),
+			Type:      "scale_down",
+			Resource:  "default/nginx-deployment",
+			Namespace: "default",
+			Action:    "Scale deployment to 1 replica",
+			Parameters: map[string]interface{}{
+				"replicas": 1,
+			},
+			Status:    actionStatusPending,
+			CreatedAt: co.now(),
+		},
+	}
+}
+
+// loadActions restores persisted actions. Demo mode seeds an example on first
+// run; against a cluster nothing is seeded, since the worker really executes
+// actions.
+func (co *CostOptimizer) loadActions() error {
+	var actions []OptimizationAction
+	if co.store != nil {
+		loaded, err := co.store.LoadActions()
+		if err != nil {
+			return err
+		}
+		actions = loaded
+	}
+	if len(actions) == 0 && co.demoMode {
+		actions = co.demoActions()
+	}
+	// A rollback is never resumed; whether it took effect is unknown
+	for i := range actions {
+		if actions[i].Status == actionStatusRollingBack {
+			actions[i].Status = actionStatusExecuted
+			actions[i].Error = "rollback interrupted by a restart; check the resource before rolling back again"
+		}
+	}
+
+	co.actionsMu.Lock()
+	co.actions = actions
+	co.actionsMu.Unlock()
+	return nil
+}
+
+// StartActionWorker executes queued actions one at a time. Actions that were
+// queued or running when the process stopped are picked up again first.
+func (co *CostOptimizer) StartActionWorker() {
+	co.actionsMu.Lock()
+	inFlight := make([]string, 0)
+	for _, action := range co.actions {
+		if action.Status == actionStatusQueued || action.Status == actionStatusRunning {
+			inFlight = append(inFlight, action.ID)
+		}
+	}
+	co.actionsMu.Unlock()
+
+	for _, id := range inFlight {
+		slog.Info("Resuming optimization action", "action_id", id)
+		co.processAction(id)
+	}
+
+	for id := range co.actionQueue {
+		co.processAction(id)
+	}
+}
+
+func (co *CostOptimizer) processAction(id string) {
+	// An action queued while the worker was collecting the ones to resume is
+	// both resumed and received from the queue; whichever comes second finds
+	// it already handled
+	claimed := false
+	action, ok := co.updateAction(id, func(a *OptimizationAction) {
+		if a.Status != actionStatusQueued && a.Status != actionStatusRunning {
+			return
+		}
+		claimed = true
+		a.Status = actionStatusRunning
+		a.Error = ""
+	})
+	if !ok {
+		slog.Warn("Skipping unknown optimization action", "action_id", id)
+		return
+	}
+	if !claimed {
+		slog.Info("Skipping optimization action that is no longer queued", "action_id", id, "status", action.Status)
+		return
+	}
+
+	// The window may have opened while the action sat in the queue
+	var change *ActionChange
+	var err error
+	if co.readOnly {
+		err = errReadOnly
+	} else if co.dryRun {
+		// Only actions queued before a restart into dry-run mode get here
+		err = errDryRun
+	} else if co.actionProtected(action) {
+		err = errProtected
+	} else if reason, _ := co.mutationBlocked(co.now()); reason != "" {
+		err = fmt.Errorf("not executed: %s", reason)
+	} else {
+		change, err = co.executeAction(context.Background(), action, false)
+	}
+
+	co.updateAction(id, func(a *OptimizationAction) {
+		executedAt := co.now()
+		a.ExecutedAt = &executedAt
+		a.RolledBackAt = nil
+		if err != nil {
+			a.Status = actionStatusFailed
+			a.Error = err.Error()
+			a.Change = nil
+			return
+		}
+		a.Status = actionStatusExecuted
+		a.Change = change
+	})
+	if err != nil {
+		slog.Error("Optimization action failed", "action_id", id, "error", err)
+	}
+}
+
+// executeAction carries out an action and describes the change it made. A dry
+// run reads the current state and runs the same checks, but makes no write at
+// all, not even a server-side dry run.
+func (co *CostOptimizer) executeAction(ctx context.Context, action OptimizationAction, dryRun bool) (*ActionChange, error) {
+	if err := validateActionParameters(action); err != nil {
+		return nil, err
+	}
+	switch action.Type {
+	case "scale_down":
+		replicas, _ := actionReplicas(action.Parameters)
+		return co.scaleDeployment(ctx, action.Namespace, resourceName(action.Resource), replicas, dryRun)
+	}
+	return nil, fmt.Errorf("unsupported action type %q", action.Type)
+}
+
+// validateActionParameters checks the parameters an action type needs, so a
+// malformed action is rejected before it's queued
+func validateActionParameters(action OptimizationAction) error {
+	switch action.Type {
+	case "scale_down":
+		_, err := actionReplicas(action.Parameters)
+		return err
+	}
+	return nil
+}
+
+// actionReplicas reads the replicas parameter, which is a float64 once the
+// action has been through JSON
+func actionReplicas(parameters map[string]interface{}) (int32, error) {
+	var replicas float64
+	switch value := parameters["replicas"].(type) {
+	case int:
+		replicas = float64(value)
+	case int32:
+		replicas = float64(value)
+	case int64:
+		replicas = float64(value)
+	case float64:
+		replicas = value
+	case nil:
+		return 0, errors.New("missing replicas parameter")
+	default:
+		return 0, fmt.Errorf("replicas parameter %v is not a number", value)
+	}
+	if replicas < 0 || replicas != math.Trunc(replicas) || replicas > math.MaxInt32 {
+		return 0, fmt.Errorf("replicas parameter %v is not a non-negative integer", replicas)
+	}
+	return int32(replicas), nil
+}
+
+// resourceName strips the namespace from a namespace/name resource
+func resourceName(resource string) string {
+	return resource[strings.LastIndex(resource, "/")+1:]
+}
+
+// scaleDeployment sets a Deployment's replicas through its scale subresource
+func (co *CostOptimizer) scaleDeployment(ctx context.Context, namespace, name string, replicas int32, dryRun bool) (*ActionChange, error) {
+	change := &ActionChange{
+		Kind:        "Deployment",
+		Namespace:   namespace,
+		Name:        name,
+		NewReplicas: replicas,
+		Patch:       fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas),
+	}
+	if co.demoMode || co.clientset == nil {
+		slog.Info("Demo mode: would scale deployment", "namespace", namespace, "deployment", name, "replicas", replicas)
+		return change, nil
+	}
+	deployments := co.clientset.AppsV1().Deployments(namespace)
+	scale, err := deployments.GetScale(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get scale of deployment %s/%s: %w", namespace, name, err)
+	}
+	previous := scale.Spec.Replicas
+	change.CurrentReplicas = &previous
+	if replicas < previous {
+		if err := co.checkPDBFloor(ctx, namespace, name, previous, replicas); err != nil {
+			return nil, err
+		}
+	}
+	if dryRun {
+		slog.Info("Dry run: would scale deployment", "namespace", namespace, "deployment", name, "previous_replicas", previous, "replicas", replicas)
+		return change, nil
+	}
+	scale.Spec.Replicas = replicas
+	if _, err := deployments.UpdateScale(ctx, name, scale, metav1.UpdateOptions{}); err != nil {
+		return nil, fmt.Errorf("scale deployment %s/%s: %w", namespace, name, err)
+	}
+	slog.Info("Scaled deployment", "namespace", namespace, "deployment", name, "previous_replicas", previous, "replicas", replicas)
+	return change, nil
+}
+
+// checkPDBFloor refuses a scale-down of a deployment below the minAvailable
+// of a PodDisruptionBudget covering its pods, which would leave the budget
+// unsatisfiable and block every later drain
+func (co *CostOptimizer) checkPDBFloor(ctx context.Context, namespace, name string, current, replicas int32) error {
+	deployment, err := co.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("get deployment %s/%s: %w", namespace, name, err)
+	}
+	pdbs, err := co.clientset.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("list PodDisruptionBudgets in %s: %w", namespace, err)
+	}
+	covering := coveringPDBs(namespace, deployment.Spec.Template.Labels, pdbs.Items)
+	if floor, pdbName := pdbReplicaFloor(covering, current); replicas < floor {
+		return fmt.Errorf("scaling deployment %s/%s to %d replicas would violate PodDisruptionBudget %s, which requires %d available", namespace, name, replicas, pdbName, floor)
+	}
+	return nil
+}
+
+// handleDryRunAction answers an execute request in dry-run mode with the
+// change the action would make. The action itself stays as it was.
+func (co *CostOptimizer) handleDryRunAction(w http.ResponseWriter, r *http.Request, action OptimizationAction) {
+	change, err := co.executeAction(r.Context(), action, true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"dry_run":   true,
+		"action_id": action.ID,
+		"type":      action.Type,
+		"resource":  action.Resource,
+		"change":    change,
+	})
+}
+
+// updateAction applies fn to the action with the given ID and persists the
+// result, returning the updated copy.
+func (co *CostOptimizer) updateAction(id string, fn func(*OptimizationAction)) (OptimizationAction, bool) {
+	co.actionsMu.Lock()
+	defer co.actionsMu.Unlock()
+
+	for i := range co.actions {
+		if co.actions[i].ID == id {
+			fn(&co.actions[i])
+			co.saveActionsLocked()
+			return co.actions[i], true
+		}
+	}
+	return OptimizationAction{}, false
+}
+
+func (co *CostOptimizer) getAction(id string) (OptimizationAction, bool) {
+	co.actionsMu.Lock()
+	defer co.actionsMu.Unlock()
+
+	for _, action := range co.actions {
+		if action.ID == id {
+			action.Protected = co.actionProtected(action)
+			return action, true
+		}
+	}
+	return OptimizationAction{}, false
+}
+
+func (co *CostOptimizer) listActions() []OptimizationAction {
+	co.actionsMu.Lock()
+	defer co.actionsMu.Unlock()
+
+	actions := make([]OptimizationAction, len(co.actions))
+	copy(actions, co.actions)
+	for i := range actions {
+		actions[i].Protected = co.actionProtected(actions[i])
+	}
+	return actions
+}
+
+// saveActionsLocked persists the action list; callers must hold actionsMu
+func (co *CostOptimizer) saveActionsLocked() {
+	if co.store == nil {
+		return
+	}
+	if err := co.store.SaveActions(co.actions); err != nil {
+		slog.Error("Failed to persist actions", "error", err)
+	}
+}
+
+func (co *CostOptimizer) handleActions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(co.listActions())
+}
+
+func (co *CostOptimizer) handleGetAction(w http.ResponseWriter, r *http.Request) {
+	actionID := mux.Vars(r)["id"]
+
+	action, ok := co.getAction(actionID)
+	if !ok {
+		http.Error(w, "action not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(action)
+}
+
+func (co *CostOptimizer) handleExecuteAction(w http.ResponseWriter, r *http.Request) {
+	actionID := mux.Vars(r)["id"]
+
+	co.actionsMu.Lock()
+	var action *OptimizationAction
+	for i := range co.actions {
+		if co.actions[i].ID == actionID {
+			action = &co.actions[i]
+			break
+		}
+	}
+	if action == nil {
+		co.actionsMu.Unlock()
+		http.Error(w, "action not found", http.StatusNotFound)
+		return
+	}
+	if action.Status == actionStatusQueued || action.Status == actionStatusRunning || action.Status == actionStatusRollingBack {
+		co.actionsMu.Unlock()
+		http.Error(w, "action is already "+action.Status, http.StatusConflict)
+		return
+	}
+	if co.actionProtected(*action) {
+		co.actionsMu.Unlock()
+		http.Error(w, errProtected.Error(), http.StatusForbidden)
+		return
+	}
+	if err := validateActionParameters(*action); err != nil {
+		co.actionsMu.Unlock()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dryRun := co.dryRun
+	if raw := r.URL.Query().Get("dry_run"); raw != "" {
+		requested, err := strconv.ParseBool(raw)
+		if err != nil {
+			co.actionsMu.Unlock()
+			http.Error(w, fmt.Sprintf("invalid dry_run %q", raw), http.StatusBadRequest)
+			return
+		}
+		// The query can ask for a dry run but can't lift dry-run mode
+		dryRun = dryRun || requested
+	}
+	if dryRun {
+		snapshot := *action
+		co.actionsMu.Unlock()
+		co.handleDryRunAction(w, r, snapshot)
+		return
+	}
+	now := co.now()
+	if reason, next := co.mutationBlocked(now); reason != "" {
+		co.actionsMu.Unlock()
+		writeLocked(w, reason, now, next)
+		return
+	}
+
+	select {
+	case co.actionQueue <- actionID:
+	default:
+		co.actionsMu.Unlock()
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "action queue is full", http.StatusServiceUnavailable)
+		return
+	}
+	action.Status = actionStatusQueued
+	action.Error = ""
+	co.saveActionsLocked()
+	co.actionsMu.Unlock()
+
+	slog.Info("Queued optimization action", "action_id", actionID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/actions/"+actionID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":    actionStatusQueued,
+		"action_id": actionID,
+		"message":   "Optimization action queued for execution",
+	})
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testScaleAction is a pending scale_down of a deployment in default
+func testScaleAction(name string, replicas interface{}) OptimizationAction {
+	return OptimizationAction{
+		ID:         uuid.NewString(),
+		Type:       "scale_down",
+		Resource:   "default/" + name,
+		Namespace:  "default",
+		Action:     "Scale deployment",
+		Parameters: map[string]interface{}{"replicas": replicas},
+		Status:     actionStatusPending,
+		CreatedAt:  time.Now(),
+	}
+}
+
+// startTestWorker runs the action worker until the test ends
+func startTestWorker(t *testing.T, co *CostOptimizer) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		co.StartActionWorker()
+		close(done)
+	}()
+	t.Cleanup(func() {
+		close(co.actionQueue)
+		<-done
+	})
+}
+
+// waitForAction polls GET /api/actions/{id} until the action leaves the
+// queued and running states
+func waitForAction(t *testing.T, router *mux.Router, id string) OptimizationAction {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rec := serve(router, http.MethodGet, "/api/actions/"+id, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET /api/actions/%s: status %d: %s", id, rec.Code, rec.Body)
+		}
+		var action OptimizationAction
+		if err := json.Unmarshal(rec.Body.Bytes(), &action); err != nil {
+			t.Fatalf("decode action: %v", err)
+		}
+		if action.Status != actionStatusQueued && action.Status != actionStatusRunning {
+			return action
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("action %s still %s after 5s", id, action.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// deploymentReplicas reads a deployment's replicas from the fake clientset
+func deploymentReplicas(t *testing.T, client *fake.Clientset, namespace, name string) int32 {
+	t.Helper()
+	deployment, err := client.AppsV1().Deployments(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get deployment %s/%s: %v", namespace, name, err)
+	}
+	return *deployment.Spec.Replicas
+}
+
+func TestActionWorkerExecutesQueuedActions(t *testing.T) {
+	failed := testScaleAction("web", 1)
+	failed.Status = actionStatusFailed
+	failed.Error = "previous attempt failed"
+
+	tests := []struct {
+		name         string
+		action       OptimizationAction
+		wantStatus   string
+		wantError    bool
+		wantReplicas int32 // of default/web afterwards
+	}{
+		{name: "scale down", action: testScaleAction("web", 1), wantStatus: actionStatusExecuted, wantReplicas: 1},
+		{name: "retry after failure", action: failed, wantStatus: actionStatusExecuted, wantReplicas: 1},
+		{name: "missing deployment", action: testScaleAction("gone", 1), wantStatus: actionStatusFailed, wantError: true, wantReplicas: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, client := newTestOptimizer(t, testDeployment("default", "web", 3))
+			co.actions = []OptimizationAction{tt.action}
+			router := co.newRouter()
+			startTestWorker(t, co)
+
+			rec := serve(router, http.MethodPost, "/api/actions/"+tt.action.ID+"/execute", nil)
+			if rec.Code != http.StatusAccepted {
+				t.Fatalf("execute: status %d, want %d: %s", rec.Code, http.StatusAccepted, rec.Body)
+			}
+			if got, want := rec.Header().Get("Location"), "/api/actions/"+tt.action.ID; got != want {
+				t.Errorf("Location = %q, want %q", got, want)
+			}
+
+			action := waitForAction(t, router, tt.action.ID)
+			if action.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q (error %q)", action.Status, tt.wantStatus, action.Error)
+			}
+			if (action.Error != "") != tt.wantError {
+				t.Errorf("error = %q, want error %v", action.Error, tt.wantError)
+			}
+			if action.ExecutedAt == nil {
+				t.Error("executed_at is not set")
+			}
+			if got := deploymentReplicas(t, client, "default", "web"); got != tt.wantReplicas {
+				t.Errorf("replicas = %d, want %d", got, tt.wantReplicas)
+			}
+		})
+	}
+}
+
+func TestActionWorkerRejectsActionInFlight(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string // of the action before the request
+		id     string // requested, if not the action's own
+		want   int
+	}{
+		{name: "queued", status: actionStatusQueued, want: http.StatusConflict},
+		{name: "running", status: actionStatusRunning, want: http.StatusConflict},
+		{name: "unknown", status: actionStatusPending, id: uuid.NewString(), want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t)
+			action := testScaleAction("web", 1)
+			action.Status = tt.status
+			co.actions = []OptimizationAction{action}
+			id := tt.id
+			if id == "" {
+				id = action.ID
+			}
+
+			rec := serve(co.newRouter(), http.MethodPost, "/api/actions/"+id+"/execute", nil)
+			if rec.Code != tt.want {
+				t.Errorf("status %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if got, _ := co.getAction(action.ID); got.Status != tt.status {
+				t.Errorf("action status = %q, want it left %q", got.Status, tt.status)
+			}
+		})
+	}
+}
+
+func TestActionWorkerResumesPersistedActions(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("OPTIMKUBE_STATE_DIR", dir)
+
+	// An action queued before a restart is only in the state dir
+	before, _ := newTestOptimizer(t)
+	queued := testScaleAction("web", 1)
+	queued.Status = actionStatusQueued
+	persistActions(before, []OptimizationAction{queued})
+
+	co, client := newTestOptimizer(t, testDeployment("default", "web", 3))
+	if err := co.loadActions(); err != nil {
+		t.Fatalf("loadActions: %v", err)
+	}
+	startTestWorker(t, co)
+
+	action := waitForAction(t, co.newRouter(), queued.ID)
+	if action.Status != actionStatusExecuted {
+		t.Fatalf("status = %q, want %q (error %q)", action.Status, actionStatusExecuted, action.Error)
+	}
+	if got := deploymentReplicas(t, client, "default", "web"); got != 1 {
+		t.Errorf("replicas = %d, want 1", got)
+	}
+}
+
+// persistActions replaces the optimizer's actions and saves them to its store
+func persistActions(co *CostOptimizer, actions []OptimizationAction) {
+	co.actionsMu.Lock()
+	defer co.actionsMu.Unlock()
+	co.actions = actions
+	co.saveActionsLocked()
+}
+
+func TestExecuteActionScalesDeployment(t *testing.T) {
+	co, client := newTestOptimizer(t, testDeployment("default", "web", 3))
+	action := testScaleAction("web", 1)
+	co.actions = []OptimizationAction{action}
+	router := co.newRouter()
+	startTestWorker(t, co)
+
+	if rec := serve(router, http.MethodPost, "/api/actions/"+action.ID+"/execute", nil); rec.Code != http.StatusAccepted {
+		t.Fatalf("execute: status %d, want %d: %s", rec.Code, http.StatusAccepted, rec.Body)
+	}
+	executed := waitForAction(t, router, action.ID)
+	if executed.Status != actionStatusExecuted || executed.ExecutedAt == nil {
+		t.Fatalf("status = %q, executed_at = %v (error %q), want executed with a time", executed.Status, executed.ExecutedAt, executed.Error)
+	}
+	if got := deploymentReplicas(t, client, "default", "web"); got != 1 {
+		t.Errorf("replicas = %d, want 1", got)
+	}
+	if executed.Change == nil || executed.Change.CurrentReplicas == nil || *executed.Change.CurrentReplicas != 3 {
+		t.Errorf("change = %+v, want current replicas 3", executed.Change)
+	}
+}
+
+func TestExecuteActionRejectsBadRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		replicas interface{}
+		unknown  bool
+		want     int
+	}{
+		{name: "unknown action", replicas: 1, unknown: true, want: http.StatusNotFound},
+		{name: "negative replicas", replicas: -1, want: http.StatusBadRequest},
+		{name: "fractional replicas", replicas: 1.5, want: http.StatusBadRequest},
+		{name: "string replicas", replicas: "one", want: http.StatusBadRequest},
+		{name: "missing replicas", replicas: nil, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, client := newTestOptimizer(t, testDeployment("default", "web", 3))
+			action := testScaleAction("web", tt.replicas)
+			co.actions = []OptimizationAction{action}
+			id := action.ID
+			if tt.unknown {
+				id = uuid.NewString()
+			}
+
+			rec := serve(co.newRouter(), http.MethodPost, "/api/actions/"+id+"/execute", nil)
+			if rec.Code != tt.want {
+				t.Errorf("status %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if len(co.actionQueue) != 0 {
+				t.Error("a rejected action was queued")
+			}
+			if got := deploymentReplicas(t, client, "default", "web"); got != 3 {
+				t.Errorf("replicas = %d, want 3", got)
+			}
+		})
+	}
+}
+
+func TestLoadActionsSeedsOnlyInDemoMode(t *testing.T) {
+	tests := []struct {
+		demoMode bool
+		want     int
+	}{
+		{demoMode: true, want: 1},
+		{demoMode: false, want: 0},
+	}
+
+	for _, tt := range tests {
+		co, _ := newTestOptimizer(t)
+		co.demoMode = tt.demoMode
+		if err := co.loadActions(); err != nil {
+			t.Fatalf("loadActions: %v", err)
+		}
+		if got := len(co.listActions()); got != tt.want {
+			t.Errorf("demo mode %v: %d actions, want %d", tt.demoMode, got, tt.want)
+		}
+	}
+}
+
+func TestExecuteActionDryRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		dryRunMode bool // OPTIMKUBE_DRY_RUN
+		query      string
+	}{
+		{name: "dry-run mode", dryRunMode: true},
+		{name: "dry_run query", query: "?dry_run=true"},
+		{name: "query can't lift dry-run mode", dryRunMode: true, query: "?dry_run=false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, client := newTestOptimizer(t, testDeployment("default", "web", 3))
+			co.dryRun = tt.dryRunMode
+			action := testScaleAction("web", 1)
+			co.actions = []OptimizationAction{action}
+
+			rec := serve(co.newRouter(), http.MethodPost, "/api/actions/"+action.ID+"/execute"+tt.query, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+			}
+			var response struct {
+				DryRun bool          `json:"dry_run"`
+				Change *ActionChange `json:"change"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			change := response.Change
+			if !response.DryRun || change == nil || change.CurrentReplicas == nil ||
+				*change.CurrentReplicas != 3 || change.NewReplicas != 1 || change.Patch != `{"spec":{"replicas":1}}` {
+				t.Errorf("response = %s, want a dry run from 3 to 1 replicas", rec.Body)
+			}
+
+			for _, call := range client.Actions() {
+				if verb := call.GetVerb(); verb != "get" && verb != "list" && verb != "watch" {
+					t.Errorf("dry run made a %s call on %s", verb, call.GetResource().Resource)
+				}
+			}
+			if got := deploymentReplicas(t, client, "default", "web"); got != 3 {
+				t.Errorf("replicas = %d, want 3", got)
+			}
+			if got, _ := co.getAction(action.ID); got.Status != actionStatusPending || len(co.actionQueue) != 0 {
+				t.Errorf("status = %q with %d queued, want the action left pending", got.Status, len(co.actionQueue))
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AdmissionPreview predicts whether applying a suggested change would pass the
+// namespace's LimitRange and ResourceQuota admission checks
+type AdmissionPreview struct {
+	Admitted bool     `json:"admitted"`
+	Reasons  []string `json:"reasons,omitempty"`
+}
+
+// namespacePolicies holds the LimitRanges and ResourceQuotas seen by one scan,
+// by namespace
+type namespacePolicies struct {
+	limitRanges map[string][]corev1.LimitRange
+	quotas      map[string][]corev1.ResourceQuota
+}
+
+// loadNamespacePolicies lists LimitRanges and ResourceQuotas across the
+// cluster. It returns nil when either can't be listed, since a preview
+// missing half the policies would be misleading.
+func (co *CostOptimizer) loadNamespacePolicies(ctx context.Context) *namespacePolicies {
+	limitRanges, err := co.clientset.CoreV1().LimitRanges("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		slog.Warn("Failed to list limit ranges, skipping admission previews", "error", err)
+		return nil
+	}
+	quotas, err := co.clientset.CoreV1().ResourceQuotas("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		slog.Warn("Failed to list resource quotas, skipping admission previews", "error", err)
+		return nil
+	}
+
+	policies := &namespacePolicies{
+		limitRanges: make(map[string][]corev1.LimitRange),
+		quotas:      make(map[string][]corev1.ResourceQuota),
+	}
+	for _, limitRange := range limitRanges.Items {
+		policies.limitRanges[limitRange.Namespace] = append(policies.limitRanges[limitRange.Namespace], limitRange)
+	}
+	for _, quota := range quotas.Items {
+		policies.quotas[quota.Namespace] = append(policies.quotas[quota.Namespace], quota)
+	}
+	return policies
+}
+
+// previewRequestChange checks setting one container's request for
+// resourceName to newRequest against the pod's namespace policies. A nil
+// receiver means the policies are unknown and yields no preview.
+func (p *namespacePolicies) previewRequestChange(pod *corev1.Pod, container corev1.Container, resourceName corev1.ResourceName, newRequest resource.Quantity) *AdmissionPreview {
+	if p == nil {
+		return nil
+	}
+
+	reasons := make([]string, 0)
+	if limit, ok := container.Resources.Limits[resourceName]; ok && newRequest.Cmp(limit) > 0 {
+		reasons = append(reasons, fmt.Sprintf("%s request %s would exceed the container's limit %s", resourceName, newRequest.String(), limit.String()))
+	}
+
+	// The pod total with this container's request replaced
+	podRequest := newRequest.DeepCopy()
+	for _, other := range pod.Spec.Containers {
+		if other.Name != container.Name {
+			podRequest.Add(other.Resources.Requests[resourceName])
+		}
+	}
+
+	for _, limitRange := range p.limitRanges[pod.Namespace] {
+		for _, item := range limitRange.Spec.Limits {
+			request := newRequest
+			switch item.Type {
+			case corev1.LimitTypeContainer:
+			case corev1.LimitTypePod:
+				request = podRequest
+			default:
+				continue
+			}
+			scope := string(item.Type)
+
+			if minimum, ok := item.Min[resourceName]; ok && request.Cmp(minimum) < 0 {
+				reasons = append(reasons, fmt.Sprintf("LimitRange %s requires a %s %s request of at least %s, got %s", limitRange.Name, scope, resourceName, minimum.String(), request.String()))
+			}
+			if maximum, ok := item.Max[resourceName]; ok && request.Cmp(maximum) > 0 {
+				reasons = append(reasons, fmt.Sprintf("LimitRange %s allows a %s %s request of at most %s, got %s", limitRange.Name, scope, resourceName, maximum.String(), request.String()))
+			}
+			if item.Type != corev1.LimitTypeContainer {
+				continue
+			}
+			ratio, hasRatio := item.MaxLimitRequestRatio[resourceName]
+			limit, hasLimit := container.Resources.Limits[resourceName]
+			if hasRatio && hasLimit && !newRequest.IsZero() &&
+				float64(limit.MilliValue())/float64(newRequest.MilliValue()) > float64(ratio.MilliValue())/1000 {
+				reasons = append(reasons, fmt.Sprintf("LimitRange %s caps the %s limit/request ratio at %s, and limit %s over request %s exceeds it", limitRange.Name, resourceName, ratio.String(), limit.String(), newRequest.String()))
+			}
+		}
+	}
+
+	// Quotas only constrain increases; the pod already counts its current request
+	increase := newRequest.DeepCopy()
+	increase.Sub(container.Resources.Requests[resourceName])
+	if increase.Sign() > 0 {
+		for _, quota := range p.quotas[pod.Namespace] {
+			for _, quotaResource := range []corev1.ResourceName{resourceName, "requests." + resourceName} {
+				hard, ok := quota.Status.Hard[quotaResource]
+				if !ok {
+					continue
+				}
+				remaining := hard.DeepCopy()
+				remaining.Sub(quota.Status.Used[quotaResource])
+				if increase.Cmp(remaining) > 0 {
+					reasons = append(reasons, fmt.Sprintf("ResourceQuota %s has %s of %s left, less than the %s increase", quota.Name, remaining.String(), quotaResource, increase.String()))
+				}
+			}
+		}
+	}
+
+	if len(reasons) == 0 {
+		return &AdmissionPreview{Admitted: true}
+	}
+	return &AdmissionPreview{Admitted: false, Reasons: reasons}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// testLimitRangeItem bounds cpu requests of type between min and max; an
+// empty bound is left unset
+func testLimitRangeItem(limitType corev1.LimitType, min, max string) corev1.LimitRangeItem {
+	item := corev1.LimitRangeItem{Type: limitType, Min: corev1.ResourceList{}, Max: corev1.ResourceList{}}
+	if min != "" {
+		item.Min[corev1.ResourceCPU] = resource.MustParse(min)
+	}
+	if max != "" {
+		item.Max[corev1.ResourceCPU] = resource.MustParse(max)
+	}
+	return item
+}
+
+func TestPreviewRequestChange(t *testing.T) {
+	limitRange := func(items ...corev1.LimitRangeItem) []corev1.LimitRange {
+		return []corev1.LimitRange{{ObjectMeta: metav1.ObjectMeta{Name: "bounds", Namespace: "shop"}, Spec: corev1.LimitRangeSpec{Limits: items}}}
+	}
+	quota := func(hard, used string) []corev1.ResourceQuota {
+		return []corev1.ResourceQuota{{
+			ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: "shop"},
+			Status: corev1.ResourceQuotaStatus{
+				Hard: corev1.ResourceList{"requests.cpu": resource.MustParse(hard)},
+				Used: corev1.ResourceList{"requests.cpu": resource.MustParse(used)},
+			},
+		}}
+	}
+
+	tests := []struct {
+		name        string
+		limitRanges []corev1.LimitRange
+		quotas      []corev1.ResourceQuota
+		limit       string // the container's CPU limit, if any
+		request     string
+		wantReasons []string // substrings, one per expected reason
+	}{
+		{name: "no policies", request: "200m"},
+		{name: "within container bounds", limitRanges: limitRange(testLimitRangeItem(corev1.LimitTypeContainer, "100m", "1")), request: "200m"},
+		{name: "above container max", limitRanges: limitRange(testLimitRangeItem(corev1.LimitTypeContainer, "", "100m")), request: "200m", wantReasons: []string{"at most 100m, got 200m"}},
+		{name: "below container min", limitRanges: limitRange(testLimitRangeItem(corev1.LimitTypeContainer, "250m", "")), request: "200m", wantReasons: []string{"at least 250m, got 200m"}},
+		{name: "pod total above pod max", limitRanges: limitRange(testLimitRangeItem(corev1.LimitTypePod, "", "600m")), request: "200m", wantReasons: []string{"Pod cpu request of at most 600m, got 700m"}},
+		{name: "other limit types ignored", limitRanges: limitRange(testLimitRangeItem(corev1.LimitTypePersistentVolumeClaim, "", "1m")), request: "200m"},
+		{name: "above the container limit", limit: "150m", request: "200m", wantReasons: []string{"exceed the container's limit 150m"}},
+		{
+			name:        "limit/request ratio too high",
+			limitRanges: limitRange(corev1.LimitRangeItem{Type: corev1.LimitTypeContainer, MaxLimitRequestRatio: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")}}),
+			limit:       "1",
+			request:     "200m",
+			wantReasons: []string{"limit/request ratio at 2"},
+		},
+		{name: "increase within quota", quotas: quota("4", "3"), request: "1500m"},
+		{name: "increase beyond quota", quotas: quota("4", "3800m"), request: "1500m", wantReasons: []string{"ResourceQuota compute has 200m of requests.cpu left, less than the 500m increase"}},
+		{name: "decrease under exhausted quota", quotas: quota("4", "4"), request: "200m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := testPod("shop", "web", "node-1", "1", "1Gi")
+			pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{
+				Name:      "sidecar",
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}},
+			})
+			if tt.limit != "" {
+				pod.Spec.Containers[0].Resources.Limits = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(tt.limit)}
+			}
+			policies := &namespacePolicies{
+				limitRanges: map[string][]corev1.LimitRange{"shop": tt.limitRanges},
+				quotas:      map[string][]corev1.ResourceQuota{"shop": tt.quotas},
+			}
+
+			preview := policies.previewRequestChange(pod, pod.Spec.Containers[0], corev1.ResourceCPU, resource.MustParse(tt.request))
+			if preview == nil || preview.Admitted != (len(tt.wantReasons) == 0) || len(preview.Reasons) != len(tt.wantReasons) {
+				t.Fatalf("preview %+v, want reasons %q", preview, tt.wantReasons)
+			}
+			for i, want := range tt.wantReasons {
+				if !strings.Contains(preview.Reasons[i], want) {
+					t.Errorf("reason %q, want it to mention %q", preview.Reasons[i], want)
+				}
+			}
+		})
+	}
+
+	var unknown *namespacePolicies
+	pod := testPod("shop", "web", "node-1", "1", "1Gi")
+	if preview := unknown.previewRequestChange(pod, pod.Spec.Containers[0], corev1.ResourceCPU, resource.MustParse("1")); preview != nil {
+		t.Errorf("preview %+v without policies, want none", preview)
+	}
+}
+
+func TestRightsizingAdmissionPreview(t *testing.T) {
+	tests := []struct {
+		name         string
+		limitRange   *corev1.LimitRange
+		wantAdmitted bool
+	}{
+		{name: "no limit range", wantAdmitted: true},
+		{
+			name:       "suggestion above the LimitRange max",
+			limitRange: &corev1.LimitRange{ObjectMeta: metav1.ObjectMeta{Name: "small", Namespace: "shop"}, Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{testLimitRangeItem(corev1.LimitTypeContainer, "", "100m")}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The pod predates the limit range; memory is fully used, so only
+			// CPU is flagged
+			objects := []runtime.Object{testPod("shop", "web", "node-1", "2", "1Gi"), testPodMetrics("shop", "web", "200m", "1Gi")}
+			if tt.limitRange != nil {
+				objects = append(objects, tt.limitRange)
+			}
+			co, _ := newTestOptimizer(t, objects...)
+
+			recommendations := co.analyzePods(context.Background())
+			if len(recommendations) != 1 {
+				t.Fatalf("%d recommendations, want one for CPU", len(recommendations))
+			}
+			admission := recommendations[0].Admission
+			if admission == nil || admission.Admitted != tt.wantAdmitted {
+				t.Fatalf("admission %+v, want admitted %v", admission, tt.wantAdmitted)
+			}
+			if !tt.wantAdmitted && !strings.Contains(strings.Join(admission.Reasons, "; "), "LimitRange small allows a Container cpu request of at most 100m") {
+				t.Errorf("reasons %q don't name the LimitRange max", admission.Reasons)
+			}
+		})
+	}
+}
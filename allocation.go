@@ -0,0 +1,71 @@
+package main
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// billedNodeHourlyCost is a node's hourly cost after the spot rate and any
+// reservation discount, with listPrice the on-demand cost it started from.
+// reserved is the result of reservedNodes for the node's cluster.
+func (co *CostOptimizer) billedNodeHourlyCost(node *corev1.Node, reserved map[string]float64) (listPrice, billed float64) {
+	listPrice, billed = co.effectiveNodeHourlyCost(node)
+	return listPrice, billed * (1 - reserved[node.Name]/100)
+}
+
+// nodeAllocation is the hourly cost of a node that is shared among its pods
+// by their requests
+type nodeAllocation struct {
+	hourlyCost  float64 // billed cost less what its GPUs are attributed
+	cpu, memory int64   // allocatable millicores and bytes
+	gpu         gpuInfo
+	gpuFactor   float64 // discount of the billed cost, applied to GPU attribution too
+}
+
+// nodeAllocations resolves the cost of every node for allocation to pods
+func (co *CostOptimizer) nodeAllocations(nodes []corev1.Node) map[string]nodeAllocation {
+	reserved := co.reservedNodes(nodes)
+	allocations := make(map[string]nodeAllocation, len(nodes))
+	for i := range nodes {
+		node := &nodes[i]
+		listPrice, billed := co.billedNodeHourlyCost(node, reserved)
+		factor := 1.0
+		if listPrice > 0 {
+			factor = billed / listPrice
+		}
+		gpu := nodeGPUInfo(node)
+		allocatable := node.Status.Allocatable
+		allocations[node.Name] = nodeAllocation{
+			hourlyCost: max(billed-co.costCalculator.gpuHourlyCost(gpu)*factor, 0),
+			cpu:        allocatable.Cpu().MilliValue(),
+			memory:     allocatable.Memory().Value(),
+			gpu:        gpu,
+			gpuFactor:  factor,
+		}
+	}
+	return allocations
+}
+
+// podHourlyCost is a pod's share of the node's cost: half by its share of
+// allocatable CPU and half by its share of allocatable memory, the same even
+// split the idle cost uses. Requests beyond allocatable are capped at the
+// whole node.
+func (a nodeAllocation) podHourlyCost(cpuRequest, memoryRequest int64) float64 {
+	share := 0.0
+	if a.cpu > 0 {
+		share += min(float64(cpuRequest)/float64(a.cpu), 1) / 2
+	}
+	if a.memory > 0 {
+		share += min(float64(memoryRequest)/float64(a.memory), 1) / 2
+	}
+	return a.hourlyCost * share
+}
+
+// requestMonthlyCost prices requests on the named node as their share of its
+// cost, falling back to flat per-resource rates when the node is unknown
+func (co *CostOptimizer) requestMonthlyCost(allocations map[string]nodeAllocation, nodeName string, cpu, memory resource.Quantity) float64 {
+	if allocation, ok := allocations[nodeName]; ok {
+		return allocation.podHourlyCost(cpu.MilliValue(), memory.Value()) * 24 * 30
+	}
+	return co.estimatePodCost(cpu, memory)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestPodCostAllocation(t *testing.T) {
+	tests := []struct {
+		name string
+		pods []*corev1.Pod
+		want map[string]float64 // pod -> share of the node's monthly cost
+	}{
+		{
+			name: "two equal pods split the node",
+			pods: []*corev1.Pod{testPod("shop", "web", "node-1", "2", "8Gi"), testPod("shop", "api", "node-1", "2", "8Gi")},
+			want: map[string]float64{"web": 0.5, "api": 0.5},
+		},
+		{
+			name: "split evenly between cpu and memory",
+			pods: []*corev1.Pod{testPod("shop", "web", "node-1", "1", "8Gi")},
+			want: map[string]float64{"web": (0.25 + 0.5) / 2},
+		},
+		{
+			name: "requests beyond allocatable cap at the node",
+			pods: []*corev1.Pod{testPod("shop", "web", "node-1", "8", "32Gi")},
+			want: map[string]float64{"web": 1},
+		},
+		{
+			name: "unscheduled pod is skipped",
+			pods: []*corev1.Pod{testPod("shop", "web", "node-1", "2", "8Gi"), testPod("shop", "pending", "", "2", "8Gi")},
+			want: map[string]float64{"web": 0.5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := testNode("node-1", "4", "16Gi")
+			objects := []runtime.Object{node, testNodeMetrics("node-1", "1", "4Gi")}
+			for _, pod := range tt.pods {
+				objects = append(objects, pod, testPodMetrics(pod.Namespace, pod.Name, "100m", "128Mi"))
+			}
+			co, _ := newTestOptimizer(t, objects...)
+			nodeCost := co.nodeHourlyCost(node) * 24 * 30
+
+			got := co.getPodMetrics(context.Background(), "")
+			if len(got) != len(tt.want) {
+				t.Fatalf("got metrics for %d pods, want %d", len(got), len(tt.want))
+			}
+			for _, m := range got {
+				share, ok := tt.want[m.Name]
+				if !ok {
+					t.Errorf("unexpected metrics for pod %s", m.Name)
+					continue
+				}
+				if math.Abs(m.EstimatedCost-nodeCost*share) > 1e-9 {
+					t.Errorf("pod %s costs %v, want %v of the node's %v", m.Name, m.EstimatedCost, share, nodeCost)
+				}
+			}
+		})
+	}
+}
+
+func TestPodCostAllocationDiscounts(t *testing.T) {
+	spot := reservationNode("node-1", 10)
+	spot.Labels["node.kubernetes.io/capacity-type"] = "spot"
+	tests := []struct {
+		name         string
+		node         *corev1.Node
+		reservations []Reservation
+		want         func(co *CostOptimizer) float64 // the node's billed hourly cost
+	}{
+		{name: "on demand", node: reservationNode("node-1", 10), want: func(*CostOptimizer) float64 { return 0.192 }},
+		{name: "spot", node: spot, want: func(co *CostOptimizer) float64 { return 0.192 * co.spotPriceFactor }},
+		{
+			name:         "reserved",
+			node:         reservationNode("node-1", 10),
+			reservations: []Reservation{{InstanceType: "m5.xlarge", Count: 1, DiscountPercent: 40}},
+			want:         func(*CostOptimizer) float64 { return 0.192 * 0.6 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t, tt.node, testPod("shop", "web", "node-1", "2", "8Gi"), testPodMetrics("shop", "web", "100m", "128Mi"))
+			co.reservations = tt.reservations
+
+			got := co.getPodMetrics(context.Background(), "")
+			if len(got) != 1 {
+				t.Fatalf("got metrics for %d pods, want 1", len(got))
+			}
+			if want := tt.want(co) * 24 * 30 / 2; math.Abs(got[0].EstimatedCost-want) > 1e-9 {
+				t.Errorf("pod costs %v, want half the billed node, %v", got[0].EstimatedCost, want)
+			}
+		})
+	}
+}
+
+// TestRightsizingSavingsAtNodeRate checks that the requests freed by
+// rightsizing are priced as their share of the node the pod runs on
+func TestRightsizingSavingsAtNodeRate(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     string // the pod is scheduled on
+		nodeCPU  string // allocatable CPU of node-1, the only node listed
+		wantRate func(co *CostOptimizer) float64
+	}{
+		// A $1/hour node: half its cost is spread over its allocatable CPU
+		{name: "small node", node: "node-1", nodeCPU: "4", wantRate: func(*CostOptimizer) float64 { return 24 * 30 / 4.0 / 2 }},
+		{name: "large node", node: "node-1", nodeCPU: "16", wantRate: func(*CostOptimizer) float64 { return 24 * 30 / 16.0 / 2 }},
+		{
+			name: "unknown node",
+			node: "node-2", nodeCPU: "4",
+			wantRate: func(co *CostOptimizer) float64 { return co.estimatePodCost(resource.MustParse("1"), resource.Quantity{}) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t,
+				testNode("node-1", tt.nodeCPU, "16Gi"),
+				testPod("shop", "web", tt.node, "2", "1Gi"), testPodMetrics("shop", "web", "100m", "1Gi"),
+			)
+			co.costCalculator.NodeCostPerHour = map[string]float64{"default": 1}
+
+			recommendations := co.analyzePods(context.Background())
+			if len(recommendations) != 1 {
+				t.Fatalf("got %+v, want one CPU recommendation", recommendations)
+			}
+			suggested, _ := co.rounding.cpuRequest(100)
+			want := float64(2000-suggested.MilliValue()) / 1000 * tt.wantRate(co)
+			if got := recommendations[0].Savings; math.Abs(got-want) > 1e-9 {
+				t.Errorf("savings %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// streamTokenParam carries the token on /api/stream, since a browser's
+// EventSource can't set an Authorization header
+const streamTokenParam = "access_token"
+
+// bearerAuth requires "Authorization: Bearer <token>" on every request except
+// the probe endpoints. /api/stream also accepts the token as the
+// access_token query parameter.
+func bearerAuth(token string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if probeEndpoints[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok && r.URL.Path == "/api/stream" {
+				presented = r.URL.Query().Get(streamTokenParam)
+				ok = presented != ""
+			}
+			if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="optimkube"`)
+				http.Error(w, "missing or invalid bearer token", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
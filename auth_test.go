@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestBearerAuth(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string // OPTIMKUBE_API_TOKEN
+		path          string
+		authorization string
+		want          int
+	}{
+		{name: "missing token", token: "secret", path: "/api/recommendations", want: http.StatusUnauthorized},
+		{name: "wrong token", token: "secret", path: "/api/recommendations", authorization: "Bearer wrong", want: http.StatusUnauthorized},
+		{name: "token prefix", token: "secret", path: "/api/recommendations", authorization: "Bearer secre", want: http.StatusUnauthorized},
+		{name: "not a bearer token", token: "secret", path: "/api/recommendations", authorization: "Basic secret", want: http.StatusUnauthorized},
+		{name: "correct token", token: "secret", path: "/api/recommendations", authorization: "Bearer secret", want: http.StatusOK},
+		{name: "query token off the stream", token: "secret", path: "/api/recommendations?access_token=secret", want: http.StatusUnauthorized},
+		{name: "health without token", token: "secret", path: "/health", want: http.StatusOK},
+		{name: "auth disabled", path: "/api/recommendations", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OPTIMKUBE_API_TOKEN", tt.token)
+			co, _ := newTestOptimizer(t)
+
+			header := make([]string, 0, 2)
+			if tt.authorization != "" {
+				header = append(header, "Authorization", tt.authorization)
+			}
+			rec := serve(co.newRouter(), http.MethodGet, tt.path, nil, header...)
+			if rec.Code != tt.want {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
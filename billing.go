@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Node billing models. Monthly assumes every node runs the whole month;
+// per-second charges young nodes only for the time they've existed, with the
+// one-minute minimum providers apply to each instance.
+const (
+	billingMonthly   = "monthly"
+	billingPerSecond = "per-second"
+)
+
+const (
+	billingMonth   = 30 * 24 * time.Hour
+	billingMinimum = time.Minute
+)
+
+func validateBillingMode(mode string) error {
+	switch mode {
+	case billingMonthly, billingPerSecond:
+		return nil
+	}
+	return fmt.Errorf("invalid OPTIMKUBE_NODE_BILLING %q: expected %s or %s", mode, billingMonthly, billingPerSecond)
+}
+
+// nodeMonthlyCost is a node's cost over the month at hourlyCost. Under
+// per-second billing a node younger than a month, typically one the
+// autoscaler added, is charged only for its age so far, and prorated is true.
+func (co *CostOptimizer) nodeMonthlyCost(node *corev1.Node, hourlyCost float64, now time.Time) (cost float64, prorated bool) {
+	monthly := hourlyCost * 24 * 30
+	if co.nodeBilling != billingPerSecond || node.CreationTimestamp.IsZero() {
+		return monthly, false
+	}
+	age := now.Sub(node.CreationTimestamp.Time)
+	if age >= billingMonth {
+		return monthly, false
+	}
+	billed := max(age, billingMinimum)
+	return hourlyCost * billed.Seconds() / 3600, true
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodeMonthlyCost(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	const hourly = 0.5
+	month := hourly * 24 * 30
+	tests := []struct {
+		name         string
+		billing      string
+		age          time.Duration // zero leaves the creation timestamp unset
+		want         float64
+		wantProrated bool
+	}{
+		{name: "monthly ignores age", billing: billingMonthly, age: 3 * 24 * time.Hour, want: month},
+		{name: "three days old", billing: billingPerSecond, age: 3 * 24 * time.Hour, want: hourly * 72, wantProrated: true},
+		{name: "one-minute minimum", billing: billingPerSecond, age: 10 * time.Second, want: hourly / 60, wantProrated: true},
+		{name: "older than a month", billing: billingPerSecond, age: 45 * 24 * time.Hour, want: month},
+		{name: "no creation timestamp", billing: billingPerSecond, want: month},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t)
+			co.nodeBilling = tt.billing
+			node := testNode("node-1", "4", "16Gi")
+			if tt.age > 0 {
+				node.CreationTimestamp = metav1.NewTime(now.Add(-tt.age))
+			}
+			got, prorated := co.nodeMonthlyCost(node, hourly, now)
+			if math.Abs(got-tt.want) > 1e-9 || prorated != tt.wantProrated {
+				t.Errorf("nodeMonthlyCost = %v, %v, want %v, %v", got, prorated, tt.want, tt.wantProrated)
+			}
+		})
+	}
+}
+
+// TestProratedNodeMetrics checks that a node the autoscaler added three days
+// ago reports three days of cost rather than a month's
+func TestProratedNodeMetrics(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	young := testNode("node-young", "4", "16Gi")
+	young.CreationTimestamp = metav1.NewTime(now.Add(-3 * 24 * time.Hour))
+	old := testNode("node-old", "4", "16Gi")
+	old.CreationTimestamp = metav1.NewTime(now.Add(-90 * 24 * time.Hour))
+	co, _ := newTestOptimizer(t, young, old,
+		testNodeMetrics("node-young", "1", "4Gi"),
+		testNodeMetrics("node-old", "1", "4Gi"))
+	co.nodeBilling = billingPerSecond
+	co.now = func() time.Time { return now }
+
+	byName := make(map[string]NodeMetrics)
+	for _, m := range co.getNodeMetrics(context.Background()) {
+		byName[m.Name] = m
+	}
+	hourly := co.nodeHourlyCost(young)
+	if got := byName["node-young"]; !got.Prorated || math.Abs(got.EstimatedCost-hourly*72) > 1e-9 {
+		t.Errorf("young node cost %.2f prorated %v, want %.2f prorated", got.EstimatedCost, got.Prorated, hourly*72)
+	}
+	if got := byName["node-old"]; got.Prorated || math.Abs(got.EstimatedCost-hourly*24*30) > 1e-9 {
+		t.Errorf("old node cost %.2f prorated %v, want %.2f", got.EstimatedCost, got.Prorated, hourly*24*30)
+	}
+}
+
+func TestNodeBillingSetting(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: billingMonthly},
+		{value: "monthly", want: billingMonthly},
+		{value: "per-second", want: billingPerSecond},
+		{value: "hourly", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("DEMO_MODE", "true")
+			t.Setenv("OPTIMKUBE_NODE_BILLING", tt.value)
+			co, err := NewCostOptimizer()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCostOptimizer error %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && co.nodeBilling != tt.want {
+				t.Errorf("billing %q, want %q", co.nodeBilling, tt.want)
+			}
+		})
+	}
+}
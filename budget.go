@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// workloadScope selects pods either by namespace or by label selector
+type workloadScope struct {
+	Namespace string `json:"namespace,omitempty"`
+	Selector  string `json:"selector,omitempty"`
+
+	selector labels.Selector
+}
+
+func (s *workloadScope) validate() error {
+	if (s.Namespace == "") == (s.Selector == "") {
+		return errors.New("exactly one of namespace or selector must be set")
+	}
+	if s.Selector != "" {
+		selector, err := labels.Parse(s.Selector)
+		if err != nil {
+			return fmt.Errorf("invalid selector: %w", err)
+		}
+		s.selector = selector
+	}
+	return nil
+}
+
+func (s *workloadScope) matches(pod PodMetrics) bool {
+	if s.Namespace != "" {
+		return pod.Namespace == s.Namespace
+	}
+	return s.selector.Matches(labels.Set(pod.Labels))
+}
+
+// Budget caps the projected monthly cost of a namespace or of every pod
+// matching a label selector.
+type Budget struct {
+	Name string `json:"name"`
+	workloadScope
+	MonthlyLimit float64 `json:"monthly_limit"`
+}
+
+func (b *Budget) validate() error {
+	if b.Name == "" {
+		return errors.New("name is required")
+	}
+	if err := b.workloadScope.validate(); err != nil {
+		return err
+	}
+	if b.MonthlyLimit <= 0 {
+		return errors.New("monthly_limit must be positive")
+	}
+	return nil
+}
+
+// checkBudgets compares the projected monthly cost of each budget's pods
+// against its limit. Every breached budget yields a recommendation on each
+// scan, but operators are only notified when a budget first goes over.
+func (co *CostOptimizer) checkBudgets(ctx context.Context) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+	if len(co.budgets) == 0 {
+		return recommendations
+	}
+
+	pods := co.getPodMetrics(ctx, "")
+
+	// Notifications go out after budgetMu is released, so a slow webhook
+	// doesn't hold the lock
+	var notifications []Notification
+	co.budgetMu.Lock()
+	breached := make(map[string]bool)
+	for i := range co.budgets {
+		budget := &co.budgets[i]
+
+		var projected float64
+		for _, pod := range pods {
+			if budget.matches(pod) {
+				projected += pod.EstimatedCost
+			}
+		}
+		if projected <= budget.MonthlyLimit {
+			continue
+		}
+
+		breached[budget.Name] = true
+		description := fmt.Sprintf("Budget %s is projected at $%.2f/month, exceeding its $%.2f limit by $%.2f", budget.Name, projected, budget.MonthlyLimit, projected-budget.MonthlyLimit)
+		recommendations = append(recommendations, Recommendation{
+			Type:        "budget_breach",
+			Category:    CategoryConfigure,
+			Resource:    budget.Name,
+			Namespace:   budget.Namespace,
+			Description: description,
+			Impact:      "Review the workloads covered by this budget or raise the limit",
+			Priority:    "high",
+			Timestamp:   time.Now(),
+		})
+
+		if !co.breachedBudgets[budget.Name] {
+			notifications = append(notifications, Notification{
+				Title:    fmt.Sprintf("Budget exceeded: %s", budget.Name),
+				Text:     description,
+				Priority: "high",
+			})
+		}
+	}
+	co.breachedBudgets = breached
+	co.budgetMu.Unlock()
+
+	for _, notification := range notifications {
+		co.notify(ctx, notification)
+	}
+	return recommendations
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingNotifier keeps the notifications sent to it
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []Notification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+func (n *recordingNotifier) titles() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	titles := make([]string, len(n.sent))
+	for i, notification := range n.sent {
+		titles[i] = notification.Title
+	}
+	return titles
+}
+
+// newBudget validates a budget as loading the config file does
+func newBudget(t *testing.T, budget Budget) Budget {
+	t.Helper()
+	if err := budget.validate(); err != nil {
+		t.Fatalf("budget %s: %v", budget.Name, err)
+	}
+	return budget
+}
+
+func TestCheckBudgets(t *testing.T) {
+	api := testPod("team", "api", "node-1", "2", "4Gi")
+	api.Labels = map[string]string{"cost-center": "search"}
+	co, _ := newTestOptimizer(t,
+		testNode("node-1", "8", "32Gi"), testNodeMetrics("node-1", "1", "4Gi"),
+		api, testPodMetrics("team", "api", "1", "2Gi"),
+		testPod("other", "batch", "node-1", "1", "1Gi"), testPodMetrics("other", "batch", "1", "1Gi"),
+	)
+	// api requests a quarter of the node's CPU and an eighth of its memory
+	cost := co.nodeHourlyCost(testNode("node-1", "8", "32Gi")) * 24 * 30 * (1.0/4 + 1.0/8) / 2
+
+	tests := []struct {
+		name     string
+		budget   Budget
+		breached bool
+	}{
+		{name: "namespace over budget", budget: Budget{Name: "team", workloadScope: workloadScope{Namespace: "team"}, MonthlyLimit: cost / 2}, breached: true},
+		{name: "namespace within budget", budget: Budget{Name: "team", workloadScope: workloadScope{Namespace: "team"}, MonthlyLimit: cost * 2}},
+		{name: "selector over budget", budget: Budget{Name: "search", workloadScope: workloadScope{Selector: "cost-center=search"}, MonthlyLimit: cost / 2}, breached: true},
+		{name: "selector matching nothing", budget: Budget{Name: "ads", workloadScope: workloadScope{Selector: "cost-center=ads"}, MonthlyLimit: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := &recordingNotifier{}
+			co.notifier = notifier
+			co.budgets = []Budget{newBudget(t, tt.budget)}
+			co.breachedBudgets = nil
+
+			recommendations := co.checkBudgets(context.Background())
+			if !tt.breached {
+				if len(recommendations) != 0 || len(notifier.titles()) != 0 {
+					t.Errorf("got %+v and notifications %v, want no breach", recommendations, notifier.titles())
+				}
+				return
+			}
+			if len(recommendations) != 1 {
+				t.Fatalf("got %d recommendations, want 1", len(recommendations))
+			}
+			rec := recommendations[0]
+			if rec.Type != "budget_breach" || rec.Priority != "high" || rec.Resource != tt.budget.Name {
+				t.Errorf("got a %s %s recommendation for %s, want a high budget_breach for %s", rec.Priority, rec.Type, rec.Resource, tt.budget.Name)
+			}
+			if got := notifier.titles(); len(got) != 1 || got[0] != "Budget exceeded: "+tt.budget.Name {
+				t.Errorf("notifications = %v, want one for %s", got, tt.budget.Name)
+			}
+		})
+	}
+}
+
+// TestBudgetNotifiesOncePerBreach keeps recommending a breached budget every
+// scan, but notifies only when it goes over, again after it recovers
+func TestBudgetNotifiesOncePerBreach(t *testing.T) {
+	co, _ := newTestOptimizer(t,
+		testNode("node-1", "8", "32Gi"), testNodeMetrics("node-1", "1", "4Gi"),
+		testPod("team", "api", "node-1", "2", "4Gi"), testPodMetrics("team", "api", "1", "2Gi"),
+	)
+	notifier := &recordingNotifier{}
+	co.notifier = notifier
+	over := newBudget(t, Budget{Name: "team", workloadScope: workloadScope{Namespace: "team"}, MonthlyLimit: 0.01})
+	under := newBudget(t, Budget{Name: "team", workloadScope: workloadScope{Namespace: "team"}, MonthlyLimit: 1e6})
+
+	for i, budget := range []Budget{over, over, under, over} {
+		co.budgets = []Budget{budget}
+		co.checkBudgets(context.Background())
+		if i == 1 && len(notifier.titles()) != 1 {
+			t.Fatalf("notified %d times for one breach, want once", len(notifier.titles()))
+		}
+	}
+	if got := len(notifier.titles()); got != 2 {
+		t.Errorf("notified %d times for two breaches, want 2", got)
+	}
+}
+
+// lockCheckingNotifier records whether budgetMu was free while it notified
+type lockCheckingNotifier struct {
+	co     *CostOptimizer
+	locked []bool
+}
+
+func (n *lockCheckingNotifier) Notify(ctx context.Context, notification Notification) error {
+	free := n.co.budgetMu.TryLock()
+	if free {
+		n.co.budgetMu.Unlock()
+	}
+	n.locked = append(n.locked, !free)
+	return nil
+}
+
+func TestBudgetNotifiesWithoutLock(t *testing.T) {
+	co, _ := newTestOptimizer(t,
+		testNode("node-1", "8", "32Gi"), testNodeMetrics("node-1", "1", "4Gi"),
+		testPod("team", "api", "node-1", "2", "4Gi"), testPodMetrics("team", "api", "1", "2Gi"),
+		testPod("search", "index", "node-1", "2", "4Gi"), testPodMetrics("search", "index", "1", "2Gi"),
+	)
+	notifier := &lockCheckingNotifier{co: co}
+	co.notifier = notifier
+	co.budgets = []Budget{
+		newBudget(t, Budget{Name: "team", workloadScope: workloadScope{Namespace: "team"}, MonthlyLimit: 0.01}),
+		newBudget(t, Budget{Name: "search", workloadScope: workloadScope{Namespace: "search"}, MonthlyLimit: 0.01}),
+	}
+
+	co.checkBudgets(context.Background())
+	if len(notifier.locked) != 2 {
+		t.Fatalf("sent %d notifications, want one per breached budget", len(notifier.locked))
+	}
+	for i, locked := range notifier.locked {
+		if locked {
+			t.Errorf("notification %d sent while holding budgetMu", i)
+		}
+	}
+}
+
+func TestLoadFileConfigBudgets(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{name: "namespace budget", config: "budgets:\n- name: team\n  namespace: team\n  monthly_limit: 5000\n"},
+		{name: "selector budget", config: "budgets:\n- name: search\n  selector: cost-center in (search, ads)\n  monthly_limit: 5000\n"},
+		{name: "namespace and selector", config: "budgets:\n- name: team\n  namespace: team\n  selector: app=web\n  monthly_limit: 5000\n", wantErr: "exactly one of namespace or selector"},
+		{name: "no limit", config: "budgets:\n- name: team\n  namespace: team\n", wantErr: "monthly_limit must be positive"},
+		{name: "bad selector", config: "budgets:\n- name: team\n  selector: 'app in web'\n  monthly_limit: 5000\n", wantErr: "invalid selector"},
+		{name: "unknown field", config: "budgets:\n- name: team\n  namespace: team\n  monthly_limt: 5000\n", wantErr: "monthly_limt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadFileConfig(path)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("loadFileConfig: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("loadFileConfig error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
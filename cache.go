@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// defaultListCacheTTL is how long a cluster-wide list is reused. A scan runs
+// every analyzer within it, so each list is fetched about once per scan
+// rather than once per analyzer, and handlers share the scan's lists.
+const defaultListCacheTTL = 30 * time.Second
+
+// cachedList holds one list result. The mutex is held while fetching, so
+// concurrent callers wait for a single request instead of each sending one.
+type cachedList[T any] struct {
+	mu      sync.Mutex
+	value   T
+	fetched time.Time
+}
+
+// get returns the cached value while it's younger than ttl and was fetched
+// after notBefore, and fetches it otherwise. Errors aren't cached.
+func (c *cachedList[T]) get(ctx context.Context, now time.Time, ttl time.Duration, notBefore time.Time, fetch func(context.Context) (T, error)) (T, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.fetched.IsZero() && now.Sub(c.fetched) < ttl && !c.fetched.Before(notBefore) {
+		return c.value, nil
+	}
+	value, err := fetch(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	c.value, c.fetched = value, now
+	return value, nil
+}
+
+// listCache fronts the cluster-wide node, pod and metrics lists. The lists it
+// returns are shared between callers and must be treated as read-only.
+type listCache struct {
+	ttl time.Duration // 0 disables caching
+
+	mu        sync.Mutex
+	notBefore time.Time // lists fetched before this are stale
+
+	nodes       cachedList[*corev1.NodeList]
+	pods        cachedList[*corev1.PodList]
+	nodeMetrics cachedList[*metricsv1beta1.NodeMetricsList]
+	podMetrics  cachedList[*metricsv1beta1.PodMetricsList]
+}
+
+// invalidate makes the next call for every list fetch it again
+func (c *listCache) invalidate(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notBefore = now
+}
+
+func (c *listCache) validAfter() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.notBefore
+}
+
+// listNodes lists every node, through the cache
+func (co *CostOptimizer) listNodes(ctx context.Context) (*corev1.NodeList, error) {
+	return co.listCache.nodes.get(ctx, co.now(), co.listCache.ttl, co.listCache.validAfter(), func(ctx context.Context) (*corev1.NodeList, error) {
+		return co.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	})
+}
+
+// listPods lists the pods of namespace. Only the cluster-wide list, which
+// every analyzer shares, is cached.
+func (co *CostOptimizer) listPods(ctx context.Context, namespace string) (*corev1.PodList, error) {
+	fetch := func(ctx context.Context) (*corev1.PodList, error) {
+		list, err := co.clientset.CoreV1().Pods(namespace).List(ctx, co.workloadListOptions())
+		if err != nil {
+			return nil, err
+		}
+		list.Items = inScope(co, list.Items)
+		return list, nil
+	}
+	if namespace != "" {
+		return fetch(ctx)
+	}
+	return co.listCache.pods.get(ctx, co.now(), co.listCache.ttl, co.listCache.validAfter(), fetch)
+}
+
+// listNodeMetrics lists the usage of every node, through the cache
+func (co *CostOptimizer) listNodeMetrics(ctx context.Context) (*metricsv1beta1.NodeMetricsList, error) {
+	return co.listCache.nodeMetrics.get(ctx, co.now(), co.listCache.ttl, co.listCache.validAfter(), func(ctx context.Context) (*metricsv1beta1.NodeMetricsList, error) {
+		list, err := co.metricsClient.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
+		co.metricsAPI.observeMetricsList(err)
+		return list, err
+	})
+}
+
+// listPodMetrics lists the usage of the pods of namespace. As with pods, only
+// the cluster-wide list is cached.
+func (co *CostOptimizer) listPodMetrics(ctx context.Context, namespace string) (*metricsv1beta1.PodMetricsList, error) {
+	fetch := func(ctx context.Context) (*metricsv1beta1.PodMetricsList, error) {
+		list, err := co.metricsClient.MetricsV1beta1().PodMetricses(namespace).List(ctx, co.workloadListOptions())
+		co.metricsAPI.observeMetricsList(err)
+		if err != nil {
+			return nil, err
+		}
+		list.Items = inScope(co, list.Items)
+		return list, nil
+	}
+	if namespace != "" {
+		return fetch(ctx)
+	}
+	return co.listCache.podMetrics.get(ctx, co.now(), co.listCache.ttl, co.listCache.validAfter(), fetch)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+func TestListCache(t *testing.T) {
+	start := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		ttl  time.Duration
+		// between runs between the two calls, on the optimizer and its clock
+		between func(co *CostOptimizer, now *time.Time)
+		want    int // lists sent for each kind over both calls
+	}{
+		{name: "within the ttl", ttl: 30 * time.Second, between: func(co *CostOptimizer, now *time.Time) { *now = now.Add(10 * time.Second) }, want: 1},
+		{name: "after the ttl", ttl: 30 * time.Second, between: func(co *CostOptimizer, now *time.Time) { *now = now.Add(30 * time.Second) }, want: 2},
+		{name: "caching disabled", between: func(*CostOptimizer, *time.Time) {}, want: 2},
+		{
+			name: "invalidated",
+			ttl:  30 * time.Second,
+			between: func(co *CostOptimizer, now *time.Time) {
+				*now = now.Add(time.Second)
+				co.listCache.invalidate(*now)
+			},
+			want: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, client := newTestOptimizer(t,
+				testNode("node-1", "4", "16Gi"), testNodeMetrics("node-1", "1", "4Gi"),
+				testPod("shop", "web", "node-1", "1", "1Gi"), testPodMetrics("shop", "web", "100m", "1Gi"),
+			)
+			metrics := co.metricsClient.(*metricsfake.Clientset)
+			now := start
+			co.now = func() time.Time { return now }
+			co.listCache.ttl = tt.ttl
+
+			ctx := context.Background()
+			for i := 0; i < 2; i++ {
+				if i == 1 {
+					tt.between(co, &now)
+				}
+				nodes, err := co.listNodes(ctx)
+				if err != nil || len(nodes.Items) != 1 {
+					t.Fatalf("listNodes = %v, %v; want node-1", nodes, err)
+				}
+				pods, err := co.listPods(ctx, "")
+				if err != nil || len(pods.Items) != 1 {
+					t.Fatalf("listPods = %v, %v; want web", pods, err)
+				}
+				nodeMetrics, err := co.listNodeMetrics(ctx)
+				if err != nil || len(nodeMetrics.Items) != 1 {
+					t.Fatalf("listNodeMetrics = %v, %v; want node-1", nodeMetrics, err)
+				}
+				podMetrics, err := co.listPodMetrics(ctx, "")
+				if err != nil || len(podMetrics.Items) != 1 {
+					t.Fatalf("listPodMetrics = %v, %v; want web", podMetrics, err)
+				}
+			}
+
+			lists := map[string]int{
+				"nodes":        countLists(client.Actions(), "nodes"),
+				"pods":         countLists(client.Actions(), "pods"),
+				"node metrics": countLists(metrics.Actions(), "nodes"),
+				"pod metrics":  countLists(metrics.Actions(), "pods"),
+			}
+			for kind, got := range lists {
+				if got != tt.want {
+					t.Errorf("listed %s %d times, want %d", kind, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestListCacheNamespacedPods(t *testing.T) {
+	co, client := newTestOptimizer(t, testPod("shop", "web", "node-1", "1", "1Gi"))
+	for i := 0; i < 2; i++ {
+		if _, err := co.listPods(context.Background(), "shop"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := countLists(client.Actions(), "pods"); got != 2 {
+		t.Errorf("listed pods %d times, want every namespaced list sent", got)
+	}
+}
+
+func TestListCacheSkipsErrors(t *testing.T) {
+	co, client := newTestOptimizer(t, testNode("node-1", "4", "16Gi"))
+	failures := 1
+	client.PrependReactor("list", "nodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if failures > 0 {
+			failures--
+			return true, nil, errors.New("connection refused")
+		}
+		return false, nil, nil
+	})
+
+	if _, err := co.listNodes(context.Background()); err == nil {
+		t.Fatal("first list succeeded, want the injected error")
+	}
+	nodes, err := co.listNodes(context.Background())
+	if err != nil || len(nodes.Items) != 1 {
+		t.Errorf("listNodes after an error = %v, %v; want node-1 fetched again", nodes, err)
+	}
+}
+
+// TestOptimizeRefreshesLists checks that POST /api/optimize scans the
+// cluster as it is, not as a cached list last saw it
+func TestOptimizeRefreshesLists(t *testing.T) {
+	co, client := newTestOptimizer(t, testNode("node-1", "4", "16Gi"))
+	co.listCache.ttl = time.Hour
+	if _, err := co.listNodes(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	serve(http.HandlerFunc(co.handleOptimize), http.MethodPost, "/api/optimize", nil)
+	deadline := time.Now().Add(time.Second)
+	for len(co.history.all()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no scan a second after /api/optimize")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := countLists(client.Actions(), "nodes"); got < 2 {
+		t.Errorf("listed nodes %d times, want the list fetched again after /api/optimize", got)
+	}
+}
+
+func TestListCacheTTLSetting(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "", want: defaultListCacheTTL},
+		{value: "0s", want: 0},
+		{value: "2m", want: 2 * time.Minute},
+		{value: "-1s", wantErr: true},
+		{value: "brief", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("DEMO_MODE", "true")
+			t.Setenv("OPTIMKUBE_LIST_CACHE_TTL", tt.value)
+			co, err := NewCostOptimizer()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCostOptimizer error %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && co.listCache.ttl != tt.want {
+				t.Errorf("ttl %v, want %v", co.listCache.ttl, tt.want)
+			}
+		})
+	}
+}
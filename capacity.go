@@ -0,0 +1,72 @@
+package main
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// UnallocatedCapacity is node allocatable capacity that no pod requests: the
+// slack the cluster pays for regardless of how busy its pods are
+type UnallocatedCapacity struct {
+	CPUCores    float64
+	MemoryGB    float64
+	MonthlyCost float64
+}
+
+// podRequests returns what the scheduler reserves for a pod: the larger of its
+// containers' summed requests and its largest init container request, plus
+// pod overhead
+func podRequests(pod *corev1.Pod, resourceName corev1.ResourceName) int64 {
+	value := func(list corev1.ResourceList) int64 {
+		quantity := list[resourceName]
+		if resourceName == corev1.ResourceCPU {
+			return quantity.MilliValue()
+		}
+		return quantity.Value()
+	}
+
+	var total int64
+	for _, container := range pod.Spec.Containers {
+		total += value(container.Resources.Requests)
+	}
+	for _, container := range pod.Spec.InitContainers {
+		total = max(total, value(container.Resources.Requests))
+	}
+	return total + value(pod.Spec.Overhead)
+}
+
+// unallocatedCapacity prices each node's allocatable capacity left over after
+// the requests of the pods scheduled on it. A node's cost is split evenly
+// between CPU and memory, so a node with half its CPU and all of its memory
+// requested counts a quarter of its cost as unallocated.
+func (co *CostOptimizer) unallocatedCapacity(nodes []corev1.Node, pods []corev1.Pod) UnallocatedCapacity {
+	requestedCPU := make(map[string]int64)
+	requestedMemory := make(map[string]int64)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		requestedCPU[pod.Spec.NodeName] += podRequests(pod, corev1.ResourceCPU)
+		requestedMemory[pod.Spec.NodeName] += podRequests(pod, corev1.ResourceMemory)
+	}
+
+	var unallocated UnallocatedCapacity
+	for i := range nodes {
+		node := &nodes[i]
+		allocatableCPU := node.Status.Allocatable.Cpu().MilliValue()
+		allocatableMemory := node.Status.Allocatable.Memory().Value()
+		if allocatableCPU == 0 || allocatableMemory == 0 {
+			continue
+		}
+
+		freeCPU := max(allocatableCPU-requestedCPU[node.Name], 0)
+		freeMemory := max(allocatableMemory-requestedMemory[node.Name], 0)
+		unallocated.CPUCores += float64(freeCPU) / 1000
+		unallocated.MemoryGB += float64(freeMemory) / (1024 * 1024 * 1024)
+
+		freeShare := (float64(freeCPU)/float64(allocatableCPU) + float64(freeMemory)/float64(allocatableMemory)) / 2
+		_, hourlyCost := co.effectiveNodeHourlyCost(node)
+		unallocated.MonthlyCost += hourlyCost * freeShare * 24 * 30
+	}
+	return unallocated
+}
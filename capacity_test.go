@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestPodRequests(t *testing.T) {
+	requests := func(cpu string) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}}
+	}
+	tests := []struct {
+		name     string
+		init     []string
+		sidecars []string
+		overhead string
+		want     int64 // millicores
+	}{
+		{name: "one container", want: 500},
+		{name: "containers summed", sidecars: []string{"250m", "100m"}, want: 850},
+		{name: "small init container", init: []string{"200m"}, want: 500},
+		{name: "large init container", init: []string{"2", "1"}, want: 2000},
+		{name: "pod overhead", overhead: "50m", want: 550},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := testPod("shop", "web", "node-1", "500m", "1Gi")
+			for _, cpu := range tt.sidecars {
+				pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: "sidecar", Resources: requests(cpu)})
+			}
+			for _, cpu := range tt.init {
+				pod.Spec.InitContainers = append(pod.Spec.InitContainers, corev1.Container{Name: "init", Resources: requests(cpu)})
+			}
+			if tt.overhead != "" {
+				pod.Spec.Overhead = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(tt.overhead)}
+			}
+
+			if got := podRequests(pod, corev1.ResourceCPU); got != tt.want {
+				t.Errorf("podRequests = %dm, want %dm", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnallocatedCapacity(t *testing.T) {
+	finished := testPod("batch", "done", "node-1", "4", "16Gi")
+	finished.Status.Phase = corev1.PodSucceeded
+	pending := testPod("batch", "queued", "", "4", "16Gi")
+
+	tests := []struct {
+		name        string
+		nodes       []*corev1.Node
+		pods        []*corev1.Pod
+		wantCPU     float64 // cores
+		wantMemory  float64 // GB
+		wantMonthly float64
+	}{
+		{
+			name:        "empty node",
+			nodes:       []*corev1.Node{testNode("node-1", "4", "16Gi")},
+			wantCPU:     4,
+			wantMemory:  16,
+			wantMonthly: 720,
+		},
+		{
+			name:        "half the CPU and all the memory requested",
+			nodes:       []*corev1.Node{testNode("node-1", "4", "16Gi")},
+			pods:        []*corev1.Pod{testPod("shop", "web", "node-1", "2", "16Gi")},
+			wantCPU:     2,
+			wantMonthly: 180,
+		},
+		{
+			name:        "overcommitted node counts no slack",
+			nodes:       []*corev1.Node{testNode("node-1", "4", "16Gi"), testNode("node-2", "4", "16Gi")},
+			pods:        []*corev1.Pod{testPod("shop", "web", "node-1", "6", "20Gi"), testPod("shop", "api", "node-2", "1", "4Gi")},
+			wantCPU:     3,
+			wantMemory:  12,
+			wantMonthly: 540,
+		},
+		{
+			name:        "finished and unscheduled pods ignored",
+			nodes:       []*corev1.Node{testNode("node-1", "4", "16Gi")},
+			pods:        []*corev1.Pod{finished, pending, testPod("shop", "web", "node-1", "1", "4Gi")},
+			wantCPU:     3,
+			wantMemory:  12,
+			wantMonthly: 540,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t)
+			co.costCalculator.NodeCostPerHour = map[string]float64{"default": 1}
+			nodes := make([]corev1.Node, 0, len(tt.nodes))
+			for _, node := range tt.nodes {
+				nodes = append(nodes, *node)
+			}
+			pods := make([]corev1.Pod, 0, len(tt.pods))
+			for _, pod := range tt.pods {
+				pods = append(pods, *pod)
+			}
+
+			got := co.unallocatedCapacity(nodes, pods)
+			if math.Abs(got.CPUCores-tt.wantCPU) > 1e-9 || math.Abs(got.MemoryGB-tt.wantMemory) > 1e-9 || math.Abs(got.MonthlyCost-tt.wantMonthly) > 1e-6 {
+				t.Errorf("unallocated %+v, want %v cores, %v GB, %v a month", got, tt.wantCPU, tt.wantMemory, tt.wantMonthly)
+			}
+		})
+	}
+}
+
+func TestCostSummaryUnallocatedCost(t *testing.T) {
+	co, _ := newTestOptimizer(t,
+		testNode("node-1", "4", "16Gi"), testNodeMetrics("node-1", "3", "12Gi"),
+		testPod("shop", "web", "node-1", "1", "8Gi"), testPodMetrics("shop", "web", "1", "8Gi"),
+	)
+	co.costCalculator.NodeCostPerHour = map[string]float64{"default": 1}
+
+	summary := co.generateCostSummary(context.Background())
+	// 3 of 4 cores and 8 of 16Gi are free: (0.75 + 0.5) / 2 of $720
+	if summary.UnallocatedCPU != 3 || summary.UnallocatedMemory != 8 || math.Abs(summary.UnallocatedCost-450) > 1e-6 {
+		t.Errorf("unallocated %v cores, %v GB, $%v; want 3 cores, 8 GB, $450", summary.UnallocatedCPU, summary.UnallocatedMemory, summary.UnallocatedCost)
+	}
+}
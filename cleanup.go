@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultCleanupTTL is how long a finished Job or pod, or a crashlooping pod,
+// may be left before it's reported for cleanup
+const defaultCleanupTTL = time.Hour
+
+// analyzeCleanup flags what is left behind once work is done or has stopped
+// making progress:
+//   - finished Jobs without a ttlSecondsAfterFinished, whose pods stay until
+//     the Job is deleted; Jobs a CronJob owns are trimmed by its history limits
+//   - finished pods no controller owns, which nothing will ever delete
+//   - pods in CrashLoopBackOff, which keep their requests reserved
+//
+// Finished pods no longer hold requested capacity, so only crashlooping pods
+// carry savings; the rest is clutter in listings and cost reports.
+func (co *CostOptimizer) analyzeCleanup(ctx context.Context) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	if co.demoMode || co.clientset == nil || co.analyzerDisabled("cleanup") {
+		return recommendations
+	}
+
+	jobs, err := co.clientset.BatchV1().Jobs("").List(ctx, co.workloadListOptions())
+	if err != nil {
+		co.analyzerListFailed("cleanup", "jobs", err)
+		return recommendations
+	}
+	jobs.Items = inScope(co, jobs.Items)
+	pods, err := co.listPods(ctx, "")
+	if err != nil {
+		co.analyzerListFailed("cleanup", "pods", err)
+		return recommendations
+	}
+
+	now := co.now()
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		finishedAt, finished := jobFinishedAt(job)
+		if !finished || job.Spec.TTLSecondsAfterFinished != nil || metav1.GetControllerOf(job) != nil {
+			continue
+		}
+		age := now.Sub(finishedAt)
+		if age < co.cleanupTTL {
+			continue
+		}
+		recommendations = append(recommendations, Recommendation{
+			Type:        "cleanup",
+			Category:    CategoryDelete,
+			Resource:    fmt.Sprintf("%s/%s", job.Namespace, job.Name),
+			Namespace:   job.Namespace,
+			Release:     helmRelease(job.Labels),
+			labels:      job.Labels,
+			Description: fmt.Sprintf("Job %s finished %s ago and its pods are still kept", job.Name, age.Round(time.Minute)),
+			Impact:      "Delete the Job, and set ttlSecondsAfterFinished so finished Jobs are removed automatically",
+			ActionHint:  &ActionHint{Verb: "delete", Target: hintTarget("job", job.Namespace, job.Name)},
+			Priority:    "low",
+			Timestamp:   time.Now(),
+		})
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		age := now.Sub(pod.CreationTimestamp.Time)
+
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			// A controller deletes its own finished pods, and a Job's are
+			// covered by the Job above
+			finishedAt, ok := podFinishedAt(pod)
+			if metav1.GetControllerOf(pod) != nil || !ok || now.Sub(finishedAt) < co.cleanupTTL {
+				continue
+			}
+			recommendations = append(recommendations, Recommendation{
+				Type:        "cleanup",
+				Category:    CategoryDelete,
+				Resource:    fmt.Sprintf("%s/%s", pod.Namespace, pod.Name),
+				Namespace:   pod.Namespace,
+				Release:     helmRelease(pod.Labels),
+				labels:      pod.Labels,
+				Description: fmt.Sprintf("Pod %s finished (%s) %s ago and has no controller to remove it", pod.Name, pod.Status.Phase, now.Sub(finishedAt).Round(time.Minute)),
+				Impact:      "Delete the finished pod",
+				ActionHint:  &ActionHint{Verb: "delete", Target: hintTarget("pod", pod.Namespace, pod.Name)},
+				Priority:    "low",
+				Timestamp:   time.Now(),
+			})
+			continue
+		}
+
+		container := crashLoopingContainer(pod)
+		if container == "" || age < co.cleanupTTL {
+			continue
+		}
+		cpu := resource.NewMilliQuantity(podRequests(pod, corev1.ResourceCPU), resource.DecimalSI)
+		memory := resource.NewQuantity(podRequests(pod, corev1.ResourceMemory), resource.BinarySI)
+		rec := Recommendation{
+			Type:        "cleanup",
+			Category:    CategoryDelete,
+			Resource:    fmt.Sprintf("%s/%s", pod.Namespace, pod.Name),
+			Namespace:   pod.Namespace,
+			Release:     helmRelease(pod.Labels),
+			labels:      pod.Labels,
+			Description: fmt.Sprintf("Container %s of pod %s is in CrashLoopBackOff, holding %s CPU and %s memory it doesn't use", container, pod.Name, cpu.String(), memory.String()),
+			Savings:     co.estimatePodCost(*cpu, *memory),
+			Priority:    "medium",
+			Timestamp:   time.Now(),
+		}
+		// Deleting a controlled pod only gets it recreated; the fix belongs
+		// to its workload
+		if metav1.GetControllerOf(pod) != nil {
+			rec.Impact = fmt.Sprintf("Fix the crash or scale %s to zero to release its requests", podWorkload(pod))
+		} else {
+			rec.Impact = "Fix the crash or delete the pod to release its requests"
+			rec.ActionHint = &ActionHint{Verb: "delete", Target: hintTarget("pod", pod.Namespace, pod.Name)}
+		}
+		recommendations = append(recommendations, rec)
+	}
+
+	return recommendations
+}
+
+// jobFinishedAt returns when a Job completed or failed, and whether it has
+// finished at all
+func jobFinishedAt(job *batchv1.Job) (time.Time, bool) {
+	for _, condition := range job.Status.Conditions {
+		if (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) && condition.Status == corev1.ConditionTrue {
+			return condition.LastTransitionTime.Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// podFinishedAt returns when the last of a finished pod's containers exited,
+// falling back to its start time when no container ever ran
+func podFinishedAt(pod *corev1.Pod) (time.Time, bool) {
+	var finishedAt time.Time
+	for _, status := range pod.Status.ContainerStatuses {
+		if terminated := status.State.Terminated; terminated != nil && terminated.FinishedAt.After(finishedAt) {
+			finishedAt = terminated.FinishedAt.Time
+		}
+	}
+	if finishedAt.IsZero() && pod.Status.StartTime != nil {
+		finishedAt = pod.Status.StartTime.Time
+	}
+	return finishedAt, !finishedAt.IsZero()
+}
+
+// crashLoopingContainer names the first container of a pod waiting in
+// CrashLoopBackOff, or returns "" when none is
+func crashLoopingContainer(pod *corev1.Pod) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if waiting := status.State.Waiting; waiting != nil && waiting.Reason == "CrashLoopBackOff" {
+			return status.Name
+		}
+	}
+	return ""
+}
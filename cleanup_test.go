@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// finishedJob is a Job that completed finishedAgo before now
+func finishedJob(name string, now time.Time, finishedAgo time.Duration) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+		Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{
+			Type:               batchv1.JobComplete,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(now.Add(-finishedAgo)),
+		}}},
+	}
+}
+
+// finishedPod is a pod whose container exited finishedAgo before now
+func finishedPod(name string, now time.Time, finishedAgo time.Duration) *corev1.Pod {
+	pod := testPod("shop", name, "node-1", "500m", "1Gi")
+	pod.Status.Phase = corev1.PodSucceeded
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  "app",
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{FinishedAt: metav1.NewTime(now.Add(-finishedAgo))}},
+	}}
+	return pod
+}
+
+// crashLooping puts a pod's container in CrashLoopBackOff, created age
+// before now
+func crashLooping(pod *corev1.Pod, now time.Time, age time.Duration) *corev1.Pod {
+	pod.CreationTimestamp = metav1.NewTime(now.Add(-age))
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  "app",
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+	}}
+	return pod
+}
+
+func TestAnalyzeCleanup(t *testing.T) {
+	now := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	withTTL := finishedJob("report", now, 2*time.Hour)
+	withTTL.Spec.TTLSecondsAfterFinished = new(int32)
+	cronJobRun := finishedJob("report-28491", now, 2*time.Hour)
+	cronJobRun.OwnerReferences = []metav1.OwnerReference{{Kind: "CronJob", Name: "report", Controller: boolPtr(true)}}
+	running := testPod("shop", "web", "node-1", "500m", "1Gi")
+	running.CreationTimestamp = metav1.NewTime(now.Add(-48 * time.Hour))
+
+	tests := []struct {
+		name        string
+		object      runtime.Object
+		want        string // in the description; no recommendation when empty
+		wantHint    string // the hint's target, if it has one
+		wantSavings bool
+	}{
+		{name: "completed job", object: finishedJob("report", now, 2*time.Hour), want: "Job report finished 2h0m0s ago", wantHint: "job/shop/report"},
+		{name: "recently completed job", object: finishedJob("report", now, 10*time.Minute)},
+		{name: "job with a ttl", object: withTTL},
+		{name: "cronjob run", object: cronJobRun},
+		{name: "running pod", object: running},
+		{name: "finished bare pod", object: finishedPod("migrate", now, 3*time.Hour), want: "Pod migrate finished (Succeeded) 3h0m0s ago", wantHint: "pod/shop/migrate"},
+		{name: "finished controlled pod", object: controlled(finishedPod("report-x7k2p", now, 3*time.Hour))},
+		{
+			name:        "crashlooping bare pod",
+			object:      crashLooping(testPod("shop", "debug", "node-1", "500m", "1Gi"), now, 2*time.Hour),
+			want:        "Container app of pod debug is in CrashLoopBackOff, holding 500m CPU and 1Gi memory",
+			wantHint:    "pod/shop/debug",
+			wantSavings: true,
+		},
+		{
+			name:        "crashlooping controlled pod",
+			object:      crashLooping(controlled(testPod("shop", "web", "node-1", "500m", "1Gi")), now, 2*time.Hour),
+			want:        "Container app of pod web is in CrashLoopBackOff",
+			wantSavings: true,
+		},
+		{name: "crashlooping new pod", object: crashLooping(testPod("shop", "debug", "node-1", "500m", "1Gi"), now, 5*time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t, tt.object)
+			co.now = func() time.Time { return now }
+
+			recommendations := co.analyzeCleanup(context.Background())
+			if tt.want == "" {
+				if len(recommendations) != 0 {
+					t.Errorf("got %+v, want no recommendation", recommendations)
+				}
+				return
+			}
+			if len(recommendations) != 1 {
+				t.Fatalf("got %d recommendations, want 1", len(recommendations))
+			}
+			rec := recommendations[0]
+			if rec.Type != "cleanup" || rec.Category != CategoryDelete {
+				t.Errorf("recommendation %+v, want a cleanup deletion", rec)
+			}
+			if !strings.Contains(rec.Description, tt.want) {
+				t.Errorf("description %q, want it to contain %q", rec.Description, tt.want)
+			}
+			if (rec.Savings > 0) != tt.wantSavings {
+				t.Errorf("savings %v, want savings %v", rec.Savings, tt.wantSavings)
+			}
+			var hint string
+			if rec.ActionHint != nil {
+				hint = rec.ActionHint.Target
+			}
+			if hint != tt.wantHint {
+				t.Errorf("hint target %q, want %q", hint, tt.wantHint)
+			}
+		})
+	}
+}
+
+func TestCleanupTTLSetting(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "", want: defaultCleanupTTL},
+		{value: "24h", want: 24 * time.Hour},
+		{value: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("DEMO_MODE", "true")
+			t.Setenv("OPTIMKUBE_CLEANUP_TTL", tt.value)
+			co, err := NewCostOptimizer()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCostOptimizer error %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && co.cleanupTTL != tt.want {
+				t.Errorf("ttl %v, want %v", co.cleanupTTL, tt.want)
+			}
+		})
+	}
+}
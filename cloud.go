@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PricingProvider prices nodes and storage for one cloud. OPTIMKUBE_CLOUD
+// picks the provider; each reads its cloud's instance type from the node and
+// looks it up in the CostCalculator's price table, which starts from the
+// cloud's built-in prices and can be replaced by a pricing file or ConfigMap.
+type PricingProvider interface {
+	NodeHourlyCost(node *corev1.Node) float64 // excluding GPUs
+	StorageGBMonthlyCost() float64
+
+	// instanceType is the node's priced instance type, or "default"
+	instanceType(node *corev1.Node) string
+}
+
+var (
+	_ PricingProvider = awsPricing{}
+	_ PricingProvider = gcpPricing{}
+	_ PricingProvider = azurePricing{}
+)
+
+// cloudPriceTable is a cloud's built-in on-demand pricing
+type cloudPriceTable struct {
+	nodeCostPerHour  map[string]float64 // instance type -> cost per hour
+	storageCostPerGB float64            // default block storage, per GB per month
+	provider         func(cc *CostCalculator) PricingProvider
+}
+
+// defaultCloud is the cloud priced when OPTIMKUBE_CLOUD is unset
+const defaultCloud = "aws"
+
+// cloudPriceTables holds list prices for common instance types in us-east-1,
+// us-central1 and East US respectively. EKS, GKE and AKS all set the
+// well-known instance type label, so nodes are matched the same way on each.
+var cloudPriceTables = map[string]cloudPriceTable{
+	"aws": {
+		nodeCostPerHour: map[string]float64{
+			"t3.micro":   0.0104,
+			"t3.small":   0.0208,
+			"t3.medium":  0.0416,
+			"t3.large":   0.0832,
+			"t3.xlarge":  0.1664,
+			"t3.2xlarge": 0.3328,
+			"m5.large":   0.096,
+			"m5.xlarge":  0.192,
+			"m5.2xlarge": 0.384,
+			"m5.4xlarge": 0.768,
+			"c5.large":   0.085,
+			"c5.xlarge":  0.17,
+			"c5.2xlarge": 0.34,
+			"c5.4xlarge": 0.68,
+			"default":    0.1, // fallback cost
+		},
+		storageCostPerGB: 0.10, // gp2
+		provider:         func(cc *CostCalculator) PricingProvider { return awsPricing{cc} },
+	},
+	"gcp": {
+		nodeCostPerHour: map[string]float64{
+			"e2-micro":       0.0084,
+			"e2-small":       0.0168,
+			"e2-medium":      0.0335,
+			"e2-standard-2":  0.067,
+			"e2-standard-4":  0.134,
+			"e2-standard-8":  0.268,
+			"n1-standard-1":  0.0475,
+			"n1-standard-2":  0.095,
+			"n1-standard-4":  0.19,
+			"n1-standard-8":  0.38,
+			"n2-standard-2":  0.0971,
+			"n2-standard-4":  0.1942,
+			"n2-standard-8":  0.3885,
+			"n2-standard-16": 0.777,
+			"default":        0.1,
+		},
+		storageCostPerGB: 0.10, // pd-balanced
+		provider:         func(cc *CostCalculator) PricingProvider { return gcpPricing{cc} },
+	},
+	"azure": {
+		nodeCostPerHour: map[string]float64{
+			"Standard_B2s":    0.0416,
+			"Standard_B2ms":   0.0832,
+			"Standard_B4ms":   0.166,
+			"Standard_B8ms":   0.333,
+			"Standard_D2s_v3": 0.096,
+			"Standard_D4s_v3": 0.192,
+			"Standard_D8s_v3": 0.384,
+			"Standard_D2s_v5": 0.096,
+			"Standard_D4s_v5": 0.192,
+			"Standard_D8s_v5": 0.384,
+			"default":         0.1,
+		},
+		storageCostPerGB: 0.075, // StandardSSD_LRS
+		provider:         func(cc *CostCalculator) PricingProvider { return azurePricing{cc} },
+	},
+}
+
+// newCostCalculator starts a calculator from a cloud's price table and
+// returns the cloud's provider, which prices nodes from it
+func newCostCalculator(cloud string) (*CostCalculator, PricingProvider, error) {
+	table, ok := cloudPriceTables[cloud]
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid OPTIMKUBE_CLOUD %q: expected aws, gcp or azure", cloud)
+	}
+	prices := make(map[string]float64, len(table.nodeCostPerHour))
+	for instanceType, price := range table.nodeCostPerHour {
+		prices[instanceType] = price
+	}
+	cc := &CostCalculator{
+		NodeCostPerHour:  prices,
+		StorageCostPerGB: table.storageCostPerGB,
+		GPUCostPerHour:   2.48, // per physical GPU, on top of the instance rate
+	}
+	return cc, table.provider(cc), nil
+}
+
+// labeledInstanceType reads a node's instance type from the well-known label,
+// or its deprecated beta form. EKS, GKE and AKS all set them.
+func labeledInstanceType(node *corev1.Node) (string, bool) {
+	for _, label := range []string{corev1.LabelInstanceTypeStable, corev1.LabelInstanceType} {
+		if instanceType := node.Labels[label]; instanceType != "" {
+			return instanceType, true
+		}
+	}
+	return "", false
+}
+
+// instanceTypeCost looks up an instance type's hourly price; unknown types get
+// the "default" price
+func (cc *CostCalculator) instanceTypeCost(instanceType string) float64 {
+	prices := cc.nodePrices()
+	if cost, exists := prices[instanceType]; exists {
+		return cost
+	}
+	return prices["default"]
+}
+
+// awsPricing prices EC2 instance types. Self-managed nodes may lack the
+// instance type label, so a priced type appearing in the node name is used
+// instead.
+type awsPricing struct{ *CostCalculator }
+
+func (p awsPricing) instanceType(node *corev1.Node) string {
+	if instanceType, ok := labeledInstanceType(node); ok {
+		return instanceType
+	}
+	name := strings.ToLower(node.Name)
+	for instanceType := range p.nodePrices() {
+		if strings.Contains(name, strings.ToLower(instanceType)) {
+			return instanceType
+		}
+	}
+	return "default"
+}
+
+func (p awsPricing) NodeHourlyCost(node *corev1.Node) float64 {
+	return p.instanceTypeCost(p.instanceType(node))
+}
+
+func (p awsPricing) StorageGBMonthlyCost() float64 {
+	return p.StorageCostPerGB
+}
+
+// gcpPricing prices Compute Engine machine types. GKE node names carry the
+// cluster and node pool rather than the machine type, so only the label is
+// read.
+type gcpPricing struct{ *CostCalculator }
+
+func (p gcpPricing) instanceType(node *corev1.Node) string {
+	if instanceType, ok := labeledInstanceType(node); ok {
+		return instanceType
+	}
+	return "default"
+}
+
+func (p gcpPricing) NodeHourlyCost(node *corev1.Node) float64 {
+	return p.instanceTypeCost(p.instanceType(node))
+}
+
+func (p gcpPricing) StorageGBMonthlyCost() float64 {
+	return p.StorageCostPerGB
+}
+
+// azurePricing prices VM sizes. AKS often labels nodes with the size in lower
+// case, so the size is matched to its priced spelling ignoring case.
+type azurePricing struct{ *CostCalculator }
+
+func (p azurePricing) instanceType(node *corev1.Node) string {
+	instanceType, ok := labeledInstanceType(node)
+	if !ok {
+		return "default"
+	}
+	for priced := range p.nodePrices() {
+		if strings.EqualFold(priced, instanceType) {
+			return priced
+		}
+	}
+	return instanceType
+}
+
+func (p azurePricing) NodeHourlyCost(node *corev1.Node) float64 {
+	return p.instanceTypeCost(p.instanceType(node))
+}
+
+func (p azurePricing) StorageGBMonthlyCost() float64 {
+	return p.StorageCostPerGB
+}
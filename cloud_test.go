@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// gkeNode is a GKE node of the given machine type
+func gkeNode(machineType string) *corev1.Node {
+	node := testNode("gke-prod-default-pool-1a2b3c4d-x9yz", "4", "16Gi")
+	node.Labels = map[string]string{
+		corev1.LabelInstanceTypeStable:  machineType,
+		"cloud.google.com/gke-nodepool": "default-pool",
+	}
+	return node
+}
+
+func TestCloudNodeHourlyCost(t *testing.T) {
+	azureNode := testNode("aks-nodepool1-12345678-vmss000000", "4", "16Gi")
+	azureNode.Labels = map[string]string{corev1.LabelInstanceTypeStable: "standard_d4s_v3"}
+	tests := []struct {
+		name        string
+		cloud       string
+		node        *corev1.Node
+		want        float64
+		wantStorage float64
+	}{
+		{name: "gke node on gcp", cloud: "gcp", node: gkeNode("e2-standard-4"), want: 0.134, wantStorage: 0.10},
+		{name: "gke n2 node on gcp", cloud: "gcp", node: gkeNode("n2-standard-8"), want: 0.3885, wantStorage: 0.10},
+		{name: "gke node priced as aws falls back", cloud: "aws", node: gkeNode("e2-standard-4"), want: 0.1, wantStorage: 0.10},
+		{name: "aks node, lower-case size", cloud: "azure", node: azureNode, want: 0.192, wantStorage: 0.075},
+		{name: "default cloud is aws", node: testNode("worker-m5.large-1", "2", "8Gi"), want: 0.096, wantStorage: 0.10},
+		{name: "gcp doesn't guess from the node name", cloud: "gcp", node: testNode("worker-e2-standard-4-1", "4", "16Gi"), want: 0.1, wantStorage: 0.10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OPTIMKUBE_CLOUD", tt.cloud)
+			co, _ := newTestOptimizer(t, tt.node)
+			if got := co.pricing.NodeHourlyCost(tt.node); got != tt.want {
+				t.Errorf("NodeHourlyCost = %v, want %v", got, tt.want)
+			}
+			if got := co.pricing.StorageGBMonthlyCost(); got != tt.wantStorage {
+				t.Errorf("StorageGBMonthlyCost = %v, want %v", got, tt.wantStorage)
+			}
+		})
+	}
+}
+
+func TestCloudSetting(t *testing.T) {
+	t.Setenv("DEMO_MODE", "true")
+	t.Setenv("OPTIMKUBE_CLOUD", "oracle")
+	if _, err := NewCostOptimizer(); err == nil {
+		t.Error("NewCostOptimizer accepted OPTIMKUBE_CLOUD=oracle")
+	}
+}
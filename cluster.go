@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	metricsclientset "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// clusterIdentityNamespace exists in every cluster and its UID is stable for
+// the cluster's lifetime, which makes it a practical cluster ID
+const clusterIdentityNamespace = "kube-system"
+
+// clusterCheckTimeout bounds the startup identity lookups
+const clusterCheckTimeout = 10 * time.Second
+
+// verifySameCluster fails when the core and metrics clients target different
+// API servers, which would silently pair one cluster's objects with another's
+// usage. Hosts are compared first; when both clients can read the kube-system
+// namespace its UID is compared as well, catching different names for the
+// same endpoint in the other direction.
+func verifySameCluster(ctx context.Context, clientset kubernetes.Interface, metricsClient metricsclientset.Interface) error {
+	coreREST := clientset.CoreV1().RESTClient()
+	metricsREST := metricsClient.MetricsV1beta1().RESTClient()
+
+	coreHost := apiServerHost(coreREST)
+	metricsHost := apiServerHost(metricsREST)
+	if coreHost != metricsHost {
+		return fmt.Errorf("kubernetes client targets %s but metrics client targets %s", coreHost, metricsHost)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, clusterCheckTimeout)
+	defer cancel()
+
+	coreUID, err := namespaceUID(ctx, coreREST)
+	if err != nil {
+		slog.Warn("Skipping cluster UID check", "error", err)
+		return nil
+	}
+	metricsUID, err := namespaceUID(ctx, metricsREST)
+	if err != nil {
+		slog.Warn("Skipping cluster UID check", "error", err)
+		return nil
+	}
+	if coreUID != metricsUID {
+		return fmt.Errorf("kubernetes and metrics clients reach different clusters (%s namespace UID %s vs %s)", clusterIdentityNamespace, coreUID, metricsUID)
+	}
+	return nil
+}
+
+// apiServerHost returns the normalized host:port a REST client talks to
+func apiServerHost(client rest.Interface) string {
+	u := client.Get().URL()
+	host := strings.ToLower(u.Host)
+	if u.Port() == "" {
+		switch u.Scheme {
+		case "https":
+			host += ":443"
+		case "http":
+			host += ":80"
+		}
+	}
+	return host
+}
+
+// namespaceUID reads the identity namespace through an arbitrary REST client.
+// The metrics client is scoped to metrics.k8s.io, so the core API is
+// addressed by absolute path.
+func namespaceUID(ctx context.Context, client rest.Interface) (string, error) {
+	raw, err := client.Get().AbsPath("/api/v1/namespaces", clusterIdentityNamespace).DoRaw(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get namespace %s: %w", clusterIdentityNamespace, err)
+	}
+
+	var namespace metav1.PartialObjectMetadata
+	if err := json.Unmarshal(raw, &namespace); err != nil {
+		return "", fmt.Errorf("decode namespace %s: %w", clusterIdentityNamespace, err)
+	}
+	return string(namespace.UID), nil
+}
+
+// aggregateClusterName labels the row totalling every monitored cluster
+const aggregateClusterName = "all"
+
+// ClusterStatus is a monitored cluster's health and cost at a glance. Counts
+// and cost come from the last completed scan.
+type ClusterStatus struct {
+	Name             string     `json:"name"`
+	LastScan         *time.Time `json:"last_scan,omitempty"`
+	Reachable        bool       `json:"reachable"`
+	Error            string     `json:"error,omitempty"`
+	NodeCount        int        `json:"node_count"`
+	PodCount         int        `json:"pod_count"`
+	TotalMonthlyCost float64    `json:"total_monthly_cost"`
+}
+
+// ClustersResponse lists every monitored cluster plus their totals
+type ClustersResponse struct {
+	Clusters  []ClusterStatus `json:"clusters"`
+	Aggregate ClusterStatus   `json:"aggregate"`
+}
+
+// monitoredCluster is a cluster listed by /api/clusters: a client to probe
+// its API server and the history its scans record
+type monitoredCluster struct {
+	name      string
+	clientset kubernetes.Interface // nil in demo mode, which is always reachable
+	history   *summaryHistory
+}
+
+// monitoredClusters lists the clusters /api/clusters reports, which is this
+// optimizer's own unless a list was set
+func (co *CostOptimizer) monitoredClusters() []monitoredCluster {
+	if len(co.clusters) > 0 {
+		return co.clusters
+	}
+	return []monitoredCluster{{name: co.clusterName, clientset: co.clientset, history: co.history}}
+}
+
+// status probes the cluster's API server for reachability and reports counts
+// and cost from its last scan. The probe is bounded by the client's API
+// timeout.
+func (c monitoredCluster) status() ClusterStatus {
+	status := ClusterStatus{Name: c.name, Reachable: true}
+
+	if c.clientset != nil {
+		if _, err := c.clientset.Discovery().ServerVersion(); err != nil {
+			status.Reachable = false
+			status.Error = err.Error()
+		}
+	}
+
+	if summary, ok := c.history.latest(); ok {
+		lastScan := summary.LastUpdated
+		status.LastScan = &lastScan
+		status.NodeCount = summary.NodeCount
+		status.PodCount = summary.PodCount
+		status.TotalMonthlyCost = summary.TotalMonthlyCost
+	}
+	return status
+}
+
+// aggregateClusters totals the clusters into one row. It is reachable only
+// when every cluster is, and its last scan is the most recent one.
+func aggregateClusters(clusters []ClusterStatus) ClusterStatus {
+	aggregate := ClusterStatus{Name: aggregateClusterName, Reachable: true}
+	unreachable := make([]string, 0)
+	for _, cluster := range clusters {
+		if !cluster.Reachable {
+			aggregate.Reachable = false
+			unreachable = append(unreachable, cluster.Name)
+		}
+		if cluster.LastScan != nil && (aggregate.LastScan == nil || cluster.LastScan.After(*aggregate.LastScan)) {
+			aggregate.LastScan = cluster.LastScan
+		}
+		aggregate.NodeCount += cluster.NodeCount
+		aggregate.PodCount += cluster.PodCount
+		aggregate.TotalMonthlyCost += cluster.TotalMonthlyCost
+	}
+	if len(unreachable) > 0 {
+		aggregate.Error = "unreachable: " + strings.Join(unreachable, ", ")
+	}
+	return aggregate
+}
+
+func (co *CostOptimizer) handleClusters(w http.ResponseWriter, r *http.Request) {
+	// Probed in parallel so an unreachable cluster's timeout isn't paid once
+	// per cluster after it
+	monitored := co.monitoredClusters()
+	clusters := make([]ClusterStatus, len(monitored))
+	var wg sync.WaitGroup
+	for i := range monitored {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			clusters[i] = monitored[i].status()
+		}(i)
+	}
+	wg.Wait()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ClustersResponse{
+		Clusters:  clusters,
+		Aggregate: aggregateClusters(clusters),
+	})
+}
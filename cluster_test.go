@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	metricsclientset "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// namespaceServer serves the kube-system namespace with the UIDs in turn,
+// or 403 when there are none
+func namespaceServer(t *testing.T, uids ...string) *httptest.Server {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/kube-system" {
+			http.NotFound(w, r)
+			return
+		}
+		if len(uids) == 0 {
+			http.Error(w, `{"kind":"Status","code":403}`, http.StatusForbidden)
+			return
+		}
+		uid := uids[int(calls.Add(1)-1)%len(uids)]
+		fmt.Fprintf(w, `{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"kube-system","uid":%q}}`, uid)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVerifySameCluster(t *testing.T) {
+	tests := []struct {
+		name    string
+		core    *httptest.Server
+		metrics *httptest.Server // nil means the same server as core
+		wantErr string
+	}{
+		{name: "same cluster", core: namespaceServer(t, "uid-a")},
+		{name: "different API servers", core: namespaceServer(t, "uid-a"), metrics: namespaceServer(t, "uid-a"), wantErr: "metrics client targets"},
+		{name: "different cluster UIDs", core: namespaceServer(t, "uid-a", "uid-b"), wantErr: "different clusters"},
+		{name: "namespace unreadable", core: namespaceServer(t)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := tt.metrics
+			if metrics == nil {
+				metrics = tt.core
+			}
+			clientset, err := kubernetes.NewForConfig(&rest.Config{Host: tt.core.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			metricsClient, err := metricsclientset.NewForConfig(&rest.Config{Host: metrics.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = verifySameCluster(context.Background(), clientset, metricsClient)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("verifySameCluster: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("verifySameCluster error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAPIServerHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "https://API.example.com", want: "api.example.com:443"},
+		{host: "https://api.example.com:443", want: "api.example.com:443"},
+		{host: "http://10.0.0.1", want: "10.0.0.1:80"},
+		{host: "https://10.0.0.1:6443", want: "10.0.0.1:6443"},
+	}
+
+	for _, tt := range tests {
+		clientset, err := kubernetes.NewForConfig(&rest.Config{Host: tt.host})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := apiServerHost(clientset.CoreV1().RESTClient()); got != tt.want {
+			t.Errorf("apiServerHost(%s) = %s, want %s", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestHandleClusters(t *testing.T) {
+	scannedAt := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	reachableHistory := newSummaryHistory(1)
+	reachableHistory.add(ClusterCostSummary{LastUpdated: scannedAt, NodeCount: 3, PodCount: 20, TotalMonthlyCost: 900})
+	failingHistory := newSummaryHistory(1)
+	failingHistory.add(ClusterCostSummary{LastUpdated: scannedAt.Add(-time.Hour), NodeCount: 2, PodCount: 5, TotalMonthlyCost: 100.5})
+
+	failing := fake.NewSimpleClientset()
+	failWith(&failing.Fake, "get", "version", errors.New("connection refused"))
+
+	co, _ := newTestOptimizer(t)
+	co.clusters = []monitoredCluster{
+		{name: "prod", clientset: fake.NewSimpleClientset(), history: reachableHistory},
+		{name: "staging", clientset: failing, history: failingHistory},
+	}
+
+	rec := serve(co.newRouter(), http.MethodGet, "/api/clusters", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var response ClustersResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	tests := []struct {
+		got           ClusterStatus
+		wantName      string
+		wantReachable bool
+		wantError     string
+		wantNodes     int
+		wantPods      int
+		wantCost      float64
+		wantLastScan  time.Time
+	}{
+		{response.Clusters[0], "prod", true, "", 3, 20, 900, scannedAt},
+		{response.Clusters[1], "staging", false, "connection refused", 2, 5, 100.5, scannedAt.Add(-time.Hour)},
+		{response.Aggregate, aggregateClusterName, false, "unreachable: staging", 5, 25, 1000.5, scannedAt},
+	}
+	if len(response.Clusters) != 2 {
+		t.Fatalf("got %d clusters, want 2", len(response.Clusters))
+	}
+	for _, tt := range tests {
+		got := tt.got
+		if got.Name != tt.wantName || got.Reachable != tt.wantReachable || got.Error != tt.wantError {
+			t.Errorf("%s: name/reachable/error = %q/%v/%q, want %q/%v/%q",
+				tt.wantName, got.Name, got.Reachable, got.Error, tt.wantName, tt.wantReachable, tt.wantError)
+		}
+		if got.NodeCount != tt.wantNodes || got.PodCount != tt.wantPods || got.TotalMonthlyCost != tt.wantCost {
+			t.Errorf("%s: nodes/pods/cost = %d/%d/%v, want %d/%d/%v",
+				tt.wantName, got.NodeCount, got.PodCount, got.TotalMonthlyCost, tt.wantNodes, tt.wantPods, tt.wantCost)
+		}
+		if got.LastScan == nil || !got.LastScan.Equal(tt.wantLastScan) {
+			t.Errorf("%s: last scan = %v, want %v", tt.wantName, got.LastScan, tt.wantLastScan)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+)
+
+// defaultMaxInflightRequests bounds concurrent expensive requests, each of
+// which lists every pod or node in the cluster
+const defaultMaxInflightRequests = 8
+
+// concurrencyLimiter sheds load once a fixed number of requests are in flight,
+// rather than queueing them behind slow Kubernetes API calls
+type concurrencyLimiter struct {
+	slots chan struct{}
+}
+
+func newConcurrencyLimiter(max int) *concurrencyLimiter {
+	return &concurrencyLimiter{slots: make(chan struct{}, max)}
+}
+
+// limit wraps a handler so that requests beyond the limit get 503 with
+// Retry-After instead of piling onto the API server
+func (l *concurrencyLimiter) limit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case l.slots <- struct{}{}:
+			defer func() { <-l.slots }()
+			next(w, r)
+		default:
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "too many concurrent requests", http.StatusServiceUnavailable)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestConcurrencyLimiterShedsExcessRequests(t *testing.T) {
+	const excess = 5
+	limiter := newConcurrencyLimiter(defaultMaxInflightRequests)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := limiter.limit(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Fill every slot with a request that blocks until released
+	var wg sync.WaitGroup
+	admitted := make([]*httptest.ResponseRecorder, defaultMaxInflightRequests)
+	for i := range admitted {
+		admitted[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(rec *httptest.ResponseRecorder) {
+			defer wg.Done()
+			handler(rec, httptest.NewRequest(http.MethodGet, "/api/cost-summary", nil))
+		}(admitted[i])
+	}
+	for range admitted {
+		<-started
+	}
+
+	for i := 0; i < excess; i++ {
+		rec := serve(handler, http.MethodGet, "/api/cost-summary", nil)
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("request %d over the limit got %d, want 503", i+1, rec.Code)
+		}
+		if rec.Header().Get("Retry-After") == "" {
+			t.Errorf("503 response has no Retry-After")
+		}
+	}
+
+	close(release)
+	wg.Wait()
+	for i, rec := range admitted {
+		if rec.Code != http.StatusOK {
+			t.Errorf("admitted request %d got %d, want 200", i, rec.Code)
+		}
+	}
+
+	// Freed slots admit new requests
+	go func() { <-started }()
+	if rec := serve(handler, http.MethodGet, "/api/cost-summary", nil); rec.Code != http.StatusOK {
+		t.Errorf("request after draining got %d, want 200", rec.Code)
+	}
+}
+
+func TestMaxInflightRequestsSetting(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr string
+	}{
+		{value: "", want: defaultMaxInflightRequests},
+		{value: "32", want: 32},
+		{value: "0", wantErr: "must be at least 1"},
+		{value: "many", wantErr: "OPTIMKUBE_MAX_INFLIGHT_REQUESTS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("DEMO_MODE", "true")
+			t.Setenv("OPTIMKUBE_MAX_INFLIGHT_REQUESTS", tt.value)
+			co, err := NewCostOptimizer()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("NewCostOptimizer error = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if co.maxInflightRequests != tt.want {
+				t.Errorf("maxInflightRequests = %d, want %d", co.maxInflightRequests, tt.want)
+			}
+		})
+	}
+}
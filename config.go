@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+)
+
+// FileConfig holds structured settings that don't fit in environment
+// variables, loaded from the YAML or JSON file named by OPTIMKUBE_CONFIG_FILE.
+type FileConfig struct {
+	Budgets    []Budget          `json:"budgets"`
+	Throughput []ThroughputQuery `json:"throughput"`
+	QuietHours []QuietWindow     `json:"quiet_hours"`
+
+	// NodeCostExpression overrides table pricing with a formula; see costModelEnv
+	NodeCostExpression string `json:"node_cost_expression"`
+
+	// ExcludedNamespaces replaces defaultExcludedNamespaces when set, so an
+	// empty list analyzes every namespace
+	ExcludedNamespaces *[]string `json:"excluded_namespaces"`
+
+	// ProductionNamespaces are where multi-replica workloads are expected to
+	// have a PodDisruptionBudget; every namespace when unset
+	ProductionNamespaces []string `json:"production_namespaces"`
+
+	// Protected resources still get recommendations but never actions
+	Protected []ProtectedSelector `json:"protected"`
+
+	// Suppress drops matching recommendations during generation
+	Suppress []SuppressionRule `json:"suppress"`
+
+	// Reservations bill covered nodes at a discount, one entry per instance type
+	Reservations []Reservation `json:"reservations"`
+
+	// Thresholds overrides individual analysis cutoffs; see defaultThresholds
+	Thresholds Thresholds `json:"thresholds"`
+}
+
+// loadFileConfig reads and validates the config file. Unknown fields are
+// rejected so typos surface at startup instead of being silently ignored.
+func loadFileConfig(path string) (*FileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	// Thresholds the file leaves out keep their defaults
+	cfg := FileConfig{Thresholds: defaultThresholds()}
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	for i := range cfg.Budgets {
+		if err := cfg.Budgets[i].validate(); err != nil {
+			return nil, fmt.Errorf("config file %s: budgets[%d]: %w", path, i, err)
+		}
+	}
+
+	for i := range cfg.Throughput {
+		if err := cfg.Throughput[i].validate(); err != nil {
+			return nil, fmt.Errorf("config file %s: throughput[%d]: %w", path, i, err)
+		}
+	}
+
+	if cfg.ExcludedNamespaces != nil {
+		if err := validateNamespacePatterns(*cfg.ExcludedNamespaces); err != nil {
+			return nil, fmt.Errorf("config file %s: excluded_namespaces: %w", path, err)
+		}
+	}
+	if err := validateNamespacePatterns(cfg.ProductionNamespaces); err != nil {
+		return nil, fmt.Errorf("config file %s: production_namespaces: %w", path, err)
+	}
+
+	for i := range cfg.Protected {
+		if err := cfg.Protected[i].validate(); err != nil {
+			return nil, fmt.Errorf("config file %s: protected[%d]: %w", path, i, err)
+		}
+	}
+
+	names := make(map[string]bool, len(cfg.Suppress))
+	for i := range cfg.Suppress {
+		if err := cfg.Suppress[i].validate(); err != nil {
+			return nil, fmt.Errorf("config file %s: suppress[%d]: %w", path, i, err)
+		}
+		if names[cfg.Suppress[i].Name] {
+			return nil, fmt.Errorf("config file %s: suppress[%d]: duplicate name %q", path, i, cfg.Suppress[i].Name)
+		}
+		names[cfg.Suppress[i].Name] = true
+	}
+
+	reservedTypes := make(map[string]bool, len(cfg.Reservations))
+	for i := range cfg.Reservations {
+		if err := cfg.Reservations[i].validate(); err != nil {
+			return nil, fmt.Errorf("config file %s: reservations[%d]: %w", path, i, err)
+		}
+		if reservedTypes[cfg.Reservations[i].InstanceType] {
+			return nil, fmt.Errorf("config file %s: reservations[%d]: duplicate instance_type %q", path, i, cfg.Reservations[i].InstanceType)
+		}
+		reservedTypes[cfg.Reservations[i].InstanceType] = true
+	}
+
+	if err := cfg.Thresholds.validate(); err != nil {
+		return nil, fmt.Errorf("config file %s: thresholds: %w", path, err)
+	}
+
+	for i := range cfg.QuietHours {
+		if err := cfg.QuietHours[i].validate(); err != nil {
+			return nil, fmt.Errorf("config file %s: quiet_hours[%d]: %w", path, i, err)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// envInt reads an integer environment variable, returning def when unset
+func envInt(name string, def int) (int, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: expected an integer", name, raw)
+	}
+	return value, nil
+}
+
+// envFloat reads a floating point environment variable, returning def when unset
+func envFloat(name string, def float64) (float64, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: expected a number", name, raw)
+	}
+	return value, nil
+}
+
+// envDuration reads a Go duration environment variable, returning def when unset
+func envDuration(name string, def time.Duration) (time.Duration, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: expected a duration such as 30s or 10m", name, raw)
+	}
+	return value, nil
+}
+
+// envQuantity reads a Kubernetes resource quantity environment variable, such
+// as 50m or 128Mi, returning def when unset
+func envQuantity(name string, def resource.Quantity) (resource.Quantity, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	value, err := resource.ParseQuantity(raw)
+	if err != nil {
+		return resource.Quantity{}, fmt.Errorf("invalid %s %q: expected a quantity such as 50m or 128Mi", name, raw)
+	}
+	return value, nil
+}
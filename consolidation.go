@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// removableNodes repacks workloads to find nodes that could be drained and
+// removed together. Candidates are tried emptiest first, by the larger of
+// their requested CPU and memory share, and a candidate is kept when the pods
+// of every node kept so far, plus its own, still fit on the rest. Placement is
+// simulateDrain's first-fit-decreasing, so nodeSelector, taints and required
+// node affinity are respected. A node with a pod that can't be moved is never
+// a candidate. A nil snapshot can't simulate anything and finds no nodes.
+func (s *schedulingSnapshot) removableNodes(candidates []*corev1.Node) []string {
+	if s == nil {
+		return nil
+	}
+
+	load := func(node *corev1.Node) float64 {
+		allocatable := node.Status.Allocatable
+		cpu, memory := allocatable.Cpu().MilliValue(), allocatable.Memory().Value()
+		if cpu == 0 || memory == 0 {
+			return math.Inf(1)
+		}
+		return math.Max(float64(s.cpu[node.Name])/float64(cpu), float64(s.memory[node.Name])/float64(memory))
+	}
+	ordered := make([]*corev1.Node, 0, len(candidates))
+	for _, node := range candidates {
+		if !s.pinned(node.Name) {
+			ordered = append(ordered, node)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool { return load(ordered[i]) < load(ordered[j]) })
+
+	removable := make([]string, 0)
+	for _, node := range ordered {
+		trial := append(slices.Clone(removable), node.Name)
+		if len(s.simulateDrain(trial)) == 0 {
+			removable = trial
+		}
+	}
+	return removable
+}
+
+// pinned reports whether a node runs a pod that a drain would lose rather
+// than reschedule. DaemonSet pods go away with the node and don't pin it.
+func (s *schedulingSnapshot) pinned(node string) bool {
+	for _, pod := range s.pods[node] {
+		if !ownedByDaemonSet(pod) && !podMovable(pod) {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzeConsolidation recommends removing each node a repack of current pod
+// requests would leave empty, with that node's billed cost as the savings.
+// Nodes in a node group are sized by nodeGroupRecommendations instead, but
+// still take pods from the nodes removed here.
+func (co *CostOptimizer) analyzeConsolidation(ctx context.Context) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	if co.demoMode || co.clientset == nil || co.analyzerDisabled("consolidation") {
+		return recommendations
+	}
+
+	nodes, err := co.listNodes(ctx)
+	if err != nil {
+		co.analyzerListFailed("consolidation", "nodes", err)
+		return recommendations
+	}
+
+	snapshot := co.loadSchedulingSnapshot(ctx, nodes.Items)
+	candidates := make([]*corev1.Node, 0)
+	byName := make(map[string]*corev1.Node)
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		byName[node.Name] = node
+		if nodeGroupName(node) == "" && !node.Spec.Unschedulable {
+			candidates = append(candidates, node)
+		}
+	}
+
+	removable := snapshot.removableNodes(candidates)
+	if len(removable) == 0 {
+		return recommendations
+	}
+
+	podsOnNode := co.podsPerNode(ctx)
+	for _, name := range removable {
+		_, hourlyCost := co.effectiveNodeHourlyCost(byName[name])
+		rec := Recommendation{
+			Type:     "node_consolidation",
+			Category: CategoryScale,
+			Resource: name,
+			Description: fmt.Sprintf("Node %s can be removed: repacking pod requests fits its pods on the remaining nodes (%d of %d nodes removable)",
+				name, len(removable), len(nodes.Items)),
+			Impact:     fmt.Sprintf("Drain and remove node %s", name),
+			ActionHint: &ActionHint{Verb: "drain", Target: hintTarget("node", "", name)},
+			Savings:    hourlyCost * 24 * 30,
+			Priority:   "medium",
+			Timestamp:  time.Now(),
+		}
+		co.applyMigrationCost(&rec, podsOnNode[name])
+		recommendations = append(recommendations, rec)
+	}
+
+	return recommendations
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// consolidationCluster is three identical 4-core, 16Gi nodes, each running
+// perNode controlled pods of the given CPU request
+func consolidationCluster(perNode int, cpu string) ([]*corev1.Node, []*corev1.Pod) {
+	var nodes []*corev1.Node
+	var pods []*corev1.Pod
+	for i := 1; i <= 3; i++ {
+		name := fmt.Sprintf("node-%d", i)
+		node := testNode(name, "4", "16Gi")
+		node.Labels = map[string]string{"pool": name}
+		nodes = append(nodes, node)
+		for j := 0; j < perNode; j++ {
+			pods = append(pods, controlled(testPod("shop", fmt.Sprintf("web-%d-%d", i, j), name, cpu, "2Gi")))
+		}
+	}
+	return nodes, pods
+}
+
+func TestAnalyzeConsolidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		perNode       int
+		cpu           string
+		setup         func(nodes []*corev1.Node, pods []*corev1.Pod)
+		wantRemovable int
+	}{
+		{name: "half-empty nodes collapse to two", perNode: 2, cpu: "1", wantRemovable: 1},
+		{name: "nearly empty nodes collapse to one", perNode: 1, cpu: "500m", wantRemovable: 2},
+		{name: "full nodes", perNode: 3, cpu: "1"},
+		{
+			name: "bare pods pin their nodes", perNode: 2, cpu: "1",
+			setup: func(_ []*corev1.Node, pods []*corev1.Pod) {
+				for _, pod := range pods {
+					pod.OwnerReferences = nil
+				}
+			},
+		},
+		{
+			name: "node selectors keep pods in place", perNode: 2, cpu: "1",
+			setup: func(_ []*corev1.Node, pods []*corev1.Pod) {
+				for _, pod := range pods {
+					pod.Spec.NodeSelector = map[string]string{"pool": pod.Spec.NodeName}
+				}
+			},
+		},
+		{
+			name: "taints leave one node to move to", perNode: 2, cpu: "1",
+			setup: func(nodes []*corev1.Node, _ []*corev1.Pod) {
+				withTaint(nodes[1], "dedicated", "batch", corev1.TaintEffectNoSchedule)
+				withTaint(nodes[2], "dedicated", "batch", corev1.TaintEffectNoSchedule)
+			},
+			// node-1 can't move to the tainted nodes, but one tainted node
+			// can move to node-1
+			wantRemovable: 1,
+		},
+		{
+			name: "node group members are left to node group sizing", perNode: 2, cpu: "1",
+			setup: func(nodes []*corev1.Node, _ []*corev1.Pod) {
+				for _, node := range nodes {
+					node.Labels["eks.amazonaws.com/nodegroup"] = "workers"
+				}
+			},
+		},
+		{
+			name: "cordoned nodes aren't removed twice", perNode: 2, cpu: "1",
+			setup: func(nodes []*corev1.Node, _ []*corev1.Pod) {
+				for _, node := range nodes {
+					node.Spec.Unschedulable = true
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, pods := consolidationCluster(tt.perNode, tt.cpu)
+			if tt.setup != nil {
+				tt.setup(nodes, pods)
+			}
+			var objects []runtime.Object
+			for _, node := range nodes {
+				objects = append(objects, node)
+			}
+			for _, pod := range pods {
+				objects = append(objects, pod)
+			}
+			co, _ := newTestOptimizer(t, objects...)
+
+			recommendations := co.analyzeConsolidation(context.Background())
+			if len(recommendations) != tt.wantRemovable {
+				t.Fatalf("got %d recommendations, want %d: %+v", len(recommendations), tt.wantRemovable, recommendations)
+			}
+			wantSavings := co.nodeHourlyCost(nodes[0]) * 24 * 30
+			for _, rec := range recommendations {
+				if rec.Type != "node_consolidation" || rec.ActionHint == nil || rec.ActionHint.Target != "node/"+rec.Resource {
+					t.Errorf("recommendation %+v, want a node drain", rec)
+				}
+				if math.Abs(rec.Savings-wantSavings) > 1e-9 {
+					t.Errorf("%s saves %v, want the node's cost %v", rec.Resource, rec.Savings, wantSavings)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// unallocatedLabelValue collects the cost of pods that neither they nor their
+// namespace carry the cost label for
+const unallocatedLabelValue = "unallocated"
+
+// validateLabelKey checks that key is a valid label key, such as "team" or
+// "example.com/cost-center"
+func validateLabelKey(key string) error {
+	if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// namespaceLabels returns the labels of every namespace by name. Without
+// them pods are grouped by their own labels only.
+func (co *CostOptimizer) namespaceLabels(ctx context.Context) map[string]map[string]string {
+	if co.demoMode || co.clientset == nil {
+		return nil
+	}
+	namespaces, err := co.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		slog.Warn("Failed to list namespaces for label costs", "error", err)
+		return nil
+	}
+	labels := make(map[string]map[string]string, len(namespaces.Items))
+	for _, namespace := range namespaces.Items {
+		labels[namespace.Name] = namespace.Labels
+	}
+	return labels
+}
+
+// labelCostKey is the value of the cost label a pod's cost is grouped under:
+// the pod's own label, else its namespace's, so a team can label its
+// namespaces once and override individual workloads
+func (co *CostOptimizer) labelCostKey(pod PodMetrics, namespaceLabels map[string]map[string]string) string {
+	if value := pod.Labels[co.costLabel]; value != "" {
+		return value
+	}
+	if value := namespaceLabels[pod.Namespace][co.costLabel]; value != "" {
+		return value
+	}
+	return unallocatedLabelValue
+}
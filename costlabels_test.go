@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// labelled sets a label on a pod
+func labelled(pod *corev1.Pod, key, value string) *corev1.Pod {
+	pod.Labels = map[string]string{key: value}
+	return pod
+}
+
+func TestCostSummaryLabelCosts(t *testing.T) {
+	tests := []struct {
+		name  string
+		label string
+		want  map[string][]string // label value -> pods whose cost it sums
+	}{
+		{
+			name:  "team",
+			label: "team",
+			want: map[string][]string{
+				"a":                   {"web", "api"},
+				"b":                   {"etl", "override"},
+				"c":                   {"reports"},
+				unallocatedLabelValue: {"debug"},
+			},
+		},
+		{name: "no cost label"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OPTIMKUBE_COST_LABEL", tt.label)
+			analytics := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "analytics", Labels: map[string]string{"team": "c"}}}
+			co, _ := newTestOptimizer(t, analytics,
+				testNode("node-1", "8", "32Gi"), testNodeMetrics("node-1", "2", "8Gi"),
+				labelled(testPod("shop", "web", "node-1", "1", "2Gi"), "team", "a"), testPodMetrics("shop", "web", "500m", "1Gi"),
+				labelled(testPod("shop", "api", "node-1", "500m", "1Gi"), "team", "a"), testPodMetrics("shop", "api", "250m", "512Mi"),
+				labelled(testPod("batch", "etl", "node-1", "2", "4Gi"), "team", "b"), testPodMetrics("batch", "etl", "1", "2Gi"),
+				testPod("analytics", "reports", "node-1", "1", "1Gi"), testPodMetrics("analytics", "reports", "500m", "512Mi"),
+				labelled(testPod("analytics", "override", "node-1", "1", "1Gi"), "team", "b"), testPodMetrics("analytics", "override", "500m", "512Mi"),
+				testPod("default", "debug", "node-1", "100m", "128Mi"), testPodMetrics("default", "debug", "10m", "64Mi"),
+			)
+
+			podCost := make(map[string]float64)
+			for _, pod := range co.getPodMetrics(context.Background(), "") {
+				podCost[pod.Name] = pod.EstimatedCost
+			}
+			summary := co.generateCostSummary(context.Background())
+			if tt.want == nil {
+				if summary.LabelCosts != nil {
+					t.Errorf("label costs %v, want none without a cost label", summary.LabelCosts)
+				}
+				return
+			}
+			if len(summary.LabelCosts) != len(tt.want) {
+				t.Errorf("label costs %v, want groups for %v", summary.LabelCosts, tt.want)
+			}
+			for value, pods := range tt.want {
+				var want float64
+				for _, pod := range pods {
+					want += podCost[pod]
+				}
+				if got := summary.LabelCosts[value]; math.Abs(got-want) > 1e-9 {
+					t.Errorf("%s=%s costs %v, want %v for %v", tt.label, value, got, want, pods)
+				}
+			}
+		})
+	}
+}
+
+func TestCostLabelSetting(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{value: "team"},
+		{value: "example.com/cost-center"},
+		{value: "cost center", wantErr: true},
+		{value: "-team", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("DEMO_MODE", "true")
+			t.Setenv("OPTIMKUBE_COST_LABEL", tt.value)
+			if _, err := NewCostOptimizer(); (err != nil) != tt.wantErr {
+				t.Errorf("NewCostOptimizer error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
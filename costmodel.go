@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// costModelEnv is the set of variables a node cost expression can reference
+type costModelEnv struct {
+	CPUCores     float64 `expr:"cpuCores"`
+	MemGB        float64 `expr:"memGB"`
+	GPUCount     float64 `expr:"gpuCount"`
+	Spot         bool    `expr:"spot"`
+	Region       string  `expr:"region"`
+	InstanceType string  `expr:"instanceType"`
+	TableCost    float64 `expr:"tableCost"` // hourly price from the built-in table
+}
+
+// costModel computes node hourly cost from a user-supplied expression, e.g.
+// "tableCost * 1.15 + cpuCores * 0.002" for a platform markup and carbon fee
+type costModel struct {
+	source  string
+	program *vm.Program
+}
+
+// compileCostModel type-checks the expression against costModelEnv so
+// mistakes fail at startup rather than on the first scan
+func compileCostModel(source string) (*costModel, error) {
+	program, err := expr.Compile(source, expr.Env(costModelEnv{}), expr.AsFloat64())
+	if err != nil {
+		return nil, fmt.Errorf("invalid node cost expression: %w", err)
+	}
+	return &costModel{source: source, program: program}, nil
+}
+
+func (m *costModel) hourlyCost(env costModelEnv) (float64, error) {
+	out, err := expr.Run(m.program, env)
+	if err != nil {
+		return 0, err
+	}
+	return out.(float64), nil
+}
+
+// nodeCostEnv collects the expression variables for a node
+func (co *CostOptimizer) nodeCostEnv(node *corev1.Node, instanceType string) costModelEnv {
+	return costModelEnv{
+		CPUCores:     float64(node.Status.Capacity.Cpu().MilliValue()) / 1000,
+		MemGB:        float64(node.Status.Capacity.Memory().Value()) / (1024 * 1024 * 1024),
+		GPUCount:     float64(nodeGPUInfo(node).Physical),
+		Spot:         isSpotNode(node),
+		Region:       node.Labels[corev1.LabelTopologyRegion],
+		InstanceType: instanceType,
+		TableCost:    co.pricing.NodeHourlyCost(node),
+	}
+}
+
+// modelNodeCost evaluates the configured cost model for a node. ok is false
+// when no model is configured or evaluation fails, in which case the caller
+// falls back to table pricing.
+func (co *CostOptimizer) modelNodeCost(node *corev1.Node, instanceType string) (cost float64, ok bool) {
+	if co.costModel == nil {
+		return 0, false
+	}
+	cost, err := co.costModel.hourlyCost(co.nodeCostEnv(node, instanceType))
+	if err != nil {
+		slog.Warn("Node cost expression failed, using table pricing", "node", node.Name, "error", err)
+		return 0, false
+	}
+	return cost, true
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCostModelNodeHourlyCost(t *testing.T) {
+	node := testNode("node-1", "4", "16Gi")
+	tests := []struct {
+		name       string
+		expression string
+		want       func(tableCost float64) float64
+	}{
+		{name: "table pricing", want: func(tableCost float64) float64 { return tableCost }},
+		{name: "platform markup", expression: "tableCost * 1.15", want: func(tableCost float64) float64 { return tableCost * 1.15 }},
+		{name: "markup and carbon fee", expression: "tableCost * 1.1 + cpuCores * 0.002 + memGB * 0.001", want: func(tableCost float64) float64 { return tableCost*1.1 + 4*0.002 + 16*0.001 }},
+		{name: "spot discount", expression: "spot ? tableCost * 0.3 : tableCost", want: func(tableCost float64) float64 { return tableCost }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t, node)
+			tableCost := co.nodeHourlyCost(node)
+			if tt.expression != "" {
+				model, err := compileCostModel(tt.expression)
+				if err != nil {
+					t.Fatal(err)
+				}
+				co.costModel = model
+			}
+
+			if got, want := co.nodeHourlyCost(node), tt.want(tableCost); math.Abs(got-want) > 1e-9 {
+				t.Errorf("nodeHourlyCost = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCompileCostModelRejectsBadExpressions(t *testing.T) {
+	for _, expression := range []string{
+		"tableCost *",         // syntax
+		"tableCost * markup",  // unknown variable
+		`region + "-premium"`, // not a number
+	} {
+		if _, err := compileCostModel(expression); err == nil {
+			t.Errorf("compileCostModel(%q) succeeded, want an error", expression)
+		}
+	}
+}
+
+func TestNewCostOptimizerValidatesCostExpression(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("node_cost_expression: tableCost * markup\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DEMO_MODE", "true")
+	t.Setenv("OPTIMKUBE_CONFIG_FILE", path)
+
+	if _, err := NewCostOptimizer(); err == nil || !strings.Contains(err.Error(), "invalid node cost expression") {
+		t.Errorf("NewCostOptimizer error = %v, want the expression rejected at startup", err)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var recommendationCSVHeader = []string{
+	"id", "type", "category", "priority", "namespace", "resource", "release",
+	"description", "impact", "potential_savings", "timestamp",
+}
+
+var costSummaryCSVHeader = []string{"namespace", "cost", "used_cost", "idle_cost"}
+
+// formatMoney renders a monetary value for CSV the way it's marshaled to JSON
+func formatMoney(value float64) string {
+	return strconv.FormatFloat(roundMoney(value), 'f', -1, 64)
+}
+
+// writeCSV sends a header row and rows as text/csv. encoding/csv quotes
+// fields containing commas, quotes or line breaks, such as descriptions.
+func writeCSV(w http.ResponseWriter, header []string, rows [][]string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	writer := csv.NewWriter(w)
+	writer.Write(header)
+	writer.WriteAll(rows)
+}
+
+// handleRecommendationsCSV serves /api/recommendations as CSV, with the same
+// filters, sorting and paging
+func (co *CostOptimizer) handleRecommendationsCSV(w http.ResponseWriter, r *http.Request) {
+	query, err := parseRecommendationQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page := query.apply(co.activeRecommendations())
+	rows := make([][]string, 0, len(page.Items))
+	for _, rec := range page.Items {
+		rows = append(rows, []string{
+			rec.ID, rec.Type, rec.Category, rec.Priority, rec.Namespace, rec.Resource, rec.Release,
+			rec.Description, rec.Impact, formatMoney(rec.Savings), rec.Timestamp.Format(time.RFC3339),
+		})
+	}
+	writeCSV(w, recommendationCSVHeader, rows)
+}
+
+// handleCostSummaryCSV serves the cost summary's per-namespace costs as CSV,
+// one row per namespace in name order. It accepts the same ?at= as
+// /api/cost-summary.
+func (co *CostOptimizer) handleCostSummaryCSV(w http.ResponseWriter, r *http.Request) {
+	summary, ok := co.requestedCostSummary(w, r)
+	if !ok {
+		return
+	}
+
+	namespaces := make([]string, 0, len(summary.NamespaceCosts))
+	for namespace := range summary.NamespaceCosts {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	rows := make([][]string, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		rows = append(rows, []string{
+			namespace,
+			formatMoney(summary.NamespaceCosts[namespace]),
+			formatMoney(summary.NamespaceUsedCost[namespace]),
+			formatMoney(summary.NamespaceIdleCost[namespace]),
+		})
+	}
+	writeCSV(w, costSummaryCSVHeader, rows)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readCSV parses a CSV response, checking its content type and header
+func readCSV(t *testing.T, body string, contentType string, header []string) [][]string {
+	t.Helper()
+	if contentType != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/csv", contentType)
+	}
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatalf("parse CSV: %v\n%s", err, body)
+	}
+	if len(records) == 0 || !reflect.DeepEqual(records[0], header) {
+		t.Fatalf("CSV header %v, want %v", records, header)
+	}
+	return records[1:]
+}
+
+func TestRecommendationsCSV(t *testing.T) {
+	co, _ := newTestOptimizer(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	co.recommendations = []Recommendation{
+		{ID: "a", Type: "resource_rightsizing", Category: CategoryRightsize, Priority: "low", Namespace: "shop", Resource: "shop/web",
+			Description: `Container app is over-provisioned for CPU (request: 2000m, usage: 100m, suggested: 120m)`, Impact: `Reduce the "cpu" request`, Savings: 12.345, Timestamp: now},
+		{ID: "b", Type: "node_optimization", Category: CategoryScale, Priority: "high", Resource: "node-1",
+			Description: "Node node-1 is underutilized\nacross the day", Savings: 100, Timestamp: now},
+		{ID: "c", Type: "resource_governance", Category: CategoryConfigure, Priority: "medium", Namespace: "batch", Resource: "batch/etl", Release: "etl",
+			Description: "Deployment etl lacks resource requests/limits", Savings: 20, Timestamp: now},
+	}
+
+	tests := []struct {
+		query   string
+		wantIDs []string
+	}{
+		{query: "", wantIDs: []string{"a", "b", "c"}},
+		{query: "?namespace=shop", wantIDs: []string{"a"}},
+		{query: "?sort=savings&limit=2", wantIDs: []string{"b", "c"}},
+		{query: "?priority=low", wantIDs: []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			jsonRec := serve(http.HandlerFunc(co.handleRecommendations), http.MethodGet, "/api/recommendations"+tt.query, nil)
+			var page RecommendationPage
+			if err := json.Unmarshal(jsonRec.Body.Bytes(), &page); err != nil {
+				t.Fatalf("decode recommendations: %v", err)
+			}
+
+			csvRec := serve(http.HandlerFunc(co.handleRecommendationsCSV), http.MethodGet, "/api/recommendations.csv"+tt.query, nil)
+			rows := readCSV(t, csvRec.Body.String(), csvRec.Header().Get("Content-Type"), recommendationCSVHeader)
+			if len(rows) != len(page.Items) {
+				t.Fatalf("%d CSV rows for %d JSON recommendations", len(rows), len(page.Items))
+			}
+
+			var ids []string
+			for i, rec := range page.Items {
+				ids = append(ids, rec.ID)
+				want := []string{
+					rec.ID, rec.Type, rec.Category, rec.Priority, rec.Namespace, rec.Resource, rec.Release,
+					rec.Description, rec.Impact, strconv.FormatFloat(rec.Savings, 'f', -1, 64), rec.Timestamp.Format(time.RFC3339),
+				}
+				if !reflect.DeepEqual(rows[i], want) {
+					t.Errorf("row %d\n%q\nwant the JSON values\n%q", i, rows[i], want)
+				}
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("recommendations %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+
+	if rec := serve(http.HandlerFunc(co.handleRecommendationsCSV), http.MethodGet, "/api/recommendations.csv?limit=lots", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid limit: status %d, want 400", rec.Code)
+	}
+}
+
+func TestCostSummaryCSV(t *testing.T) {
+	co, _ := newTestOptimizer(t,
+		testNode("node-1", "8", "32Gi"), testNodeMetrics("node-1", "2", "8Gi"),
+		testPod("shop", "web", "node-1", "1", "2Gi"), testPodMetrics("shop", "web", "500m", "1Gi"),
+		testPod("batch", "etl", "node-1", "2", "4Gi"), testPodMetrics("batch", "etl", "1", "2Gi"),
+	)
+	co.history.add(co.generateCostSummary(context.Background()))
+	recorded := co.history.all()[0].LastUpdated.Format(time.RFC3339)
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+	}{
+		{name: "live", wantCode: http.StatusOK},
+		{name: "recorded", query: "?at=" + recorded, wantCode: http.StatusOK},
+		{name: "nothing recorded then", query: "?at=2020-01-01T00:00:00Z", wantCode: http.StatusNotFound},
+		{name: "invalid at", query: "?at=yesterday", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csvRec := serve(http.HandlerFunc(co.handleCostSummaryCSV), http.MethodGet, "/api/cost-summary.csv"+tt.query, nil)
+			if csvRec.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d: %s", csvRec.Code, tt.wantCode, csvRec.Body)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			jsonRec := serve(http.HandlerFunc(co.handleCostSummary), http.MethodGet, "/api/cost-summary"+tt.query, nil)
+			var summary ClusterCostSummary
+			if err := json.Unmarshal(jsonRec.Body.Bytes(), &summary); err != nil {
+				t.Fatalf("decode cost summary: %v", err)
+			}
+
+			rows := readCSV(t, csvRec.Body.String(), csvRec.Header().Get("Content-Type"), costSummaryCSVHeader)
+			var namespaces []string
+			for _, row := range rows {
+				namespace := row[0]
+				namespaces = append(namespaces, namespace)
+				want := []string{
+					namespace,
+					strconv.FormatFloat(summary.NamespaceCosts[namespace], 'f', -1, 64),
+					strconv.FormatFloat(summary.NamespaceUsedCost[namespace], 'f', -1, 64),
+					strconv.FormatFloat(summary.NamespaceIdleCost[namespace], 'f', -1, 64),
+				}
+				if !reflect.DeepEqual(row, want) {
+					t.Errorf("row %q, want the JSON values %q", row, want)
+				}
+			}
+			if want := []string{"batch", "shop"}; !reflect.DeepEqual(namespaces, want) {
+				t.Errorf("namespaces %v, want %v", namespaces, want)
+			}
+		})
+	}
+}
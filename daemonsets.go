@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// analyzeDaemonSets flags DaemonSets whose pods run without requests or
+// limits, and containers whose requests are oversized across the DaemonSet's
+// pods. A DaemonSet runs one identical pod per node, so it's rightsized as a
+// whole, from its pods' usage combined by the replica aggregation, and the
+// savings count every pod at the rate of its node. The pod analyzer leaves DaemonSet pods to this one.
+// Horizontal scaling is never suggested, since the node count sets the
+// number of pods.
+func (co *CostOptimizer) analyzeDaemonSets(ctx context.Context) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	if co.demoMode || co.clientset == nil || co.analyzerDisabled("daemonsets") {
+		return recommendations
+	}
+
+	daemonSets, err := co.clientset.AppsV1().DaemonSets("").List(ctx, co.workloadListOptions())
+	if err != nil {
+		co.analyzerListFailed("daemonsets", "daemonsets", err)
+		return recommendations
+	}
+	daemonSets.Items = inScope(co, daemonSets.Items)
+
+	for i := range daemonSets.Items {
+		daemonSet := &daemonSets.Items[i]
+		if rec := missingResourcesRecommendation("DaemonSet", daemonSet.Namespace, daemonSet.Name, daemonSet.Labels, &daemonSet.Spec.Template.Spec); rec != nil {
+			recommendations = append(recommendations, *rec)
+		}
+	}
+
+	if co.metricsClient == nil {
+		return recommendations
+	}
+	usage, err := co.daemonSetContainerUsage(ctx)
+	if err != nil {
+		warnUsageFailed("Failed to collect DaemonSet usage, skipping DaemonSet rightsizing", err)
+		return recommendations
+	}
+	var allocations map[string]nodeAllocation
+	if nodes, err := co.listNodes(ctx); err != nil {
+		slog.Warn("Failed to list nodes for DaemonSet rightsizing savings", "error", err)
+	} else {
+		allocations = co.nodeAllocations(nodes.Items)
+	}
+	for i := range daemonSets.Items {
+		recommendations = append(recommendations, co.daemonSetRightsizing(&daemonSets.Items[i], usage, allocations)...)
+	}
+
+	return recommendations
+}
+
+// daemonSetUsage holds the per-pod usage of one DaemonSet container, in
+// millicores and bytes, and the node each pod runs on
+type daemonSetUsage struct {
+	cpu, memory []int64
+	nodes       []string
+}
+
+// daemonSetContainerUsage collects the usage of every running DaemonSet pod
+// old enough to rightsize, keyed by namespace/daemonset/container
+func (co *CostOptimizer) daemonSetContainerUsage(ctx context.Context) (map[string]*daemonSetUsage, error) {
+	pods, err := co.listPods(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("list pods: %w", err)
+	}
+	podMetrics, err := co.listPodMetrics(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("get pod metrics: %w", err)
+	}
+
+	owners := make(map[string]string)
+	nodes := make(map[string]string)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase != corev1.PodRunning || co.tooYoungForRightsizing(pod) {
+			continue
+		}
+		if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "DaemonSet" {
+			owners[pod.Namespace+"/"+pod.Name] = pod.Namespace + "/" + owner.Name
+			nodes[pod.Namespace+"/"+pod.Name] = pod.Spec.NodeName
+		}
+	}
+
+	usage := make(map[string]*daemonSetUsage)
+	for _, metrics := range podMetrics.Items {
+		owner, ok := owners[metrics.Namespace+"/"+metrics.Name]
+		if !ok {
+			continue
+		}
+		for _, container := range metrics.Containers {
+			key := owner + "/" + container.Name
+			if usage[key] == nil {
+				usage[key] = &daemonSetUsage{}
+			}
+			usage[key].cpu = append(usage[key].cpu, container.Usage.Cpu().MilliValue())
+			usage[key].memory = append(usage[key].memory, container.Usage.Memory().Value())
+			usage[key].nodes = append(usage[key].nodes, nodes[metrics.Namespace+"/"+metrics.Name])
+		}
+	}
+	return usage, nil
+}
+
+// daemonSetRightsizing suggests lower template requests for the DaemonSet's
+// over-provisioned containers
+func (co *CostOptimizer) daemonSetRightsizing(daemonSet *appsv1.DaemonSet, usage map[string]*daemonSetUsage, allocations map[string]nodeAllocation) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	for _, container := range daemonSet.Spec.Template.Spec.Containers {
+		observed := usage[daemonSet.Namespace+"/"+daemonSet.Name+"/"+container.Name]
+		if observed == nil {
+			continue
+		}
+		// The excess is freed on every pod, each priced at its node's rate
+		price := func(cpu, memory resource.Quantity) float64 {
+			var total float64
+			for _, node := range observed.nodes {
+				total += co.requestMonthlyCost(allocations, node, cpu, memory)
+			}
+			return total
+		}
+
+		cpuRequest := container.Resources.Requests[corev1.ResourceCPU]
+		cpuBasis := aggregateReplicas(observed.cpu, co.replicaAggregation)
+		suggested, clamped := co.rounding.cpuRequest(float64(cpuBasis))
+		if co.thresholds.overProvisioned(cpuBasis, cpuRequest.MilliValue()) && suggested.Cmp(cpuRequest) < 0 {
+			excess := cpuRequest.DeepCopy()
+			excess.Sub(*suggested)
+			recommendations = append(recommendations, co.daemonSetRightsizingRecommendation(daemonSet, container.Name, corev1.ResourceCPU,
+				fmt.Sprintf("%dm", cpuRequest.MilliValue()), fmt.Sprintf("%dm", cpuBasis), suggested, clamped,
+				price(excess, resource.Quantity{})))
+		}
+
+		memoryRequest := container.Resources.Requests[corev1.ResourceMemory]
+		memoryBasis := aggregateReplicas(observed.memory, co.replicaAggregation)
+		suggested, clamped = co.rounding.memoryRequest(float64(memoryBasis))
+		if co.thresholds.overProvisioned(memoryBasis, memoryRequest.Value()) && suggested.Cmp(memoryRequest) < 0 {
+			excess := memoryRequest.DeepCopy()
+			excess.Sub(*suggested)
+			recommendations = append(recommendations, co.daemonSetRightsizingRecommendation(daemonSet, container.Name, corev1.ResourceMemory,
+				memoryRequest.String(), resource.NewQuantity(memoryBasis, resource.BinarySI).String(), suggested, clamped,
+				price(resource.Quantity{}, excess)))
+		}
+	}
+
+	return recommendations
+}
+
+func (co *CostOptimizer) daemonSetRightsizingRecommendation(daemonSet *appsv1.DaemonSet, container string, name corev1.ResourceName, request, usage string, suggested *resource.Quantity, clamped bool, savings float64) Recommendation {
+	rec := Recommendation{
+		Type:        "resource_rightsizing",
+		Category:    CategoryRightsize,
+		Resource:    fmt.Sprintf("%s/%s", daemonSet.Namespace, daemonSet.Name),
+		Namespace:   daemonSet.Namespace,
+		Release:     helmRelease(daemonSet.Labels),
+		labels:      daemonSet.Labels,
+		Description: fmt.Sprintf("Container %s of DaemonSet %s is over-provisioned for %s on every node (request: %s, usage: %s, suggested: %s)", container, daemonSet.Name, name, request, usage, suggested.String()),
+		Impact:      fmt.Sprintf("Reduce %s request to %s%s across all of the DaemonSet's pods", name, suggested.String(), clampNote(clamped, suggested)),
+		ActionHint: &ActionHint{
+			Verb:         "patch",
+			Target:       hintTarget("daemonset", daemonSet.Namespace, daemonSet.Name),
+			Field:        "spec.template." + containerRequestField(container, name),
+			CurrentValue: request,
+			NewValue:     suggested.String(),
+		},
+		Savings:   savings,
+		Priority:  "low",
+		Timestamp: time.Now(),
+	}
+	if name == corev1.ResourceCPU {
+		rec.SuggestedCPURequest = suggested.String()
+	} else {
+		rec.SuggestedMemoryRequest = suggested.String()
+	}
+	return rec
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// testDaemonSet runs an "agent" container with the given requests, none when
+// both are empty
+func testDaemonSet(namespace, name, cpu, memory string) *appsv1.DaemonSet {
+	container := corev1.Container{Name: "agent"}
+	if cpu != "" || memory != "" {
+		container.Resources.Requests = corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(memory),
+		}
+	}
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{container}},
+			},
+		},
+	}
+}
+
+// daemonSetPod is the DaemonSet's pod on node, reporting usage for its agent
+func daemonSetPod(daemonSet *appsv1.DaemonSet, node, cpu, memory string) []runtime.Object {
+	name := daemonSet.Name + "-" + node
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       daemonSet.Namespace,
+			Labels:          daemonSet.Spec.Template.Labels,
+			OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: daemonSet.Name, Controller: boolPtr(true)}},
+		},
+		Spec:   *daemonSet.Spec.Template.Spec.DeepCopy(),
+		Status: corev1.PodStatus{Phase: corev1.PodRunning, StartTime: &testStarted},
+	}
+	pod.Spec.NodeName = node
+	metrics := testPodMetrics(daemonSet.Namespace, name, cpu, memory)
+	metrics.Containers[0].Name = "agent"
+	return []runtime.Object{pod, metrics}
+}
+
+func TestAnalyzeDaemonSets(t *testing.T) {
+	tests := []struct {
+		name      string
+		daemonSet *appsv1.DaemonSet
+		usage     [2]string // of each of the three pods; no pods when empty
+		want      []string  // recommendation types with the request they resize
+	}{
+		{
+			name:      "oversized requests",
+			daemonSet: testDaemonSet("monitoring", "node-exporter", "1", "1Gi"),
+			usage:     [2]string{"100m", "128Mi"},
+			want:      []string{"resource_rightsizing cpu", "resource_rightsizing memory"},
+		},
+		{
+			name:      "oversized cpu only",
+			daemonSet: testDaemonSet("monitoring", "node-exporter", "1", "256Mi"),
+			usage:     [2]string{"100m", "200Mi"},
+			want:      []string{"resource_rightsizing cpu"},
+		},
+		{
+			name:      "right-sized",
+			daemonSet: testDaemonSet("monitoring", "node-exporter", "200m", "256Mi"),
+			usage:     [2]string{"150m", "200Mi"},
+		},
+		{
+			name:      "missing requests",
+			daemonSet: testDaemonSet("monitoring", "node-exporter", "", ""),
+			usage:     [2]string{"100m", "128Mi"},
+			want:      []string{"resource_governance"},
+		},
+		{
+			name:      "no pods yet",
+			daemonSet: testDaemonSet("monitoring", "node-exporter", "1", "1Gi"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := []runtime.Object{tt.daemonSet}
+			if tt.usage[0] != "" {
+				for _, node := range []string{"node-1", "node-2", "node-3"} {
+					objects = append(objects, daemonSetPod(tt.daemonSet, node, tt.usage[0], tt.usage[1])...)
+				}
+			}
+			co, _ := newTestOptimizer(t, objects...)
+
+			recommendations := co.analyzeDaemonSets(context.Background())
+			if len(recommendations) != len(tt.want) {
+				t.Fatalf("got %+v, want %v", recommendations, tt.want)
+			}
+			for i, rec := range recommendations {
+				if rec.Resource != "monitoring/node-exporter" || rec.ActionHint == nil || rec.ActionHint.Target != "daemonset/monitoring/node-exporter" {
+					t.Errorf("recommendation %+v, want one patching daemonset/monitoring/node-exporter", rec)
+				}
+				if strings.Contains(rec.ActionHint.Field, "replicas") {
+					t.Errorf("recommendation %+v scales a DaemonSet", rec)
+				}
+				kind, resourceName, _ := strings.Cut(tt.want[i], " ")
+				if rec.Type != kind {
+					t.Errorf("recommendation %d is %s, want %s", i, rec.Type, kind)
+					continue
+				}
+				if kind != "resource_rightsizing" {
+					continue
+				}
+
+				request := tt.daemonSet.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceName(resourceName)]
+				usageCPU, usageMemory := resource.MustParse(tt.usage[0]), resource.MustParse(tt.usage[1])
+				var suggested *resource.Quantity
+				var wantSavings float64
+				if resourceName == "cpu" {
+					suggested, _ = co.rounding.cpuRequest(float64(usageCPU.MilliValue()))
+					excess := request.DeepCopy()
+					excess.Sub(*suggested)
+					wantSavings = co.estimatePodCost(excess, resource.Quantity{}) * 3
+				} else {
+					suggested, _ = co.rounding.memoryRequest(float64(usageMemory.Value()))
+					excess := request.DeepCopy()
+					excess.Sub(*suggested)
+					wantSavings = co.estimatePodCost(resource.Quantity{}, excess) * 3
+				}
+				if rec.ActionHint.Field != "spec.template."+containerRequestField("agent", corev1.ResourceName(resourceName)) || rec.ActionHint.NewValue != suggested.String() {
+					t.Errorf("hint %+v, want the agent's %s request set to %s", rec.ActionHint, resourceName, suggested)
+				}
+				if math.Abs(rec.Savings-wantSavings) > 1e-9 {
+					t.Errorf("savings %v, want %v for all three pods", rec.Savings, wantSavings)
+				}
+			}
+		})
+	}
+}
+
+// TestAnalyzePodsSkipsDaemonSetPods checks that an over-provisioned DaemonSet
+// pod is left to the DaemonSet analyzer
+func TestAnalyzePodsSkipsDaemonSetPods(t *testing.T) {
+	daemonSet := testDaemonSet("monitoring", "node-exporter", "1", "1Gi")
+	co, _ := newTestOptimizer(t, daemonSetPod(daemonSet, "node-1", "100m", "128Mi")...)
+
+	if recommendations := co.analyzePods(context.Background()); len(recommendations) != 0 {
+		t.Errorf("got %+v, want no pod recommendations", recommendations)
+	}
+}
+
+// TestDaemonSetSavingsAtNodeRate checks that each DaemonSet pod's freed
+// requests are priced at the rate of its own node
+func TestDaemonSetSavingsAtNodeRate(t *testing.T) {
+	tests := []struct {
+		name     string
+		nodes    map[string]string // listed node -> allocatable CPU
+		wantRate func(co *CostOptimizer) float64
+	}{
+		// $1/hour nodes: half their cost is spread over allocatable CPU
+		{name: "same size nodes", nodes: map[string]string{"node-1": "4", "node-2": "4"}, wantRate: func(*CostOptimizer) float64 { return 24 * 30 * (1/4.0 + 1/4.0) / 2 }},
+		{name: "mixed size nodes", nodes: map[string]string{"node-1": "4", "node-2": "16"}, wantRate: func(*CostOptimizer) float64 { return 24 * 30 * (1/4.0 + 1/16.0) / 2 }},
+		{
+			name:     "one node unknown",
+			nodes:    map[string]string{"node-1": "4"},
+			wantRate: func(co *CostOptimizer) float64 { return 24*30/4.0/2 + co.estimatePodCost(resource.MustParse("1"), resource.Quantity{}) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daemonSet := testDaemonSet("monitoring", "node-exporter", "1", "128Mi")
+			objects := []runtime.Object{daemonSet}
+			for _, node := range []string{"node-1", "node-2"} {
+				objects = append(objects, daemonSetPod(daemonSet, node, "100m", "128Mi")...)
+				if cpu, ok := tt.nodes[node]; ok {
+					objects = append(objects, testNode(node, cpu, "16Gi"))
+				}
+			}
+			co, _ := newTestOptimizer(t, objects...)
+			co.costCalculator.NodeCostPerHour = map[string]float64{"default": 1}
+
+			recommendations := co.analyzeDaemonSets(context.Background())
+			if len(recommendations) != 1 {
+				t.Fatalf("got %+v, want one CPU recommendation", recommendations)
+			}
+			suggested, _ := co.rounding.cpuRequest(100)
+			want := float64(1000-suggested.MilliValue()) / 1000 * tt.wantRate(co)
+			if got := recommendations[0].Savings; math.Abs(got-want) > 1e-9 {
+				t.Errorf("savings %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Diagnostics reports the health of the analysis pipeline
+type Diagnostics struct {
+	DisabledAnalyzers map[string]string `json:"disabled_analyzers"` // analyzer -> reason
+	AnalyzerPanics    map[string]string `json:"analyzer_panics"`    // analyzer -> last panic
+
+	// BlockedConsolidations lists, per node/<name> or nodegroup/<name>, the
+	// pods that kept the last scan from recommending its drain
+	BlockedConsolidations map[string][]string `json:"blocked_consolidations"`
+
+	// SuppressedRecommendations counts, per suppression rule, the
+	// recommendations the last scan dropped
+	SuppressedRecommendations map[string]int `json:"suppressed_recommendations"`
+}
+
+func (co *CostOptimizer) analyzerDisabled(analyzer string) bool {
+	co.diagMu.Lock()
+	defer co.diagMu.Unlock()
+
+	_, disabled := co.disabledAnalyzers[analyzer]
+	return disabled
+}
+
+// disableAnalyzer stops an analyzer from running for the rest of the session,
+// logging the reason only the first time.
+func (co *CostOptimizer) disableAnalyzer(analyzer, reason string) {
+	co.diagMu.Lock()
+	defer co.diagMu.Unlock()
+
+	if _, disabled := co.disabledAnalyzers[analyzer]; disabled {
+		return
+	}
+	if co.disabledAnalyzers == nil {
+		co.disabledAnalyzers = make(map[string]string)
+	}
+	co.disabledAnalyzers[analyzer] = reason
+	slog.Warn("Disabling analyzer for this session", "analyzer", analyzer, "reason", reason)
+}
+
+// analyzerListFailed handles a failed List call made by an analyzer. Forbidden
+// errors mean the service account lacks RBAC for the resource, which won't fix
+// itself between scans, so the analyzer is disabled instead of failing every run.
+func (co *CostOptimizer) analyzerListFailed(analyzer, resource string, err error) {
+	if apierrors.IsForbidden(err) {
+		co.disableAnalyzer(analyzer, fmt.Sprintf("forbidden to list %s (check the service account's RBAC): %v", resource, err))
+		return
+	}
+	slog.Warn("Failed to list resources", "analyzer", analyzer, "resource", resource, "error", err)
+}
+
+// runAnalyzer runs one analyzer, recovering from a panic so the rest of the
+// scan still completes with partial results. The panic is logged with its
+// stack and kept in diagnostics; the analyzer runs again on the next scan.
+func (co *CostOptimizer) runAnalyzer(ctx context.Context, analyzer string, analyze func(context.Context) []Recommendation) (recommendations []Recommendation) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Analyzer panicked, continuing without its results", "analyzer", analyzer, "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+			co.recordAnalyzerPanic(analyzer, fmt.Sprintf("%s: %v", co.now().Format(time.RFC3339), r))
+			recommendations = nil
+		}
+	}()
+	recommendations = analyze(ctx)
+	for i := range recommendations {
+		recommendations[i].analyzer = analyzer
+	}
+	return recommendations
+}
+
+func (co *CostOptimizer) recordAnalyzerPanic(analyzer, message string) {
+	co.diagMu.Lock()
+	defer co.diagMu.Unlock()
+
+	if co.analyzerPanics == nil {
+		co.analyzerPanics = make(map[string]string)
+	}
+	co.analyzerPanics[analyzer] = message
+}
+
+func (co *CostOptimizer) diagnostics() Diagnostics {
+	co.diagMu.Lock()
+	defer co.diagMu.Unlock()
+
+	disabled := make(map[string]string, len(co.disabledAnalyzers))
+	for analyzer, reason := range co.disabledAnalyzers {
+		disabled[analyzer] = reason
+	}
+	panics := make(map[string]string, len(co.analyzerPanics))
+	for analyzer, message := range co.analyzerPanics {
+		panics[analyzer] = message
+	}
+	blocked := make(map[string][]string, len(co.blockedConsolidations))
+	for target, pods := range co.blockedConsolidations {
+		blocked[target] = pods
+	}
+	suppressed := make(map[string]int, len(co.suppressedRecommendations))
+	for rule, count := range co.suppressedRecommendations {
+		suppressed[rule] = count
+	}
+	return Diagnostics{
+		DisabledAnalyzers:         disabled,
+		AnalyzerPanics:            panics,
+		BlockedConsolidations:     blocked,
+		SuppressedRecommendations: suppressed,
+	}
+}
+
+func (co *CostOptimizer) handleDiagnostics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(co.diagnostics())
+}
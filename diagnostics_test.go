@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// countLists counts the list calls made on resource
+func countLists(calls []k8stesting.Action, resource string) int {
+	n := 0
+	for _, call := range calls {
+		if call.GetVerb() == "list" && call.GetResource().Resource == resource {
+			n++
+		}
+	}
+	return n
+}
+
+func TestForbiddenAnalyzerIsDisabled(t *testing.T) {
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Group: "apps", Resource: "deployments"}, "", errors.New("no RBAC"))
+	tests := []struct {
+		name         string
+		err          error
+		wantDisabled bool
+		wantRetry    bool // whether the second scan lists deployments again
+	}{
+		{name: "forbidden", err: forbidden, wantDisabled: true},
+		{name: "transient error", err: apierrors.NewServiceUnavailable("try again"), wantRetry: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, client := newTestOptimizer(t, testNode("node-1", "4", "16Gi"), testNodeMetrics("node-1", "1", "4Gi"))
+			co.listCache.ttl = 0
+			client.PrependReactor("list", "deployments", func(k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, tt.err
+			})
+
+			co.analyzeAndGenerateRecommendations(context.Background())
+			first := countLists(client.Actions(), "deployments")
+			co.analyzeAndGenerateRecommendations(context.Background())
+
+			calls := client.Actions()
+			second := countLists(calls, "deployments") - first
+			if first == 0 {
+				t.Fatal("first scan never listed deployments")
+			}
+			if (second > 0) != tt.wantRetry {
+				t.Errorf("second scan listed deployments %d times after %d in the first, want retried %v", second, first, tt.wantRetry)
+			}
+			// The other analyzers keep running
+			if got := countLists(calls, "nodes"); got < 2 {
+				t.Errorf("listed nodes %d times, want at least once per scan", got)
+			}
+
+			rec := serve(http.HandlerFunc(co.handleDiagnostics), http.MethodGet, "/api/diagnostics", nil)
+			var diagnostics Diagnostics
+			if err := json.Unmarshal(rec.Body.Bytes(), &diagnostics); err != nil {
+				t.Fatalf("decode diagnostics: %v", err)
+			}
+			if _, disabled := diagnostics.DisabledAnalyzers["deployments"]; disabled != tt.wantDisabled {
+				t.Errorf("disabled analyzers = %v, want deployments disabled %v", diagnostics.DisabledAnalyzers, tt.wantDisabled)
+			}
+		})
+	}
+}
+
+func TestRunAnalyzerRecoversPanics(t *testing.T) {
+	tests := []struct {
+		name      string
+		analyze   func(context.Context) []Recommendation
+		wantCount int
+		wantPanic string
+	}{
+		{
+			name:      "healthy analyzer",
+			analyze:   func(context.Context) []Recommendation { return []Recommendation{{Type: "node_optimization"}} },
+			wantCount: 1,
+		},
+		{
+			name: "nil pointer",
+			analyze: func(context.Context) []Recommendation {
+				var node *corev1.Node
+				return []Recommendation{{Resource: node.Name}}
+			},
+			wantPanic: "nil pointer dereference",
+		},
+		{
+			name:      "explicit panic",
+			analyze:   func(context.Context) []Recommendation { panic("unexpected object shape") },
+			wantPanic: "unexpected object shape",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t)
+			co.now = func() time.Time { return time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC) }
+
+			if got := co.runAnalyzer(context.Background(), "test", tt.analyze); len(got) != tt.wantCount {
+				t.Errorf("runAnalyzer returned %d recommendations, want %d", len(got), tt.wantCount)
+			}
+			message, panicked := co.diagnostics().AnalyzerPanics["test"]
+			if panicked != (tt.wantPanic != "") || !strings.Contains(message, tt.wantPanic) {
+				t.Errorf("recorded panic %q, want one mentioning %q", message, tt.wantPanic)
+			}
+			if panicked && !strings.HasPrefix(message, "2024-03-05T09:00:00Z: ") {
+				t.Errorf("recorded panic %q, want it timestamped", message)
+			}
+		})
+	}
+}
+
+func TestScanSurvivesPanickingAnalyzer(t *testing.T) {
+	// The fake clientset has no REST client, so the emptyDir analyzer panics
+	// when it fetches kubelet stats for the large emptyDir
+	co, _ := newTestOptimizer(t,
+		testNode("node-1", "8", "32Gi"), testNodeMetrics("node-1", "1", "4Gi"),
+		withEmptyDir(testPod("shop", "builder", "node-1", "2", "1Gi"), "scratch", "10Gi", ""),
+		testPodMetrics("shop", "builder", "100m", "1Gi"),
+	)
+
+	co.analyzeAndGenerateRecommendations(context.Background())
+
+	var rightsizing bool
+	for _, rec := range co.recommendations {
+		rightsizing = rightsizing || rec.Type == "resource_rightsizing"
+	}
+	if !rightsizing {
+		t.Errorf("recommendations %+v, want the pod analyzer's results despite the panic", co.recommendations)
+	}
+
+	rec := serve(http.HandlerFunc(co.handleDiagnostics), http.MethodGet, "/api/diagnostics", nil)
+	var diagnostics Diagnostics
+	if err := json.Unmarshal(rec.Body.Bytes(), &diagnostics); err != nil {
+		t.Fatalf("decode diagnostics: %v", err)
+	}
+	if _, ok := diagnostics.AnalyzerPanics["emptydir"]; !ok || len(diagnostics.AnalyzerPanics) != 1 {
+		t.Errorf("analyzer panics = %v, want only emptydir", diagnostics.AnalyzerPanics)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// egressMinimumAge is how long a pod's network must have run before its
+// transmit rate is extrapolated to a month
+const egressMinimumAge = 10 * time.Minute
+
+// networkCosts estimates a month of network egress for the running pods from
+// the bytes each has transmitted since it started, as reported by the
+// kubelets, in total and by namespace. Traffic to other pods in the cluster
+// is counted too, so this is an upper bound. Pods on host networking share
+// the node's counters and are skipped. Both are empty when no egress price is
+// configured.
+func (co *CostOptimizer) networkCosts(ctx context.Context, pods []corev1.Pod) (float64, map[string]float64) {
+	price := co.costCalculator.EgressCostPerGB
+	if price <= 0 {
+		return 0, nil
+	}
+
+	// Pods whose traffic is their own, by node
+	running := make(map[string]map[string]bool)
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase != corev1.PodRunning || pod.Spec.HostNetwork {
+			continue
+		}
+		if running[pod.Spec.NodeName] == nil {
+			running[pod.Spec.NodeName] = make(map[string]bool)
+		}
+		running[pod.Spec.NodeName][pod.Namespace+"/"+pod.Name] = true
+	}
+
+	nodeNames := make([]string, 0, len(running))
+	for nodeName := range running {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	now := co.now()
+	var total float64
+	byNamespace := make(map[string]float64)
+	for _, nodeName := range nodeNames {
+		summary, err := co.kubeletStatsSummary(ctx, nodeName)
+		if err != nil {
+			slog.Warn("Failed to read network usage, leaving the node's pods out of the network cost", "node", nodeName, "error", err)
+			continue
+		}
+		for _, pod := range summary.Pods {
+			if !running[nodeName][pod.PodRef.Namespace+"/"+pod.PodRef.Name] || pod.Network == nil || pod.Network.TxBytes == nil {
+				continue
+			}
+			age := now.Sub(pod.StartTime)
+			if pod.StartTime.IsZero() || age < egressMinimumAge {
+				continue
+			}
+			monthlyBytes := float64(*pod.Network.TxBytes) / age.Hours() * 24 * 30
+			cost := monthlyBytes / (1024 * 1024 * 1024) * price
+			total += cost
+			byNamespace[pod.PodRef.Namespace] += cost
+		}
+	}
+	return total, byNamespace
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// txStats serves a kubelet stats summary in which each pod on the node has
+// sent its given bytes since started
+func txStats(started time.Time, sent map[string]uint64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/nodes/node-1/proxy/stats/summary" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"pods":[`)
+		i := 0
+		for pod, bytes := range sent {
+			if i > 0 {
+				fmt.Fprint(w, ",")
+			}
+			i++
+			fmt.Fprintf(w, `{"podRef":{"name":%q,"namespace":"shop"},"startTime":%q,"network":{"txBytes":%d}}`,
+				pod, started.Format(time.RFC3339), bytes)
+		}
+		fmt.Fprint(w, `]}`)
+	}
+}
+
+func TestNetworkCosts(t *testing.T) {
+	const gi = 1 << 30
+	now := time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)
+	hostNetwork := testPod("shop", "agent", "node-1", "100m", "128Mi")
+	hostNetwork.Spec.HostNetwork = true
+
+	tests := []struct {
+		name    string
+		price   float64
+		age     time.Duration
+		sent    map[string]uint64
+		pods    []runtime.Object
+		want    float64
+		wantErr bool // the kubelet fails
+	}{
+		// 6 GiB over 6 hours is 720 GiB a month
+		{name: "priced", price: 0.09, age: 6 * time.Hour, sent: map[string]uint64{"web": 6 * gi}, want: 720 * 0.09},
+		{name: "unpriced", age: 6 * time.Hour, sent: map[string]uint64{"web": 6 * gi}},
+		{name: "pod too young to extrapolate", price: 0.09, age: 5 * time.Minute, sent: map[string]uint64{"web": gi}},
+		{name: "host network pod", price: 0.09, age: 6 * time.Hour, sent: map[string]uint64{"agent": 6 * gi}, pods: []runtime.Object{hostNetwork}},
+		{name: "pod no longer listed", price: 0.09, age: 6 * time.Hour, sent: map[string]uint64{"web": 6 * gi, "gone": 6 * gi}, want: 720 * 0.09},
+		{name: "kubelet unreachable", price: 0.09, age: 6 * time.Hour, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{testNode("node-1", "4", "16Gi"), testPod("shop", "web", "node-1", "100m", "128Mi")}, tt.pods...)
+			co, client := newTestOptimizer(t, objects...)
+			co.now = func() time.Time { return now }
+			co.costCalculator.EgressCostPerGB = tt.price
+			handler := txStats(now.Add(-tt.age), tt.sent)
+			if tt.wantErr {
+				handler = func(w http.ResponseWriter, r *http.Request) { http.Error(w, "unavailable", http.StatusBadGateway) }
+			}
+			withRESTServer(t, co, client, handler)
+
+			pods, err := client.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			total, byNamespace := co.networkCosts(context.Background(), pods.Items)
+			if math.Abs(total-tt.want) > 1e-6 || math.Abs(byNamespace["shop"]-tt.want) > 1e-6 {
+				t.Errorf("total %v, by namespace %v; want %v, all in shop", total, byNamespace, tt.want)
+			}
+		})
+	}
+}
+
+// TestCostSummaryEphemeralAndNetworkCost checks that the new costs reach the
+// total and the namespaces, and stay zero when unpriced
+func TestCostSummaryEphemeralAndNetworkCost(t *testing.T) {
+	const gi = 1 << 30
+	now := time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		ephemeral     float64
+		egress        float64
+		wantEphemeral float64
+		wantNetwork   float64
+	}{
+		{name: "unpriced"},
+		// 20Gi requested, and 1 GiB an hour is 720 GiB a month
+		{name: "priced", ephemeral: 0.1, egress: 0.05, wantEphemeral: 2, wantNetwork: 36},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, client := newTestOptimizer(t,
+				testNode("node-1", "4", "16Gi"), testNodeMetrics("node-1", "1", "4Gi"),
+				withEphemeralRequest(testPod("shop", "web", "node-1", "1", "1Gi"), "20Gi"), testPodMetrics("shop", "web", "500m", "1Gi"),
+			)
+			co.now = func() time.Time { return now }
+			withRESTServer(t, co, client, txStats(now.Add(-6*time.Hour), map[string]uint64{"web": 6 * gi}))
+			unpriced := co.generateCostSummary(context.Background())
+			co.costCalculator.EphemeralStorageCostPerGB = tt.ephemeral
+			co.costCalculator.EgressCostPerGB = tt.egress
+
+			summary := co.generateCostSummary(context.Background())
+			if math.Abs(summary.EphemeralStorageCost-tt.wantEphemeral) > 1e-6 || math.Abs(summary.NetworkCost-tt.wantNetwork) > 1e-6 {
+				t.Errorf("ephemeral storage %v, network %v; want %v, %v", summary.EphemeralStorageCost, summary.NetworkCost, tt.wantEphemeral, tt.wantNetwork)
+			}
+			extra := tt.wantEphemeral + tt.wantNetwork
+			if math.Abs(summary.TotalMonthlyCost-unpriced.TotalMonthlyCost-extra) > 1e-6 {
+				t.Errorf("total %v, want %v more than unpriced %v", summary.TotalMonthlyCost, extra, unpriced.TotalMonthlyCost)
+			}
+			if math.Abs(summary.NamespaceCosts["shop"]-unpriced.NamespaceCosts["shop"]-extra) > 1e-6 {
+				t.Errorf("shop costs %v, want %v more than unpriced %v", summary.NamespaceCosts["shop"], extra, unpriced.NamespaceCosts["shop"])
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// emaTracker keeps an exponential moving average of a pair of values (CPU and
+// memory) per key across scans. A nil tracker is disabled and passes samples
+// through unchanged.
+type emaTracker struct {
+	alpha float64
+
+	mu       sync.Mutex
+	values   map[string][2]float64
+	observed map[string]bool // keys seen since the last sweep
+}
+
+// newEMATracker weights each new sample by alpha; smaller values smooth more
+func newEMATracker(alpha float64) (*emaTracker, error) {
+	if alpha <= 0 || alpha > 1 {
+		return nil, fmt.Errorf("EMA alpha %v must be in (0, 1]", alpha)
+	}
+	return &emaTracker{
+		alpha:    alpha,
+		values:   make(map[string][2]float64),
+		observed: make(map[string]bool),
+	}, nil
+}
+
+// observe folds a sample into the average for key and returns the smoothed
+// values. The first sample for a key seeds the average.
+func (t *emaTracker) observe(key string, cpu, memory float64) (float64, float64) {
+	if t == nil {
+		return cpu, memory
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.observed[key] = true
+	prev, ok := t.values[key]
+	if !ok {
+		t.values[key] = [2]float64{cpu, memory}
+		return cpu, memory
+	}
+	next := [2]float64{
+		t.alpha*cpu + (1-t.alpha)*prev[0],
+		t.alpha*memory + (1-t.alpha)*prev[1],
+	}
+	t.values[key] = next
+	return next[0], next[1]
+}
+
+// get returns the current smoothed values for key without updating them
+func (t *emaTracker) get(key string) (cpu, memory float64, ok bool) {
+	if t == nil {
+		return 0, 0, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	v, ok := t.values[key]
+	return v[0], v[1], ok
+}
+
+// sweep forgets keys not observed since the previous sweep, so deleted pods
+// and nodes don't accumulate
+func (t *emaTracker) sweep() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.values {
+		if !t.observed[key] {
+			delete(t.values, key)
+		}
+	}
+	t.observed = make(map[string]bool)
+}
+
+func containerEMAKey(namespace, pod, container string) string {
+	return namespace + "/" + pod + "/" + container
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+func TestEMATrackerSmoothsSpikes(t *testing.T) {
+	tracker, err := newEMATracker(0.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Alternating spikes around a mean of 50
+	var smoothed float64
+	for i := 0; i < 40; i++ {
+		sample := 10.0
+		if i%2 == 1 {
+			sample = 90
+		}
+		smoothed, _ = tracker.observe("node-1", sample, sample)
+		if i > 20 && math.Abs(smoothed-50) > 10 {
+			t.Errorf("sample %d: EMA %v strays more than 10 from the mean 50 (raw %v)", i, smoothed, sample)
+		}
+	}
+	if cpu, _, ok := tracker.get("node-1"); !ok || cpu != smoothed {
+		t.Errorf("get = %v, %v, want the last smoothed value %v", cpu, ok, smoothed)
+	}
+}
+
+func TestEMATracker(t *testing.T) {
+	tests := []struct {
+		name    string
+		alpha   float64
+		samples []float64
+		want    float64
+		wantErr bool
+	}{
+		{name: "first sample seeds", alpha: 0.5, samples: []float64{40}, want: 40},
+		{name: "weights new sample by alpha", alpha: 0.25, samples: []float64{40, 80}, want: 50},
+		{name: "alpha one tracks raw", alpha: 1, samples: []float64{40, 80}, want: 80},
+		{name: "zero alpha", alpha: 0, wantErr: true},
+		{name: "alpha above one", alpha: 1.5, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, err := newEMATracker(tt.alpha)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newEMATracker error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var got float64
+			for _, sample := range tt.samples {
+				got, _ = tracker.observe("key", sample, sample)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("EMA = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEMATrackerSweepAndDisabled(t *testing.T) {
+	tracker, _ := newEMATracker(0.5)
+	tracker.observe("kept", 1, 1)
+	tracker.observe("gone", 1, 1)
+	tracker.sweep()
+	tracker.observe("kept", 1, 1)
+	tracker.sweep()
+	if _, _, ok := tracker.get("gone"); ok {
+		t.Error("sweep kept a key not observed since the previous sweep")
+	}
+	if _, _, ok := tracker.get("kept"); !ok {
+		t.Error("sweep dropped an observed key")
+	}
+
+	var disabled *emaTracker
+	if cpu, memory := disabled.observe("key", 3, 4); cpu != 3 || memory != 4 {
+		t.Errorf("disabled tracker returned %v, %v, want the samples unchanged", cpu, memory)
+	}
+}
+
+func TestEMAOverProvisioningAcrossScans(t *testing.T) {
+	tests := []struct {
+		name        string
+		alpha       string
+		wantFlagged bool
+		wantEMA     float64 // CPU cores reported in pod metrics
+	}{
+		{name: "raw samples", wantFlagged: false},
+		{name: "smoothed", alpha: "0.3", wantFlagged: true, wantEMA: 0.34},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OPTIMKUBE_EMA_ALPHA", tt.alpha)
+			co, _ := newTestOptimizer(t,
+				testPod("shop", "api", "node-1", "1", "1Gi"),
+				testPodMetrics("shop", "api", "100m", "1Gi"))
+			// Each call stands for a scan of the cluster as it is then
+			co.listCache.ttl = 0
+			co.analyzePods(context.Background())
+
+			// A one-scan spike past half the request
+			metrics := co.metricsClient.(*metricsfake.Clientset)
+			if err := metrics.Tracker().Update(metricsv1beta1.SchemeGroupVersion.WithResource("pods"), testPodMetrics("shop", "api", "900m", "1Gi"), "shop"); err != nil {
+				t.Fatal(err)
+			}
+
+			flagged := false
+			for _, rec := range co.analyzePods(context.Background()) {
+				flagged = flagged || strings.Contains(rec.Description, "for CPU")
+			}
+			if flagged != tt.wantFlagged {
+				t.Errorf("CPU over-provisioning flagged %v after the spike, want %v", flagged, tt.wantFlagged)
+			}
+
+			pods := co.getPodMetrics(context.Background(), "")
+			if len(pods) != 1 || math.Abs(pods[0].CPUUsageEMA-tt.wantEMA) > 1e-9 {
+				t.Errorf("pod metrics %+v, want CPU usage EMA %v", pods, tt.wantEMA)
+			}
+		})
+	}
+}
+
+func TestEMAAlphaSetting(t *testing.T) {
+	for _, value := range []string{"0", "2", "smooth"} {
+		t.Setenv("DEMO_MODE", "true")
+		t.Setenv("OPTIMKUBE_EMA_ALPHA", value)
+		if _, err := NewCostOptimizer(); err == nil || !strings.Contains(err.Error(), "OPTIMKUBE_EMA_ALPHA") {
+			t.Errorf("OPTIMKUBE_EMA_ALPHA=%s: error = %v, want it rejected", value, err)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// emptyDirLargeBytes is the per-pod emptyDir sizeLimit total worth checking
+const emptyDirLargeBytes = 1 << 30
+
+// emptyDirHeadroom is how many times its current usage a reservation may be
+// before it's considered unjustified
+const emptyDirHeadroom = 2
+
+// emptyDirMinimumLimit is the smallest sizeLimit suggested for a volume that
+// barely uses anything
+const emptyDirMinimumLimit = 128 << 20
+
+// kubeletSummary is the part of the kubelet's /stats/summary response that
+// reports per-pod volume usage and network traffic
+type kubeletSummary struct {
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		StartTime time.Time `json:"startTime"`
+		Volumes   []struct {
+			Name      string  `json:"name"`
+			UsedBytes *uint64 `json:"usedBytes"`
+		} `json:"volume"`
+		Network *struct {
+			TxBytes *uint64 `json:"txBytes"` // since the pod's network started
+		} `json:"network"`
+	} `json:"pods"`
+}
+
+// emptyDirReservation is the emptyDir storage one pod reserves through sizeLimits
+type emptyDirReservation struct {
+	pod      *corev1.Pod
+	reserved int64
+	volumes  map[string]bool
+}
+
+// analyzeEmptyDirReservations flags pods whose emptyDir sizeLimits reserve far
+// more node ephemeral storage than they use. The scheduler counts the limits
+// against the node's allocatable ephemeral storage, so oversized limits can
+// block scheduling on nodes that have disk to spare.
+func (co *CostOptimizer) analyzeEmptyDirReservations(ctx context.Context) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	if co.demoMode || co.clientset == nil || co.analyzerDisabled("emptydir") {
+		return recommendations
+	}
+
+	pods, err := co.listPods(ctx, "")
+	if err != nil {
+		co.analyzerListFailed("emptydir", "pods", err)
+		return recommendations
+	}
+	nodes, err := co.listNodes(ctx)
+	if err != nil {
+		co.analyzerListFailed("emptydir", "nodes", err)
+		return recommendations
+	}
+	allocatable := make(map[string]int64, len(nodes.Items))
+	for _, node := range nodes.Items {
+		allocatable[node.Name] = node.Status.Allocatable.StorageEphemeral().Value()
+	}
+
+	reservedOnNode := make(map[string]int64)
+	large := make(map[string][]emptyDirReservation)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		reservation := emptyDirReservation{pod: pod, volumes: make(map[string]bool)}
+		for _, volume := range pod.Spec.Volumes {
+			if volume.EmptyDir == nil || volume.EmptyDir.SizeLimit == nil || volume.EmptyDir.Medium == corev1.StorageMediumMemory {
+				continue
+			}
+			reservation.reserved += volume.EmptyDir.SizeLimit.Value()
+			reservation.volumes[volume.Name] = true
+		}
+		reservedOnNode[pod.Spec.NodeName] += reservation.reserved
+		if reservation.reserved >= emptyDirLargeBytes {
+			large[pod.Spec.NodeName] = append(large[pod.Spec.NodeName], reservation)
+		}
+	}
+
+	nodeNames := make([]string, 0, len(large))
+	for nodeName := range large {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	for _, nodeName := range nodeNames {
+		reservations := large[nodeName]
+		summary, err := co.kubeletStatsSummary(ctx, nodeName)
+		if err != nil {
+			slog.Warn("Failed to read volume usage, skipping the node's emptyDir check", "node", nodeName, "error", err)
+			continue
+		}
+
+		for _, reservation := range reservations {
+			used, ok := summary.emptyDirUsage(reservation)
+			if !ok || used*emptyDirHeadroom >= reservation.reserved {
+				continue
+			}
+
+			pod := reservation.pod
+			priority := "low"
+			if 2*reservedOnNode[nodeName] >= allocatable[nodeName] {
+				priority = "medium"
+			}
+			suggested := resource.NewQuantity(max(used*emptyDirHeadroom, emptyDirMinimumLimit), resource.BinarySI)
+
+			recommendations = append(recommendations, Recommendation{
+				Type:      "emptydir_oversized",
+				Category:  CategoryRightsize,
+				Resource:  fmt.Sprintf("%s/%s", pod.Namespace, pod.Name),
+				Namespace: pod.Namespace,
+				Release:   helmRelease(pod.Labels),
+				labels:    pod.Labels,
+				Description: fmt.Sprintf("Pod %s reserves %.1f GB of emptyDir storage but uses %.1f GB; node %s has %.1f GB of its %.1f GB allocatable ephemeral storage reserved by emptyDir limits",
+					pod.Name, gigabytes(reservation.reserved), gigabytes(used), nodeName, gigabytes(reservedOnNode[nodeName]), gigabytes(allocatable[nodeName])),
+				Impact: fmt.Sprintf("Lower the emptyDir sizeLimit to about %s to free node ephemeral storage for scheduling", suggested.String()),
+				ActionHint: &ActionHint{
+					Verb:         "patch",
+					Target:       hintTarget("pod", pod.Namespace, pod.Name),
+					Field:        "spec.volumes[*].emptyDir.sizeLimit",
+					CurrentValue: resource.NewQuantity(reservation.reserved, resource.BinarySI).String(),
+					NewValue:     suggested.String(),
+				},
+				Priority:  priority,
+				Timestamp: time.Now(),
+			})
+		}
+	}
+
+	return recommendations
+}
+
+// kubeletStatsSummary fetches a node's kubelet stats summary through the API
+// server's node proxy
+func (co *CostOptimizer) kubeletStatsSummary(ctx context.Context, nodeName string) (*kubeletSummary, error) {
+	raw, err := co.clientset.CoreV1().RESTClient().Get().
+		AbsPath("/api/v1/nodes", nodeName, "proxy", "stats", "summary").DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var summary kubeletSummary
+	if err := json.Unmarshal(raw, &summary); err != nil {
+		return nil, fmt.Errorf("decode stats summary: %w", err)
+	}
+	return &summary, nil
+}
+
+// emptyDirUsage sums the bytes used by a pod's size-limited emptyDir volumes,
+// reporting false when the kubelet has no usage for the pod
+func (s *kubeletSummary) emptyDirUsage(reservation emptyDirReservation) (int64, bool) {
+	for _, pod := range s.Pods {
+		if pod.PodRef.Namespace != reservation.pod.Namespace || pod.PodRef.Name != reservation.pod.Name {
+			continue
+		}
+		var used int64
+		for _, volume := range pod.Volumes {
+			if reservation.volumes[volume.Name] && volume.UsedBytes != nil {
+				used += int64(*volume.UsedBytes)
+			}
+		}
+		return used, true
+	}
+	return 0, false
+}
+
+func gigabytes(bytes int64) float64 {
+	return float64(bytes) / (1024 * 1024 * 1024)
+}
+
+// ephemeralStorageCosts prices the ephemeral-storage requests of scheduled,
+// running pods, in total and by namespace. Both are empty when no ephemeral
+// storage price is configured.
+func (co *CostOptimizer) ephemeralStorageCosts(pods []corev1.Pod) (float64, map[string]float64) {
+	price := co.costCalculator.EphemeralStorageCostPerGB
+	if price <= 0 {
+		return 0, nil
+	}
+
+	var total float64
+	byNamespace := make(map[string]float64)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		cost := gigabytes(podRequests(pod, corev1.ResourceEphemeralStorage)) * price
+		if cost == 0 {
+			continue
+		}
+		total += cost
+		byNamespace[pod.Namespace] += cost
+	}
+	return total, byNamespace
+}
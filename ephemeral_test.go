@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+)
+
+// restClientset is a fake clientset whose core REST client, nil in the fake,
+// reaches a real server for raw requests such as the kubelet node proxy
+type restClientset struct {
+	*fake.Clientset
+	rest rest.Interface
+}
+
+func (c restClientset) CoreV1() corev1client.CoreV1Interface {
+	return restCoreV1{c.Clientset.CoreV1(), c.rest}
+}
+
+type restCoreV1 struct {
+	corev1client.CoreV1Interface
+	rest rest.Interface
+}
+
+func (c restCoreV1) RESTClient() rest.Interface { return c.rest }
+
+// withRESTServer points the optimizer's raw core requests at handler
+func withRESTServer(t *testing.T, co *CostOptimizer, client *fake.Clientset, handler http.Handler) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	real, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	co.clientset = restClientset{client, real.CoreV1().RESTClient()}
+}
+
+// withEmptyDir adds a disk-backed emptyDir volume limited to size
+func withEmptyDir(pod *corev1.Pod, name, size string, medium corev1.StorageMedium) *corev1.Pod {
+	limit := resource.MustParse(size)
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name:         name,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: &limit, Medium: medium}},
+	})
+	return pod
+}
+
+func TestAnalyzeEmptyDirReservations(t *testing.T) {
+	const gi = 1 << 30
+	tests := []struct {
+		name         string
+		size         string
+		medium       corev1.StorageMedium
+		used         int64 // bytes; no stats for the pod when negative
+		ephemeral    string
+		wantPriority string // no recommendation when empty
+		wantNewLimit string
+	}{
+		{name: "large and barely used", size: "10Gi", used: gi, ephemeral: "100Gi", wantPriority: "low", wantNewLimit: "2Gi"},
+		{name: "reservations crowd the node", size: "10Gi", used: gi, ephemeral: "15Gi", wantPriority: "medium", wantNewLimit: "2Gi"},
+		{name: "nearly empty gets the minimum", size: "10Gi", used: 1 << 20, ephemeral: "100Gi", wantPriority: "low", wantNewLimit: "128Mi"},
+		{name: "usage justifies the limit", size: "10Gi", used: 6 * gi, ephemeral: "100Gi"},
+		{name: "small limit", size: "512Mi", used: 0, ephemeral: "100Gi"},
+		{name: "memory-backed", size: "10Gi", medium: corev1.StorageMediumMemory, used: 0, ephemeral: "100Gi"},
+		{name: "no usage reported", size: "10Gi", used: -1, ephemeral: "100Gi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := testNode("node-1", "8", "32Gi")
+			node.Status.Allocatable[corev1.ResourceEphemeralStorage] = resource.MustParse(tt.ephemeral)
+			pod := withEmptyDir(testPod("shop", "builder", "node-1", "100m", "128Mi"), "scratch", tt.size, tt.medium)
+			co, client := newTestOptimizer(t, node, pod)
+
+			withRESTServer(t, co, client, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v1/nodes/node-1/proxy/stats/summary" {
+					http.NotFound(w, r)
+					return
+				}
+				if tt.used < 0 {
+					fmt.Fprint(w, `{"pods":[]}`)
+					return
+				}
+				fmt.Fprintf(w, `{"pods":[{"podRef":{"name":"builder","namespace":"shop"},"volume":[{"name":"scratch","usedBytes":%d},{"name":"kube-api-access","usedBytes":4096}]}]}`, tt.used)
+			}))
+
+			recommendations := co.analyzeEmptyDirReservations(context.Background())
+			if tt.wantPriority == "" {
+				if len(recommendations) != 0 {
+					t.Fatalf("got %+v, want no recommendation", recommendations)
+				}
+				return
+			}
+			if len(recommendations) != 1 {
+				t.Fatalf("got %d recommendations, want 1", len(recommendations))
+			}
+			rec := recommendations[0]
+			if rec.Type != "emptydir_oversized" || rec.Resource != "shop/builder" || rec.Priority != tt.wantPriority {
+				t.Errorf("recommendation = %s %s %s, want emptydir_oversized shop/builder %s", rec.Type, rec.Resource, rec.Priority, tt.wantPriority)
+			}
+			if !strings.Contains(rec.Description, "reserves 10.0 GB of emptyDir storage") {
+				t.Errorf("Description = %q, want the reserved figure", rec.Description)
+			}
+			if rec.ActionHint == nil || rec.ActionHint.CurrentValue != "10Gi" || rec.ActionHint.NewValue != tt.wantNewLimit {
+				t.Errorf("ActionHint = %+v, want 10Gi lowered to %s", rec.ActionHint, tt.wantNewLimit)
+			}
+		})
+	}
+}
+
+// withEphemeralRequest makes a pod's container request size of ephemeral
+// storage
+func withEphemeralRequest(pod *corev1.Pod, size string) *corev1.Pod {
+	pod.Spec.Containers[0].Resources.Requests[corev1.ResourceEphemeralStorage] = resource.MustParse(size)
+	return pod
+}
+
+func TestEphemeralStorageCosts(t *testing.T) {
+	pending := withEphemeralRequest(testPod("shop", "queued", "", "100m", "128Mi"), "50Gi")
+	pending.Status.Phase = corev1.PodPending
+	pods := []corev1.Pod{
+		*withEphemeralRequest(testPod("shop", "builder", "node-1", "100m", "128Mi"), "20Gi"),
+		*withEphemeralRequest(testPod("shop", "cache", "node-1", "100m", "128Mi"), "512Mi"),
+		*withEphemeralRequest(testPod("batch", "etl", "node-1", "100m", "128Mi"), "10Gi"),
+		*testPod("batch", "web", "node-1", "100m", "128Mi"),
+		*pending,
+	}
+
+	tests := []struct {
+		name      string
+		price     float64
+		want      float64
+		wantShare map[string]float64
+	}{
+		{name: "priced", price: 0.1, want: 3.05, wantShare: map[string]float64{"shop": 2.05, "batch": 1}},
+		{name: "unpriced", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t)
+			co.costCalculator.EphemeralStorageCostPerGB = tt.price
+
+			total, byNamespace := co.ephemeralStorageCosts(pods)
+			if math.Abs(total-tt.want) > 1e-9 {
+				t.Errorf("total %v, want %v", total, tt.want)
+			}
+			if len(byNamespace) != len(tt.wantShare) {
+				t.Errorf("by namespace %v, want %v", byNamespace, tt.wantShare)
+			}
+			for namespace, want := range tt.wantShare {
+				if math.Abs(byNamespace[namespace]-want) > 1e-9 {
+					t.Errorf("%s costs %v, want %v", namespace, byNamespace[namespace], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultExportInterval is how often reports are written when no interval is configured
+const defaultExportInterval = time.Hour
+
+// objectStore uploads a single object to a bucket
+type objectStore interface {
+	Put(ctx context.Context, key string, body []byte, contentType, contentEncoding string) error
+}
+
+// ExportReport is the document written to object storage on each export
+type ExportReport struct {
+	Cluster         string             `json:"cluster"`
+	GeneratedAt     time.Time          `json:"generated_at"`
+	Summary         ClusterCostSummary `json:"summary"`
+	Recommendations []Recommendation   `json:"recommendations"`
+}
+
+// reportExporter periodically snapshots the current report to object storage
+type reportExporter struct {
+	store    objectStore
+	prefix   string
+	interval time.Duration
+	gzip     bool
+}
+
+// newReportExporterFromEnv builds an exporter from OPTIMKUBE_EXPORT_* settings.
+// It returns nil when exporting is not configured.
+func newReportExporterFromEnv(ctx context.Context) (*reportExporter, error) {
+	target := os.Getenv("OPTIMKUBE_EXPORT_URL")
+	if target == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid OPTIMKUBE_EXPORT_URL %q: expected s3://bucket/prefix or gs://bucket/prefix", target)
+	}
+
+	exporter := &reportExporter{
+		prefix:   strings.Trim(u.Path, "/"),
+		interval: defaultExportInterval,
+		gzip:     strings.EqualFold(os.Getenv("OPTIMKUBE_EXPORT_GZIP"), "true"),
+	}
+
+	if raw := os.Getenv("OPTIMKUBE_EXPORT_INTERVAL"); raw != "" {
+		interval, err := time.ParseDuration(raw)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid OPTIMKUBE_EXPORT_INTERVAL %q: expected a positive duration such as 1h", raw)
+		}
+		exporter.interval = interval
+	}
+
+	switch u.Scheme {
+	case "s3":
+		exporter.store, err = newS3Store(ctx, u.Host)
+	case "gs":
+		exporter.store, err = newGCSStore(ctx, u.Host, os.Getenv("OPTIMKUBE_EXPORT_TOKEN"))
+	default:
+		err = fmt.Errorf("unsupported OPTIMKUBE_EXPORT_URL scheme %q: expected s3 or gs", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return exporter, nil
+}
+
+// StartExporter writes a report on every export interval until ctx is
+// cancelled. Failures are logged and never interrupt scanning.
+func (co *CostOptimizer) StartExporter(ctx context.Context) {
+	ticker := time.NewTicker(co.exporter.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := co.exportReport(ctx); err != nil {
+				slog.Error("Failed to export report", "error", err)
+			}
+		}
+	}
+}
+
+func (co *CostOptimizer) exportReport(ctx context.Context) error {
+	report := ExportReport{
+		Cluster:         co.clusterName,
+		GeneratedAt:     co.now().UTC(),
+		Summary:         co.generateCostSummary(ctx),
+		Recommendations: co.activeRecommendations(),
+	}
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("encode report: %w", err)
+	}
+
+	key := co.exporter.objectKey(co.clusterName, report.GeneratedAt)
+	contentEncoding := ""
+	if co.exporter.gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return fmt.Errorf("compress report: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("compress report: %w", err)
+		}
+		body = buf.Bytes()
+		contentEncoding = "gzip"
+	}
+
+	if err := co.exporter.store.Put(ctx, key, body, "application/json", contentEncoding); err != nil {
+		return fmt.Errorf("upload %s: %w", key, err)
+	}
+	slog.Info("Exported report", "key", key)
+	return nil
+}
+
+// objectKey lays reports out by cluster and day, e.g.
+// prefix/prod/2024/01/15/report-20240115T103000Z.json.gz
+func (e *reportExporter) objectKey(cluster string, at time.Time) string {
+	name := fmt.Sprintf("%s/%s/report-%s.json", cluster, at.Format("2006/01/02"), at.Format("20060102T150405Z"))
+	if e.gzip {
+		name += ".gz"
+	}
+	if e.prefix != "" {
+		name = e.prefix + "/" + name
+	}
+	return name
+}
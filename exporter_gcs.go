@@ -0,0 +1,45 @@
+//go:build !no_gcs
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/storage"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+)
+
+// gcsStore uploads through the Cloud Storage client. Without an explicit
+// token it uses Application Default Credentials, which cover Workload
+// Identity and the node's service account.
+type gcsStore struct {
+	bucket *storage.BucketHandle
+}
+
+func newGCSStore(ctx context.Context, bucket, token string) (objectStore, error) {
+	opts := make([]option.ClientOption, 0)
+	if token != "" {
+		opts = append(opts, option.WithTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})))
+	}
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create GCS client: %w", err)
+	}
+	return &gcsStore{bucket: client.Bucket(bucket)}, nil
+}
+
+func (s *gcsStore) Put(ctx context.Context, key string, body []byte, contentType, contentEncoding string) error {
+	// Cancelling the writer's context abandons a partial upload
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := s.bucket.Object(key).NewWriter(ctx)
+	w.ContentType = contentType
+	w.ContentEncoding = contentEncoding
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,12 @@
+//go:build no_gcs
+
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+func newGCSStore(ctx context.Context, bucket, token string) (objectStore, error) {
+	return nil, errors.New("gs export is not available: optimkube was built with the no_gcs tag")
+}
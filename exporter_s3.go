@@ -0,0 +1,55 @@
+//go:build !no_s3
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// defaultS3Region is used when neither the environment nor the shared config
+// names a region
+const defaultS3Region = "us-east-1"
+
+// s3PutObjectAPI is the part of the S3 client s3Store uses
+type s3PutObjectAPI interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// s3Store uploads through the AWS SDK. Credentials come from the SDK's default
+// chain: AWS_* variables, shared config files, IRSA web identity tokens, and
+// the instance profile.
+type s3Store struct {
+	bucket string
+	client s3PutObjectAPI
+}
+
+func newS3Store(ctx context.Context, bucket string) (objectStore, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load AWS config: %w", err)
+	}
+	if cfg.Region == "" {
+		cfg.Region = defaultS3Region
+	}
+	return &s3Store{bucket: bucket, client: s3.NewFromConfig(cfg)}, nil
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, body []byte, contentType, contentEncoding string) error {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String(contentType),
+	}
+	if contentEncoding != "" {
+		input.ContentEncoding = aws.String(contentEncoding)
+	}
+	_, err := s.client.PutObject(ctx, input)
+	return err
+}
@@ -0,0 +1,12 @@
+//go:build no_s3
+
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+func newS3Store(ctx context.Context, bucket string) (objectStore, error) {
+	return nil, errors.New("s3 export is not available: optimkube was built with the no_s3 tag")
+}
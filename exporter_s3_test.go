@@ -0,0 +1,63 @@
+//go:build !no_s3
+
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// fakeS3 records the PutObject input it's given
+type fakeS3 struct {
+	input *s3.PutObjectInput
+	body  []byte
+}
+
+func (f *fakeS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	f.input = params
+	body, err := io.ReadAll(params.Body)
+	f.body = body
+	return &s3.PutObjectOutput{}, err
+}
+
+func TestS3StorePut(t *testing.T) {
+	tests := []struct {
+		name            string
+		contentEncoding string
+		wantEncoding    *string
+	}{
+		{name: "plain"},
+		{name: "gzip", contentEncoding: "gzip", wantEncoding: aws.String("gzip")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeS3{}
+			store := &s3Store{bucket: "costs", client: client}
+
+			err := store.Put(context.Background(), "prod/report.json", []byte(`{}`), "application/json", tt.contentEncoding)
+			if err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			if got := aws.ToString(client.input.Bucket); got != "costs" {
+				t.Errorf("bucket = %q, want costs", got)
+			}
+			if got := aws.ToString(client.input.Key); got != "prod/report.json" {
+				t.Errorf("key = %q, want prod/report.json", got)
+			}
+			if got := aws.ToString(client.input.ContentType); got != "application/json" {
+				t.Errorf("content type = %q, want application/json", got)
+			}
+			if aws.ToString(client.input.ContentEncoding) != aws.ToString(tt.wantEncoding) || (client.input.ContentEncoding == nil) != (tt.wantEncoding == nil) {
+				t.Errorf("content encoding = %v, want %v", client.input.ContentEncoding, tt.wantEncoding)
+			}
+			if string(client.body) != `{}` {
+				t.Errorf("body = %q, want {}", client.body)
+			}
+		})
+	}
+}
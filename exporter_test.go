@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+)
+
+// recordingStore keeps the objects put to it
+type recordingStore struct {
+	puts []recordedPut
+}
+
+type recordedPut struct {
+	key, contentType, contentEncoding string
+	body                              []byte
+}
+
+func (s *recordingStore) Put(ctx context.Context, key string, body []byte, contentType, contentEncoding string) error {
+	s.puts = append(s.puts, recordedPut{key: key, contentType: contentType, contentEncoding: contentEncoding, body: body})
+	return nil
+}
+
+func TestExportReport(t *testing.T) {
+	at := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		prefix       string
+		gzip         bool
+		wantKey      string
+		wantEncoding string
+	}{
+		{
+			name:    "plain",
+			prefix:  "reports",
+			wantKey: "reports/prod/2024/01/15/report-20240115T103000Z.json",
+		},
+		{
+			name:         "gzip without prefix",
+			gzip:         true,
+			wantKey:      "prod/2024/01/15/report-20240115T103000Z.json.gz",
+			wantEncoding: "gzip",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t)
+			store := &recordingStore{}
+			co.clusterName = "prod"
+			co.now = func() time.Time { return at }
+			co.exporter = &reportExporter{store: store, prefix: tt.prefix, interval: time.Hour, gzip: tt.gzip}
+			co.recommendations = []Recommendation{{ID: "rec-1", Type: "rightsizing", Savings: 12.5, Timestamp: at}}
+
+			if err := co.exportReport(context.Background()); err != nil {
+				t.Fatalf("exportReport: %v", err)
+			}
+			if len(store.puts) != 1 {
+				t.Fatalf("got %d uploads, want 1", len(store.puts))
+			}
+			put := store.puts[0]
+			if put.key != tt.wantKey {
+				t.Errorf("key = %q, want %q", put.key, tt.wantKey)
+			}
+			if put.contentType != "application/json" || put.contentEncoding != tt.wantEncoding {
+				t.Errorf("content type/encoding = %q/%q, want application/json/%q", put.contentType, put.contentEncoding, tt.wantEncoding)
+			}
+
+			body := put.body
+			if tt.gzip {
+				zr, err := gzip.NewReader(bytes.NewReader(body))
+				if err != nil {
+					t.Fatalf("gzip reader: %v", err)
+				}
+				if body, err = io.ReadAll(zr); err != nil {
+					t.Fatalf("decompress: %v", err)
+				}
+			}
+			var report ExportReport
+			if err := json.Unmarshal(body, &report); err != nil {
+				t.Fatalf("decode report: %v", err)
+			}
+			if report.Cluster != "prod" || !report.GeneratedAt.Equal(at) {
+				t.Errorf("report cluster/time = %q/%v, want prod/%v", report.Cluster, report.GeneratedAt, at)
+			}
+			if len(report.Recommendations) != 1 || report.Recommendations[0].ID != "rec-1" {
+				t.Errorf("recommendations = %+v, want rec-1", report.Recommendations)
+			}
+		})
+	}
+}
+
+func TestStartExporterStopsOnCancel(t *testing.T) {
+	co, _ := newTestOptimizer(t)
+	co.exporter = &reportExporter{store: &recordingStore{}, interval: time.Hour}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		co.StartExporter(ctx)
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("StartExporter did not return after its context was cancelled")
+	}
+}
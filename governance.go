@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// analyzeBestEffortPods flags running pods without any requests or limits.
+// They're evicted first under pressure, invisible to the scheduler's
+// accounting, and their usage is never attributed to anyone's cost.
+func (co *CostOptimizer) analyzeBestEffortPods(ctx context.Context) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	if co.demoMode || co.clientset == nil || co.metricsClient == nil || co.analyzerDisabled("besteffort") {
+		return recommendations
+	}
+
+	pods, err := co.listPods(ctx, "")
+	if err != nil {
+		co.analyzerListFailed("besteffort", "pods", err)
+		return recommendations
+	}
+
+	// Without the metrics API the pods are still flagged, just without usage
+	podMetrics, err := co.listPodMetrics(ctx, "")
+	if err != nil {
+		if !metricsAPIMissing(err) {
+			slog.Warn("Failed to get pod metrics", "error", err)
+			return recommendations
+		}
+		podMetrics = &metricsv1beta1.PodMetricsList{}
+	}
+
+	type podKey struct{ namespace, name string }
+	usage := make(map[podKey]corev1.ResourceList, len(podMetrics.Items))
+	for _, m := range podMetrics.Items {
+		var cpu, memory resource.Quantity
+		for _, c := range m.Containers {
+			cpu.Add(c.Usage[corev1.ResourceCPU])
+			memory.Add(c.Usage[corev1.ResourceMemory])
+		}
+		usage[podKey{m.Namespace, m.Name}] = corev1.ResourceList{corev1.ResourceCPU: cpu, corev1.ResourceMemory: memory}
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.QOSClass != corev1.PodQOSBestEffort {
+			continue
+		}
+
+		description := fmt.Sprintf("Pod %s runs as BestEffort with no CPU or memory requests", pod.Name)
+		if used, ok := usage[podKey{pod.Namespace, pod.Name}]; ok {
+			cpu, memory := used[corev1.ResourceCPU], used[corev1.ResourceMemory]
+			description = fmt.Sprintf("Pod %s runs as BestEffort with no CPU or memory requests while using %dm CPU and %s memory (about $%.2f/month not attributed to any workload)",
+				pod.Name, cpu.MilliValue(), memory.String(), co.estimatePodCost(cpu, memory))
+		}
+
+		recommendations = append(recommendations, Recommendation{
+			Type:        "resource_governance",
+			Category:    CategoryConfigure,
+			Resource:    fmt.Sprintf("%s/%s", pod.Namespace, pod.Name),
+			Namespace:   pod.Namespace,
+			Release:     helmRelease(pod.Labels),
+			labels:      pod.Labels,
+			Description: description,
+			Impact:      "Set CPU and memory requests so the pod is scheduled, protected from eviction, and costed accurately",
+			ActionHint:  &ActionHint{Verb: "patch", Target: hintTarget("pod", pod.Namespace, pod.Name), Field: "spec.containers[*].resources.requests"},
+			Priority:    "medium",
+			Timestamp:   time.Now(),
+		})
+	}
+
+	return recommendations
+}
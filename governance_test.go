@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// withQOS sets a pod's QoS class, as the kubelet reports it
+func withQOS(pod *corev1.Pod, class corev1.PodQOSClass) *corev1.Pod {
+	if class == corev1.PodQOSBestEffort {
+		pod.Spec.Containers[0].Resources = corev1.ResourceRequirements{}
+	}
+	pod.Status.QOSClass = class
+	return pod
+}
+
+func TestAnalyzeBestEffortPods(t *testing.T) {
+	pending := withQOS(testPod("shop", "queued", "", "0", "0"), corev1.PodQOSBestEffort)
+	pending.Status.Phase = corev1.PodPending
+
+	co, _ := newTestOptimizer(t,
+		withQOS(testPod("shop", "scraper", "node-1", "0", "0"), corev1.PodQOSBestEffort),
+		testPodMetrics("shop", "scraper", "750m", "512Mi"),
+		withQOS(testPod("shop", "cron", "node-1", "0", "0"), corev1.PodQOSBestEffort),
+		withQOS(testPod("shop", "api", "node-1", "500m", "1Gi"), corev1.PodQOSGuaranteed),
+		testPodMetrics("shop", "api", "400m", "800Mi"),
+		pending,
+	)
+
+	tests := []struct {
+		resource string
+		want     string // description fragment
+	}{
+		{resource: "shop/scraper", want: "using 750m CPU and 512Mi memory"},
+		{resource: "shop/cron", want: "no CPU or memory requests"},
+	}
+
+	recommendations := co.analyzeBestEffortPods(context.Background())
+	if len(recommendations) != len(tests) {
+		t.Fatalf("got %d recommendations, want %d: %+v", len(recommendations), len(tests), recommendations)
+	}
+	byResource := make(map[string]Recommendation)
+	for _, rec := range recommendations {
+		byResource[rec.Resource] = rec
+	}
+	for _, tt := range tests {
+		rec, ok := byResource[tt.resource]
+		if !ok {
+			t.Errorf("BestEffort pod %s not flagged", tt.resource)
+			continue
+		}
+		if rec.Type != "resource_governance" || !strings.Contains(rec.Description, tt.want) {
+			t.Errorf("%s: %s %q, want resource_governance mentioning %q", tt.resource, rec.Type, rec.Description, tt.want)
+		}
+	}
+}
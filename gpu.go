@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// resourceGPU is the extended resource advertised by the NVIDIA device plugin
+const resourceGPU corev1.ResourceName = "nvidia.com/gpu"
+
+// resourceMIGPrefix prefixes MIG slices advertised under the "mixed" strategy,
+// e.g. nvidia.com/mig-1g.5gb
+const resourceMIGPrefix = "nvidia.com/mig-"
+
+// Labels published by NVIDIA GPU feature discovery
+const (
+	labelGPUCount           = "nvidia.com/gpu.count"
+	labelGPUReplicas        = "nvidia.com/gpu.replicas"
+	labelGPUSharingStrategy = "nvidia.com/gpu.sharing-strategy"
+	labelMIGStrategy        = "nvidia.com/mig.strategy"
+)
+
+// GPU sharing modes reported in NodeMetrics
+const (
+	gpuSharingNone        = ""
+	gpuSharingTimeSlicing = "time-slicing"
+	gpuSharingMIG         = "mig"
+)
+
+// gpuInfo describes how a node's physical GPUs are exposed to the scheduler.
+// With time-slicing or MIG the device plugin advertises more GPUs than are
+// physically installed, so cost must be based on Physical, not Advertised.
+type gpuInfo struct {
+	Advertised int64
+	Physical   int64
+	Sharing    string
+}
+
+func nodeGPUInfo(node *corev1.Node) gpuInfo {
+	var info gpuInfo
+
+	for name, quantity := range node.Status.Capacity {
+		if name == resourceGPU || strings.HasPrefix(string(name), resourceMIGPrefix) {
+			info.Advertised += quantity.Value()
+		}
+	}
+	if info.Advertised == 0 {
+		return info
+	}
+
+	replicas := labelInt(node.Labels, labelGPUReplicas)
+	strategy := node.Labels[labelGPUSharingStrategy]
+	mig := node.Labels[labelMIGStrategy]
+
+	switch {
+	case mig != "" && mig != "none":
+		info.Sharing = gpuSharingMIG
+	case replicas > 1 || strategy == "time-slicing" || strategy == "mps":
+		info.Sharing = gpuSharingTimeSlicing
+	}
+
+	// Prefer the physical count discovered on the node, then derive it from
+	// the time-slicing replica factor, and only then trust the advertised count.
+	switch {
+	case labelInt(node.Labels, labelGPUCount) > 0:
+		info.Physical = labelInt(node.Labels, labelGPUCount)
+	case replicas > 1:
+		info.Physical = (info.Advertised + replicas - 1) / replicas
+	case info.Sharing == gpuSharingNone:
+		info.Physical = info.Advertised
+	default:
+		// Shared GPUs without a discoverable physical count: assume one card
+		// rather than pricing every advertised slice as a full GPU.
+		info.Physical = 1
+	}
+
+	return info
+}
+
+// gpuHourlyCost prices a node's GPUs by physical card
+func (cc *CostCalculator) gpuHourlyCost(info gpuInfo) float64 {
+	return float64(info.Physical) * cc.GPUCostPerHour
+}
+
+// podGPUHourlyCost attributes a share of the node's physical GPU cost to a pod
+// in proportion to the advertised GPUs (or slices) it requests.
+func (cc *CostCalculator) podGPUHourlyCost(requested int64, info gpuInfo) float64 {
+	if requested <= 0 || info.Advertised <= 0 {
+		return 0
+	}
+	return float64(requested) / float64(info.Advertised) * cc.gpuHourlyCost(info)
+}
+
+// podGPURequest sums the GPUs or MIG slices requested by a pod's containers
+func podGPURequest(pod *corev1.Pod) int64 {
+	var total int64
+	for _, container := range pod.Spec.Containers {
+		for name, quantity := range container.Resources.Requests {
+			if name == resourceGPU || strings.HasPrefix(string(name), resourceMIGPrefix) {
+				total += quantity.Value()
+			}
+		}
+		// Extended resources may be set only as limits, which implies an equal request
+		for name, quantity := range container.Resources.Limits {
+			if _, requested := container.Resources.Requests[name]; requested {
+				continue
+			}
+			if name == resourceGPU || strings.HasPrefix(string(name), resourceMIGPrefix) {
+				total += quantity.Value()
+			}
+		}
+	}
+	return total
+}
+
+// gpuRequestsByNode sums the GPUs requested by the pods scheduled on each node
+// that haven't finished
+func gpuRequestsByNode(pods []corev1.Pod) map[string]int64 {
+	requested := make(map[string]int64)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		requested[pod.Spec.NodeName] += podGPURequest(pod)
+	}
+	return requested
+}
+
+// gpuIdleGracePeriod gives a new GPU node time to receive the pods it was
+// added for before its GPUs are reported idle
+const gpuIdleGracePeriod = 30 * time.Minute
+
+// analyzeIdleGPUs flags GPU nodes on which no pod requests a GPU. Their GPUs
+// are paid for in full whatever else runs there, so the savings are the
+// node's GPU cost, at its spot or reserved rate.
+func (co *CostOptimizer) analyzeIdleGPUs(ctx context.Context) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	if co.demoMode || co.clientset == nil || co.analyzerDisabled("gpu") {
+		return recommendations
+	}
+
+	nodes, err := co.listNodes(ctx)
+	if err != nil {
+		co.analyzerListFailed("gpu", "nodes", err)
+		return recommendations
+	}
+	gpuNodes := make([]*corev1.Node, 0)
+	now := co.now()
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if nodeGPUInfo(node).Advertised > 0 && now.Sub(node.CreationTimestamp.Time) >= gpuIdleGracePeriod {
+			gpuNodes = append(gpuNodes, node)
+		}
+	}
+	if len(gpuNodes) == 0 {
+		return recommendations
+	}
+
+	pods, err := co.listPods(ctx, "")
+	if err != nil {
+		co.analyzerListFailed("gpu", "pods", err)
+		return recommendations
+	}
+	requested := gpuRequestsByNode(pods.Items)
+	allocations := co.nodeAllocations(nodes.Items)
+
+	for _, node := range gpuNodes {
+		if requested[node.Name] > 0 {
+			continue
+		}
+		allocation := allocations[node.Name]
+		recommendations = append(recommendations, Recommendation{
+			Type:        "gpu_idle",
+			Category:    CategoryScale,
+			Resource:    node.Name,
+			Description: fmt.Sprintf("Node %s has %d GPUs (%d physical) and no pod requesting any", node.Name, allocation.gpu.Advertised, allocation.gpu.Physical),
+			Impact:      "Scale down the GPU node pool or move the node's other pods to CPU nodes",
+			Savings:     co.costCalculator.gpuHourlyCost(allocation.gpu) * allocation.gpuFactor * 24 * 30,
+			Priority:    "high",
+			Timestamp:   time.Now(),
+		})
+	}
+
+	return recommendations
+}
+
+func labelInt(labels map[string]string, key string) int64 {
+	value, err := strconv.ParseInt(labels[key], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
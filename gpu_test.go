@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// testGPUNode is a node advertising gpus of resourceName, with labels
+func testGPUNode(name string, resourceName corev1.ResourceName, gpus string, labels map[string]string) *corev1.Node {
+	node := testNode(name, "8", "32Gi")
+	node.Labels = labels
+	node.Status.Capacity[resourceName] = resource.MustParse(gpus)
+	node.Status.Allocatable[resourceName] = resource.MustParse(gpus)
+	return node
+}
+
+func TestNodeGPUInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		node *corev1.Node
+		want gpuInfo
+	}{
+		{
+			name: "no GPUs",
+			node: testNode("cpu-only", "8", "32Gi"),
+			want: gpuInfo{},
+		},
+		{
+			name: "dedicated",
+			node: testGPUNode("dedicated", resourceGPU, "4", nil),
+			want: gpuInfo{Advertised: 4, Physical: 4},
+		},
+		{
+			name: "time-sliced with a physical count",
+			node: testGPUNode("sliced", resourceGPU, "8", map[string]string{
+				labelGPUCount: "1", labelGPUReplicas: "8", labelGPUSharingStrategy: "time-slicing",
+			}),
+			want: gpuInfo{Advertised: 8, Physical: 1, Sharing: gpuSharingTimeSlicing},
+		},
+		{
+			name: "time-sliced from the replica factor",
+			node: testGPUNode("replicas", resourceGPU, "8", map[string]string{labelGPUReplicas: "4"}),
+			want: gpuInfo{Advertised: 8, Physical: 2, Sharing: gpuSharingTimeSlicing},
+		},
+		{
+			name: "MIG slices",
+			node: testGPUNode("mig", "nvidia.com/mig-1g.5gb", "7", map[string]string{labelMIGStrategy: "mixed"}),
+			want: gpuInfo{Advertised: 7, Physical: 1, Sharing: gpuSharingMIG},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nodeGPUInfo(tt.node); got != tt.want {
+				t.Errorf("nodeGPUInfo = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTimeSlicedGPUCost checks that a node advertising 8 time-sliced GPUs
+// backed by one card is billed for one, and a pod using one slice pays an
+// eighth of it
+func TestTimeSlicedGPUCost(t *testing.T) {
+	node := testGPUNode("gpu-node", resourceGPU, "8", map[string]string{
+		labelGPUCount: "1", labelGPUReplicas: "8", labelGPUSharingStrategy: "time-slicing",
+	})
+	pod := testPod("default", "trainer", "gpu-node", "500m", "1Gi")
+	pod.Spec.Containers[0].Resources.Limits = corev1.ResourceList{resourceGPU: resource.MustParse("1")}
+	co, _ := newTestOptimizer(t, node, pod,
+		testNodeMetrics("gpu-node", "1", "4Gi"),
+		testPodMetrics("default", "trainer", "250m", "512Mi"))
+
+	nodes := co.getNodeMetrics(context.Background())
+	if len(nodes) != 1 {
+		t.Fatalf("got %d node metrics, want 1", len(nodes))
+	}
+	gpuCost := co.costCalculator.GPUCostPerHour * 24 * 30
+	nodeCost := co.pricing.NodeHourlyCost(node)*24*30 + gpuCost
+	if got := nodes[0]; got.GPUCapacity != 8 || got.PhysicalGPUs != 1 || got.GPUSharing != gpuSharingTimeSlicing ||
+		math.Abs(got.EstimatedCost-nodeCost) > 1e-9 {
+		t.Errorf("node metrics = %+v, want 8 time-sliced GPUs on 1 card costing %.2f", got, nodeCost)
+	}
+
+	pods := co.getPodMetrics(context.Background(), "")
+	if len(pods) != 1 {
+		t.Fatalf("got %d pod metrics, want 1", len(pods))
+	}
+	// The pod requests a sixteenth of the node's CPU and a thirty-second of
+	// its memory; the card is split across the slices
+	podCost := (nodeCost-gpuCost)*(1.0/16+1.0/32)/2 + gpuCost/8
+	if got := pods[0]; got.GPURequest != 1 || math.Abs(got.EstimatedCost-podCost) > 1e-9 {
+		t.Errorf("pod metrics = %+v, want 1 GPU costing %.2f", got, podCost)
+	}
+}
+
+// gpuPod is a pod on node with a limit of gpus GPUs
+func gpuPod(name, node, gpus string) *corev1.Pod {
+	pod := testPod("ml", name, node, "1", "4Gi")
+	pod.Spec.Containers[0].Resources.Limits = corev1.ResourceList{resourceGPU: resource.MustParse(gpus)}
+	return pod
+}
+
+func TestAnalyzeIdleGPUs(t *testing.T) {
+	now := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	finished := gpuPod("trainer", "gpu-node", "1")
+	finished.Status.Phase = corev1.PodSucceeded
+	tests := []struct {
+		name     string
+		age      time.Duration // of the node
+		pods     []runtime.Object
+		wantIdle bool
+	}{
+		{name: "pod requesting a gpu", age: time.Hour, pods: []runtime.Object{gpuPod("trainer", "gpu-node", "1")}},
+		{name: "only cpu pods", age: time.Hour, pods: []runtime.Object{testPod("shop", "web", "gpu-node", "1", "1Gi")}, wantIdle: true},
+		{name: "gpu pod has finished", age: time.Hour, pods: []runtime.Object{finished}, wantIdle: true},
+		{name: "gpu pod on another node", age: time.Hour, pods: []runtime.Object{gpuPod("trainer", "gpu-node-2", "1")}, wantIdle: true},
+		{name: "new node", age: 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := testGPUNode("gpu-node", resourceGPU, "4", nil)
+			node.CreationTimestamp = metav1.NewTime(now.Add(-tt.age))
+			co, _ := newTestOptimizer(t, append([]runtime.Object{node, testNode("cpu-node", "8", "32Gi")}, tt.pods...)...)
+			co.now = func() time.Time { return now }
+
+			recommendations := co.analyzeIdleGPUs(context.Background())
+			if !tt.wantIdle {
+				if len(recommendations) != 0 {
+					t.Errorf("got %+v, want no recommendation", recommendations)
+				}
+				return
+			}
+			if len(recommendations) != 1 {
+				t.Fatalf("got %d recommendations, want 1", len(recommendations))
+			}
+			rec := recommendations[0]
+			want := 4 * co.costCalculator.GPUCostPerHour * 24 * 30
+			if rec.Type != "gpu_idle" || rec.Resource != "gpu-node" || rec.Priority != "high" || math.Abs(rec.Savings-want) > 1e-9 {
+				t.Errorf("recommendation %+v, want a high gpu_idle for gpu-node saving %.2f", rec, want)
+			}
+		})
+	}
+}
+
+// TestGPURequestedNodeMetrics checks that a node with 4 GPUs reports the one
+// its pod requests
+func TestGPURequestedNodeMetrics(t *testing.T) {
+	co, _ := newTestOptimizer(t,
+		testGPUNode("gpu-node", resourceGPU, "4", nil), testNodeMetrics("gpu-node", "1", "4Gi"),
+		testNode("cpu-node", "8", "32Gi"), testNodeMetrics("cpu-node", "1", "4Gi"),
+		gpuPod("trainer", "gpu-node", "1"),
+	)
+
+	got := make(map[string][2]int64)
+	for _, m := range co.getNodeMetrics(context.Background()) {
+		got[m.Name] = [2]int64{m.GPUCapacity, m.GPURequested}
+	}
+	want := map[string][2]int64{"gpu-node": {4, 1}, "cpu-node": {0, 0}}
+	for name, w := range want {
+		if got[name] != w {
+			t.Errorf("node %s has %d GPUs with %d requested, want %d with %d", name, got[name][0], got[name][1], w[0], w[1])
+		}
+	}
+}
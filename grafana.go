@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Grafana SimpleJson targets backed by the summary history. Per-namespace cost
+// is exposed as namespaceTargetPrefix followed by the namespace.
+const namespaceTargetPrefix = "namespace_cost:"
+
+var summaryTargets = map[string]func(ClusterCostSummary) float64{
+	"total_cost":        func(s ClusterCostSummary) float64 { return s.TotalMonthlyCost },
+	"compute_cost":      func(s ClusterCostSummary) float64 { return s.ComputeCost },
+	"storage_cost":      func(s ClusterCostSummary) float64 { return s.StorageCost },
+	"potential_savings": func(s ClusterCostSummary) float64 { return s.PotentialSavings },
+	"wasted_resources":  func(s ClusterCostSummary) float64 { return s.WastedResources },
+}
+
+// grafanaRange is the time range of a SimpleJson query or annotation request
+type grafanaRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+func (r grafanaRange) contains(t time.Time) bool {
+	return !t.Before(r.From) && !t.After(r.To)
+}
+
+type grafanaQueryRequest struct {
+	Range   grafanaRange `json:"range"`
+	Targets []struct {
+		Target string `json:"target"`
+	} `json:"targets"`
+}
+
+// grafanaSeries is a SimpleJson time series: datapoints are [value, epoch ms]
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+type grafanaAnnotationRequest struct {
+	Range      grafanaRange    `json:"range"`
+	Annotation json.RawMessage `json:"annotation"`
+}
+
+type grafanaAnnotation struct {
+	Annotation json.RawMessage `json:"annotation"`
+	Time       int64           `json:"time"`
+	Title      string          `json:"title"`
+	Text       string          `json:"text"`
+	Tags       []string        `json:"tags"`
+}
+
+// registerGrafanaRoutes serves the Grafana SimpleJson datasource contract under
+// prefix, so the datasource URL is the service address plus prefix
+func (co *CostOptimizer) registerGrafanaRoutes(router *mux.Router, prefix string) {
+	router.HandleFunc(prefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}).Methods("GET")
+	router.HandleFunc(prefix+"/search", co.handleGrafanaSearch).Methods("POST")
+	router.HandleFunc(prefix+"/query", co.handleGrafanaQuery).Methods("POST")
+	router.HandleFunc(prefix+"/annotations", co.handleGrafanaAnnotations).Methods("POST")
+}
+
+// grafanaTargets lists every series the history can answer, namespaces
+// included, in sorted order
+func (co *CostOptimizer) grafanaTargets() []string {
+	targets := make([]string, 0, len(summaryTargets))
+	for target := range summaryTargets {
+		targets = append(targets, target)
+	}
+
+	namespaces := make(map[string]bool)
+	for _, summary := range co.history.all() {
+		for namespace := range summary.NamespaceCosts {
+			namespaces[namespace] = true
+		}
+	}
+	for namespace := range namespaces {
+		targets = append(targets, namespaceTargetPrefix+namespace)
+	}
+
+	sort.Strings(targets)
+	return targets
+}
+
+func (co *CostOptimizer) handleGrafanaSearch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(co.grafanaTargets())
+}
+
+// grafanaSeriesFor builds one target's series from the summaries in range
+func grafanaSeriesFor(target string, summaries []ClusterCostSummary, within grafanaRange) (grafanaSeries, error) {
+	value, ok := summaryTargets[target]
+	if namespace, isNamespace := strings.CutPrefix(target, namespaceTargetPrefix); isNamespace {
+		value = func(s ClusterCostSummary) float64 { return s.NamespaceCosts[namespace] }
+		ok = true
+	}
+	if !ok {
+		return grafanaSeries{}, fmt.Errorf("unknown target %q", target)
+	}
+
+	series := grafanaSeries{Target: target, Datapoints: make([][2]float64, 0)}
+	for _, summary := range summaries {
+		if within.contains(summary.LastUpdated) {
+			series.Datapoints = append(series.Datapoints, [2]float64{value(summary), float64(summary.LastUpdated.UnixMilli())})
+		}
+	}
+	return series, nil
+}
+
+func (co *CostOptimizer) handleGrafanaQuery(w http.ResponseWriter, r *http.Request) {
+	var query grafanaQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http.Error(w, fmt.Sprintf("invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	summaries := co.history.all()
+	response := make([]grafanaSeries, 0, len(query.Targets))
+	for _, target := range query.Targets {
+		series, err := grafanaSeriesFor(target.Target, summaries, query.Range)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		response = append(response, series)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGrafanaAnnotations marks high-priority recommendations, such as cost
+// spikes and budget breaches, on the dashboard timeline
+func (co *CostOptimizer) handleGrafanaAnnotations(w http.ResponseWriter, r *http.Request) {
+	var request grafanaAnnotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("invalid annotation request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	annotations := make([]grafanaAnnotation, 0)
+	for _, rec := range co.activeRecommendations() {
+		if rec.Priority != "high" || !request.Range.contains(rec.Timestamp) {
+			continue
+		}
+		tags := []string{rec.Category}
+		if rec.Namespace != "" {
+			tags = append(tags, rec.Namespace)
+		}
+		annotations = append(annotations, grafanaAnnotation{
+			Annotation: request.Annotation,
+			Time:       rec.Timestamp.UnixMilli(),
+			Title:      rec.Type,
+			Text:       rec.Description,
+			Tags:       tags,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(annotations)
+}
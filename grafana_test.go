@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// grafanaRouter serves the datasource over a history of three scans, five
+// minutes apart, where the batch namespace appears from the second scan
+func grafanaRouter(t *testing.T) (*mux.Router, time.Time) {
+	co, _ := newTestOptimizer(t)
+	start := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		namespaces := map[string]float64{"shop": float64(100 * (i + 1))}
+		if i > 0 {
+			namespaces["batch"] = 50
+		}
+		co.history.add(ClusterCostSummary{
+			TotalMonthlyCost: float64(1000 * (i + 1)),
+			PotentialSavings: 10,
+			NamespaceCosts:   namespaces,
+			LastUpdated:      start.Add(time.Duration(i) * 5 * time.Minute),
+		})
+	}
+	co.recommendations = []Recommendation{
+		{Type: "cost_spike", Category: CategoryConfigure, Resource: "batch", Namespace: "batch", Priority: "high", Description: "batch jumped", Timestamp: start.Add(10 * time.Minute)},
+		{Type: "resource_rightsizing", Category: CategoryRightsize, Priority: "low", Timestamp: start.Add(10 * time.Minute)},
+	}
+
+	router := mux.NewRouter()
+	co.registerGrafanaRoutes(router, "/grafana")
+	return router, start
+}
+
+func TestGrafanaSearch(t *testing.T) {
+	router, _ := grafanaRouter(t)
+
+	if rec := serve(router, http.MethodGet, "/grafana/", nil); rec.Code != http.StatusOK {
+		t.Errorf("health check = %d, want 200", rec.Code)
+	}
+
+	rec := serve(router, http.MethodPost, "/grafana/search", strings.NewReader(`{"target":""}`))
+	var targets []string
+	if err := json.NewDecoder(rec.Body).Decode(&targets); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"compute_cost", "namespace_cost:batch", "namespace_cost:shop", "potential_savings", "storage_cost", "total_cost", "wasted_resources"}
+	if !reflect.DeepEqual(targets, want) {
+		t.Errorf("search = %v, want %v", targets, want)
+	}
+}
+
+func TestGrafanaQuery(t *testing.T) {
+	router, start := grafanaRouter(t)
+	ms := func(minutes int) float64 { return float64(start.Add(time.Duration(minutes) * time.Minute).UnixMilli()) }
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		want       []grafanaSeries
+	}{
+		{
+			name:       "total cost over the whole range",
+			body:       `{"range":{"from":"2024-03-05T08:00:00Z","to":"2024-03-05T10:00:00Z"},"targets":[{"target":"total_cost"}]}`,
+			wantStatus: http.StatusOK,
+			want:       []grafanaSeries{{Target: "total_cost", Datapoints: [][2]float64{{1000, ms(0)}, {2000, ms(5)}, {3000, ms(10)}}}},
+		},
+		{
+			name:       "namespace cost within a narrower range",
+			body:       `{"range":{"from":"2024-03-05T09:05:00Z","to":"2024-03-05T09:10:00Z"},"targets":[{"target":"namespace_cost:shop"},{"target":"namespace_cost:batch"}]}`,
+			wantStatus: http.StatusOK,
+			want: []grafanaSeries{
+				{Target: "namespace_cost:shop", Datapoints: [][2]float64{{200, ms(5)}, {300, ms(10)}}},
+				{Target: "namespace_cost:batch", Datapoints: [][2]float64{{50, ms(5)}, {50, ms(10)}}},
+			},
+		},
+		{
+			name:       "range before any scan",
+			body:       `{"range":{"from":"2024-03-04T00:00:00Z","to":"2024-03-04T01:00:00Z"},"targets":[{"target":"potential_savings"}]}`,
+			wantStatus: http.StatusOK,
+			want:       []grafanaSeries{{Target: "potential_savings", Datapoints: [][2]float64{}}},
+		},
+		{name: "unknown target", body: `{"targets":[{"target":"carbon"}]}`, wantStatus: http.StatusBadRequest},
+		{name: "malformed body", body: `{"targets":`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(router, http.MethodPost, "/grafana/query", strings.NewReader(tt.body))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got []grafanaSeries
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("query = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrafanaAnnotations(t *testing.T) {
+	router, _ := grafanaRouter(t)
+
+	rec := serve(router, http.MethodPost, "/grafana/annotations", strings.NewReader(
+		`{"range":{"from":"2024-03-05T09:00:00Z","to":"2024-03-05T10:00:00Z"},"annotation":{"name":"spikes"}}`))
+	var annotations []grafanaAnnotation
+	if err := json.NewDecoder(rec.Body).Decode(&annotations); err != nil {
+		t.Fatal(err)
+	}
+	if len(annotations) != 1 {
+		t.Fatalf("annotations = %+v, want only the high-priority recommendation", annotations)
+	}
+	if a := annotations[0]; a.Title != "cost_spike" || !reflect.DeepEqual(a.Tags, []string{CategoryConfigure, "batch"}) || string(a.Annotation) != `{"name":"spikes"}` {
+		t.Errorf("annotation = %+v", a)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// readinessTimeout bounds the metrics check of one readiness check. The
+// version request takes no context; OPTIMKUBE_API_TIMEOUT bounds it.
+const readinessTimeout = 5 * time.Second
+
+// probeEndpoints are served without authentication, since probes and
+// container health checks call them without credentials
+var probeEndpoints = map[string]bool{"/health": true, "/healthz": true, "/readyz": true}
+
+// handleHealthz is the liveness check: it only reports that the process is up
+// and serving, so a flaky API server never gets the pod restarted
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+}
+
+// ReadinessCheck is the outcome of one dependency check of /readyz
+type ReadinessCheck struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+	// What runs instead when the dependency is missing; a failed check with
+	// a fallback degrades readiness rather than failing it
+	Fallback string `json:"fallback,omitempty"`
+}
+
+// handleReadyz is the readiness check: the API server must answer a version
+// request and the metrics server a one-item node metrics list. Either failing
+// returns 503 with the failing check's error, except that a metrics API that
+// isn't installed or served reports "degraded" with 200, since analysis falls
+// back to requests. Demo mode has no cluster to check and is always ready.
+func (co *CostOptimizer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	checks := make(map[string]ReadinessCheck)
+	if !co.demoMode && co.clientset != nil && co.metricsClient != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		_, err := co.clientset.Discovery().ServerVersion()
+		checks["kubernetes"] = readinessCheck(err)
+		_, err = co.metricsClient.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{Limit: 1})
+		co.metricsAPI.observeMetricsList(err)
+		metricsCheck := readinessCheck(err)
+		if metricsAPIMissing(err) {
+			metricsCheck.Fallback = metricsAPIFallback
+		}
+		checks["metrics_server"] = metricsCheck
+	}
+
+	status, code := "ready", http.StatusOK
+	for _, check := range checks {
+		switch {
+		case check.OK:
+		case check.Fallback == "":
+			status, code = "not_ready", http.StatusServiceUnavailable
+		case code == http.StatusOK:
+			status = "degraded"
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": status, "checks": checks})
+}
+
+func readinessCheck(err error) ReadinessCheck {
+	if err != nil {
+		return ReadinessCheck{Error: err.Error()}
+	}
+	return ReadinessCheck{OK: true}
+}
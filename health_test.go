@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+// failWith makes a fake's verb on resource fail with err
+func failWith(fake *k8stesting.Fake, verb, resource string, err error) {
+	fake.PrependReactor(verb, resource, func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, err
+	})
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name         string
+		versionErr   error
+		metricsErr   error
+		demo         bool
+		wantCode     int
+		wantStatus   string
+		wantFailing  []string
+		wantNoChecks bool
+	}{
+		{name: "healthy", wantCode: http.StatusOK, wantStatus: "ready"},
+		{
+			name:        "api server down",
+			versionErr:  errors.New("connection refused"),
+			wantCode:    http.StatusServiceUnavailable,
+			wantStatus:  "not_ready",
+			wantFailing: []string{"kubernetes"},
+		},
+		{
+			name:        "metrics server down",
+			metricsErr:  errors.New("the server is currently unable to handle the request"),
+			wantCode:    http.StatusServiceUnavailable,
+			wantStatus:  "not_ready",
+			wantFailing: []string{"metrics_server"},
+		},
+		{
+			name:        "both down",
+			versionErr:  errors.New("connection refused"),
+			metricsErr:  errors.New("connection refused"),
+			wantCode:    http.StatusServiceUnavailable,
+			wantStatus:  "not_ready",
+			wantFailing: []string{"kubernetes", "metrics_server"},
+		},
+		{
+			name:        "metrics server not installed",
+			metricsErr:  metricsNotFound,
+			wantCode:    http.StatusOK,
+			wantStatus:  "degraded",
+			wantFailing: []string{"metrics_server"},
+		},
+		{
+			name:        "api server down, metrics server not installed",
+			versionErr:  errors.New("connection refused"),
+			metricsErr:  metricsNotFound,
+			wantCode:    http.StatusServiceUnavailable,
+			wantStatus:  "not_ready",
+			wantFailing: []string{"kubernetes", "metrics_server"},
+		},
+		{name: "demo mode", versionErr: errors.New("connection refused"), demo: true, wantCode: http.StatusOK, wantStatus: "ready", wantNoChecks: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, client := newTestOptimizer(t, testNode("node-1", "4", "16Gi"), testNodeMetrics("node-1", "1", "4Gi"))
+			co.demoMode = tt.demo
+			if tt.versionErr != nil {
+				failWith(&client.Fake, "get", "version", tt.versionErr)
+			}
+			if tt.metricsErr != nil {
+				failWith(&co.metricsClient.(*metricsfake.Clientset).Fake, "list", "nodes", tt.metricsErr)
+			}
+
+			rec := serve(http.HandlerFunc(co.handleReadyz), http.MethodGet, "/readyz", nil)
+			if rec.Code != tt.wantCode {
+				t.Errorf("status %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			var body struct {
+				Status string                    `json:"status"`
+				Checks map[string]ReadinessCheck `json:"checks"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if body.Status != tt.wantStatus {
+				t.Errorf("status %q, want %q", body.Status, tt.wantStatus)
+			}
+			if tt.wantNoChecks {
+				if len(body.Checks) != 0 {
+					t.Errorf("checks %+v, want none in demo mode", body.Checks)
+				}
+				return
+			}
+			failing := make(map[string]bool)
+			for _, name := range tt.wantFailing {
+				failing[name] = true
+			}
+			for _, name := range []string{"kubernetes", "metrics_server"} {
+				check, ok := body.Checks[name]
+				if !ok {
+					t.Errorf("no %s check in %+v", name, body.Checks)
+					continue
+				}
+				if check.OK == failing[name] || (check.Error != "") != failing[name] {
+					t.Errorf("%s check %+v, want failing %v", name, check, failing[name])
+				}
+				if wantFallback := tt.metricsErr == metricsNotFound && name == "metrics_server"; (check.Fallback != "") != wantFallback {
+					t.Errorf("%s check %+v, want a fallback %v", name, check, wantFallback)
+				}
+			}
+		})
+	}
+}
+
+func TestProbesSkipAuth(t *testing.T) {
+	co, client := newTestOptimizer(t)
+	failWith(&client.Fake, "get", "version", errors.New("connection refused"))
+	handler := bearerAuth("s3cret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/readyz":
+			co.handleReadyz(w, r)
+		default:
+			handleHealthz(w, r)
+		}
+	}))
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{path: "/healthz", want: http.StatusOK},
+		{path: "/health", want: http.StatusOK},
+		{path: "/readyz", want: http.StatusServiceUnavailable},
+		{path: "/api/recommendations", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if rec := serve(handler, http.MethodGet, tt.path, nil); rec.Code != tt.want {
+				t.Errorf("status %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+// Labels Helm charts put on workloads to name their release. The recommended
+// app.kubernetes.io/instance label is checked before the older "release".
+var helmReleaseLabels = []string{"app.kubernetes.io/instance", "release"}
+
+// unmanagedRelease collects the cost of pods that no Helm release owns
+const unmanagedRelease = "unmanaged"
+
+// helmRelease returns the Helm release a workload belongs to, or "" if none
+func helmRelease(labels map[string]string) string {
+	for _, label := range helmReleaseLabels {
+		if release := labels[label]; release != "" {
+			return release
+		}
+	}
+	return ""
+}
+
+// releaseCostKey groups pod cost by release. Release names are only unique
+// within a namespace, so the key includes it.
+func releaseCostKey(pod PodMetrics) string {
+	release := helmRelease(pod.Labels)
+	if release == "" {
+		return unmanagedRelease
+	}
+	return pod.Namespace + "/" + release
+}
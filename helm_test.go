@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// withLabels sets a pod's labels
+func withLabels(pod *corev1.Pod, labels map[string]string) *corev1.Pod {
+	pod.Labels = labels
+	return pod
+}
+
+func TestHelmRelease(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   string
+	}{
+		{name: "instance label", labels: map[string]string{"app.kubernetes.io/instance": "checkout"}, want: "checkout"},
+		{name: "legacy release label", labels: map[string]string{"release": "legacy"}, want: "legacy"},
+		{name: "instance preferred", labels: map[string]string{"app.kubernetes.io/instance": "checkout", "release": "legacy"}, want: "checkout"},
+		{name: "unlabeled", labels: map[string]string{"app": "web"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := helmRelease(tt.labels); got != tt.want {
+				t.Errorf("helmRelease(%v) = %q, want %q", tt.labels, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCostByRelease(t *testing.T) {
+	co, _ := newTestOptimizer(t,
+		testNode("node-1", "8", "32Gi"), testNodeMetrics("node-1", "2", "8Gi"),
+		withLabels(testPod("shop", "checkout-0", "node-1", "2", "1Gi"), map[string]string{"app.kubernetes.io/instance": "checkout"}),
+		testPodMetrics("shop", "checkout-0", "100m", "1Gi"),
+		withLabels(testPod("shop", "checkout-1", "node-1", "2", "1Gi"), map[string]string{"app.kubernetes.io/instance": "checkout"}),
+		testPodMetrics("shop", "checkout-1", "100m", "1Gi"),
+		withLabels(testPod("billing", "checkout-0", "node-1", "1", "1Gi"), map[string]string{"release": "checkout"}),
+		testPodMetrics("billing", "checkout-0", "100m", "1Gi"),
+		testPod("shop", "debug", "node-1", "1", "1Gi"),
+		testPodMetrics("shop", "debug", "100m", "1Gi"),
+	)
+
+	summary := co.generateCostSummary(context.Background())
+	if len(summary.CostByRelease) != 3 {
+		t.Fatalf("CostByRelease = %v, want shop/checkout, billing/checkout and %s", summary.CostByRelease, unmanagedRelease)
+	}
+	shop, billing, unmanaged := summary.CostByRelease["shop/checkout"], summary.CostByRelease["billing/checkout"], summary.CostByRelease[unmanagedRelease]
+	if shop == 0 || billing == 0 || unmanaged == 0 {
+		t.Fatalf("CostByRelease = %v, want every release costed", summary.CostByRelease)
+	}
+	if shop <= billing {
+		t.Errorf("shop/checkout costs %v, want more than billing/checkout's %v for twice the pods", shop, billing)
+	}
+
+	for _, rec := range co.analyzePods(context.Background()) {
+		want := map[string]string{"shop/checkout-0": "checkout", "shop/checkout-1": "checkout", "billing/checkout-0": "checkout"}[rec.Resource]
+		if rec.Release != want {
+			t.Errorf("%s recommendation has release %q, want %q", rec.Resource, rec.Release, want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// defaultHistorySize keeps roughly a day of summaries at the 5 minute scan interval
+const defaultHistorySize = 288
+
+// defaultHistoryTolerance is how far a stored summary may be from a requested
+// timestamp and still be served for it
+const defaultHistoryTolerance = 15 * time.Minute
+
+// summaryHistory is a bounded buffer of cost summaries, oldest first
+type summaryHistory struct {
+	mu        sync.RWMutex
+	snapshots []ClusterCostSummary
+	capacity  int
+}
+
+func newSummaryHistory(capacity int) *summaryHistory {
+	return &summaryHistory{
+		snapshots: make([]ClusterCostSummary, 0, capacity),
+		capacity:  capacity,
+	}
+}
+
+// add records a summary, evicting the oldest one once the buffer is full
+func (h *summaryHistory) add(summary ClusterCostSummary) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.snapshots = append(h.snapshots, summary)
+	if len(h.snapshots) > h.capacity {
+		h.snapshots = append(h.snapshots[:0], h.snapshots[len(h.snapshots)-h.capacity:]...)
+	}
+}
+
+func (h *summaryHistory) all() []ClusterCostSummary {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	snapshots := make([]ClusterCostSummary, len(h.snapshots))
+	copy(snapshots, h.snapshots)
+	return snapshots
+}
+
+// nearest returns the summary recorded closest to at, provided it lies within
+// tolerance of the requested time.
+func (h *summaryHistory) nearest(at time.Time, tolerance time.Duration) (ClusterCostSummary, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var best ClusterCostSummary
+	bestDistance := time.Duration(-1)
+	for _, snapshot := range h.snapshots {
+		distance := snapshot.LastUpdated.Sub(at)
+		if distance < 0 {
+			distance = -distance
+		}
+		if bestDistance < 0 || distance < bestDistance {
+			best = snapshot
+			bestDistance = distance
+		}
+	}
+
+	if bestDistance < 0 || bestDistance > tolerance {
+		return ClusterCostSummary{}, false
+	}
+	return best, true
+}
+
+// latest returns the most recently recorded summary, if any
+func (h *summaryHistory) latest() (ClusterCostSummary, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(h.snapshots) == 0 {
+		return ClusterCostSummary{}, false
+	}
+	return h.snapshots[len(h.snapshots)-1], true
+}
+
+// restore replaces the buffer with persisted summaries, oldest first, keeping
+// only the newest ones that fit
+func (h *summaryHistory) restore(snapshots []ClusterCostSummary) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(snapshots) > h.capacity {
+		snapshots = snapshots[len(snapshots)-h.capacity:]
+	}
+	h.snapshots = append(h.snapshots[:0], snapshots...)
+}
+
+// storedSummary is a persisted history entry. WorkloadCosts is left out of
+// the summary's JSON, so it's stored alongside to keep workload histories.
+type storedSummary struct {
+	Summary       storedCostSummary  `json:"summary"`
+	WorkloadCosts map[string]float64 `json:"workload_costs,omitempty"`
+}
+
+// recordSummary adds a scan's summary to the history and persists the history
+func (co *CostOptimizer) recordSummary(summary ClusterCostSummary) {
+	co.history.add(summary)
+	if co.store == nil {
+		return
+	}
+
+	snapshots := co.history.all()
+	stored := make([]storedSummary, len(snapshots))
+	for i, snapshot := range snapshots {
+		stored[i] = storedSummary{Summary: newStoredCostSummary(snapshot), WorkloadCosts: snapshot.WorkloadCosts}
+	}
+	if err := co.store.SaveHistory(stored); err != nil {
+		slog.Error("Failed to persist cost summary history", "error", err)
+	}
+}
+
+// loadHistory restores the summaries recorded before a restart, so trends
+// and historical lookups don't start over
+func (co *CostOptimizer) loadHistory() error {
+	if co.store == nil {
+		return nil
+	}
+	stored, err := co.store.LoadHistory()
+	if err != nil {
+		return err
+	}
+	snapshots := make([]ClusterCostSummary, len(stored))
+	for i, entry := range stored {
+		snapshots[i] = entry.Summary.summary()
+		snapshots[i].WorkloadCosts = entry.WorkloadCosts
+	}
+	co.history.restore(snapshots)
+	return nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHistoricalCostSummary(t *testing.T) {
+	start := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	co, _ := newTestOptimizer(t)
+	for i := 0; i < 3; i++ {
+		co.history.add(ClusterCostSummary{TotalMonthlyCost: float64(100 * (i + 1)), LastUpdated: start.Add(time.Duration(i) * 5 * time.Minute)})
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		wantCost float64
+	}{
+		{name: "exact", query: "at=2024-03-05T09:05:00Z", wantCode: http.StatusOK, wantCost: 200},
+		{name: "near", query: "at=2024-03-05T09:11:30Z", wantCode: http.StatusOK, wantCost: 300},
+		{name: "other zone", query: "at=2024-03-05T10:01:00%2B01:00", wantCode: http.StatusOK, wantCost: 100},
+		{name: "too far", query: "at=2024-03-05T10:00:00Z", wantCode: http.StatusNotFound},
+		{name: "wider tolerance", query: "at=2024-03-05T10:00:00Z&tolerance=1h", wantCode: http.StatusOK, wantCost: 300},
+		{name: "bad timestamp", query: "at=last-tuesday", wantCode: http.StatusBadRequest},
+		{name: "bad tolerance", query: "at=2024-03-05T09:05:00Z&tolerance=-5m", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(http.HandlerFunc(co.handleCostSummary), http.MethodGet, "/api/cost-summary?"+tt.query, nil)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var summary ClusterCostSummary
+			if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil {
+				t.Fatalf("decode summary: %v", err)
+			}
+			if summary.TotalMonthlyCost != tt.wantCost {
+				t.Errorf("served the summary costing %v, want %v", summary.TotalMonthlyCost, tt.wantCost)
+			}
+		})
+	}
+}
+
+func TestSummaryHistoryEvictsOldest(t *testing.T) {
+	start := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	history := newSummaryHistory(2)
+	for i := 0; i < 3; i++ {
+		history.add(ClusterCostSummary{LastUpdated: start.Add(time.Duration(i) * time.Minute)})
+	}
+
+	all := history.all()
+	if len(all) != 2 || !all[0].LastUpdated.Equal(start.Add(time.Minute)) {
+		t.Errorf("history = %v, want the last two summaries", all)
+	}
+	if _, ok := history.nearest(start, 30*time.Second); ok {
+		t.Error("the evicted summary is still served")
+	}
+}
+
+func TestWorkloadCostHistory(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	web := testDeployment("shop", "web", 1)
+	pod := testReplica(web, "web-7d9f8-a", "node-1")
+	pod.Labels = map[string]string{"app": "web", "pod-template-hash": "7d9f8"}
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-7d9f8", Controller: boolPtr(true)}}
+	pod.Spec.Containers[0].Resources.Requests = corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("4"),
+		corev1.ResourceMemory: resource.MustParse("8Gi"),
+	}
+	co, clientset := newTestOptimizer(t,
+		testNode("node-1", "32", "128Gi"), testNodeMetrics("node-1", "4", "16Gi"),
+		pod, testPodMetrics("shop", pod.Name, "500m", "1Gi"),
+		testPod("shop", "web", "node-1", "1", "1Gi"), testPodMetrics("shop", "web", "100m", "128Mi"),
+	)
+
+	// Three scans, the deployment rightsized before each of the later two
+	for i, cpu := range []string{"4", "2", "1"} {
+		pod.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU] = resource.MustParse(cpu)
+		if _, err := clientset.CoreV1().Pods("shop").Update(ctx, pod, metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		co.now = func() time.Time { return start.Add(time.Duration(i) * 5 * time.Minute) }
+		co.history.add(co.generateCostSummary(ctx))
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/workloads/{namespace}/{name}/history", co.handleWorkloadHistory)
+	tests := []struct {
+		name      string
+		target    string
+		wantCode  int
+		wantKinds []string
+	}{
+		{name: "every kind", target: "/api/workloads/shop/web/history", wantCode: http.StatusOK, wantKinds: []string{"deployment", "pod"}},
+		{name: "one kind", target: "/api/workloads/shop/web/history?kind=Deployment", wantCode: http.StatusOK, wantKinds: []string{"deployment"}},
+		{name: "other namespace", target: "/api/workloads/batch/web/history", wantCode: http.StatusNotFound},
+		{name: "unknown workload", target: "/api/workloads/shop/api/history", wantCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(router, http.MethodGet, tt.target, nil)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var histories []WorkloadCostHistory
+			if err := json.Unmarshal(rec.Body.Bytes(), &histories); err != nil {
+				t.Fatalf("decode histories: %v", err)
+			}
+			kinds := make([]string, 0, len(histories))
+			for _, history := range histories {
+				kinds = append(kinds, history.Kind)
+			}
+			if !reflect.DeepEqual(kinds, tt.wantKinds) {
+				t.Fatalf("histories for kinds %v, want %v", kinds, tt.wantKinds)
+			}
+
+			points := histories[0].Points
+			if len(points) != 3 {
+				t.Fatalf("%d points, want one per scan", len(points))
+			}
+			for i := 1; i < len(points); i++ {
+				if !points[i].Timestamp.Equal(start.Add(time.Duration(i) * 5 * time.Minute)) {
+					t.Errorf("point %d at %v", i, points[i].Timestamp)
+				}
+				if points[i].MonthlyCost >= points[i-1].MonthlyCost {
+					t.Errorf("cost went from %v to %v after rightsizing", points[i-1].MonthlyCost, points[i].MonthlyCost)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkloadHistoriesSkipsScansWithoutPods(t *testing.T) {
+	start := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	summaries := []ClusterCostSummary{
+		{LastUpdated: start, WorkloadCosts: map[string]float64{"shop/deployment/web": 30, "shop/statefulset/db": 50}},
+		{LastUpdated: start.Add(time.Minute), WorkloadCosts: map[string]float64{"shop/statefulset/db": 50}},
+		{LastUpdated: start.Add(2 * time.Minute), WorkloadCosts: map[string]float64{"shop/deployment/web": 20}},
+	}
+
+	histories := workloadHistories(summaries, "shop", "", "web")
+	if len(histories) != 1 || histories[0].Kind != "deployment" {
+		t.Fatalf("histories %+v, want the web deployment only", histories)
+	}
+	want := []WorkloadCostPoint{{Timestamp: start, MonthlyCost: 30}, {Timestamp: start.Add(2 * time.Minute), MonthlyCost: 20}}
+	if !reflect.DeepEqual(histories[0].Points, want) {
+		t.Errorf("points %+v, want %+v", histories[0].Points, want)
+	}
+}
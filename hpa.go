@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// analyzeHPATargets flags Deployments whose CPU requests are so far above
+// usage that their HPA's utilization target is never reached. Such an HPA sits
+// at minReplicas and every replica pays for the oversized request.
+func (co *CostOptimizer) analyzeHPATargets(ctx context.Context) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	if co.demoMode || co.clientset == nil || co.metricsClient == nil || co.analyzerDisabled("hpa") {
+		return recommendations
+	}
+
+	hpas, err := co.clientset.AutoscalingV2().HorizontalPodAutoscalers("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		co.analyzerListFailed("hpa", "horizontalpodautoscalers", err)
+		return recommendations
+	}
+
+	for _, hpa := range hpas.Items {
+		if hpa.Spec.ScaleTargetRef.Kind != "Deployment" {
+			continue
+		}
+		target, ok := hpaCPUTarget(hpa)
+		if !ok {
+			continue
+		}
+
+		deployment, err := co.clientset.AppsV1().Deployments(hpa.Namespace).Get(ctx, hpa.Spec.ScaleTargetRef.Name, metav1.GetOptions{})
+		if err != nil {
+			slog.Warn("Failed to get HPA target", "namespace", hpa.Namespace, "target", hpa.Spec.ScaleTargetRef.Name, "error", err)
+			continue
+		}
+
+		avgRequest, podUsage, err := co.deploymentCPUPerPod(ctx, deployment)
+		if err != nil {
+			slog.Warn("Failed to collect CPU usage for HPA", "namespace", hpa.Namespace, "hpa", hpa.Name, "error", err)
+			continue
+		}
+		if avgRequest == 0 {
+			continue
+		}
+		// The HPA itself averages across replicas; the suggestion is sized
+		// from the configured aggregation so busier replicas aren't starved
+		avgUsage := aggregateReplicas(podUsage, replicaAggregationAvg)
+		basis := aggregateReplicas(podUsage, co.replicaAggregation)
+
+		minReplicas := int32(1)
+		if hpa.Spec.MinReplicas != nil {
+			minReplicas = *hpa.Spec.MinReplicas
+		}
+		replicas := hpa.Status.CurrentReplicas
+
+		// Only pinned-at-floor HPAs with usage under half the target are
+		// mis-sized; anything else is scaling as designed
+		utilization := avgUsage * 100 / avgRequest
+		if replicas > minReplicas || 2*avgUsage*100 >= avgRequest*int64(target) {
+			continue
+		}
+
+		suggested := basis * 100 / int64(target)
+		if suggested < 1 {
+			suggested = 1
+		}
+		if suggested >= avgRequest {
+			continue
+		}
+		excess := resource.NewMilliQuantity(avgRequest-suggested, resource.DecimalSI)
+		scaleOutAt := avgRequest * int64(target) / 100
+
+		recommendations = append(recommendations, Recommendation{
+			Type:      "hpa_request_mismatch",
+			Category:  CategoryRightsize,
+			Resource:  fmt.Sprintf("%s/%s", hpa.Namespace, hpa.Spec.ScaleTargetRef.Name),
+			Namespace: hpa.Namespace,
+			Release:   helmRelease(deployment.Labels),
+			labels:    deployment.Labels,
+			Description: fmt.Sprintf("HPA %s targets %d%% CPU but pods request %dm and use %dm (%d%%), so it stays at its floor of %d replicas and only scales out above %dm per pod",
+				hpa.Name, target, avgRequest, avgUsage, utilization, minReplicas, scaleOutAt),
+			Impact: fmt.Sprintf("Lower the CPU request to about %dm so the %d%% target tracks real load", suggested, target),
+			ActionHint: &ActionHint{
+				Verb:         "patch",
+				Target:       hintTarget("deployment", hpa.Namespace, deployment.Name),
+				Field:        "spec.template.spec.containers[*].resources.requests.cpu",
+				CurrentValue: fmt.Sprintf("%dm", avgRequest),
+				NewValue:     fmt.Sprintf("%dm", suggested),
+			},
+			Savings:   co.estimatePodCost(*excess, resource.Quantity{}) * float64(replicas),
+			Priority:  "medium",
+			Timestamp: time.Now(),
+		})
+	}
+
+	return recommendations
+}
+
+// CPU utilization targets of generated HPAs
+const (
+	defaultHPACPUTarget = 70
+	maxHPACPUTarget     = 85
+)
+
+// hpaManifest builds an autoscaling/v2 HPA for a Deployment that has none.
+// It scales between the minReplicas the hint asks for, 1 by default, and
+// twice the current replicas. The CPU target is 70%, raised to the observed
+// utilization (rounded up to 5, at most 85%) when replicas already run
+// hotter, so the HPA doesn't scale out the moment it's applied.
+func (co *CostOptimizer) hpaManifest(ctx context.Context, namespace, name string, hint *ActionHint) (map[string]interface{}, error) {
+	if co.demoMode || co.clientset == nil {
+		return nil, fmt.Errorf("deployment %s/%s can't be read in demo mode", namespace, name)
+	}
+	deployment, err := co.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get deployment %s/%s: %w", namespace, name, err)
+	}
+	// Utilization is measured against requests, so every container needs one
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if container.Resources.Requests.Cpu().IsZero() {
+			return nil, fmt.Errorf("container %s has no CPU request for an HPA to measure utilization against", container.Name)
+		}
+	}
+
+	minReplicas := int32(1)
+	if hint.Field == "spec.minReplicas" && hint.NewValue != "" {
+		value, err := strconv.ParseInt(hint.NewValue, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid minReplicas %q: %w", hint.NewValue, err)
+		}
+		minReplicas = int32(value)
+	}
+	replicas := deployment.Status.Replicas
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	maxReplicas := max(2*replicas, minReplicas+1)
+
+	target := int64(defaultHPACPUTarget)
+	if co.metricsClient != nil {
+		avgRequest, podUsage, err := co.deploymentCPUPerPod(ctx, deployment)
+		if err != nil {
+			slog.Warn("Failed to collect CPU usage, using the default HPA target", "namespace", namespace, "deployment", name, "error", err)
+		} else if avgRequest > 0 {
+			observed := aggregateReplicas(podUsage, replicaAggregationAvg) * 100 / avgRequest
+			if observed > target {
+				target = min((observed+4)/5*5, maxHPACPUTarget)
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"apiVersion": "autoscaling/v2",
+		"kind":       "HorizontalPodAutoscaler",
+		"metadata":   objectMeta(namespace, name),
+		"spec": map[string]interface{}{
+			"scaleTargetRef": map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"name":       name,
+			},
+			"minReplicas": minReplicas,
+			"maxReplicas": maxReplicas,
+			"metrics": []interface{}{
+				map[string]interface{}{
+					"type": "Resource",
+					"resource": map[string]interface{}{
+						"name": "cpu",
+						"target": map[string]interface{}{
+							"type":               "Utilization",
+							"averageUtilization": target,
+						},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// hpaCPUTarget returns the HPA's CPU average utilization target, if it has one
+func hpaCPUTarget(hpa autoscalingv2.HorizontalPodAutoscaler) (int32, bool) {
+	for _, metric := range hpa.Spec.Metrics {
+		if metric.Type != autoscalingv2.ResourceMetricSourceType || metric.Resource == nil {
+			continue
+		}
+		if metric.Resource.Name != corev1.ResourceCPU || metric.Resource.Target.Type != autoscalingv2.UtilizationMetricType {
+			continue
+		}
+		if metric.Resource.Target.AverageUtilization != nil && *metric.Resource.Target.AverageUtilization > 0 {
+			return *metric.Resource.Target.AverageUtilization, true
+		}
+	}
+	return 0, false
+}
+
+// deploymentCPUPerPod returns the average CPU request and each pod's usage, in
+// millicores, across a Deployment's running pods that have metrics
+func (co *CostOptimizer) deploymentCPUPerPod(ctx context.Context, deployment *appsv1.Deployment) (request int64, usage []int64, err error) {
+	namespace := deployment.Namespace
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return 0, nil, err
+	}
+	opts := metav1.ListOptions{LabelSelector: selector.String()}
+
+	pods, err := co.clientset.CoreV1().Pods(namespace).List(ctx, opts)
+	if err != nil {
+		return 0, nil, fmt.Errorf("list pods: %w", err)
+	}
+	podMetrics, err := co.metricsClient.MetricsV1beta1().PodMetricses(namespace).List(ctx, opts)
+	if err != nil {
+		return 0, nil, fmt.Errorf("get pod metrics: %w", err)
+	}
+
+	usageByPod := make(map[string]int64, len(podMetrics.Items))
+	for _, m := range podMetrics.Items {
+		for _, c := range m.Containers {
+			usageByPod[m.Name] += c.Usage.Cpu().MilliValue()
+		}
+	}
+
+	var totalRequest int64
+	for _, pod := range pods.Items {
+		podUsage, ok := usageByPod[pod.Name]
+		if pod.Status.Phase != corev1.PodRunning || !ok {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			totalRequest += container.Resources.Requests.Cpu().MilliValue()
+		}
+		usage = append(usage, podUsage)
+	}
+	if len(usage) == 0 {
+		return 0, nil, nil
+	}
+	return totalRequest / int64(len(usage)), usage, nil
+}
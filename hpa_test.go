@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// testHPA scales a deployment on a resource utilization target
+func testHPA(deployment *appsv1.Deployment, resourceName corev1.ResourceName, target, minReplicas, current int32) *autoscalingv2.HorizontalPodAutoscaler {
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: deployment.Name, Namespace: deployment.Namespace},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: deployment.Name},
+			MinReplicas:    &minReplicas,
+			MaxReplicas:    10,
+			Metrics: []autoscalingv2.MetricSpec{{
+				Type: autoscalingv2.ResourceMetricSourceType,
+				Resource: &autoscalingv2.ResourceMetricSource{
+					Name:   resourceName,
+					Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &target},
+				},
+			}},
+		},
+		Status: autoscalingv2.HorizontalPodAutoscalerStatus{CurrentReplicas: current},
+	}
+}
+
+func TestAnalyzeHPATargets(t *testing.T) {
+	tests := []struct {
+		name        string
+		resource    corev1.ResourceName
+		usage       string
+		minReplicas int32
+		current     int32
+		want        string // expected Impact, or no recommendation when empty
+	}{
+		{name: "over-requested at floor", resource: corev1.ResourceCPU, usage: "100m", minReplicas: 2, current: 2, want: "Lower the CPU request to about 200m"},
+		{name: "scaled out", resource: corev1.ResourceCPU, usage: "100m", minReplicas: 2, current: 4},
+		{name: "usage near target", resource: corev1.ResourceCPU, usage: "300m", minReplicas: 2, current: 2},
+		{name: "memory target", resource: corev1.ResourceMemory, usage: "100m", minReplicas: 2, current: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := testDeployment("shop", "web", 2)
+			deployment.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU] = resource.MustParse("1")
+			objects := []runtime.Object{deployment, testHPA(deployment, tt.resource, 50, tt.minReplicas, tt.current)}
+			for _, name := range []string{"web-a", "web-b"} {
+				metrics := testPodMetrics("shop", name, tt.usage, "64Mi")
+				metrics.Labels = deployment.Spec.Template.Labels
+				objects = append(objects, testReplica(deployment, name, "node-a"), metrics)
+			}
+			optimizer, _ := newTestOptimizer(t, objects...)
+
+			recommendations := optimizer.analyzeHPATargets(context.Background())
+			if tt.want == "" {
+				if len(recommendations) != 0 {
+					t.Fatalf("got %d recommendations, want none: %+v", len(recommendations), recommendations)
+				}
+				return
+			}
+			if len(recommendations) != 1 {
+				t.Fatalf("got %d recommendations, want 1", len(recommendations))
+			}
+			rec := recommendations[0]
+			if rec.Type != "hpa_request_mismatch" || rec.Resource != "shop/web" {
+				t.Errorf("recommendation = %s %s, want hpa_request_mismatch shop/web", rec.Type, rec.Resource)
+			}
+			if !strings.HasPrefix(rec.Impact, tt.want) {
+				t.Errorf("Impact = %q, want prefix %q", rec.Impact, tt.want)
+			}
+			if !strings.Contains(rec.Description, "floor of 2 replicas") || !strings.Contains(rec.Description, "above 500m per pod") {
+				t.Errorf("Description = %q, want the current scaling behavior", rec.Description)
+			}
+			if rec.Savings <= 0 {
+				t.Errorf("Savings = %v, want the cost of the excess request", rec.Savings)
+			}
+		})
+	}
+}
+
+func TestHPASuggestionReplicaAggregation(t *testing.T) {
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{mode: replicaAggregationAvg, want: "Lower the CPU request to about 200m"},
+		{mode: replicaAggregationMax, want: "Lower the CPU request to about 300m"},
+		{mode: replicaAggregationP95, want: "Lower the CPU request to about 300m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			deployment := testDeployment("shop", "web", 3)
+			deployment.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU] = resource.MustParse("1")
+			objects := []runtime.Object{deployment, testHPA(deployment, corev1.ResourceCPU, 50, 3, 3)}
+			for name, usage := range map[string]string{"web-a": "60m", "web-b": "90m", "web-c": "150m"} {
+				metrics := testPodMetrics("shop", name, usage, "64Mi")
+				metrics.Labels = deployment.Spec.Template.Labels
+				objects = append(objects, testReplica(deployment, name, "node-a"), metrics)
+			}
+			optimizer, _ := newTestOptimizer(t, objects...)
+			optimizer.replicaAggregation = tt.mode
+
+			recommendations := optimizer.analyzeHPATargets(context.Background())
+			if len(recommendations) != 1 {
+				t.Fatalf("got %d recommendations, want 1", len(recommendations))
+			}
+			rec := recommendations[0]
+			if !strings.HasPrefix(rec.Impact, tt.want) {
+				t.Errorf("Impact = %q, want prefix %q", rec.Impact, tt.want)
+			}
+			// The HPA averages across replicas whatever the suggestion is based on
+			if !strings.Contains(rec.Description, "use 100m (10%)") {
+				t.Errorf("Description = %q, want the average usage", rec.Description)
+			}
+		})
+	}
+}
+
+func TestAggregateReplicas(t *testing.T) {
+	twenty := make([]int64, 0, 20)
+	for i := int64(20); i >= 1; i-- {
+		twenty = append(twenty, i*10)
+	}
+
+	tests := []struct {
+		name   string
+		values []int64
+		mode   string
+		want   int64
+	}{
+		{name: "no replicas", mode: replicaAggregationMax, want: 0},
+		{name: "avg", values: []int64{60, 90, 150}, mode: replicaAggregationAvg, want: 100},
+		{name: "max", values: []int64{60, 150, 90}, mode: replicaAggregationMax, want: 150},
+		{name: "p95 of few replicas is the max", values: []int64{150, 60, 90}, mode: replicaAggregationP95, want: 150},
+		{name: "p95 of twenty replicas", values: twenty, mode: replicaAggregationP95, want: 190},
+		{name: "avg of twenty replicas", values: twenty, mode: replicaAggregationAvg, want: 105},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := aggregateReplicas(tt.values, tt.mode); got != tt.want {
+				t.Errorf("aggregateReplicas(%v, %s) = %d, want %d", tt.values, tt.mode, got, tt.want)
+			}
+		})
+	}
+	if twenty[0] != 200 {
+		t.Errorf("aggregateReplicas reordered its input: %v", twenty)
+	}
+}
+
+func TestReplicaAggregationSetting(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: replicaAggregationP95},
+		{value: "avg", want: replicaAggregationAvg},
+		{value: "max", want: replicaAggregationMax},
+		{value: "median", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("DEMO_MODE", "true")
+			t.Setenv("OPTIMKUBE_REPLICA_AGGREGATION", tt.value)
+			co, err := NewCostOptimizer()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCostOptimizer error %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && co.replicaAggregation != tt.want {
+				t.Errorf("aggregation %q, want %q", co.replicaAggregation, tt.want)
+			}
+		})
+	}
+}
+
+func TestHPAManifest(t *testing.T) {
+	tests := []struct {
+		name        string
+		usage       string // of each of the three replicas; no metrics when empty
+		minReplicas string // asked for by the hint
+		noRequest   bool
+		wantMin     int32
+		wantMax     int32
+		wantTarget  int32
+		wantErr     bool
+	}{
+		{name: "no usage metrics", wantMin: 1, wantMax: 6, wantTarget: 70},
+		{name: "cool replicas", usage: "40m", wantMin: 1, wantMax: 6, wantTarget: 70},
+		{name: "warm replicas", usage: "72m", wantMin: 1, wantMax: 6, wantTarget: 75},
+		{name: "hot replicas", usage: "95m", wantMin: 1, wantMax: 6, wantTarget: 85},
+		{name: "hinted floor", minReplicas: "2", wantMin: 2, wantMax: 6, wantTarget: 70},
+		{name: "floor above the replicas", minReplicas: "8", wantMin: 8, wantMax: 9, wantTarget: 70},
+		{name: "no cpu request", noRequest: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := testDeployment("shop", "web", 3)
+			if tt.noRequest {
+				delete(deployment.Spec.Template.Spec.Containers[0].Resources.Requests, corev1.ResourceCPU)
+			}
+			objects := []runtime.Object{deployment}
+			for _, name := range []string{"web-a", "web-b", "web-c"} {
+				objects = append(objects, testReplica(deployment, name, "node-1"))
+				if tt.usage != "" {
+					metrics := testPodMetrics("shop", name, tt.usage, "64Mi")
+					metrics.Labels = deployment.Spec.Template.Labels
+					objects = append(objects, metrics)
+				}
+			}
+			co, _ := newTestOptimizer(t, objects...)
+
+			hint := &ActionHint{Verb: "create", Target: hintTarget("horizontalpodautoscaler", "shop", "web")}
+			if tt.minReplicas != "" {
+				hint.Field, hint.NewValue = "spec.minReplicas", tt.minReplicas
+			}
+			manifest, err := co.remediationManifest(context.Background(), Recommendation{ID: "hpa", ActionHint: hint})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("manifest %+v, want an error", manifest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if manifest.Format != manifestApply || manifest.Command != "kubectl apply -f manifest.yaml" {
+				t.Errorf("manifest %+v, want one to kubectl apply", manifest)
+			}
+
+			var hpa autoscalingv2.HorizontalPodAutoscaler
+			if err := yaml.UnmarshalStrict([]byte(manifest.Manifest), &hpa); err != nil {
+				t.Fatalf("manifest isn't an HPA: %v\n%s", err, manifest.Manifest)
+			}
+			if hpa.APIVersion != "autoscaling/v2" || hpa.Kind != "HorizontalPodAutoscaler" || hpa.Namespace != "shop" || hpa.Name != "web" {
+				t.Errorf("HPA %s %s %s/%s, want autoscaling/v2 HorizontalPodAutoscaler shop/web", hpa.APIVersion, hpa.Kind, hpa.Namespace, hpa.Name)
+			}
+			if ref := hpa.Spec.ScaleTargetRef; ref.APIVersion != "apps/v1" || ref.Kind != "Deployment" || ref.Name != "web" {
+				t.Errorf("scaleTargetRef %+v, want Deployment web", ref)
+			}
+			if hpa.Spec.MinReplicas == nil || *hpa.Spec.MinReplicas != tt.wantMin || hpa.Spec.MaxReplicas != tt.wantMax {
+				t.Errorf("replicas %v to %d, want %d to %d", hpa.Spec.MinReplicas, hpa.Spec.MaxReplicas, tt.wantMin, tt.wantMax)
+			}
+			target, ok := hpaCPUTarget(hpa)
+			if !ok || target != tt.wantTarget {
+				t.Errorf("CPU target %d (set %v), want %d", target, ok, tt.wantTarget)
+			}
+		})
+	}
+}
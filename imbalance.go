@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultImbalanceStdDev is the spread of node utilization within a pool, in
+// percentage points, at which rebalancing is suggested
+const defaultImbalanceStdDev = 25.0
+
+// imbalanceRecommendations flags node groups whose nodes are unevenly loaded,
+// such as one node at 95% next to another at 10%. Moving pods from the hot
+// nodes to the cold ones relieves the pressure without adding a node. A node's
+// utilization is the higher of its CPU and memory utilization.
+func (co *CostOptimizer) imbalanceRecommendations(groups map[string]*nodeGroupUsage) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		group := groups[name]
+		if len(group.Utilization) < 2 {
+			continue
+		}
+
+		var mean float64
+		for _, utilization := range group.Utilization {
+			mean += utilization
+		}
+		mean /= float64(len(group.Utilization))
+		var variance float64
+		for _, utilization := range group.Utilization {
+			variance += (utilization - mean) * (utilization - mean)
+		}
+		stdDev := math.Sqrt(variance / float64(len(group.Utilization)))
+		if stdDev < co.imbalanceStdDev {
+			continue
+		}
+
+		hot := make([]string, 0)
+		cold := make([]string, 0)
+		hottest := 0.0
+		for i, utilization := range group.Utilization {
+			node := fmt.Sprintf("%s (%.0f%%)", group.NodeNames[i], utilization)
+			switch {
+			case utilization >= mean+stdDev:
+				hot = append(hot, node)
+			case utilization <= mean-stdDev:
+				cold = append(cold, node)
+			}
+			hottest = math.Max(hottest, utilization)
+		}
+		if len(hot) == 0 || len(cold) == 0 {
+			continue
+		}
+
+		priority := "low"
+		if hottest >= 90 {
+			priority = "medium"
+		}
+		recommendations = append(recommendations, Recommendation{
+			Type:        "rebalance",
+			Category:    CategoryConfigure,
+			Resource:    group.Name,
+			Description: fmt.Sprintf("Node group %s is unevenly loaded (mean utilization %.0f%%, standard deviation %.0f points): hot nodes %s, cold nodes %s", group.Name, mean, stdDev, strings.Join(hot, ", "), strings.Join(cold, ", ")),
+			Impact:      "Rebalance pods from the hot nodes onto the cold ones, for example with the descheduler's LowNodeUtilization strategy, instead of scaling up",
+			Priority:    priority,
+			Timestamp:   time.Now(),
+		})
+	}
+
+	return recommendations
+}
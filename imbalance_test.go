@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// testPool is a node group of 4 CPU, 16Gi nodes, one per CPU usage given,
+// each using 1Gi of memory
+func testPool(group string, cpuUsage ...string) []runtime.Object {
+	var objects []runtime.Object
+	for i, cpu := range cpuUsage {
+		name := fmt.Sprintf("%s-%d", group, i+1)
+		node := testNode(name, "4", "16Gi")
+		node.Labels = map[string]string{"eks.amazonaws.com/nodegroup": group}
+		objects = append(objects, node, testNodeMetrics(name, cpu, "1Gi"))
+	}
+	return objects
+}
+
+func TestImbalanceRecommendations(t *testing.T) {
+	tests := []struct {
+		name         string
+		nodes        []runtime.Object
+		stdDev       float64
+		wantPriority string
+		wantNodes    []string // hot then cold, in the description
+	}{
+		{
+			name:         "hot and cold node",
+			nodes:        testPool("general", "3800m", "400m"),
+			wantPriority: "medium",
+			wantNodes:    []string{"hot nodes general-1 (95%)", "cold nodes general-2 (10%)"},
+		},
+		{
+			name:         "moderately imbalanced",
+			nodes:        testPool("general", "3", "3", "400m", "400m"),
+			wantPriority: "low",
+			wantNodes:    []string{"hot nodes general-1 (75%), general-2 (75%)", "cold nodes general-3 (10%), general-4 (10%)"},
+		},
+		{name: "balanced pool", nodes: testPool("general", "2", "2400m", "1800m")},
+		{name: "single node", nodes: testPool("general", "3800m")},
+		{name: "higher threshold", nodes: testPool("general", "3800m", "400m"), stdDev: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t, tt.nodes...)
+			if tt.stdDev != 0 {
+				co.imbalanceStdDev = tt.stdDev
+			}
+
+			var rebalances []Recommendation
+			for _, rec := range co.analyzeNodes(context.Background()) {
+				if rec.Type == "rebalance" {
+					rebalances = append(rebalances, rec)
+				}
+			}
+			if tt.wantNodes == nil {
+				if len(rebalances) != 0 {
+					t.Errorf("got %+v, want no rebalance", rebalances)
+				}
+				return
+			}
+			if len(rebalances) != 1 {
+				t.Fatalf("got %d rebalance recommendations, want 1", len(rebalances))
+			}
+			rec := rebalances[0]
+			if rec.Resource != "general" || rec.Priority != tt.wantPriority {
+				t.Errorf("rebalance %s at %s priority, want general at %s", rec.Resource, rec.Priority, tt.wantPriority)
+			}
+			for _, want := range tt.wantNodes {
+				if !strings.Contains(rec.Description, want) {
+					t.Errorf("Description = %q, want it to name %q", rec.Description, want)
+				}
+			}
+		})
+	}
+}
+
+func TestImbalanceStdDevSetting(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{value: "", want: defaultImbalanceStdDev},
+		{value: "15", want: 15},
+		{value: "0", wantErr: true},
+		{value: "wide", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("DEMO_MODE", "true")
+			t.Setenv("OPTIMKUBE_IMBALANCE_STDDEV", tt.value)
+			co, err := NewCostOptimizer()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCostOptimizer error %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && co.imbalanceStdDev != tt.want {
+				t.Errorf("threshold %v, want %v", co.imbalanceStdDev, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// objectAnalyzers lists, per kind, the analyzers whose recommendations are
+// about a single object of that kind, keyed by its namespace/name Resource.
+// They're dropped when the object is deleted.
+var objectAnalyzers = map[string][]string{
+	"pod":        {"pods", "besteffort", "terminating", "emptydir", "cleanup"},
+	"deployment": {"deployments", "hpa", "spot", "pdb"},
+}
+
+// watchIncremental keeps the recommendation set current between scans from
+// informer events. A changed Deployment is re-evaluated on its own; a deleted
+// or finished pod, or a deleted Deployment, has its recommendations dropped.
+// Findings that need metrics or cluster-wide state still wait for the next
+// scan, which remains the periodic reconcile. The informers run until stop is
+// closed.
+func (co *CostOptimizer) watchIncremental(clientset kubernetes.Interface, stop <-chan struct{}) informers.SharedInformerFactory {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = co.workloadSelector
+		}))
+
+	factory.Apps().V1().Deployments().Informer().AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			// The first scan already covers what existed at startup
+			if deployment, ok := obj.(*appsv1.Deployment); ok && !isInInitialList {
+				co.refreshDeployment(deployment)
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			if deployment, ok := obj.(*appsv1.Deployment); ok {
+				co.refreshDeployment(deployment)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if deployment, ok := deletedObject(obj).(*appsv1.Deployment); ok {
+				co.dropObjectRecommendations("deployment", deployment.Namespace, deployment.Name)
+			}
+		},
+	})
+
+	factory.Core().V1().Pods().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(_, obj interface{}) {
+			pod, ok := obj.(*corev1.Pod)
+			if ok && (pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed) {
+				co.dropObjectRecommendations("pod", pod.Namespace, pod.Name)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if pod, ok := deletedObject(obj).(*corev1.Pod); ok {
+				co.dropObjectRecommendations("pod", pod.Namespace, pod.Name)
+			}
+		},
+	})
+
+	factory.Start(stop)
+	return factory
+}
+
+// deletedObject unwraps the tombstone an informer delivers when it missed the
+// final state of a deleted object
+func deletedObject(obj interface{}) interface{} {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return tombstone.Obj
+	}
+	return obj
+}
+
+// refreshDeployment replaces the Deployment analyzer's findings for one
+// Deployment with a fresh evaluation
+func (co *CostOptimizer) refreshDeployment(deployment *appsv1.Deployment) {
+	if co.analyzerDisabled("deployments") {
+		return
+	}
+	resource := fmt.Sprintf("%s/%s", deployment.Namespace, deployment.Name)
+	var pdbs []policyv1.PodDisruptionBudget
+	if co.clientset != nil {
+		pdbs = co.listPDBs(context.Background(), deployment.Namespace)
+	}
+	fresh := co.deploymentRecommendations(deployment, pdbs)
+	for i := range fresh {
+		fresh[i].analyzer = "deployments"
+	}
+	co.patchRecommendations(func(rec Recommendation) bool {
+		return rec.analyzer == "deployments" && rec.Resource == resource
+	}, fresh)
+}
+
+// dropObjectRecommendations removes the findings about one deleted object
+func (co *CostOptimizer) dropObjectRecommendations(kind, namespace, name string) {
+	resource := fmt.Sprintf("%s/%s", namespace, name)
+	analyzers := objectAnalyzers[kind]
+	co.patchRecommendations(func(rec Recommendation) bool {
+		return rec.Resource == resource && slices.Contains(analyzers, rec.analyzer)
+	}, nil)
+}
+
+// patchRecommendations removes the recommendations matched by stale and adds
+// fresh ones, applying the same filtering and stamping as a full scan
+func (co *CostOptimizer) patchRecommendations(stale func(Recommendation) bool, fresh []Recommendation) {
+	fresh = co.dropExcludedNamespaces(fresh)
+	fresh, _ = co.suppressRecommendations(fresh)
+	fresh = dedupRecommendations(fresh)
+	now := co.now()
+	co.stampExpiry(fresh, now)
+
+	co.recommendationsMu.Lock()
+	defer co.recommendationsMu.Unlock()
+
+	patched := make([]Recommendation, 0, len(co.recommendations)+len(fresh))
+	removed := 0
+	for _, rec := range co.recommendations {
+		if stale(rec) {
+			removed++
+			continue
+		}
+		patched = append(patched, rec)
+	}
+	if removed == 0 && len(fresh) == 0 {
+		return
+	}
+	kept := len(patched)
+	patched = append(patched, fresh...)
+	co.markProtected(patched)
+	// Kept findings keep their IDs; stamping the whole set again would
+	// renumber collisions that a removal moved up
+	stampNewIDs(patched[:kept], patched[kept:])
+	stampSeen(patched[kept:], co.recommendations, now)
+	co.recommendations = patched
+	if removed != len(fresh) {
+		slog.Info("Incrementally updated recommendations", "removed", removed, "added", len(fresh))
+	}
+}
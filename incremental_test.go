@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestIncrementalAnalysis(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default"},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	co, client := newTestOptimizer(t, testDeployment("default", "api", 3), pod)
+
+	// The deployments and hpa findings about api share a key, so the hpa one
+	// is numbered after the deployments one
+	co.recommendations = []Recommendation{
+		{Type: "horizontal_scaling", Resource: "default/api", Namespace: "default", analyzer: "deployments"},
+		{Type: "horizontal_scaling", Resource: "default/api", Namespace: "default", analyzer: "hpa"},
+		{Type: "rightsizing", Resource: "default/web-1", Namespace: "default", analyzer: "pods"},
+	}
+	stampIDs(co.recommendations)
+	before := recommendationIDs(co)
+
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	factory := co.watchIncremental(client, stop)
+	for informer, synced := range factory.WaitForCacheSync(stop) {
+		if !synced {
+			t.Fatalf("informer %v did not sync", informer)
+		}
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		event   func() error
+		present []string
+		absent  []string
+	}{
+		{
+			name: "add",
+			event: func() error {
+				_, err := client.AppsV1().Deployments("default").Create(ctx, testDeployment("default", "worker", 4), metav1.CreateOptions{})
+				return err
+			},
+			present: []string{"default/worker deployments", "default/api deployments", "default/api hpa", "default/web-1 pods"},
+		},
+		{
+			name: "update",
+			event: func() error {
+				_, err := client.AppsV1().Deployments("default").Update(ctx, testDeployment("default", "api", 1), metav1.UpdateOptions{})
+				return err
+			},
+			present: []string{"default/worker deployments", "default/api hpa", "default/web-1 pods"},
+			absent:  []string{"default/api deployments"},
+		},
+		{
+			name: "delete",
+			event: func() error {
+				return client.CoreV1().Pods("default").Delete(ctx, "web-1", metav1.DeleteOptions{})
+			},
+			present: []string{"default/worker deployments", "default/api hpa"},
+			absent:  []string{"default/api deployments", "default/web-1 pods"},
+		},
+	}
+
+	for _, tt := range tests {
+		if err := tt.event(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		waitFor(t, tt.name+" event", func() bool {
+			ids := recommendationIDs(co)
+			for _, key := range tt.present {
+				if ids[key] == "" {
+					return false
+				}
+			}
+			for _, key := range tt.absent {
+				if ids[key] != "" {
+					return false
+				}
+			}
+			return true
+		})
+
+		// Findings the event didn't touch keep their IDs
+		ids := recommendationIDs(co)
+		for _, key := range tt.present {
+			if id, ok := before[key]; ok && ids[key] != id {
+				t.Errorf("%s: %s ID changed from %s to %s", tt.name, key, id, ids[key])
+			}
+		}
+	}
+}
+
+// TestIncrementalAnalysisAfterRestart deletes objects whose findings were
+// reloaded from the state dir, which relies on each finding's analyzer
+// surviving the restart
+func TestIncrementalAnalysisAfterRestart(t *testing.T) {
+	t.Setenv("OPTIMKUBE_STATE_DIR", t.TempDir())
+	before, _ := newTestOptimizer(t)
+	saved := []Recommendation{
+		{Type: "horizontal_scaling", Resource: "default/api", Namespace: "default", analyzer: "deployments", labels: map[string]string{"app": "api"}},
+		{Type: "rightsizing", Resource: "default/web-1", Namespace: "default", analyzer: "pods"},
+		{Type: "node_consolidation", Resource: "node-1", analyzer: "nodes"},
+	}
+	stampIDs(saved)
+	before.saveRecommendations(saved)
+
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		delete  func(client *fake.Clientset) error
+		present []string
+		absent  []string
+	}{
+		{
+			name: "deployment",
+			delete: func(client *fake.Clientset) error {
+				return client.AppsV1().Deployments("default").Delete(ctx, "api", metav1.DeleteOptions{})
+			},
+			present: []string{"default/web-1 pods", "node-1 nodes"},
+			absent:  []string{"default/api deployments"},
+		},
+		{
+			name: "pod",
+			delete: func(client *fake.Clientset) error {
+				return client.CoreV1().Pods("default").Delete(ctx, "web-1", metav1.DeleteOptions{})
+			},
+			present: []string{"default/api deployments", "node-1 nodes"},
+			absent:  []string{"default/web-1 pods"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default"},
+				Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+			}
+			co, client := newTestOptimizer(t, testDeployment("default", "api", 3), pod)
+			if err := co.loadRecommendations(); err != nil {
+				t.Fatalf("loadRecommendations: %v", err)
+			}
+			if !reflect.DeepEqual(co.recommendations, saved) {
+				t.Fatalf("reloaded %+v, want %+v", co.recommendations, saved)
+			}
+
+			stop := make(chan struct{})
+			t.Cleanup(func() { close(stop) })
+			factory := co.watchIncremental(client, stop)
+			factory.WaitForCacheSync(stop)
+
+			if err := tt.delete(client); err != nil {
+				t.Fatal(err)
+			}
+			waitFor(t, "the deleted object's findings to go", func() bool {
+				ids := recommendationIDs(co)
+				for _, key := range tt.absent {
+					if ids[key] != "" {
+						return false
+					}
+				}
+				return true
+			})
+			ids := recommendationIDs(co)
+			for _, key := range tt.present {
+				if ids[key] == "" {
+					t.Errorf("%s was dropped", key)
+				}
+			}
+		})
+	}
+}
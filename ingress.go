@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// analyzeIngressBackends flags Ingress rules whose backend Service is missing
+// or has no ready endpoints. Such rules keep the controller and its load
+// balancer programmed for traffic that can only fail.
+func (co *CostOptimizer) analyzeIngressBackends(ctx context.Context) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	if co.demoMode || co.clientset == nil || co.analyzerDisabled("ingress") {
+		return recommendations
+	}
+
+	ingresses, err := co.clientset.NetworkingV1().Ingresses("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		co.analyzerListFailed("ingress", "ingresses", err)
+		return recommendations
+	}
+	if len(ingresses.Items) == 0 {
+		return recommendations
+	}
+
+	services, err := co.clientset.CoreV1().Services("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		co.analyzerListFailed("ingress", "services", err)
+		return recommendations
+	}
+	slices, err := co.clientset.DiscoveryV1().EndpointSlices("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		co.analyzerListFailed("ingress", "endpointslices", err)
+		return recommendations
+	}
+
+	existing := make(map[string]bool, len(services.Items))
+	for _, service := range services.Items {
+		existing[service.Namespace+"/"+service.Name] = true
+	}
+	ready := make(map[string]bool)
+	for _, slice := range slices.Items {
+		if service := slice.Labels[discoveryv1.LabelServiceName]; service != "" && hasReadyEndpoint(slice) {
+			ready[slice.Namespace+"/"+service] = true
+		}
+	}
+
+	// deadBackend explains why a backend can't serve, or returns "" if it can
+	deadBackend := func(namespace string, backend networkingv1.IngressBackend) string {
+		if backend.Service == nil {
+			return ""
+		}
+		key := namespace + "/" + backend.Service.Name
+		switch {
+		case !existing[key]:
+			return fmt.Sprintf("Service %s does not exist", backend.Service.Name)
+		case !ready[key]:
+			return fmt.Sprintf("Service %s has no ready endpoints", backend.Service.Name)
+		}
+		return ""
+	}
+
+	for _, ingress := range ingresses.Items {
+		target := hintTarget("ingress", ingress.Namespace, ingress.Name)
+
+		if backend := ingress.Spec.DefaultBackend; backend != nil {
+			if reason := deadBackend(ingress.Namespace, *backend); reason != "" {
+				recommendations = append(recommendations, ingressCleanupRecommendation(ingress, "*", "*", reason,
+					&ActionHint{Verb: "delete", Target: target, Field: "spec.defaultBackend"}))
+			}
+		}
+
+		for i, rule := range ingress.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+			host := rule.Host
+			if host == "" {
+				host = "*"
+			}
+			for j, path := range rule.HTTP.Paths {
+				if reason := deadBackend(ingress.Namespace, path.Backend); reason != "" {
+					recommendations = append(recommendations, ingressCleanupRecommendation(ingress, host, path.Path, reason,
+						&ActionHint{Verb: "delete", Target: target, Field: fmt.Sprintf("spec.rules[%d].http.paths[%d]", i, j)}))
+				}
+			}
+		}
+	}
+
+	return recommendations
+}
+
+func ingressCleanupRecommendation(ingress networkingv1.Ingress, host, path, reason string, hint *ActionHint) Recommendation {
+	if path == "" {
+		path = "/"
+	}
+	return Recommendation{
+		Type:        "networking_cleanup",
+		Category:    CategoryDelete,
+		Resource:    fmt.Sprintf("%s/%s", ingress.Namespace, ingress.Name),
+		Namespace:   ingress.Namespace,
+		Release:     helmRelease(ingress.Labels),
+		labels:      ingress.Labels,
+		Description: fmt.Sprintf("Ingress %s routes host %s path %s to a dead backend: %s", ingress.Name, host, path, reason),
+		Impact:      "Remove the rule or restore its backend so the controller and load balancer stop serving it",
+		ActionHint:  hint,
+		Priority:    "low",
+		Timestamp:   time.Now(),
+	}
+}
+
+// hasReadyEndpoint reports whether a slice has an endpoint accepting traffic.
+// A missing ready condition means ready, per the EndpointSlice API.
+func hasReadyEndpoint(slice discoveryv1.EndpointSlice) bool {
+	for _, endpoint := range slice.Endpoints {
+		if endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// testIngressPath routes host and path to service
+func testIngressPath(host, path, service string) networkingv1.IngressRule {
+	return networkingv1.IngressRule{
+		Host: host,
+		IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+			Paths: []networkingv1.HTTPIngressPath{{
+				Path:    path,
+				Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: service}},
+			}},
+		}},
+	}
+}
+
+// testEndpointSlice is a slice of service with one endpoint per ready value;
+// a nil value leaves the ready condition unset
+func testEndpointSlice(namespace, service string, ready ...*bool) *discoveryv1.EndpointSlice {
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{Name: service + "-abc12", Namespace: namespace, Labels: map[string]string{discoveryv1.LabelServiceName: service}},
+	}
+	for _, r := range ready {
+		slice.Endpoints = append(slice.Endpoints, discoveryv1.Endpoint{Addresses: []string{"10.0.0.1"}, Conditions: discoveryv1.EndpointConditions{Ready: r}})
+	}
+	return slice
+}
+
+func TestAnalyzeIngressBackends(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []networkingv1.IngressRule
+		backend string // the default backend's service, if any
+		objects []runtime.Object
+		want    []string // descriptions
+	}{
+		{
+			name:    "ready backend",
+			rules:   []networkingv1.IngressRule{testIngressPath("shop.example.com", "/api", "api")},
+			objects: []runtime.Object{testService("shop", "api", corev1.ServiceTypeClusterIP, ""), testEndpointSlice("shop", "api", boolPtr(false), boolPtr(true))},
+		},
+		{
+			name:    "ready condition unset",
+			rules:   []networkingv1.IngressRule{testIngressPath("shop.example.com", "/api", "api")},
+			objects: []runtime.Object{testService("shop", "api", corev1.ServiceTypeClusterIP, ""), testEndpointSlice("shop", "api", nil)},
+		},
+		{
+			name:    "backend without ready endpoints",
+			rules:   []networkingv1.IngressRule{testIngressPath("shop.example.com", "/api", "api")},
+			objects: []runtime.Object{testService("shop", "api", corev1.ServiceTypeClusterIP, ""), testEndpointSlice("shop", "api", boolPtr(false))},
+			want:    []string{"Ingress web routes host shop.example.com path /api to a dead backend: Service api has no ready endpoints"},
+		},
+		{
+			name:    "backend without any endpoint slice",
+			rules:   []networkingv1.IngressRule{testIngressPath("", "", "api")},
+			objects: []runtime.Object{testService("shop", "api", corev1.ServiceTypeClusterIP, "")},
+			want:    []string{"Ingress web routes host * path / to a dead backend: Service api has no ready endpoints"},
+		},
+		{
+			name:    "missing service",
+			rules:   []networkingv1.IngressRule{testIngressPath("shop.example.com", "/old", "legacy")},
+			objects: []runtime.Object{testEndpointSlice("other", "legacy", boolPtr(true))},
+			want:    []string{"Ingress web routes host shop.example.com path /old to a dead backend: Service legacy does not exist"},
+		},
+		{
+			name:    "dead default backend",
+			backend: "fallback",
+			want:    []string{"Ingress web routes host * path * to a dead backend: Service fallback does not exist"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ingress := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+				Spec:       networkingv1.IngressSpec{Rules: tt.rules},
+			}
+			if tt.backend != "" {
+				ingress.Spec.DefaultBackend = &networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: tt.backend}}
+			}
+			co, _ := newTestOptimizer(t, append(tt.objects, ingress)...)
+
+			descriptions := make([]string, 0)
+			for _, rec := range co.analyzeIngressBackends(context.Background()) {
+				descriptions = append(descriptions, rec.Description)
+				if rec.Type != "networking_cleanup" || rec.Resource != "shop/web" || rec.ActionHint == nil || rec.ActionHint.Verb != "delete" {
+					t.Errorf("recommendation %+v, want a networking_cleanup deleting from shop/web", rec)
+				}
+			}
+			if len(tt.want) == 0 {
+				tt.want = []string{}
+			}
+			if !reflect.DeepEqual(descriptions, tt.want) {
+				t.Errorf("descriptions %q, want %q", descriptions, tt.want)
+			}
+		})
+	}
+}
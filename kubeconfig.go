@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// kubeconfigContextConfig builds the client config for a named context of
+// the kubeconfig at path, or of the default kubeconfig (~/.kube/config) when
+// path is empty, instead of its current-context
+func kubeconfigContextConfig(path, contextName string) (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if path != "" {
+		rules = &clientcmd.ClientConfigLoadingRules{ExplicitPath: path}
+	}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: contextName})
+
+	raw, err := clientConfig.RawConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load kubeconfig: %w", err)
+	}
+	if _, ok := raw.Contexts[contextName]; !ok {
+		available := make([]string, 0, len(raw.Contexts))
+		for name := range raw.Contexts {
+			available = append(available, name)
+		}
+		sort.Strings(available)
+		return nil, fmt.Errorf("context %q not found in kubeconfig (available: %s)", contextName, strings.Join(available, ", "))
+	}
+	return clientConfig.ClientConfig()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKubeconfigContextConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		context    string
+		wantServer string
+		wantErr    string
+	}{
+		{name: "current context", context: "staging", wantServer: "https://staging.example.com:6443"},
+		{name: "other context", context: "production", wantServer: "https://production.example.com:6443"},
+		{name: "unknown context", context: "prod", wantErr: `context "prod" not found in kubeconfig (available: production, staging)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := kubeconfigContextConfig("testdata/kubeconfig.yaml", tt.context)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Host != tt.wantServer {
+				t.Errorf("server %q, want %q", config.Host, tt.wantServer)
+			}
+		})
+	}
+}
+
+func TestContextSetting(t *testing.T) {
+	t.Setenv("DEMO_MODE", "false")
+	t.Setenv("KUBECONFIG", "testdata/kubeconfig.yaml")
+	t.Setenv("OPTIMKUBE_CONTEXT", "prod")
+	if _, err := NewCostOptimizer(); err == nil || !strings.Contains(err.Error(), "OPTIMKUBE_CONTEXT") {
+		t.Errorf("NewCostOptimizer error %v, want an invalid OPTIMKUBE_CONTEXT", err)
+	}
+}
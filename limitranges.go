@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// limitRangeHeadroom is applied to typical usage when suggesting new defaults
+const limitRangeHeadroom = 1.2
+
+// namespaceUsage tracks observed container usage and how many containers run
+// with a given CPU or memory request, so defaults can be compared against both.
+type namespaceUsage struct {
+	containers     int
+	totalCPUMilli  int64
+	totalMemBytes  int64
+	cpuRequests    map[int64]int // millicores -> containers
+	memoryRequests map[int64]int // bytes -> containers
+}
+
+func (co *CostOptimizer) analyzeLimitRanges(ctx context.Context) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	if co.demoMode || co.clientset == nil || co.metricsClient == nil || co.analyzerDisabled("limitranges") {
+		return recommendations
+	}
+
+	limitRanges, err := co.clientset.CoreV1().LimitRanges("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		co.analyzerListFailed("limitranges", "limitranges", err)
+		return recommendations
+	}
+	if len(limitRanges.Items) == 0 {
+		return recommendations
+	}
+
+	usage, err := co.namespaceContainerUsage(ctx)
+	if err != nil {
+		warnUsageFailed("Failed to collect namespace usage for limit ranges", err)
+		return recommendations
+	}
+
+	for _, limitRange := range limitRanges.Items {
+		observed, ok := usage[limitRange.Namespace]
+		if !ok || observed.containers == 0 {
+			continue
+		}
+
+		for _, item := range limitRange.Spec.Limits {
+			if item.Type != corev1.LimitTypeContainer {
+				continue
+			}
+
+			if rec, ok := co.limitRangeCPURecommendation(limitRange, item, observed); ok {
+				recommendations = append(recommendations, rec)
+			}
+			if rec, ok := co.limitRangeMemoryRecommendation(limitRange, item, observed); ok {
+				recommendations = append(recommendations, rec)
+			}
+		}
+	}
+
+	return recommendations
+}
+
+// limitRangeCPURecommendation flags a default CPU request that is more than
+// double the typical container usage in the namespace.
+func (co *CostOptimizer) limitRangeCPURecommendation(limitRange corev1.LimitRange, item corev1.LimitRangeItem, observed namespaceUsage) (Recommendation, bool) {
+	defaultRequest, ok := item.DefaultRequest[corev1.ResourceCPU]
+	if !ok || defaultRequest.IsZero() {
+		return Recommendation{}, false
+	}
+
+	typical := observed.totalCPUMilli / int64(observed.containers)
+	if 2*typical >= defaultRequest.MilliValue() {
+		return Recommendation{}, false
+	}
+
+	suggested := resource.NewMilliQuantity(int64(float64(typical)*limitRangeHeadroom), resource.DecimalSI)
+	excess := resource.NewMilliQuantity(defaultRequest.MilliValue()-suggested.MilliValue(), resource.DecimalSI)
+	defaulted := observed.cpuRequests[defaultRequest.MilliValue()]
+
+	return Recommendation{
+		Type:        "limit_range_tuning",
+		Category:    CategoryRightsize,
+		Resource:    fmt.Sprintf("%s/%s", limitRange.Namespace, limitRange.Name),
+		Namespace:   limitRange.Namespace,
+		Description: fmt.Sprintf("LimitRange %s defaults CPU requests to %s while containers in %s typically use %dm (%d containers use the default)", limitRange.Name, defaultRequest.String(), limitRange.Namespace, typical, defaulted),
+		Impact:      fmt.Sprintf("Lower the default CPU request to about %s", suggested.String()),
+		ActionHint:  limitRangeHint(limitRange, corev1.ResourceCPU, defaultRequest, *suggested),
+		Savings:     co.estimatePodCost(*excess, resource.Quantity{}) * float64(defaulted),
+		Priority:    "medium",
+		Timestamp:   time.Now(),
+	}, true
+}
+
+// limitRangeMemoryRecommendation flags a default memory request that is more
+// than double the typical container usage in the namespace.
+func (co *CostOptimizer) limitRangeMemoryRecommendation(limitRange corev1.LimitRange, item corev1.LimitRangeItem, observed namespaceUsage) (Recommendation, bool) {
+	defaultRequest, ok := item.DefaultRequest[corev1.ResourceMemory]
+	if !ok || defaultRequest.IsZero() {
+		return Recommendation{}, false
+	}
+
+	typical := observed.totalMemBytes / int64(observed.containers)
+	if 2*typical >= defaultRequest.Value() {
+		return Recommendation{}, false
+	}
+
+	suggested := resource.NewQuantity(int64(float64(typical)*limitRangeHeadroom), resource.BinarySI)
+	excess := resource.NewQuantity(defaultRequest.Value()-suggested.Value(), resource.BinarySI)
+	defaulted := observed.memoryRequests[defaultRequest.Value()]
+
+	return Recommendation{
+		Type:        "limit_range_tuning",
+		Category:    CategoryRightsize,
+		Resource:    fmt.Sprintf("%s/%s", limitRange.Namespace, limitRange.Name),
+		Namespace:   limitRange.Namespace,
+		Description: fmt.Sprintf("LimitRange %s defaults memory requests to %s while containers in %s typically use %s (%d containers use the default)", limitRange.Name, defaultRequest.String(), limitRange.Namespace, resource.NewQuantity(typical, resource.BinarySI).String(), defaulted),
+		Impact:      fmt.Sprintf("Lower the default memory request to about %s", suggested.String()),
+		ActionHint:  limitRangeHint(limitRange, corev1.ResourceMemory, defaultRequest, *suggested),
+		Savings:     co.estimatePodCost(resource.Quantity{}, *excess) * float64(defaulted),
+		Priority:    "medium",
+		Timestamp:   time.Now(),
+	}, true
+}
+
+func limitRangeHint(limitRange corev1.LimitRange, name corev1.ResourceName, current, suggested resource.Quantity) *ActionHint {
+	return &ActionHint{
+		Verb:         "patch",
+		Target:       hintTarget("limitrange", limitRange.Namespace, limitRange.Name),
+		Field:        fmt.Sprintf("spec.limits[type=Container].defaultRequest.%s", name),
+		CurrentValue: current.String(),
+		NewValue:     suggested.String(),
+	}
+}
+
+// namespaceContainerUsage aggregates per-container usage and requests of
+// running pods by namespace.
+func (co *CostOptimizer) namespaceContainerUsage(ctx context.Context) (map[string]namespaceUsage, error) {
+	pods, err := co.listPods(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("list pods: %w", err)
+	}
+
+	podMetrics, err := co.listPodMetrics(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("get pod metrics: %w", err)
+	}
+
+	type podKey struct{ namespace, name string }
+	metricsByPod := make(map[podKey]map[string]corev1.ResourceList)
+	for _, m := range podMetrics.Items {
+		containers := make(map[string]corev1.ResourceList, len(m.Containers))
+		for _, c := range m.Containers {
+			containers[c.Name] = c.Usage
+		}
+		metricsByPod[podKey{m.Namespace, m.Name}] = containers
+	}
+
+	usage := make(map[string]namespaceUsage)
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		containers, ok := metricsByPod[podKey{pod.Namespace, pod.Name}]
+		if !ok {
+			continue
+		}
+
+		ns := usage[pod.Namespace]
+		if ns.cpuRequests == nil {
+			ns.cpuRequests = make(map[int64]int)
+			ns.memoryRequests = make(map[int64]int)
+		}
+		for _, container := range pod.Spec.Containers {
+			containerUsage, ok := containers[container.Name]
+			if !ok {
+				continue
+			}
+			cpu := containerUsage[corev1.ResourceCPU]
+			memory := containerUsage[corev1.ResourceMemory]
+			ns.containers++
+			ns.totalCPUMilli += cpu.MilliValue()
+			ns.totalMemBytes += memory.Value()
+
+			if request, ok := container.Resources.Requests[corev1.ResourceCPU]; ok {
+				ns.cpuRequests[request.MilliValue()]++
+			}
+			if request, ok := container.Resources.Requests[corev1.ResourceMemory]; ok {
+				ns.memoryRequests[request.Value()]++
+			}
+		}
+		usage[pod.Namespace] = ns
+	}
+
+	return usage, nil
+}
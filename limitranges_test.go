@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testLimitRange defaults container requests in namespace to cpu and memory
+func testLimitRange(namespace, cpu, memory string) *corev1.LimitRange {
+	return &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: namespace},
+		Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+			Type: corev1.LimitTypeContainer,
+			DefaultRequest: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			},
+		}}},
+	}
+}
+
+func TestAnalyzeLimitRanges(t *testing.T) {
+	tests := []struct {
+		name       string
+		limitRange *corev1.LimitRange
+		want       []string // resources flagged in the descriptions
+	}{
+		{name: "CPU default dwarfs usage", limitRange: testLimitRange("team", "2", "256Mi"), want: []string{"CPU"}},
+		{name: "both defaults dwarf usage", limitRange: testLimitRange("team", "2", "4Gi"), want: []string{"CPU", "memory"}},
+		{name: "defaults near usage", limitRange: testLimitRange("team", "150m", "256Mi")},
+		{name: "namespace without pods", limitRange: testLimitRange("empty", "2", "4Gi")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Two pods in team run with the defaulted requests, using 100m CPU and 200Mi each
+			defaults := tt.limitRange.Spec.Limits[0].DefaultRequest
+			cpu, memory := defaults[corev1.ResourceCPU], defaults[corev1.ResourceMemory]
+			co, _ := newTestOptimizer(t, tt.limitRange,
+				testPod("team", "api", "node-1", cpu.String(), memory.String()), testPodMetrics("team", "api", "100m", "200Mi"),
+				testPod("team", "worker", "node-1", cpu.String(), memory.String()), testPodMetrics("team", "worker", "100m", "200Mi"))
+
+			recommendations := co.analyzeLimitRanges(context.Background())
+			if len(recommendations) != len(tt.want) {
+				t.Fatalf("got %d recommendations, want %d: %+v", len(recommendations), len(tt.want), recommendations)
+			}
+			for i, rec := range recommendations {
+				if rec.Type != "limit_range_tuning" || rec.Resource != tt.limitRange.Namespace+"/defaults" {
+					t.Errorf("recommendation %d = %s for %s, want limit_range_tuning for the LimitRange", i, rec.Type, rec.Resource)
+				}
+				if !strings.Contains(rec.Description, tt.want[i]) || !strings.Contains(rec.Description, "2 containers use the default") {
+					t.Errorf("description %q, want the %s default used by 2 containers", rec.Description, tt.want[i])
+				}
+				if rec.Savings <= 0 {
+					t.Errorf("savings = %v, want positive", rec.Savings)
+				}
+			}
+		})
+	}
+}
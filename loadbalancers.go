@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Defaults for LoadBalancer consolidation analysis
+const (
+	defaultLBConsolidationThreshold = 3
+	defaultLoadBalancerMonthlyCost  = 18.0 // roughly one cloud load balancer, before traffic
+)
+
+// analyzeLoadBalancers recommends fronting LoadBalancer Services with a shared
+// ingress controller once there are enough of them that the per-LB fee adds up.
+// Only TCP services are candidates since UDP can't be routed by most ingresses.
+func (co *CostOptimizer) analyzeLoadBalancers(ctx context.Context) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	if co.demoMode || co.clientset == nil || co.analyzerDisabled("loadbalancers") {
+		return recommendations
+	}
+
+	services, err := co.clientset.CoreV1().Services("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		co.analyzerListFailed("loadbalancers", "services", err)
+		return recommendations
+	}
+
+	candidates := make([]string, 0)
+	namespaces := make(map[string]bool)
+	for _, service := range services.Items {
+		if service.Spec.Type != corev1.ServiceTypeLoadBalancer || !tcpOnly(service.Spec.Ports) {
+			continue
+		}
+		candidates = append(candidates, fmt.Sprintf("%s/%s", service.Namespace, service.Name))
+		namespaces[service.Namespace] = true
+	}
+
+	if len(candidates) < co.lbConsolidationThreshold {
+		return recommendations
+	}
+
+	recommendations = append(recommendations, Recommendation{
+		Type:        "loadbalancer_consolidation",
+		Category:    CategoryConfigure,
+		Resource:    "services",
+		Description: fmt.Sprintf("%d LoadBalancer Services across %d namespaces could share a single ingress: %s", len(candidates), len(namespaces), strings.Join(candidates, ", ")),
+		Impact:      "Route these services through one ingress controller and switch them to ClusterIP",
+		Savings:     float64(len(candidates)-1) * co.costCalculator.LoadBalancerCostPerMonth,
+		Priority:    "medium",
+		Timestamp:   time.Now(),
+	})
+
+	return recommendations
+}
+
+func tcpOnly(ports []corev1.ServicePort) bool {
+	for _, port := range ports {
+		if port.Protocol != "" && port.Protocol != corev1.ProtocolTCP {
+			return false
+		}
+	}
+	return true
+}
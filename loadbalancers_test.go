@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testService is a service of serviceType with one port of protocol
+func testService(namespace, name string, serviceType corev1.ServiceType, protocol corev1.Protocol) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: corev1.ServiceSpec{
+			Type:  serviceType,
+			Ports: []corev1.ServicePort{{Port: 443, Protocol: protocol}},
+		},
+	}
+}
+
+func TestAnalyzeLoadBalancers(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold string // OPTIMKUBE_LB_CONSOLIDATION_THRESHOLD
+		want      bool
+	}{
+		{name: "default threshold", want: true},
+		{name: "at the threshold", threshold: "4", want: true},
+		{name: "below the threshold", threshold: "5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OPTIMKUBE_LB_CONSOLIDATION_THRESHOLD", tt.threshold)
+			t.Setenv("OPTIMKUBE_LB_MONTHLY_COST", "20")
+			co, _ := newTestOptimizer(t,
+				testService("shop", "frontend", corev1.ServiceTypeLoadBalancer, corev1.ProtocolTCP),
+				testService("shop", "api", corev1.ServiceTypeLoadBalancer, ""),
+				testService("search", "api", corev1.ServiceTypeLoadBalancer, corev1.ProtocolTCP),
+				testService("admin", "console", corev1.ServiceTypeLoadBalancer, corev1.ProtocolTCP),
+				testService("dns", "resolver", corev1.ServiceTypeLoadBalancer, corev1.ProtocolUDP),
+				testService("shop", "cache", corev1.ServiceTypeClusterIP, corev1.ProtocolTCP),
+			)
+
+			recommendations := co.analyzeLoadBalancers(context.Background())
+			if !tt.want {
+				if len(recommendations) != 0 {
+					t.Errorf("got %+v, want no recommendation", recommendations)
+				}
+				return
+			}
+			if len(recommendations) != 1 {
+				t.Fatalf("got %d recommendations, want 1", len(recommendations))
+			}
+			rec := recommendations[0]
+			if rec.Type != "loadbalancer_consolidation" || rec.Savings != 3*20 {
+				t.Errorf("got %s saving %v, want loadbalancer_consolidation saving 60", rec.Type, rec.Savings)
+			}
+			for _, service := range []string{"shop/frontend", "shop/api", "search/api", "admin/console"} {
+				if !strings.Contains(rec.Description, service) {
+					t.Errorf("description %q doesn't list %s", rec.Description, service)
+				}
+			}
+			if strings.Contains(rec.Description, "dns/resolver") || strings.Contains(rec.Description, "shop/cache") {
+				t.Errorf("description %q lists a UDP or ClusterIP service", rec.Description)
+			}
+		})
+	}
+}
+
+func TestLoadBalancerSettingsMustParse(t *testing.T) {
+	for _, env := range []string{"OPTIMKUBE_LB_CONSOLIDATION_THRESHOLD", "OPTIMKUBE_LB_MONTHLY_COST"} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv("DEMO_MODE", "true")
+			t.Setenv(env, "lots")
+			if _, err := NewCostOptimizer(); err == nil || !strings.Contains(err.Error(), env) {
+				t.Errorf("NewCostOptimizer error = %v, want one naming %s", err, env)
+			}
+		})
+	}
+}
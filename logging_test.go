@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+// captureLogs sends the default logger's records to the returned buffer for
+// the rest of the test
+func captureLogs(t *testing.T, level slog.Level) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(newLogger(&buf, level))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+// logRecords decodes the JSON records in buf
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var records []map[string]interface{}
+	decoder := json.NewDecoder(buf)
+	for decoder.More() {
+		var record map[string]interface{}
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("decode log record: %v", err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestScanCompleteLogRecord(t *testing.T) {
+	co, _ := newTestOptimizer(t,
+		testNode("node-1", "4", "16Gi"), testNodeMetrics("node-1", "1", "4Gi"),
+		testPod("shop", "web", "node-1", "2", "1Gi"), testPodMetrics("shop", "web", "100m", "1Gi"),
+	)
+	logs := captureLogs(t, slog.LevelInfo)
+
+	co.analyzeAndGenerateRecommendations(context.Background())
+
+	var complete map[string]interface{}
+	for _, record := range logRecords(t, logs) {
+		if record["msg"] == "Scan complete" {
+			complete = record
+		}
+	}
+	if complete == nil {
+		t.Fatalf("no scan-complete record in\n%s", logs)
+	}
+	want := map[string]interface{}{
+		"level":           "INFO",
+		"recommendations": float64(len(co.activeRecommendations())),
+		"nodes":           float64(1),
+		"pods":            float64(1),
+	}
+	for field, value := range want {
+		if complete[field] != value {
+			t.Errorf("%s = %v, want %v", field, complete[field], value)
+		}
+	}
+	if _, ok := complete["duration"].(string); !ok {
+		t.Errorf("record %v has no duration", complete)
+	}
+}
+
+func TestActionLogRecordsCarryID(t *testing.T) {
+	co, _ := newTestOptimizer(t)
+	logs := captureLogs(t, slog.LevelInfo)
+
+	co.processAction("scale-web")
+
+	records := logRecords(t, logs)
+	if len(records) != 1 || records[0]["level"] != "WARN" || records[0]["action_id"] != "scale-web" {
+		t.Errorf("records %v, want a warning carrying action_id scale-web", records)
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    slog.Level
+		wantErr bool
+	}{
+		{value: "", want: slog.LevelInfo},
+		{value: "debug", want: slog.LevelDebug},
+		{value: "INFO", want: slog.LevelInfo},
+		{value: "warning", want: slog.LevelWarn},
+		{value: "error", want: slog.LevelError},
+		{value: "verbose", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseLogLevel(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLogLevel error %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("level %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newLogger(&buf, slog.LevelWarn)
+	logger.Info("Running cost analysis")
+	logger.Warn("Failed to get node metrics", "error", "timeout")
+
+	records := logRecords(t, &buf)
+	if len(records) != 1 || records[0]["msg"] != "Failed to get node metrics" || records[0]["error"] != "timeout" {
+		t.Errorf("records %v, want only the warning", records)
+	}
+}
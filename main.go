@@ -3,17 +3,25 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
+	"math/rand"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -23,19 +31,124 @@ import (
 
 // CostOptimizer main structure
 type CostOptimizer struct {
-	clientset       *kubernetes.Clientset
-	metricsClient   *metricsclientset.Clientset
-	costCalculator  *CostCalculator
-	recommendations []Recommendation
-	demoMode        bool
-	clusterName     string
-	now             func() time.Time
+	clientset         kubernetes.Interface
+	metricsClient     metricsclientset.Interface
+	costCalculator    *CostCalculator
+	pricing           PricingProvider
+	recommendationsMu sync.RWMutex // guards recommendations across scans, informer patches and handlers
+	recommendations   []Recommendation
+	demoMode          bool
+	readOnly          bool
+	dryRun            bool // execute requests report the change instead of making it
+	thresholds        Thresholds
+	apiToken          string // required as a bearer token on the API when set
+	clusterName       string
+	now               func() time.Time
+	store             stateStore
+	history           *summaryHistory
+	stream            *streamBroker
+	metrics           *promMetrics
+	exporter          *reportExporter
+	notifier          Notifier
+	metricsSource     metricsSource
+	logExporter       LogExporter
+	costModel         *costModel
+	nodeEMA           *emaTracker // node CPU/memory utilization percent
+	containerEMA      *emaTracker // container CPU millicores / memory bytes
+	usageHistory      *usageHistory
+
+	// clusters lists the clusters /api/clusters reports; empty means just
+	// this optimizer's own
+	clusters []monitoredCluster
+
+	// stopWatches is closed on shutdown to stop the pricing ConfigMap and
+	// incremental analysis informers
+	stopWatches chan struct{}
+
+	// scanMu serializes scans: POST /api/optimize starts one while the
+	// monitor loop may be running another. Only scans use the state below.
+	scanMu sync.Mutex
+
+	// emittedRecommendations holds the IDs exported by the previous scan
+	emittedRecommendations map[string]bool
+	// notifiedRecommendations holds the IDs of the high-priority
+	// recommendations already sent to the notifier
+	notifiedRecommendations map[string]bool
+
+	budgetMu        sync.Mutex
+	budgets         []Budget
+	breachedBudgets map[string]bool
+
+	actionsMu   sync.Mutex
+	actions     []OptimizationAction
+	actionQueue chan string
+
+	spikeMu       sync.Mutex
+	previousCosts *scanCosts
+
+	diagMu            sync.Mutex
+	disabledAnalyzers map[string]string
+	analyzerPanics    map[string]string
+	// blockedConsolidations holds the drains the last scan held back
+	blockedConsolidations map[string][]string
+	// suppressedRecommendations counts the last scan's drops per suppression rule
+	suppressedRecommendations map[string]int
+
+	protectedSelectors []ProtectedSelector
+	suppressionRules   []SuppressionRule
+	reservations       []Reservation
+	protectedMu        sync.Mutex
+	protectedResources map[string]bool // resources the last scan found protected
+
+	lbConsolidationThreshold   int
+	recommendationTTL          time.Duration
+	maxInflightRequests        int
+	snapshotRetentionThreshold int
+	reschedulePodCost          float64 // one-time cost of moving a pod during a drain
+	costSpikePercent           float64
+	costSpikeMinIncrease       float64
+	rounding                   RoundingPolicy
+	rightsizingMinPodAge       time.Duration
+	replicaAggregation         string        // how per-replica usage is combined for workload suggestions
+	costLabel                  string        // pod/namespace label that label costs are grouped by
+	scanInterval               time.Duration // average time between scans
+	spotPriceFactor            float64       // fraction of the on-demand rate a spot node costs
+	scanJitter                 float64
+	terminatingThreshold       time.Duration
+	cleanupTTL                 time.Duration
+	imbalanceStdDev            float64 // node utilization spread, in points, that triggers rebalancing
+	nodeBilling                string  // billingMonthly or billingPerSecond
+
+	throughputQueries    []ThroughputQuery
+	quietWindows         []QuietWindow
+	excludedNamespaces   []string
+	productionNamespaces []string
+	freezeFile           string
+
+	// Analysis scope from OPTIMKUBE_LABEL_SELECTOR and OPTIMKUBE_NAMESPACE_EXCLUDE
+	workloadSelector  string
+	droppedNamespaces []string
+
+	listCache listCache
+
+	// metricsAPI tracks whether metrics-server answers
+	metricsAPI metricsAPIState
 }
 
 // CostCalculator handles cost calculations
 type CostCalculator struct {
+	pricingMu        sync.RWMutex       // guards NodeCostPerHour, see nodePrices
 	NodeCostPerHour  map[string]float64 // instance type -> cost per hour
 	StorageCostPerGB float64            // cost per GB per month
+	GPUCostPerHour   float64            // cost per physical GPU per hour
+
+	LoadBalancerCostPerMonth float64 // cost per cloud load balancer per month
+	ReplicationCostFactor    float64 // share of the volume price charged per extra replica
+	SnapshotCostFactor       float64 // share of the volume price charged per retained snapshot
+
+	// Both zero unless set in the pricing file, which leaves these costs out
+	EphemeralStorageCostPerGB float64 // cost per GB of requested ephemeral storage per month
+	EgressCostPerGB           float64 // cost per GB of network egress
 }
 
 // NodeMetrics represents node resource usage
@@ -48,46 +161,134 @@ type NodeMetrics struct {
 	CPUUtilization    float64 `json:"cpu_utilization"`
 	MemoryUtilization float64 `json:"memory_utilization"`
 	EstimatedCost     float64 `json:"estimated_cost"`
+	Prorated          bool    `json:"prorated,omitempty"` // EstimatedCost covers only the node's age, see nodeMonthlyCost
 	InstanceType      string  `json:"instance_type"`
+	CapacityType      string  `json:"capacity_type"` // spot or on-demand; spot nodes are priced at spotPriceFactor
+	Reserved          bool    `json:"reserved"`      // billed at a configured reservation's discount
+	GPUCapacity       int64   `json:"gpu_capacity,omitempty"`
+	GPURequested      int64   `json:"gpu_requested,omitempty"` // by the node's unfinished pods
+	PhysicalGPUs      int64   `json:"physical_gpus,omitempty"`
+	GPUSharing        string  `json:"gpu_sharing,omitempty"`
+
+	// Exponential moving averages across scans, when smoothing is enabled
+	CPUUtilizationEMA    float64 `json:"cpu_utilization_ema,omitempty"`
+	MemoryUtilizationEMA float64 `json:"memory_utilization_ema,omitempty"`
 }
 
 // PodMetrics represents pod resource usage
 type PodMetrics struct {
-	Name          string  `json:"name"`
-	Namespace     string  `json:"namespace"`
-	CPUUsage      float64 `json:"cpu_usage"`
-	MemoryUsage   float64 `json:"memory_usage"`
-	CPURequest    float64 `json:"cpu_request"`
-	MemoryRequest float64 `json:"memory_request"`
-	CPULimit      float64 `json:"cpu_limit"`
-	MemoryLimit   float64 `json:"memory_limit"`
-	EstimatedCost float64 `json:"estimated_cost"`
+	Name          string            `json:"name"`
+	Namespace     string            `json:"namespace"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Workload      string            `json:"workload,omitempty"`
+	CPUUsage      float64           `json:"cpu_usage"`
+	MemoryUsage   float64           `json:"memory_usage"`
+	CPURequest    float64           `json:"cpu_request"`
+	MemoryRequest float64           `json:"memory_request"`
+	CPULimit      float64           `json:"cpu_limit"`
+	MemoryLimit   float64           `json:"memory_limit"`
+	GPURequest    int64             `json:"gpu_request,omitempty"`
+	EstimatedCost float64           `json:"estimated_cost"`
+
+	// Exponential moving averages across scans, when smoothing is enabled
+	CPUUsageEMA    float64 `json:"cpu_usage_ema,omitempty"`
+	MemoryUsageEMA float64 `json:"memory_usage_ema,omitempty"`
 }
 
 // Recommendation represents optimization suggestions
 type Recommendation struct {
-	Type        string    `json:"type"`
-	Resource    string    `json:"resource"`
-	Namespace   string    `json:"namespace"`
-	Description string    `json:"description"`
-	Impact      string    `json:"impact"`
-	Savings     float64   `json:"potential_savings"`
-	Priority    string    `json:"priority"`
-	Timestamp   time.Time `json:"timestamp"`
+	ID          string      `json:"id"`
+	Type        string      `json:"type"`
+	Resource    string      `json:"resource"`
+	Namespace   string      `json:"namespace"`
+	Description string      `json:"description"`
+	Impact      string      `json:"impact"`
+	Savings     float64     `json:"potential_savings"`
+	Priority    string      `json:"priority"`
+	Timestamp   time.Time   `json:"timestamp"`
+	FirstSeen   time.Time   `json:"first_seen"`
+	LastSeen    time.Time   `json:"last_seen"`
+	ExpiresAt   *time.Time  `json:"expires_at,omitempty"`
+	Release     string      `json:"release,omitempty"`
+	Category    string      `json:"category"`
+	ActionHint  *ActionHint `json:"action_hint,omitempty"`
+
+	// Protected recommendations are informational only; see markProtected
+	Protected bool `json:"protected,omitempty"`
+
+	// labels of the recommended object, when the analyzer had them, for
+	// matching protected selectors
+	labels map[string]string
+	// analyzer that produced the recommendation, see runAnalyzer
+	analyzer string
+
+	// Suggested container requests for rightsizing, rounded by the
+	// configured RoundingPolicy
+	SuggestedCPURequest    string `json:"suggested_cpu_request,omitempty"`
+	SuggestedMemoryRequest string `json:"suggested_memory_request,omitempty"`
+
+	// Admission previews whether the suggested change would pass the
+	// namespace's LimitRange and ResourceQuota checks
+	Admission *AdmissionPreview `json:"admission,omitempty"`
+
+	// Set when a reschedule cost is configured; Savings is then the
+	// first-month figure, GrossSavings minus MigrationCost
+	GrossSavings  float64 `json:"gross_savings,omitempty"`
+	MigrationCost float64 `json:"migration_cost,omitempty"`
+
+	// For rightsizing, the savings range between sizing for p95 and for
+	// p50 usage, once the usage history has enough samples
+	SavingsLow  float64 `json:"savings_low,omitempty"`
+	SavingsHigh float64 `json:"savings_high,omitempty"`
 }
 
 // ClusterCostSummary provides overall cost analysis
 type ClusterCostSummary struct {
-	TotalMonthlyCost    float64            `json:"total_monthly_cost"`
-	ComputeCost         float64            `json:"compute_cost"`
-	StorageCost         float64            `json:"storage_cost"`
-	WastedResources     float64            `json:"wasted_resources"`
-	PotentialSavings    float64            `json:"potential_savings"`
-	NodeCount           int                `json:"node_count"`
-	PodCount            int                `json:"pod_count"`
-	NamespaceCosts      map[string]float64 `json:"namespace_costs"`
-	RecommendationCount int                `json:"recommendation_count"`
-	LastUpdated         time.Time          `json:"last_updated"`
+	TotalMonthlyCost  float64                  `json:"total_monthly_cost"`
+	ComputeCost       float64                  `json:"compute_cost"`
+	StorageCost       float64                  `json:"storage_cost"`
+	WastedResources   float64                  `json:"wasted_resources"`
+	PotentialSavings  float64                  `json:"potential_savings"`
+	NodeCount         int                      `json:"node_count"`
+	PodCount          int                      `json:"pod_count"`
+	NamespaceCosts    map[string]float64       `json:"namespace_costs"`
+	NamespaceUsedCost map[string]float64       `json:"namespace_used_cost"`
+	NamespaceIdleCost map[string]float64       `json:"namespace_idle_cost"`
+	CostByRelease     map[string]float64       `json:"cost_by_release"`
+	LabelCosts        map[string]float64       `json:"label_costs,omitempty"`
+	NodeGroupCosts    map[string]NodeGroupCost `json:"node_group_costs,omitempty"`
+
+	// Cost increases of recommendations that trade money for performance,
+	// such as adding capacity, as a positive sum kept out of PotentialSavings
+	ProjectedCostIncrease float64 `json:"projected_cost_increase"`
+
+	// Allocatable capacity no pod requests, priced at node cost; unlike
+	// WastedResources this ignores usage
+	UnallocatedCPU    float64 `json:"unallocated_cpu"`    // cores
+	UnallocatedMemory float64 `json:"unallocated_memory"` // GB
+	UnallocatedCost   float64 `json:"unallocated_cost"`
+
+	// Cost of pods' ephemeral-storage requests and estimated network egress,
+	// both zero unless priced in the pricing file
+	EphemeralStorageCost float64 `json:"ephemeral_storage_cost"`
+	NetworkCost          float64 `json:"network_cost"`
+
+	// StorageCost split by StorageClass, for volumes that have one
+	StorageCostByClass map[string]float64 `json:"storage_cost_by_class,omitempty"`
+
+	// Packing compares the node count to an ideal packing of pod requests
+	Packing *PackingReport `json:"packing,omitempty"`
+
+	// Set while the metrics API is missing: costs come from requests, and the
+	// usage-based wasted resources and used/idle split are left at zero
+	UsageUnavailable bool `json:"usage_unavailable,omitempty"`
+
+	RecommendationCount int       `json:"recommendation_count"`
+	LastUpdated         time.Time `json:"last_updated"`
+
+	// WorkloadCosts is kept in the history for per-workload trends but left
+	// out of the summary itself; keys are namespace/kind/name
+	WorkloadCosts map[string]float64 `json:"-"`
 }
 
 // OptimizationAction represents actions that can be taken
@@ -101,43 +302,131 @@ type OptimizationAction struct {
 	Status     string                 `json:"status"`
 	CreatedAt  time.Time              `json:"created_at"`
 	ExecutedAt *time.Time             `json:"executed_at,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Protected  bool                   `json:"protected,omitempty"`
+
+	// Change records what execution did, including the state it replaced,
+	// so the action can be rolled back
+	Change       *ActionChange `json:"change,omitempty"`
+	RolledBackAt *time.Time    `json:"rolled_back_at,omitempty"`
 }
 
 func main() {
+	// LOG_LEVEL is the older, unprefixed name
+	rawLevel := os.Getenv("OPTIMKUBE_LOG_LEVEL")
+	if rawLevel == "" {
+		rawLevel = os.Getenv("LOG_LEVEL")
+	}
+	level, err := parseLogLevel(rawLevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	slog.SetDefault(newLogger(os.Stderr, level))
+
 	optimizer, err := NewCostOptimizer()
 	if err != nil {
-		log.Fatalf("Failed to initialize cost optimizer: %v", err)
+		slog.Error("Failed to initialize cost optimizer", "error", err)
+		os.Exit(1)
 	}
 
 	if optimizer.demoMode {
-		log.Println("Running in demo mode: serving synthetic Kubernetes metrics")
+		slog.Info("Running in demo mode: serving synthetic Kubernetes metrics")
+	}
+	if optimizer.readOnly {
+		slog.Info("Running in read-only mode: actions are disabled and cluster writes are refused")
+	}
+	if optimizer.dryRun {
+		slog.Info("Running in dry-run mode: actions report their changes without making them")
 	}
 
+	// Stop scanning and drain the server on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Start background monitoring
-	go optimizer.StartMonitoring()
+	go optimizer.StartMonitoring(ctx)
+	go optimizer.StartActionWorker()
+	if optimizer.exporter != nil {
+		go optimizer.StartExporter(ctx)
+	}
 
 	// Setup HTTP server
-	router := mux.NewRouter()
+	router := optimizer.newRouter()
+
+	server := &http.Server{Addr: ":8080", Handler: router}
+	server.RegisterOnShutdown(optimizer.stream.close)
+	go func() {
+		slog.Info("Starting Kubernetes Cost Optimizer", "addr", server.Addr, "cluster", optimizer.clusterName,
+			"demo_mode", optimizer.demoMode, "read_only", optimizer.readOnly, "dry_run", optimizer.dryRun,
+			"scan_interval", optimizer.scanInterval.String())
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("HTTP server failed", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	<-ctx.Done()
+	slog.Info("Shutting down")
+	close(optimizer.stopWatches)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("HTTP server shutdown", "error", err)
+	}
+}
 
-	// API endpoints
-	router.HandleFunc("/api/metrics/nodes", optimizer.handleNodeMetrics).Methods("GET")
-	router.HandleFunc("/api/metrics/pods", optimizer.handlePodMetrics).Methods("GET")
-	router.HandleFunc("/api/recommendations", optimizer.handleRecommendations).Methods("GET")
-	router.HandleFunc("/api/cost-summary", optimizer.handleCostSummary).Methods("GET")
-	router.HandleFunc("/api/optimize", optimizer.handleOptimize).Methods("POST")
-	router.HandleFunc("/api/actions", optimizer.handleActions).Methods("GET")
-	router.HandleFunc("/api/actions/{id}/execute", optimizer.handleExecuteAction).Methods("POST")
+// newRouter wires the API, metrics and health endpoints
+func (co *CostOptimizer) newRouter() *mux.Router {
+	router := mux.NewRouter()
+	if co.apiToken != "" {
+		router.Use(bearerAuth(co.apiToken))
+	}
+	router.Use(validateRouteVars)
 
-	// Health check
-	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
-	}).Methods("GET")
+	// Handlers that query the cluster share a concurrency limit; the rest
+	// serve in-memory state and are unlimited
+	expensive := newConcurrencyLimiter(co.maxInflightRequests)
 
-	log.Println("Starting Kubernetes Cost Optimizer on :8080")
-	log.Fatal(http.ListenAndServe(":8080", router))
+	// API endpoints
+	router.HandleFunc("/api/metrics/nodes", expensive.limit(co.handleNodeMetrics)).Methods("GET")
+	router.HandleFunc("/api/metrics/pods", expensive.limit(co.handlePodMetrics)).Methods("GET")
+	router.HandleFunc("/api/metrics/workloads", expensive.limit(co.handleWorkloadMetrics)).Methods("GET")
+	router.HandleFunc("/api/workloads/{namespace}/{name}/history", co.handleWorkloadHistory).Methods("GET")
+	router.HandleFunc("/api/recommendations", co.handleRecommendations).Methods("GET")
+	router.HandleFunc("/api/recommendations.csv", co.handleRecommendationsCSV).Methods("GET")
+	router.HandleFunc("/api/recommendations/{id}/manifest", co.handleRecommendationManifest).Methods("GET")
+	router.HandleFunc("/api/cost-summary", expensive.limit(co.handleCostSummary)).Methods("GET")
+	router.HandleFunc("/api/cost-summary.csv", expensive.limit(co.handleCostSummaryCSV)).Methods("GET")
+	router.HandleFunc("/api/cost-summary/history", co.handleCostHistory).Methods("GET")
+	router.HandleFunc("/api/cost-summary/trend", co.handleCostTrend).Methods("GET")
+	router.HandleFunc("/api/optimize", expensive.limit(co.handleOptimize)).Methods("POST")
+	router.HandleFunc("/api/stream", co.handleStream).Methods("GET")
+	router.HandleFunc("/api/actions", co.handleActions).Methods("GET")
+	router.HandleFunc("/api/actions/{id}", co.handleGetAction).Methods("GET")
+	router.HandleFunc("/api/actions/{id}/execute", co.mutating(co.handleExecuteAction)).Methods("POST")
+	router.HandleFunc("/api/actions/{id}/rollback", co.mutating(co.handleRollbackAction)).Methods("POST")
+	router.HandleFunc("/api/diagnostics", co.handleDiagnostics).Methods("GET")
+	router.HandleFunc("/api/clusters", co.handleClusters).Methods("GET")
+
+	// Prometheus exposition of the latest cost summary
+	router.Handle("/metrics", co.metrics.handler()).Methods("GET")
+
+	// Grafana JSON datasource over the summary history
+	co.registerGrafanaRoutes(router, "/grafana")
+
+	// Liveness and readiness checks; /health is the liveness check's old name
+	router.HandleFunc("/healthz", handleHealthz).Methods("GET")
+	router.HandleFunc("/health", handleHealthz).Methods("GET")
+	router.HandleFunc("/readyz", co.handleReadyz).Methods("GET")
+
+	return router
 }
 
+// shutdownTimeout bounds how long in-flight requests may finish on shutdown,
+// within the pod's default 30 second termination grace period
+const shutdownTimeout = 15 * time.Second
+
 func NewCostOptimizer() (*CostOptimizer, error) {
 	clusterName := os.Getenv("CLUSTER_NAME")
 	if clusterName == "" {
@@ -145,102 +434,489 @@ func NewCostOptimizer() (*CostOptimizer, error) {
 	}
 
 	demoMode := strings.EqualFold(os.Getenv("DEMO_MODE"), "true")
+	readOnly := strings.EqualFold(os.Getenv("OPTIMKUBE_READONLY"), "true")
+	apiTimeout, err := envDuration("OPTIMKUBE_API_TIMEOUT", defaultAPITimeout)
+	if err != nil {
+		return nil, err
+	}
+	if apiTimeout <= 0 {
+		return nil, fmt.Errorf("invalid OPTIMKUBE_API_TIMEOUT %v: must be positive", apiTimeout)
+	}
 
 	// Initialize Kubernetes client
 	var config *rest.Config
-	var err error
 
-	var clientset *kubernetes.Clientset
-	var metricsClient *metricsclientset.Clientset
+	// Interfaces stay nil unless a client is created, so nil checks hold
+	var clientset kubernetes.Interface
+	var metricsClient metricsclientset.Interface
 
 	if !demoMode {
-		if kubeconfig := os.Getenv("KUBECONFIG"); kubeconfig != "" {
+		if contextName := os.Getenv("OPTIMKUBE_CONTEXT"); contextName != "" {
+			// A named context is a deliberate choice of cluster, so a typo
+			// fails startup rather than falling back to demo mode
+			if config, err = kubeconfigContextConfig(os.Getenv("KUBECONFIG"), contextName); err != nil {
+				return nil, fmt.Errorf("invalid OPTIMKUBE_CONTEXT: %w", err)
+			}
+		} else if kubeconfig := os.Getenv("KUBECONFIG"); kubeconfig != "" {
 			config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
 		} else {
 			config, err = rest.InClusterConfig()
 		}
 
 		if err != nil {
-			log.Printf("Failed to create kubernetes config, falling back to demo mode: %v", err)
+			slog.Warn("Failed to create kubernetes config, falling back to demo mode", "error", err)
 			demoMode = true
 		} else {
-			clientset, err = kubernetes.NewForConfig(config)
-			if err != nil {
-				log.Printf("Failed to create kubernetes client, falling back to demo mode: %v", err)
+			config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+				return &timeoutTransport{next: rt, timeout: apiTimeout}
+			})
+			if readOnly {
+				config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+					return &readOnlyTransport{next: rt}
+				})
+			}
+			if client, err := kubernetes.NewForConfig(config); err != nil {
+				slog.Warn("Failed to create kubernetes client, falling back to demo mode", "error", err)
 				demoMode = true
+			} else {
+				clientset = client
 			}
 			if !demoMode {
-				metricsClient, err = metricsclientset.NewForConfig(config)
-				if err != nil {
-					log.Printf("Failed to create metrics client, falling back to demo mode: %v", err)
+				if client, err := metricsclientset.NewForConfig(config); err != nil {
+					slog.Warn("Failed to create metrics client, falling back to demo mode", "error", err)
 					demoMode = true
+				} else {
+					metricsClient = client
 				}
 			}
 		}
 	}
 
-	// Initialize cost calculator with sample pricing
-	costCalculator := &CostCalculator{
-		NodeCostPerHour: map[string]float64{
-			"t3.micro":   0.0104,
-			"t3.small":   0.0208,
-			"t3.medium":  0.0416,
-			"t3.large":   0.0832,
-			"t3.xlarge":  0.1664,
-			"t3.2xlarge": 0.3328,
-			"m5.large":   0.096,
-			"m5.xlarge":  0.192,
-			"m5.2xlarge": 0.384,
-			"m5.4xlarge": 0.768,
-			"c5.large":   0.085,
-			"c5.xlarge":  0.17,
-			"c5.2xlarge": 0.34,
-			"c5.4xlarge": 0.68,
-			"default":    0.1, // fallback cost
-		},
-		StorageCostPerGB: 0.10, // $0.10 per GB per month
+	if !demoMode && !strings.EqualFold(os.Getenv("OPTIMKUBE_SKIP_CLUSTER_CHECK"), "true") {
+		if err := verifySameCluster(context.Background(), clientset, metricsClient); err != nil {
+			slog.Error("Kubernetes and metrics clients disagree on the target cluster", "error", err)
+			return nil, fmt.Errorf("cluster mismatch: %w", err)
+		}
+	}
+
+	cloud := os.Getenv("OPTIMKUBE_CLOUD")
+	if cloud == "" {
+		cloud = defaultCloud
+	}
+	costCalculator, pricing, err := newCostCalculator(cloud)
+	if err != nil {
+		return nil, err
 	}
 
-	return &CostOptimizer{
+	if path := os.Getenv("OPTIMKUBE_PRICING_FILE"); path != "" {
+		if err := costCalculator.loadPricingFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if costCalculator.LoadBalancerCostPerMonth, err = envFloat("OPTIMKUBE_LB_MONTHLY_COST", defaultLoadBalancerMonthlyCost); err != nil {
+		return nil, err
+	}
+	lbConsolidationThreshold, err := envInt("OPTIMKUBE_LB_CONSOLIDATION_THRESHOLD", defaultLBConsolidationThreshold)
+	if err != nil {
+		return nil, err
+	}
+	if costCalculator.ReplicationCostFactor, err = envFloat("OPTIMKUBE_REPLICATION_COST_FACTOR", defaultReplicationCostFactor); err != nil {
+		return nil, err
+	}
+	if costCalculator.SnapshotCostFactor, err = envFloat("OPTIMKUBE_SNAPSHOT_COST_FACTOR", defaultSnapshotCostFactor); err != nil {
+		return nil, err
+	}
+	snapshotRetentionThreshold, err := envInt("OPTIMKUBE_SNAPSHOT_RETENTION_THRESHOLD", defaultSnapshotRetentionThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	optimizer := &CostOptimizer{
 		clientset:       clientset,
 		metricsClient:   metricsClient,
 		costCalculator:  costCalculator,
+		pricing:         pricing,
 		recommendations: make([]Recommendation, 0),
 		demoMode:        demoMode,
+		readOnly:        readOnly,
+		thresholds:      defaultThresholds(),
+		dryRun:          strings.EqualFold(os.Getenv("OPTIMKUBE_DRY_RUN"), "true"),
+		apiToken:        os.Getenv("OPTIMKUBE_API_TOKEN"),
 		clusterName:     clusterName,
 		now:             time.Now,
-	}, nil
+		actionQueue:     make(chan string, actionQueueSize),
+		stream:          newStreamBroker(),
+		metrics:         newPromMetrics(),
+		stopWatches:     make(chan struct{}),
+
+		lbConsolidationThreshold:   lbConsolidationThreshold,
+		snapshotRetentionThreshold: snapshotRetentionThreshold,
+		excludedNamespaces:         defaultExcludedNamespaces,
+		productionNamespaces:       []string{"*"},
+	}
+
+	if optimizer.recommendationTTL, err = envDuration("OPTIMKUBE_RECOMMENDATION_TTL", defaultRecommendationTTL); err != nil {
+		return nil, err
+	}
+	if optimizer.rightsizingMinPodAge, err = envDuration("OPTIMKUBE_RIGHTSIZING_MIN_POD_AGE", defaultRightsizingMinPodAge); err != nil {
+		return nil, err
+	}
+	if err := optimizer.loadAnalysisScope(); err != nil {
+		return nil, err
+	}
+	if optimizer.costLabel = os.Getenv("OPTIMKUBE_COST_LABEL"); optimizer.costLabel != "" {
+		if err := validateLabelKey(optimizer.costLabel); err != nil {
+			return nil, fmt.Errorf("invalid OPTIMKUBE_COST_LABEL %q: %w", optimizer.costLabel, err)
+		}
+	}
+	optimizer.replicaAggregation = defaultReplicaAggregation
+	if raw := os.Getenv("OPTIMKUBE_REPLICA_AGGREGATION"); raw != "" {
+		if !validReplicaAggregation(raw) {
+			return nil, fmt.Errorf("invalid OPTIMKUBE_REPLICA_AGGREGATION %q: expected avg, max, or p95", raw)
+		}
+		optimizer.replicaAggregation = raw
+	}
+	if optimizer.maxInflightRequests, err = envInt("OPTIMKUBE_MAX_INFLIGHT_REQUESTS", defaultMaxInflightRequests); err != nil {
+		return nil, err
+	}
+	if optimizer.reschedulePodCost, err = envFloat("OPTIMKUBE_RESCHEDULE_POD_COST", 0); err != nil {
+		return nil, err
+	}
+	if costPrecision, err = envInt("OPTIMKUBE_COST_PRECISION", defaultCostPrecision); err != nil {
+		return nil, err
+	}
+	if costPrecision < 0 {
+		return nil, fmt.Errorf("invalid OPTIMKUBE_COST_PRECISION %d: must not be negative", costPrecision)
+	}
+	if optimizer.costSpikePercent, err = envFloat("OPTIMKUBE_COST_SPIKE_PERCENT", defaultCostSpikePercent); err != nil {
+		return nil, err
+	}
+	if optimizer.costSpikeMinIncrease, err = envFloat("OPTIMKUBE_COST_SPIKE_MIN_INCREASE", defaultCostSpikeMinIncrease); err != nil {
+		return nil, err
+	}
+	cpuRounding, err := envQuantity("OPTIMKUBE_CPU_ROUNDING", defaultCPURounding)
+	if err != nil {
+		return nil, err
+	}
+	memoryRounding, err := envQuantity("OPTIMKUBE_MEMORY_ROUNDING", defaultMemoryRounding)
+	if err != nil {
+		return nil, err
+	}
+	minCPURequest, err := envQuantity("OPTIMKUBE_MIN_CPU_REQUEST", defaultMinCPURequest)
+	if err != nil {
+		return nil, err
+	}
+	minMemoryRequest, err := envQuantity("OPTIMKUBE_MIN_MEMORY_REQUEST", defaultMinMemoryRequest)
+	if err != nil {
+		return nil, err
+	}
+	headroom, err := envFloat("OPTIMKUBE_SUGGESTED_HEADROOM", defaultSuggestedHeadroom)
+	if err != nil {
+		return nil, err
+	}
+	if optimizer.rounding, err = newRoundingPolicy(cpuRounding, memoryRounding, minCPURequest, minMemoryRequest, headroom); err != nil {
+		return nil, err
+	}
+	if optimizer.terminatingThreshold, err = envDuration("OPTIMKUBE_TERMINATING_THRESHOLD", defaultTerminatingThreshold); err != nil {
+		return nil, err
+	}
+	if optimizer.cleanupTTL, err = envDuration("OPTIMKUBE_CLEANUP_TTL", defaultCleanupTTL); err != nil {
+		return nil, err
+	}
+	if optimizer.listCache.ttl, err = envDuration("OPTIMKUBE_LIST_CACHE_TTL", defaultListCacheTTL); err != nil {
+		return nil, err
+	}
+	if optimizer.listCache.ttl < 0 {
+		return nil, fmt.Errorf("invalid OPTIMKUBE_LIST_CACHE_TTL %v: must not be negative", optimizer.listCache.ttl)
+	}
+	optimizer.nodeBilling = billingMonthly
+	if billing := os.Getenv("OPTIMKUBE_NODE_BILLING"); billing != "" {
+		if err := validateBillingMode(billing); err != nil {
+			return nil, err
+		}
+		optimizer.nodeBilling = billing
+	}
+	if optimizer.imbalanceStdDev, err = envFloat("OPTIMKUBE_IMBALANCE_STDDEV", defaultImbalanceStdDev); err != nil {
+		return nil, err
+	}
+	if optimizer.imbalanceStdDev <= 0 {
+		return nil, fmt.Errorf("invalid OPTIMKUBE_IMBALANCE_STDDEV %v: must be positive", optimizer.imbalanceStdDev)
+	}
+	if optimizer.spotPriceFactor, err = envFloat("OPTIMKUBE_SPOT_PRICE_FACTOR", defaultSpotPriceFactor); err != nil {
+		return nil, err
+	}
+	if optimizer.spotPriceFactor <= 0 || optimizer.spotPriceFactor > 1 {
+		return nil, fmt.Errorf("invalid OPTIMKUBE_SPOT_PRICE_FACTOR %v: must be in (0, 1]", optimizer.spotPriceFactor)
+	}
+	if optimizer.scanInterval, err = envDuration("OPTIMKUBE_SCAN_INTERVAL", defaultScanInterval); err != nil {
+		return nil, err
+	}
+	if optimizer.scanInterval <= 0 {
+		return nil, fmt.Errorf("invalid OPTIMKUBE_SCAN_INTERVAL %v: must be positive", optimizer.scanInterval)
+	}
+	if optimizer.scanJitter, err = envFloat("OPTIMKUBE_SCAN_JITTER", defaultScanJitter); err != nil {
+		return nil, err
+	}
+	if optimizer.scanJitter < 0 || optimizer.scanJitter >= 1 {
+		return nil, fmt.Errorf("invalid OPTIMKUBE_SCAN_JITTER %v: must be in [0, 1)", optimizer.scanJitter)
+	}
+	if optimizer.maxInflightRequests < 1 {
+		return nil, fmt.Errorf("invalid OPTIMKUBE_MAX_INFLIGHT_REQUESTS %d: must be at least 1", optimizer.maxInflightRequests)
+	}
+	if os.Getenv("OPTIMKUBE_EMA_ALPHA") != "" {
+		value, err := envFloat("OPTIMKUBE_EMA_ALPHA", 0)
+		if err != nil {
+			return nil, err
+		}
+		if optimizer.nodeEMA, err = newEMATracker(value); err != nil {
+			return nil, fmt.Errorf("invalid OPTIMKUBE_EMA_ALPHA: %w", err)
+		}
+		optimizer.containerEMA, _ = newEMATracker(value)
+	}
+	optimizer.usageHistory = newUsageHistory(defaultUsageHistorySize)
+	historySize, err := envInt("OPTIMKUBE_HISTORY_SIZE", defaultHistorySize)
+	if err != nil {
+		return nil, err
+	}
+	if historySize < 1 {
+		return nil, fmt.Errorf("invalid OPTIMKUBE_HISTORY_SIZE %d: must be at least 1", historySize)
+	}
+	optimizer.history = newSummaryHistory(historySize)
+
+	if stateDir := os.Getenv("OPTIMKUBE_STATE_DIR"); stateDir != "" {
+		store, err := newFileStore(stateDir)
+		if err != nil {
+			return nil, err
+		}
+		optimizer.store = store
+	}
+
+	exporter, err := newReportExporterFromEnv(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	optimizer.exporter = exporter
+
+	logExporter, err := newOTLPLogExporterFromEnv(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if logExporter != nil {
+		optimizer.logExporter = logExporter
+	}
+
+	if webhookURL := os.Getenv("OPTIMKUBE_WEBHOOK_URL"); webhookURL != "" {
+		optimizer.notifier = newWebhookNotifier(webhookURL)
+	}
+
+	optimizer.freezeFile = os.Getenv("OPTIMKUBE_CHANGE_FREEZE_FILE")
+
+	if prometheusURL := os.Getenv("OPTIMKUBE_PROMETHEUS_URL"); prometheusURL != "" {
+		optimizer.metricsSource = newPrometheusSource(prometheusURL)
+	}
+
+	if configFile := os.Getenv("OPTIMKUBE_CONFIG_FILE"); configFile != "" {
+		fileConfig, err := loadFileConfig(configFile)
+		if err != nil {
+			return nil, err
+		}
+		optimizer.budgets = fileConfig.Budgets
+		optimizer.throughputQueries = fileConfig.Throughput
+		optimizer.quietWindows = fileConfig.QuietHours
+		if fileConfig.NodeCostExpression != "" {
+			if optimizer.costModel, err = compileCostModel(fileConfig.NodeCostExpression); err != nil {
+				return nil, fmt.Errorf("config file %s: %w", configFile, err)
+			}
+		}
+		if fileConfig.ExcludedNamespaces != nil {
+			optimizer.excludedNamespaces = *fileConfig.ExcludedNamespaces
+		}
+		if fileConfig.ProductionNamespaces != nil {
+			optimizer.productionNamespaces = fileConfig.ProductionNamespaces
+		}
+		optimizer.protectedSelectors = fileConfig.Protected
+		optimizer.suppressionRules = fileConfig.Suppress
+		optimizer.reservations = fileConfig.Reservations
+		optimizer.thresholds = fileConfig.Thresholds
+	}
+	if err := optimizer.thresholds.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	if len(optimizer.throughputQueries) > 0 && optimizer.metricsSource == nil {
+		return nil, fmt.Errorf("throughput queries in %s require OPTIMKUBE_PROMETHEUS_URL", os.Getenv("OPTIMKUBE_CONFIG_FILE"))
+	}
+
+	if err := optimizer.loadRecommendations(); err != nil {
+		return nil, fmt.Errorf("load recommendations: %w", err)
+	}
+	if err := optimizer.loadActions(); err != nil {
+		return nil, fmt.Errorf("load actions: %w", err)
+	}
+	if err := optimizer.loadHistory(); err != nil {
+		return nil, fmt.Errorf("load cost summary history: %w", err)
+	}
+	if !demoMode {
+		optimizer.checkMetricsAPI(context.Background())
+	}
+
+	// Started last so nothing is left watching if construction fails
+	if ref := os.Getenv("OPTIMKUBE_PRICING_CONFIGMAP"); ref != "" && !demoMode {
+		key := os.Getenv("OPTIMKUBE_PRICING_CONFIGMAP_KEY")
+		if key == "" {
+			key = defaultPricingConfigMapKey
+		}
+		optimizer.watchPricingConfigMap(clientset, ref, key, optimizer.stopWatches)
+	}
+	if strings.EqualFold(os.Getenv("OPTIMKUBE_INCREMENTAL_ANALYSIS"), "true") && !demoMode {
+		optimizer.watchIncremental(clientset, optimizer.stopWatches)
+	}
+
+	return optimizer, nil
 }
 
-func (co *CostOptimizer) StartMonitoring() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
+// defaultScanInterval is the average time between scans
+const defaultScanInterval = 5 * time.Minute
 
+// defaultScanJitter spreads scans ±20% around the interval so instances started
+// together don't hit the API server in lockstep
+const defaultScanJitter = 0.2
+
+// StartMonitoring scans once immediately and then every scanInterval, give or
+// take the jitter, until ctx is cancelled
+func (co *CostOptimizer) StartMonitoring(ctx context.Context) {
 	for {
-		log.Println("Running cost analysis...")
-		co.analyzeAndGenerateRecommendations()
-		<-ticker.C
+		slog.Info("Running cost analysis")
+		co.analyzeAndGenerateRecommendations(ctx)
+
+		timer := time.NewTimer(jitteredInterval(co.scanInterval, co.scanJitter, rand.Float64()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			slog.Info("Monitor loop stopped")
+			return
+		case <-timer.C:
+		}
 	}
 }
 
-func (co *CostOptimizer) analyzeAndGenerateRecommendations() {
-	ctx := context.Background()
+// jitteredInterval scales interval by a factor uniform in [1-jitter, 1+jitter]
+// given r uniform in [0, 1), which keeps the average at interval
+func jitteredInterval(interval time.Duration, jitter, r float64) time.Duration {
+	return time.Duration(float64(interval) * (1 + jitter*(2*r-1)))
+}
+
+// analyzeAndGenerateRecommendations runs every analyzer and replaces the
+// recommendations. Cancelling ctx fails the API calls still outstanding, and
+// the scan completes with whatever the analyzers gathered. A scan started
+// while another runs waits for it to finish.
+func (co *CostOptimizer) analyzeAndGenerateRecommendations(ctx context.Context) {
+	co.scanMu.Lock()
+	defer co.scanMu.Unlock()
+
+	started := time.Now()
 	recommendations := make([]Recommendation, 0)
 
 	// Analyze nodes
-	nodeRecommendations := co.analyzeNodes(ctx)
+	nodeRecommendations := co.runAnalyzer(ctx, "nodes", co.analyzeNodes)
 	recommendations = append(recommendations, nodeRecommendations...)
 
+	// Repack pod requests to find removable nodes
+	consolidationRecommendations := co.runAnalyzer(ctx, "consolidation", co.analyzeConsolidation)
+	recommendations = append(recommendations, consolidationRecommendations...)
+
 	// Analyze pods
-	podRecommendations := co.analyzePods(ctx)
+	podRecommendations := co.runAnalyzer(ctx, "pods", co.analyzePods)
 	recommendations = append(recommendations, podRecommendations...)
 
 	// Analyze deployments
-	deploymentRecommendations := co.analyzeDeployments(ctx)
+	deploymentRecommendations := co.runAnalyzer(ctx, "deployments", co.analyzeDeployments)
 	recommendations = append(recommendations, deploymentRecommendations...)
 
+	// Analyze StatefulSets and DaemonSets
+	statefulSetRecommendations := co.runAnalyzer(ctx, "statefulsets", co.analyzeStatefulSets)
+	recommendations = append(recommendations, statefulSetRecommendations...)
+	daemonSetRecommendations := co.runAnalyzer(ctx, "daemonsets", co.analyzeDaemonSets)
+	recommendations = append(recommendations, daemonSetRecommendations...)
+
+	// Analyze LimitRange defaults
+	limitRangeRecommendations := co.runAnalyzer(ctx, "limitranges", co.analyzeLimitRanges)
+	recommendations = append(recommendations, limitRangeRecommendations...)
+
+	// Analyze spot migration opportunities
+	spotRecommendations := co.runAnalyzer(ctx, "spot", co.analyzeSpotMigration)
+	recommendations = append(recommendations, spotRecommendations...)
+
+	// Analyze GPU nodes without GPU workloads
+	gpuRecommendations := co.runAnalyzer(ctx, "gpu", co.analyzeIdleGPUs)
+	recommendations = append(recommendations, gpuRecommendations...)
+
+	// Analyze LoadBalancer services
+	loadBalancerRecommendations := co.runAnalyzer(ctx, "loadbalancers", co.analyzeLoadBalancers)
+	recommendations = append(recommendations, loadBalancerRecommendations...)
+
+	// Analyze pods stuck terminating
+	terminatingRecommendations := co.runAnalyzer(ctx, "terminating", co.analyzeStuckTerminating)
+	recommendations = append(recommendations, terminatingRecommendations...)
+
+	// Analyze finished and crashlooping workloads
+	cleanupRecommendations := co.runAnalyzer(ctx, "cleanup", co.analyzeCleanup)
+	recommendations = append(recommendations, cleanupRecommendations...)
+
+	// Analyze Ingress backends
+	ingressRecommendations := co.runAnalyzer(ctx, "ingress", co.analyzeIngressBackends)
+	recommendations = append(recommendations, ingressRecommendations...)
+
+	// Analyze HPA targets against request sizing
+	hpaRecommendations := co.runAnalyzer(ctx, "hpa", co.analyzeHPATargets)
+	recommendations = append(recommendations, hpaRecommendations...)
+
+	// Analyze BestEffort pods
+	bestEffortRecommendations := co.runAnalyzer(ctx, "besteffort", co.analyzeBestEffortPods)
+	recommendations = append(recommendations, bestEffortRecommendations...)
+
+	// Analyze snapshot retention
+	snapshotRecommendations := co.runAnalyzer(ctx, "storage", co.analyzeSnapshotRetention)
+	recommendations = append(recommendations, snapshotRecommendations...)
+
+	// Analyze emptyDir reservations
+	emptyDirRecommendations := co.runAnalyzer(ctx, "emptydir", co.analyzeEmptyDirReservations)
+	recommendations = append(recommendations, emptyDirRecommendations...)
+
+	// Analyze disruption budget coverage
+	pdbRecommendations := co.runAnalyzer(ctx, "pdb", co.analyzeMissingPDBs)
+	recommendations = append(recommendations, pdbRecommendations...)
+
+	// Detect cost spikes since the previous scan
+	spikeRecommendations := co.runAnalyzer(ctx, "spikes", co.detectCostSpikes)
+	recommendations = append(recommendations, spikeRecommendations...)
+
+	// Check cost budgets
+	budgetRecommendations := co.runAnalyzer(ctx, "budgets", co.checkBudgets)
+	recommendations = append(recommendations, budgetRecommendations...)
+
+	recommendations = co.dropExcludedNamespaces(recommendations)
+	recommendations, suppressed := co.suppressRecommendations(recommendations)
+	co.recordSuppressed(suppressed)
+	recommendations = dedupRecommendations(recommendations)
+	co.markProtected(recommendations)
+	stampIDs(recommendations)
+	now := co.now()
+	co.stampExpiry(recommendations, now)
+	co.recommendationsMu.Lock()
+	stampSeen(recommendations, co.recommendations, now)
 	co.recommendations = recommendations
-	log.Printf("Generated %d recommendations", len(recommendations))
+	co.recommendationsMu.Unlock()
+	co.saveRecommendations(recommendations)
+	co.emitNewRecommendations(ctx, recommendations)
+	co.notifyNewRecommendations(ctx, recommendations)
+
+	summary := co.generateCostSummary(ctx)
+	co.recordSummary(summary)
+	slog.Info("Scan complete", "recommendations", len(recommendations), "nodes", summary.NodeCount,
+		"pods", summary.PodCount, "duration", time.Since(started).String())
+	co.stream.publish(ScanEvent{Summary: summary, RecommendationCount: summary.RecommendationCount})
 }
 
 func (co *CostOptimizer) analyzeNodes(ctx context.Context) []Recommendation {
@@ -250,18 +926,27 @@ func (co *CostOptimizer) analyzeNodes(ctx context.Context) []Recommendation {
 		return co.demoNodeRecommendations()
 	}
 
-	nodes, err := co.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if co.analyzerDisabled("nodes") {
+		return recommendations
+	}
+
+	nodes, err := co.listNodes(ctx)
 	if err != nil {
-		log.Printf("Failed to list nodes: %v", err)
+		co.analyzerListFailed("nodes", "nodes", err)
 		return recommendations
 	}
 
-	nodeMetrics, err := co.metricsClient.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
+	nodeMetrics, err := co.listNodeMetrics(ctx)
 	if err != nil {
-		log.Printf("Failed to get node metrics: %v", err)
+		warnUsageFailed("Failed to get node metrics", err)
 		return recommendations
 	}
 
+	groups := make(map[string]*nodeGroupUsage)
+	podsOnNode := co.podsPerNode(ctx)
+	snapshot := co.loadSchedulingSnapshot(ctx, nodes.Items)
+	blocked := make(map[string][]string)
+
 	for _, node := range nodes.Items {
 		// Find corresponding metrics
 		var metrics *metricsv1beta1.NodeMetrics
@@ -285,24 +970,47 @@ func (co *CostOptimizer) analyzeNodes(ctx context.Context) []Recommendation {
 
 		cpuUtil := float64(cpuUsage.MilliValue()) / float64(cpuCapacity.MilliValue()) * 100
 		memoryUtil := float64(memoryUsage.Value()) / float64(memoryCapacity.Value()) * 100
+		cpuUtil, memoryUtil = co.nodeEMA.observe(node.Name, cpuUtil, memoryUtil)
+
+		// Nodes in a node group are sized as a unit by nodeGroupRecommendations
+		group := nodeGroupName(&node)
+		if group != "" {
+			if groups[group] == nil {
+				groups[group] = &nodeGroupUsage{Name: group}
+			}
+			_, hourlyCost := co.effectiveNodeHourlyCost(&node)
+			groups[group].add(node.Name, node.Status.Capacity, metrics.Usage, hourlyCost, podsOnNode[node.Name])
+			groups[group].Utilization = append(groups[group].Utilization, math.Max(cpuUtil, memoryUtil))
+		}
 
 		// Underutilized node recommendation
-		if cpuUtil < 20 && memoryUtil < 30 {
-			recommendations = append(recommendations, Recommendation{
-				Type:        "node_optimization",
-				Resource:    node.Name,
-				Description: fmt.Sprintf("Node %s is underutilized (CPU: %.1f%%, Memory: %.1f%%)", node.Name, cpuUtil, memoryUtil),
-				Impact:      "Consider consolidating workloads or downsizing",
-				Savings:     co.calculateNodeCost(node.Name, "") * 24 * 30 * 0.7, // 70% potential savings
-				Priority:    "medium",
-				Timestamp:   time.Now(),
-			})
+		if group == "" && cpuUtil < co.thresholds.NodeUnderutilizedCPU && memoryUtil < co.thresholds.NodeUnderutilizedMemory {
+			// Only suggest draining a node whose pods fit on the others. The
+			// savings of removing nodes come from analyzeConsolidation, which
+			// checks that the removed nodes' pods fit together, so this finding
+			// doesn't claim any of its own.
+			if unplaced := snapshot.simulateDrain([]string{node.Name}); len(unplaced) > 0 {
+				slog.Info("Not recommending a drain: pods can't be placed elsewhere", "node", node.Name, "unplaced_pods", len(unplaced))
+				blocked["node/"+node.Name] = unplaced
+			} else {
+				recommendations = append(recommendations, Recommendation{
+					Type:        "node_optimization",
+					Category:    CategoryScale,
+					Resource:    node.Name,
+					Description: fmt.Sprintf("Node %s is underutilized (CPU: %.1f%%, Memory: %.1f%%)", node.Name, cpuUtil, memoryUtil),
+					Impact:      "Consider consolidating workloads or downsizing",
+					ActionHint:  &ActionHint{Verb: "drain", Target: hintTarget("node", "", node.Name)},
+					Priority:    "medium",
+					Timestamp:   time.Now(),
+				})
+			}
 		}
 
 		// Over-provisioned node recommendation
-		if cpuUtil > 90 || memoryUtil > 90 {
+		if cpuUtil > co.thresholds.NodeOverutilized || memoryUtil > co.thresholds.NodeOverutilized {
 			recommendations = append(recommendations, Recommendation{
 				Type:        "node_scaling",
+				Category:    CategoryScale,
 				Resource:    node.Name,
 				Description: fmt.Sprintf("Node %s is overutilized (CPU: %.1f%%, Memory: %.1f%%)", node.Name, cpuUtil, memoryUtil),
 				Impact:      "Consider scaling up or adding more nodes",
@@ -313,6 +1021,11 @@ func (co *CostOptimizer) analyzeNodes(ctx context.Context) []Recommendation {
 		}
 	}
 
+	recommendations = append(recommendations, co.nodeGroupRecommendations(groups, snapshot, blocked)...)
+	recommendations = append(recommendations, co.imbalanceRecommendations(groups)...)
+	co.recordBlockedConsolidations(blocked)
+	co.nodeEMA.sweep()
+
 	return recommendations
 }
 
@@ -323,22 +1036,45 @@ func (co *CostOptimizer) analyzePods(ctx context.Context) []Recommendation {
 		return co.demoPodRecommendations()
 	}
 
-	pods, err := co.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if co.analyzerDisabled("pods") {
+		return recommendations
+	}
+
+	pods, err := co.listPods(ctx, "")
 	if err != nil {
-		log.Printf("Failed to list pods: %v", err)
+		co.analyzerListFailed("pods", "pods", err)
 		return recommendations
 	}
 
-	podMetrics, err := co.metricsClient.MetricsV1beta1().PodMetricses("").List(ctx, metav1.ListOptions{})
+	podMetrics, err := co.listPodMetrics(ctx, "")
 	if err != nil {
-		log.Printf("Failed to get pod metrics: %v", err)
+		warnUsageFailed("Failed to get pod metrics", err)
 		return recommendations
 	}
 
+	policies := co.loadNamespacePolicies(ctx)
+
+	// Freed requests are priced at the rate of the node the pod runs on
+	var allocations map[string]nodeAllocation
+	if nodes, err := co.listNodes(ctx); err != nil {
+		slog.Warn("Failed to list nodes for rightsizing savings", "error", err)
+	} else {
+		allocations = co.nodeAllocations(nodes.Items)
+	}
+
 	for _, pod := range pods.Items {
 		if pod.Status.Phase != corev1.PodRunning {
 			continue
 		}
+		price := func(cpu, memory resource.Quantity) float64 {
+			return co.requestMonthlyCost(allocations, pod.Spec.NodeName, cpu, memory)
+		}
+
+		// Usage right after start, and for Jobs that exit in seconds, says
+		// nothing about what the pod needs
+		if co.tooYoungForRightsizing(&pod) {
+			continue
+		}
 
 		// Find corresponding metrics
 		var metrics *metricsv1beta1.PodMetrics
@@ -353,29 +1089,64 @@ func (co *CostOptimizer) analyzePods(ctx context.Context) []Recommendation {
 			continue
 		}
 
+		// metrics-server doesn't list containers in spec order, so they're
+		// paired by name
+		containerUsage := make(map[string]metricsv1beta1.ContainerMetrics, len(metrics.Containers))
+		for _, containerMetrics := range metrics.Containers {
+			containerUsage[containerMetrics.Name] = containerMetrics
+		}
+
 		// Analyze resource requests vs usage
-		for i, container := range pod.Spec.Containers {
-			if i >= len(metrics.Containers) {
+		for _, container := range pod.Spec.Containers {
+			containerMetrics, ok := containerUsage[container.Name]
+			if !ok {
 				continue
 			}
 
-			containerMetrics := metrics.Containers[i]
+			// Judge usage by its moving average when smoothing is enabled
+			usageKey := containerEMAKey(pod.Namespace, pod.Name, containerMetrics.Name)
+			rawCPU, rawMemory := float64(containerMetrics.Usage.Cpu().MilliValue()), float64(containerMetrics.Usage.Memory().Value())
+			smoothedCPU, smoothedMemory := co.containerEMA.observe(usageKey, rawCPU, rawMemory)
+			co.usageHistory.observe(usageKey, rawCPU, rawMemory)
+
+			// DaemonSets are rightsized as a whole by analyzeDaemonSets
+			if ownedByDaemonSet(&pod) {
+				continue
+			}
 
 			// Check CPU over-provisioning
 			if container.Resources.Requests != nil {
 				cpuRequest := container.Resources.Requests[corev1.ResourceCPU]
-				cpuUsage := containerMetrics.Usage[corev1.ResourceCPU]
+				cpuUsage := resource.NewMilliQuantity(int64(smoothedCPU), resource.DecimalSI)
 
-				if cpuRequest.MilliValue() > 0 && cpuUsage.MilliValue() < cpuRequest.MilliValue()/2 {
+				suggested, clamped := co.rounding.cpuRequest(smoothedCPU)
+				if co.thresholds.overProvisioned(cpuUsage.MilliValue(), cpuRequest.MilliValue()) && suggested.Cmp(cpuRequest) < 0 {
+					excess := cpuRequest.DeepCopy()
+					excess.Sub(*suggested)
+					low, high, _ := co.rightsizingSavingsBand(usageKey, corev1.ResourceCPU, cpuRequest, price)
 					recommendations = append(recommendations, Recommendation{
 						Type:        "resource_rightsizing",
+						Category:    CategoryRightsize,
 						Resource:    fmt.Sprintf("%s/%s", pod.Namespace, pod.Name),
 						Namespace:   pod.Namespace,
-						Description: fmt.Sprintf("Container %s is over-provisioned for CPU (request: %dm, usage: %dm)", container.Name, cpuRequest.MilliValue(), cpuUsage.MilliValue()),
-						Impact:      "Reduce CPU request to optimize resource allocation",
-						Savings:     15.0, // Estimated monthly savings
-						Priority:    "low",
-						Timestamp:   time.Now(),
+						Release:     helmRelease(pod.Labels),
+						labels:      pod.Labels,
+						Description: fmt.Sprintf("Container %s is over-provisioned for CPU (request: %dm, usage: %dm, suggested: %s)", container.Name, cpuRequest.MilliValue(), cpuUsage.MilliValue(), suggested.String()),
+						Impact:      fmt.Sprintf("Reduce CPU request to %s%s to optimize resource allocation", suggested.String(), clampNote(clamped, suggested)),
+						ActionHint: &ActionHint{
+							Verb:         "patch",
+							Target:       hintTarget("pod", pod.Namespace, pod.Name),
+							Field:        containerRequestField(container.Name, corev1.ResourceCPU),
+							CurrentValue: fmt.Sprintf("%dm", cpuRequest.MilliValue()),
+							NewValue:     suggested.String(),
+						},
+						SuggestedCPURequest: suggested.String(),
+						Admission:           policies.previewRequestChange(&pod, container, corev1.ResourceCPU, *suggested),
+						Savings:             price(excess, resource.Quantity{}),
+						SavingsLow:          low,
+						SavingsHigh:         high,
+						Priority:            "low",
+						Timestamp:           time.Now(),
 					})
 				}
 			}
@@ -383,23 +1154,43 @@ func (co *CostOptimizer) analyzePods(ctx context.Context) []Recommendation {
 			// Check memory over-provisioning
 			if container.Resources.Requests != nil {
 				memRequest := container.Resources.Requests[corev1.ResourceMemory]
-				memUsage := containerMetrics.Usage[corev1.ResourceMemory]
+				memUsage := resource.NewQuantity(int64(smoothedMemory), resource.BinarySI)
 
-				if memRequest.Value() > 0 && memUsage.Value() < memRequest.Value()/2 {
+				suggested, clamped := co.rounding.memoryRequest(smoothedMemory)
+				if co.thresholds.overProvisioned(memUsage.Value(), memRequest.Value()) && suggested.Cmp(memRequest) < 0 {
+					excess := memRequest.DeepCopy()
+					excess.Sub(*suggested)
+					low, high, _ := co.rightsizingSavingsBand(usageKey, corev1.ResourceMemory, memRequest, price)
 					recommendations = append(recommendations, Recommendation{
 						Type:        "resource_rightsizing",
+						Category:    CategoryRightsize,
 						Resource:    fmt.Sprintf("%s/%s", pod.Namespace, pod.Name),
 						Namespace:   pod.Namespace,
-						Description: fmt.Sprintf("Container %s is over-provisioned for memory (request: %s, usage: %s)", container.Name, memRequest.String(), memUsage.String()),
-						Impact:      "Reduce memory request to optimize resource allocation",
-						Savings:     10.0, // Estimated monthly savings
-						Priority:    "low",
-						Timestamp:   time.Now(),
+						Release:     helmRelease(pod.Labels),
+						labels:      pod.Labels,
+						Description: fmt.Sprintf("Container %s is over-provisioned for memory (request: %s, usage: %s, suggested: %s)", container.Name, memRequest.String(), memUsage.String(), suggested.String()),
+						Impact:      fmt.Sprintf("Reduce memory request to %s%s to optimize resource allocation", suggested.String(), clampNote(clamped, suggested)),
+						ActionHint: &ActionHint{
+							Verb:         "patch",
+							Target:       hintTarget("pod", pod.Namespace, pod.Name),
+							Field:        containerRequestField(container.Name, corev1.ResourceMemory),
+							CurrentValue: memRequest.String(),
+							NewValue:     suggested.String(),
+						},
+						SuggestedMemoryRequest: suggested.String(),
+						Admission:              policies.previewRequestChange(&pod, container, corev1.ResourceMemory, *suggested),
+						Savings:                price(resource.Quantity{}, excess),
+						SavingsLow:             low,
+						SavingsHigh:            high,
+						Priority:               "low",
+						Timestamp:              time.Now(),
 					})
 				}
 			}
 		}
 	}
+	co.containerEMA.sweep()
+	co.usageHistory.sweep()
 
 	return recommendations
 }
@@ -411,144 +1202,174 @@ func (co *CostOptimizer) analyzeDeployments(ctx context.Context) []Recommendatio
 		return co.demoDeploymentRecommendations()
 	}
 
-	deployments, err := co.clientset.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+	if co.analyzerDisabled("deployments") {
+		return recommendations
+	}
+
+	deployments, err := co.clientset.AppsV1().Deployments("").List(ctx, co.workloadListOptions())
 	if err != nil {
-		log.Printf("Failed to list deployments: %v", err)
+		co.analyzerListFailed("deployments", "deployments", err)
 		return recommendations
 	}
+	deployments.Items = inScope(co, deployments.Items)
 
-	for _, deployment := range deployments.Items {
-		// Check for low replica utilization during off-hours
-		if deployment.Status.Replicas > 1 {
-			recommendations = append(recommendations, Recommendation{
-				Type:        "horizontal_scaling",
-				Resource:    fmt.Sprintf("%s/%s", deployment.Namespace, deployment.Name),
-				Namespace:   deployment.Namespace,
-				Description: fmt.Sprintf("Deployment %s could benefit from auto-scaling based on metrics", deployment.Name),
-				Impact:      "Implement HPA to scale based on CPU/memory usage",
-				Savings:     25.0, // Estimated monthly savings
-				Priority:    "medium",
-				Timestamp:   time.Now(),
-			})
-		}
+	pdbs := co.listPDBs(ctx, "")
+	for i := range deployments.Items {
+		recommendations = append(recommendations, co.deploymentRecommendations(&deployments.Items[i], pdbs)...)
+	}
 
-		// Check for missing resource requests/limits
-		hasResources := false
-		for _, container := range deployment.Spec.Template.Spec.Containers {
-			if container.Resources.Requests != nil || container.Resources.Limits != nil {
-				hasResources = true
-				break
-			}
-		}
+	return recommendations
+}
 
-		if !hasResources {
-			recommendations = append(recommendations, Recommendation{
-				Type:        "resource_governance",
-				Resource:    fmt.Sprintf("%s/%s", deployment.Namespace, deployment.Name),
-				Namespace:   deployment.Namespace,
-				Description: fmt.Sprintf("Deployment %s lacks resource requests/limits", deployment.Name),
-				Impact:      "Add resource requests and limits for better scheduling and cost control",
-				Savings:     20.0, // Estimated monthly savings through better resource management
-				Priority:    "medium",
-				Timestamp:   time.Now(),
-			})
+// deploymentRecommendations evaluates a single Deployment against the given
+// disruption budgets, so informer events can refresh its findings without
+// rescanning every Deployment
+func (co *CostOptimizer) deploymentRecommendations(deployment *appsv1.Deployment, pdbs []policyv1.PodDisruptionBudget) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	// Check for low replica utilization during off-hours. An HPA may scale
+	// down to a single replica, unless a disruption budget needs more.
+	replicas := deployment.Status.Replicas
+	floor, pdbName := pdbReplicaFloor(coveringPDBs(deployment.Namespace, deployment.Spec.Template.Labels, pdbs), replicas)
+	if replicas > 1 && floor < replicas {
+		rec := Recommendation{
+			Type:        "horizontal_scaling",
+			Category:    CategoryScale,
+			Resource:    fmt.Sprintf("%s/%s", deployment.Namespace, deployment.Name),
+			Namespace:   deployment.Namespace,
+			Release:     helmRelease(deployment.Labels),
+			labels:      deployment.Labels,
+			Description: fmt.Sprintf("Deployment %s could benefit from auto-scaling based on metrics", deployment.Name),
+			Impact:      "Implement HPA to scale based on CPU/memory usage",
+			ActionHint:  &ActionHint{Verb: "create", Target: hintTarget("horizontalpodautoscaler", deployment.Namespace, deployment.Name)},
+			Savings:     25.0, // Estimated monthly savings
+			Priority:    "medium",
+			Timestamp:   time.Now(),
+		}
+		if floor > 1 {
+			// Only the replicas above the budget's floor can be scaled away
+			rec.Impact = fmt.Sprintf("Implement HPA to scale based on CPU/memory usage, with minReplicas of at least %d: PodDisruptionBudget %s requires %d available", floor, pdbName, floor)
+			rec.ActionHint.Field = "spec.minReplicas"
+			rec.ActionHint.NewValue = strconv.Itoa(int(floor))
+			rec.Savings *= float64(replicas-floor) / float64(replicas-1)
+			rec.Priority = "low"
 		}
+		recommendations = append(recommendations, rec)
+	}
+
+	// Check for missing resource requests/limits
+	if rec := missingResourcesRecommendation("Deployment", deployment.Namespace, deployment.Name, deployment.Labels, &deployment.Spec.Template.Spec); rec != nil {
+		recommendations = append(recommendations, *rec)
 	}
 
 	return recommendations
 }
 
-func (co *CostOptimizer) calculateNodeCost(nodeName, instanceType string) float64 {
-	if instanceType == "" {
-		// Try to extract instance type from node name or use default
-		for nodeType, cost := range co.costCalculator.NodeCostPerHour {
-			if strings.Contains(nodeName, nodeType) {
-				return cost
-			}
-		}
-		return co.costCalculator.NodeCostPerHour["default"]
-	}
+// containerRequestField is the ActionHint field for one container's request
+func containerRequestField(container string, name corev1.ResourceName) string {
+	return fmt.Sprintf("spec.containers[name=%s].resources.requests.%s", container, name)
+}
 
-	if cost, exists := co.costCalculator.NodeCostPerHour[instanceType]; exists {
+// nodeHourlyCost resolves the full hourly price of a node, including its GPUs
+func (co *CostOptimizer) nodeHourlyCost(node *corev1.Node) float64 {
+	instanceType := co.pricing.instanceType(node)
+	if cost, ok := co.modelNodeCost(node, instanceType); ok {
 		return cost
 	}
-	return co.costCalculator.NodeCostPerHour["default"]
+	return co.pricing.NodeHourlyCost(node) + co.costCalculator.gpuHourlyCost(nodeGPUInfo(node))
 }
 
 // HTTP Handlers
 func (co *CostOptimizer) handleNodeMetrics(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
-	nodeMetrics := co.getNodeMetrics(ctx)
+	nodeMetrics := co.getNodeMetrics(r.Context())
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(nodeMetrics)
 }
 
 func (co *CostOptimizer) handlePodMetrics(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
-	podMetrics := co.getPodMetrics(ctx)
+	namespace := r.URL.Query().Get("namespace")
+	if namespace != "" {
+		if err := validateNamespace(namespace); err != nil {
+			http.Error(w, fmt.Sprintf("invalid namespace: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	podMetrics := co.getPodMetrics(r.Context(), namespace)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(podMetrics)
 }
 
 func (co *CostOptimizer) handleRecommendations(w http.ResponseWriter, r *http.Request) {
+	query, err := parseRecommendationQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(co.recommendations)
+	json.NewEncoder(w).Encode(query.apply(co.activeRecommendations()))
 }
 
 func (co *CostOptimizer) handleCostSummary(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
-	summary := co.generateCostSummary(ctx)
+	summary, ok := co.requestedCostSummary(w, r)
+	if !ok {
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(summary)
 }
 
-func (co *CostOptimizer) handleOptimize(w http.ResponseWriter, r *http.Request) {
-	// Trigger immediate analysis
-	go co.analyzeAndGenerateRecommendations()
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"status":  "optimization_triggered",
-		"message": "Cost analysis has been triggered",
-	})
+// requestedCostSummary returns the live cost summary, or with ?at= the
+// recorded one. On invalid parameters or a miss it writes the error response
+// and returns false.
+func (co *CostOptimizer) requestedCostSummary(w http.ResponseWriter, r *http.Request) (ClusterCostSummary, bool) {
+	if at := r.URL.Query().Get("at"); at != "" {
+		return co.historicalCostSummary(w, r, at)
+	}
+	return co.generateCostSummary(r.Context()), true
 }
 
-func (co *CostOptimizer) handleActions(w http.ResponseWriter, r *http.Request) {
-	// Return available optimization actions
-	actions := []OptimizationAction{
-		{
-			ID:        "1",
-			Type:      "scale_down",
-			Resource:  "default/nginx-deployment",
-			Namespace: "default",
-			Action:    "Scale deployment to 1 replica",
-			Parameters: map[string]interface{}{
-				"replicas": 1,
-			},
-			Status:    "pending",
-			CreatedAt: time.Now(),
-		},
+// historicalCostSummary returns the recorded summary nearest to the requested
+// time, since live metrics can't be fetched retroactively.
+func (co *CostOptimizer) historicalCostSummary(w http.ResponseWriter, r *http.Request, at string) (ClusterCostSummary, bool) {
+	timestamp, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid at %q: expected an RFC3339 timestamp", at), http.StatusBadRequest)
+		return ClusterCostSummary{}, false
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(actions)
-}
+	tolerance := defaultHistoryTolerance
+	if raw := r.URL.Query().Get("tolerance"); raw != "" {
+		tolerance, err = time.ParseDuration(raw)
+		if err != nil || tolerance < 0 {
+			http.Error(w, fmt.Sprintf("invalid tolerance %q: expected a duration such as 10m", raw), http.StatusBadRequest)
+			return ClusterCostSummary{}, false
+		}
+	}
 
-func (co *CostOptimizer) handleExecuteAction(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	actionID := vars["id"]
+	summary, ok := co.history.nearest(timestamp, tolerance)
+	if !ok {
+		http.Error(w, fmt.Sprintf("no cost summary recorded within %s of %s", tolerance, timestamp.Format(time.RFC3339)), http.StatusNotFound)
+		return ClusterCostSummary{}, false
+	}
+	return summary, true
+}
 
-	// In a real implementation, this would execute the optimization action
-	log.Printf("Executing optimization action: %s", actionID)
+func (co *CostOptimizer) handleOptimize(w http.ResponseWriter, r *http.Request) {
+	// Trigger immediate analysis. It outlives the request, so it doesn't
+	// inherit the request's cancellation. An explicit request should see the
+	// cluster as it is now, not as it was up to a cache TTL ago.
+	co.listCache.invalidate(co.now())
+	go co.analyzeAndGenerateRecommendations(context.WithoutCancel(r.Context()))
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
-		"status":    "executed",
-		"action_id": actionID,
-		"message":   "Optimization action executed successfully",
+		"status":  "optimization_triggered",
+		"message": "Cost analysis has been triggered",
 	})
 }
 
@@ -559,16 +1380,38 @@ func (co *CostOptimizer) getNodeMetrics(ctx context.Context) []NodeMetrics {
 		return co.demoNodeMetrics()
 	}
 
-	nodes, err := co.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	nodes, err := co.listNodes(ctx)
 	if err != nil {
-		log.Printf("Failed to list nodes: %v", err)
+		slog.Warn("Failed to list nodes", "error", err)
 		return metrics
 	}
 
-	nodeMetricsList, err := co.metricsClient.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
+	// Without the metrics API nodes are still reported and priced, with
+	// zero usage
+	nodeMetricsList, err := co.listNodeMetrics(ctx)
+	usageKnown := err == nil
 	if err != nil {
-		log.Printf("Failed to get node metrics: %v", err)
-		return metrics
+		if !metricsAPIMissing(err) {
+			slog.Warn("Failed to get node metrics", "error", err)
+			return metrics
+		}
+		nodeMetricsList = &metricsv1beta1.NodeMetricsList{}
+	}
+
+	reserved := co.reservedNodes(nodes.Items)
+
+	// Pods are only listed when there are GPUs to report requests against
+	var gpuRequested map[string]int64
+	for i := range nodes.Items {
+		if nodeGPUInfo(&nodes.Items[i]).Advertised == 0 {
+			continue
+		}
+		if pods, err := co.listPods(ctx, ""); err != nil {
+			slog.Warn("Failed to list pods for GPU requests", "error", err)
+		} else {
+			gpuRequested = gpuRequestsByNode(pods.Items)
+		}
+		break
 	}
 
 	for _, node := range nodes.Items {
@@ -581,7 +1424,10 @@ func (co *CostOptimizer) getNodeMetrics(ctx context.Context) []NodeMetrics {
 		}
 
 		if nodeMetrics == nil {
-			continue
+			if usageKnown {
+				continue
+			}
+			nodeMetrics = &metricsv1beta1.NodeMetrics{}
 		}
 
 		cpuCapacity := node.Status.Capacity[corev1.ResourceCPU]
@@ -592,8 +1438,12 @@ func (co *CostOptimizer) getNodeMetrics(ctx context.Context) []NodeMetrics {
 		cpuUtil := float64(cpuUsage.MilliValue()) / float64(cpuCapacity.MilliValue()) * 100
 		memoryUtil := float64(memoryUsage.Value()) / float64(memoryCapacity.Value()) * 100
 
-		instanceType := co.extractInstanceType(node.Name)
-		hourlyCost := co.calculateNodeCost(node.Name, instanceType)
+		instanceType := co.pricing.instanceType(&node)
+		_, hourlyCost := co.billedNodeHourlyCost(&node, reserved)
+		_, isReserved := reserved[node.Name]
+		monthlyCost, prorated := co.nodeMonthlyCost(&node, hourlyCost, co.now())
+		gpu := nodeGPUInfo(&node)
+		cpuUtilEMA, memoryUtilEMA, _ := co.nodeEMA.get(node.Name)
 
 		metrics = append(metrics, NodeMetrics{
 			Name:              node.Name,
@@ -603,35 +1453,67 @@ func (co *CostOptimizer) getNodeMetrics(ctx context.Context) []NodeMetrics {
 			MemoryCapacity:    float64(memoryCapacity.Value()) / (1024 * 1024 * 1024),
 			CPUUtilization:    cpuUtil,
 			MemoryUtilization: memoryUtil,
-			EstimatedCost:     hourlyCost * 24 * 30, // Monthly cost
+			EstimatedCost:     monthlyCost,
+			Prorated:          prorated,
 			InstanceType:      instanceType,
+			CapacityType:      nodeCapacityType(&node),
+			Reserved:          isReserved,
+			GPUCapacity:       gpu.Advertised,
+			GPURequested:      gpuRequested[node.Name],
+			PhysicalGPUs:      gpu.Physical,
+			GPUSharing:        gpu.Sharing,
+
+			CPUUtilizationEMA:    cpuUtilEMA,
+			MemoryUtilizationEMA: memoryUtilEMA,
 		})
 	}
 
 	return metrics
 }
 
-func (co *CostOptimizer) getPodMetrics(ctx context.Context) []PodMetrics {
+// getPodMetrics reports the running pods of one namespace, or of every
+// namespace when namespace is empty
+func (co *CostOptimizer) getPodMetrics(ctx context.Context, namespace string) []PodMetrics {
 	metrics := make([]PodMetrics, 0)
 
 	if co.demoMode || co.clientset == nil || co.metricsClient == nil {
-		return co.demoPodMetrics()
+		for _, pod := range co.demoPodMetrics() {
+			if (namespace == "" || pod.Namespace == namespace) && !co.namespaceDropped(pod.Namespace) {
+				metrics = append(metrics, pod)
+			}
+		}
+		return metrics
 	}
 
-	pods, err := co.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	pods, err := co.listPods(ctx, namespace)
 	if err != nil {
-		log.Printf("Failed to list pods: %v", err)
+		slog.Warn("Failed to list pods", "error", err)
 		return metrics
 	}
 
-	podMetricsList, err := co.metricsClient.MetricsV1beta1().PodMetricses("").List(ctx, metav1.ListOptions{})
+	// Without the metrics API pods are still priced by their requests, with
+	// zero usage
+	podMetricsList, err := co.listPodMetrics(ctx, namespace)
+	usageKnown := err == nil
 	if err != nil {
-		log.Printf("Failed to get pod metrics: %v", err)
-		return metrics
+		if !metricsAPIMissing(err) {
+			slog.Warn("Failed to get pod metrics", "error", err)
+			return metrics
+		}
+		podMetricsList = &metricsv1beta1.PodMetricsList{}
+	}
+
+	// Pods are charged a share of the node they run on. Without the nodes,
+	// they fall back to flat per-resource rates.
+	var allocations map[string]nodeAllocation
+	if nodes, err := co.listNodes(ctx); err != nil {
+		slog.Warn("Failed to list nodes for pod cost allocation", "error", err)
+	} else {
+		allocations = co.nodeAllocations(nodes.Items)
 	}
 
 	for _, pod := range pods.Items {
-		if pod.Status.Phase != corev1.PodRunning {
+		if pod.Status.Phase != corev1.PodRunning || pod.Spec.NodeName == "" {
 			continue
 		}
 
@@ -644,18 +1526,27 @@ func (co *CostOptimizer) getPodMetrics(ctx context.Context) []PodMetrics {
 		}
 
 		if podMetrics == nil {
-			continue
+			if usageKnown {
+				continue
+			}
+			podMetrics = &metricsv1beta1.PodMetrics{}
 		}
 
 		// Calculate total pod resource usage
 		var totalCPUUsage, totalMemUsage resource.Quantity
 		var totalCPURequest, totalMemRequest, totalCPULimit, totalMemLimit resource.Quantity
 
+		var cpuUsageEMA, memUsageEMA float64
 		for _, containerMetrics := range podMetrics.Containers {
 			cpuUsage := containerMetrics.Usage[corev1.ResourceCPU]
 			memUsage := containerMetrics.Usage[corev1.ResourceMemory]
 			totalCPUUsage.Add(cpuUsage)
 			totalMemUsage.Add(memUsage)
+
+			if cpu, mem, ok := co.containerEMA.get(containerEMAKey(pod.Namespace, pod.Name, containerMetrics.Name)); ok {
+				cpuUsageEMA += cpu
+				memUsageEMA += mem
+			}
 		}
 
 		for _, container := range pod.Spec.Containers {
@@ -677,19 +1568,37 @@ func (co *CostOptimizer) getPodMetrics(ctx context.Context) []PodMetrics {
 			}
 		}
 
-		// Estimate pod cost based on resource requests
-		estimatedCost := co.estimatePodCost(totalCPURequest, totalMemRequest)
+		// Allocate the node's cost by the pod's share of its requests
+		var estimatedCost float64
+		allocation, ok := allocations[pod.Spec.NodeName]
+		if ok {
+			estimatedCost = allocation.podHourlyCost(totalCPURequest.MilliValue(), totalMemRequest.Value()) * 24 * 30
+		} else {
+			estimatedCost = co.estimatePodCost(totalCPURequest, totalMemRequest)
+		}
+
+		// Shared GPUs are billed by physical card, split across requesting pods
+		gpuRequest := podGPURequest(&pod)
+		if gpuRequest > 0 && ok {
+			estimatedCost += co.costCalculator.podGPUHourlyCost(gpuRequest, allocation.gpu) * allocation.gpuFactor * 24 * 30
+		}
 
 		metrics = append(metrics, PodMetrics{
 			Name:          pod.Name,
 			Namespace:     pod.Namespace,
+			Labels:        pod.Labels,
+			Workload:      podWorkload(&pod),
 			CPUUsage:      float64(totalCPUUsage.MilliValue()) / 1000,
 			MemoryUsage:   float64(totalMemUsage.Value()) / (1024 * 1024 * 1024),
 			CPURequest:    float64(totalCPURequest.MilliValue()) / 1000,
 			MemoryRequest: float64(totalMemRequest.Value()) / (1024 * 1024 * 1024),
 			CPULimit:      float64(totalCPULimit.MilliValue()) / 1000,
 			MemoryLimit:   float64(totalMemLimit.Value()) / (1024 * 1024 * 1024),
+			GPURequest:    gpuRequest,
 			EstimatedCost: estimatedCost,
+
+			CPUUsageEMA:    cpuUsageEMA / 1000,
+			MemoryUsageEMA: memUsageEMA / (1024 * 1024 * 1024),
 		})
 	}
 
@@ -698,57 +1607,141 @@ func (co *CostOptimizer) getPodMetrics(ctx context.Context) []PodMetrics {
 
 func (co *CostOptimizer) generateCostSummary(ctx context.Context) ClusterCostSummary {
 	nodeMetrics := co.getNodeMetrics(ctx)
-	podMetrics := co.getPodMetrics(ctx)
+	podMetrics := co.getPodMetrics(ctx, "")
+	usageKnown := co.metricsAPI.err() == nil
 
 	var totalComputeCost, totalStorageCost, wastedResources float64
 	namespaceCosts := make(map[string]float64)
+	namespaceUsedCost := make(map[string]float64)
+	namespaceIdleCost := make(map[string]float64)
+	costByRelease := make(map[string]float64)
+	workloadCosts := make(map[string]float64)
+
+	// Group pod cost by the cost label too, when one is configured
+	var labelCosts map[string]float64
+	var namespaceLabels map[string]map[string]string
+	if co.costLabel != "" {
+		labelCosts = make(map[string]float64)
+		namespaceLabels = co.namespaceLabels(ctx)
+	}
 
 	// Calculate compute costs
 	for _, node := range nodeMetrics {
 		totalComputeCost += node.EstimatedCost
 
 		// Calculate wasted resources (underutilized capacity)
-		if node.CPUUtilization < 50 || node.MemoryUtilization < 50 {
-			wastedResources += node.EstimatedCost * 0.3 // 30% waste factor
+		if usageKnown && (node.CPUUtilization < co.thresholds.WasteUtilization || node.MemoryUtilization < co.thresholds.WasteUtilization) {
+			wastedResources += node.EstimatedCost * co.thresholds.WasteFactor
 		}
 	}
 
 	// Calculate namespace costs
 	for _, pod := range podMetrics {
-		namespaceCosts[pod.Namespace] += pod.EstimatedCost
-	}
+		bucket := co.costBucket(pod.Namespace)
+		namespaceCosts[bucket] += pod.EstimatedCost
+
+		// Split allocated cost into the part backed by real usage and the idle remainder
+		if usageKnown {
+			used := pod.EstimatedCost * podUsedFraction(pod)
+			namespaceUsedCost[bucket] += used
+			namespaceIdleCost[bucket] += pod.EstimatedCost - used
+		}
 
-	// Estimate storage costs (simplified)
-	totalStorageCost = 100.0 // Placeholder
+		costByRelease[releaseCostKey(pod)] += pod.EstimatedCost
+		workloadCosts[pod.Namespace+"/"+pod.Workload] += pod.EstimatedCost
+		if labelCosts != nil {
+			labelCosts[co.labelCostKey(pod, namespaceLabels)] += pod.EstimatedCost
+		}
+	}
 
-	// Calculate potential savings from recommendations
-	var potentialSavings float64
-	for _, rec := range co.recommendations {
-		potentialSavings += rec.Savings
+	// Blend on-demand and spot rates within each node group, price the
+	// capacity no pod requests, and charge pods' ephemeral storage and egress
+	// to their namespaces
+	var groupCosts map[string]NodeGroupCost
+	var unallocated UnallocatedCapacity
+	var packing *PackingReport
+	var ephemeralStorageCost, networkCost float64
+	if !co.demoMode && co.clientset != nil {
+		if nodes, err := co.listNodes(ctx); err != nil {
+			slog.Warn("Failed to list nodes for node group and unallocated costs", "error", err)
+		} else {
+			groupCosts = co.nodeGroupCosts(nodes.Items)
+			if pods, err := co.listPods(ctx, ""); err != nil {
+				slog.Warn("Failed to list pods for unallocated capacity", "error", err)
+			} else {
+				unallocated = co.unallocatedCapacity(nodes.Items, pods.Items)
+				packing = co.packingReport(nodes.Items, pods.Items)
+
+				var ephemeralByNamespace, networkByNamespace map[string]float64
+				ephemeralStorageCost, ephemeralByNamespace = co.ephemeralStorageCosts(pods.Items)
+				networkCost, networkByNamespace = co.networkCosts(ctx, pods.Items)
+				for _, byNamespace := range []map[string]float64{ephemeralByNamespace, networkByNamespace} {
+					for namespace, cost := range byNamespace {
+						namespaceCosts[co.costBucket(namespace)] += cost
+					}
+				}
+			}
+		}
 	}
 
-	return ClusterCostSummary{
-		TotalMonthlyCost:    totalComputeCost + totalStorageCost,
-		ComputeCost:         totalComputeCost,
-		StorageCost:         totalStorageCost,
-		WastedResources:     wastedResources,
-		PotentialSavings:    potentialSavings,
-		NodeCount:           len(nodeMetrics),
-		PodCount:            len(podMetrics),
-		NamespaceCosts:      namespaceCosts,
-		RecommendationCount: len(co.recommendations),
-		LastUpdated:         co.now(),
+	// Price persistent volumes and charge bound ones to their namespace
+	var storageByClass map[string]float64
+	if co.demoMode || co.clientset == nil {
+		totalStorageCost = demoStorageMonthlyCost
+	} else if volumes, err := co.listVolumeStorage(ctx); err != nil {
+		slog.Warn("Failed to list persistent volumes", "error", err)
+	} else {
+		var storageByNamespace map[string]float64
+		totalStorageCost, storageByClass, storageByNamespace = co.storageCosts(volumes)
+		for namespace, cost := range storageByNamespace {
+			if co.namespaceDropped(namespace) {
+				continue
+			}
+			namespaceCosts[co.costBucket(namespace)] += cost
+		}
 	}
-}
 
-func (co *CostOptimizer) extractInstanceType(nodeName string) string {
-	// Simple heuristic to extract instance type from node name
-	for instanceType := range co.costCalculator.NodeCostPerHour {
-		if strings.Contains(strings.ToLower(nodeName), instanceType) {
-			return instanceType
+	// Calculate potential savings from recommendations. Negative savings are
+	// cost increases, totalled separately so they don't offset the savings.
+	recommendations := co.activeRecommendations()
+	var potentialSavings, projectedCostIncrease float64
+	for _, rec := range recommendations {
+		if rec.Savings < 0 {
+			projectedCostIncrease -= rec.Savings
+		} else {
+			potentialSavings += rec.Savings
 		}
 	}
-	return "default"
+
+	summary := ClusterCostSummary{
+		TotalMonthlyCost:      totalComputeCost + totalStorageCost + ephemeralStorageCost + networkCost,
+		ComputeCost:           totalComputeCost,
+		StorageCost:           totalStorageCost,
+		StorageCostByClass:    storageByClass,
+		EphemeralStorageCost:  ephemeralStorageCost,
+		NetworkCost:           networkCost,
+		WastedResources:       wastedResources,
+		PotentialSavings:      potentialSavings,
+		ProjectedCostIncrease: projectedCostIncrease,
+		NodeCount:             len(nodeMetrics),
+		PodCount:              len(podMetrics),
+		NamespaceCosts:        namespaceCosts,
+		NamespaceUsedCost:     namespaceUsedCost,
+		NamespaceIdleCost:     namespaceIdleCost,
+		CostByRelease:         costByRelease,
+		LabelCosts:            labelCosts,
+		NodeGroupCosts:        groupCosts,
+		UnallocatedCPU:        unallocated.CPUCores,
+		UnallocatedMemory:     unallocated.MemoryGB,
+		UnallocatedCost:       unallocated.MonthlyCost,
+		Packing:               packing,
+		UsageUnavailable:      !usageKnown,
+		WorkloadCosts:         workloadCosts,
+		RecommendationCount:   len(recommendations),
+		LastUpdated:           co.now(),
+	}
+	co.metrics.update(nodeMetrics, summary)
+	return summary
 }
 
 func (co *CostOptimizer) estimatePodCost(cpuRequest, memRequest resource.Quantity) float64 {
@@ -759,6 +1752,28 @@ func (co *CostOptimizer) estimatePodCost(cpuRequest, memRequest resource.Quantit
 	return cpuCost + memCost
 }
 
+// podUsedFraction reports how much of a pod's requested capacity is actually
+// used, averaged over CPU and memory. Usage above the request counts as fully
+// used, and a pod without requests has no idle allocation.
+func podUsedFraction(pod PodMetrics) float64 {
+	var total float64
+	var resources int
+
+	if pod.CPURequest > 0 {
+		total += math.Min(pod.CPUUsage/pod.CPURequest, 1)
+		resources++
+	}
+	if pod.MemoryRequest > 0 {
+		total += math.Min(pod.MemoryUsage/pod.MemoryRequest, 1)
+		resources++
+	}
+
+	if resources == 0 {
+		return 1
+	}
+	return total / float64(resources)
+}
+
 // demo helpers keep the API usable without a live cluster, making the service
 // easier to showcase in local or CI environments.
 func (co *CostOptimizer) demoNodeMetrics() []NodeMetrics {
@@ -771,8 +1786,9 @@ func (co *CostOptimizer) demoNodeMetrics() []NodeMetrics {
 			MemoryCapacity:    8,
 			CPUUtilization:    6,
 			MemoryUtilization: 31,
-			EstimatedCost:     co.costCalculator.NodeCostPerHour["t3.medium"] * 24 * 30,
+			EstimatedCost:     co.costCalculator.nodePrices()["t3.medium"] * 24 * 30,
 			InstanceType:      "t3.medium",
+			CapacityType:      capacityOnDemand,
 		},
 		{
 			Name:              fmt.Sprintf("%s-node-2", co.clusterName),
@@ -782,8 +1798,9 @@ func (co *CostOptimizer) demoNodeMetrics() []NodeMetrics {
 			MemoryCapacity:    16,
 			CPUUtilization:    40,
 			MemoryUtilization: 39,
-			EstimatedCost:     co.costCalculator.NodeCostPerHour["m5.xlarge"] * 24 * 30,
+			EstimatedCost:     co.costCalculator.nodePrices()["m5.xlarge"] * 24 * 30,
 			InstanceType:      "m5.xlarge",
+			CapacityType:      capacityOnDemand,
 		},
 	}
 }
@@ -819,15 +1836,26 @@ func (co *CostOptimizer) demoNodeRecommendations() []Recommendation {
 	return []Recommendation{
 		{
 			Type:        "node_optimization",
+			Category:    CategoryScale,
 			Resource:    fmt.Sprintf("%s-node-1", co.clusterName),
 			Description: "Node is underutilized (CPU: 6.0%, Memory: 31.0%)",
 			Impact:      "Consider consolidating workloads or downsizing",
-			Savings:     co.costCalculator.NodeCostPerHour["t3.medium"] * 24 * 30 * 0.7,
+			Priority:    "medium",
+			Timestamp:   co.now(),
+		},
+		{
+			Type:        "node_consolidation",
+			Category:    CategoryScale,
+			Resource:    fmt.Sprintf("%s-node-1", co.clusterName),
+			Description: "Node can be removed: repacking pod requests fits its pods on the remaining nodes (1 of 3 nodes removable)",
+			Impact:      fmt.Sprintf("Drain and remove node %s-node-1", co.clusterName),
+			Savings:     co.costCalculator.nodePrices()["t3.medium"] * 24 * 30,
 			Priority:    "medium",
 			Timestamp:   co.now(),
 		},
 		{
 			Type:        "node_scaling",
+			Category:    CategoryScale,
 			Resource:    fmt.Sprintf("%s-node-2", co.clusterName),
 			Description: "Node is nearing limits during peaks",
 			Impact:      "Evaluate scaling up or adding nodes",
@@ -842,6 +1870,7 @@ func (co *CostOptimizer) demoPodRecommendations() []Recommendation {
 	return []Recommendation{
 		{
 			Type:        "resource_rightsizing",
+			Category:    CategoryRightsize,
 			Resource:    "default/api-7c4d9f6c9b-abcde",
 			Namespace:   "default",
 			Description: "Container is over-provisioned for CPU (request: 200m, usage: 80m)",
@@ -852,6 +1881,7 @@ func (co *CostOptimizer) demoPodRecommendations() []Recommendation {
 		},
 		{
 			Type:        "resource_rightsizing",
+			Category:    CategoryRightsize,
 			Resource:    "batch/worker-5f7b6c6bdf-xyz12",
 			Namespace:   "batch",
 			Description: "Container is over-provisioned for memory (request: 1Gi, usage: 0.8Gi)",
@@ -867,6 +1897,7 @@ func (co *CostOptimizer) demoDeploymentRecommendations() []Recommendation {
 	return []Recommendation{
 		{
 			Type:        "horizontal_scaling",
+			Category:    CategoryScale,
 			Resource:    "default/api",
 			Namespace:   "default",
 			Description: "Deployment could benefit from auto-scaling based on CPU/memory",
@@ -877,6 +1908,7 @@ func (co *CostOptimizer) demoDeploymentRecommendations() []Recommendation {
 		},
 		{
 			Type:        "resource_governance",
+			Category:    CategoryConfigure,
 			Resource:    "batch/worker",
 			Namespace:   "batch",
 			Description: "Deployment lacks resource requests/limits",
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+// newTestOptimizer builds an optimizer from the environment, as main does,
+// then points it at fake Kubernetes and metrics clients holding objects.
+// Node and pod metrics go to the metrics client, the rest to the Kubernetes one.
+func newTestOptimizer(t *testing.T, objects ...runtime.Object) (*CostOptimizer, *fake.Clientset) {
+	t.Helper()
+	t.Setenv("DEMO_MODE", "true")
+	co, err := NewCostOptimizer()
+	if err != nil {
+		t.Fatalf("NewCostOptimizer: %v", err)
+	}
+
+	// The metrics fake would file metrics under the resource guessed from
+	// their kind, not the nodes and pods resources the client lists
+	metricsClient := metricsfake.NewSimpleClientset()
+	var kube []runtime.Object
+	for _, obj := range objects {
+		var err error
+		switch m := obj.(type) {
+		case *metricsv1beta1.NodeMetrics:
+			err = metricsClient.Tracker().Create(metricsv1beta1.SchemeGroupVersion.WithResource("nodes"), m, "")
+		case *metricsv1beta1.PodMetrics:
+			err = metricsClient.Tracker().Create(metricsv1beta1.SchemeGroupVersion.WithResource("pods"), m, m.Namespace)
+		default:
+			kube = append(kube, obj)
+		}
+		if err != nil {
+			t.Fatalf("add metrics: %v", err)
+		}
+	}
+
+	client := fake.NewSimpleClientset(kube...)
+	fakeScaleSubresource(client)
+	co.demoMode = false
+	co.clientset = client
+	co.metricsClient = metricsClient
+	co.actions = nil
+	co.recommendations = make([]Recommendation, 0)
+	return co, client
+}
+
+// fakeScaleSubresource serves the deployments scale subresource from the
+// fake's deployments, which its object tracker doesn't do on its own
+func fakeScaleSubresource(client *fake.Clientset) {
+	deployments := appsv1.SchemeGroupVersion.WithResource("deployments")
+	client.PrependReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		get, ok := action.(k8stesting.GetAction)
+		if !ok || get.GetSubresource() != "scale" {
+			return false, nil, nil
+		}
+		obj, err := client.Tracker().Get(deployments, get.GetNamespace(), get.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		deployment := obj.(*appsv1.Deployment)
+		return true, &autoscalingv1.Scale{
+			ObjectMeta: metav1.ObjectMeta{Name: deployment.Name, Namespace: deployment.Namespace},
+			Spec:       autoscalingv1.ScaleSpec{Replicas: *deployment.Spec.Replicas},
+		}, nil
+	})
+	client.PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		update, ok := action.(k8stesting.UpdateAction)
+		if !ok || update.GetSubresource() != "scale" {
+			return false, nil, nil
+		}
+		scale := update.GetObject().(*autoscalingv1.Scale)
+		obj, err := client.Tracker().Get(deployments, update.GetNamespace(), scale.Name)
+		if err != nil {
+			return true, nil, err
+		}
+		deployment := obj.(*appsv1.Deployment).DeepCopy()
+		replicas := scale.Spec.Replicas
+		deployment.Spec.Replicas = &replicas
+		if err := client.Tracker().Update(deployments, deployment, deployment.Namespace); err != nil {
+			return true, nil, err
+		}
+		return true, scale, nil
+	})
+}
+
+// testNode is a schedulable node with the given allocatable capacity
+func testNode(name, cpu, memory string) *corev1.Node {
+	allocatable := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(cpu),
+		corev1.ResourceMemory: resource.MustParse(memory),
+		corev1.ResourcePods:   resource.MustParse("110"),
+	}
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     corev1.NodeStatus{Capacity: allocatable, Allocatable: allocatable},
+	}
+}
+
+// testNodeMetrics reports a node's usage
+func testNodeMetrics(name, cpu, memory string) *metricsv1beta1.NodeMetrics {
+	return &metricsv1beta1.NodeMetrics{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Usage: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(memory),
+		},
+	}
+}
+
+// testStarted is when test pods started: long enough before any test's clock
+// for them to be analyzed
+var testStarted = metav1.NewTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+// testPod is a running pod on node whose one container requests cpu and memory
+func testPod(namespace, name, node, cpu, memory string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: corev1.PodSpec{
+			NodeName: node,
+			Containers: []corev1.Container{{
+				Name: "app",
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpu),
+					corev1.ResourceMemory: resource.MustParse(memory),
+				}},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning, StartTime: &testStarted},
+	}
+}
+
+// testPodMetrics reports the usage of a pod's one container
+func testPodMetrics(namespace, name, cpu, memory string) *metricsv1beta1.PodMetrics {
+	return &metricsv1beta1.PodMetrics{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Containers: []metricsv1beta1.ContainerMetrics{{
+			Name: "app",
+			Usage: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			},
+		}},
+	}
+}
+
+// serve sends a request through handler and returns the recorded response
+func serve(handler http.Handler, method, target string, body io.Reader, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, body)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// waitFor polls cond until it holds or the test times out
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// testDeployment is a deployment with the given replicas, ready, selecting
+// pods by an app label, whose one container has resource requests
+func testDeployment(namespace, name string, replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{
+					Name: "app",
+					Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("100m"),
+						corev1.ResourceMemory: resource.MustParse("128Mi"),
+					}},
+				}}},
+			},
+		},
+		Status: appsv1.DeploymentStatus{Replicas: replicas},
+	}
+}
+
+// testReplica is a running pod of deployment on node, with its template's
+// labels and requests
+func testReplica(deployment *appsv1.Deployment, name, node string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: deployment.Namespace, Labels: deployment.Spec.Template.Labels},
+		Spec:       *deployment.Spec.Template.Spec.DeepCopy(),
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning, StartTime: &testStarted},
+	}
+	pod.Spec.NodeName = node
+	return pod
+}
+
+func TestJitteredInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		jitter float64
+		r      float64
+		want   time.Duration
+	}{
+		{name: "low end", jitter: 0.2, r: 0, want: 4 * time.Minute},
+		{name: "midpoint", jitter: 0.2, r: 0.5, want: 5 * time.Minute},
+		{name: "high end", jitter: 0.2, r: 0.75, want: 5*time.Minute + 30*time.Second},
+		{name: "no jitter", jitter: 0, r: 0.9, want: 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jitteredInterval(5*time.Minute, tt.jitter, tt.r); got != tt.want {
+				t.Errorf("jitteredInterval = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Over many scans the waits stay in bounds, vary, and average out
+	const scans = 1000
+	seen := make(map[time.Duration]bool)
+	var total time.Duration
+	for i := 0; i < scans; i++ {
+		interval := jitteredInterval(defaultScanInterval, defaultScanJitter, rand.Float64())
+		if interval < 4*time.Minute || interval > 6*time.Minute {
+			t.Fatalf("interval %v outside ±20%% of %v", interval, defaultScanInterval)
+		}
+		seen[interval] = true
+		total += interval
+	}
+	if len(seen) < scans/2 {
+		t.Errorf("only %d distinct intervals in %d scans", len(seen), scans)
+	}
+	if mean := total / scans; mean < defaultScanInterval-15*time.Second || mean > defaultScanInterval+15*time.Second {
+		t.Errorf("mean interval %v, want about %v", mean, defaultScanInterval)
+	}
+}
+
+func TestScanJitterSetting(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{value: "", want: defaultScanJitter},
+		{value: "0", want: 0},
+		{value: "0.5", want: 0.5},
+		{value: "1", wantErr: true},
+		{value: "-0.1", wantErr: true},
+		{value: "lots", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("DEMO_MODE", "true")
+			t.Setenv("OPTIMKUBE_SCAN_JITTER", tt.value)
+			co, err := NewCostOptimizer()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCostOptimizer error %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && co.scanJitter != tt.want {
+				t.Errorf("jitter %v, want %v", co.scanJitter, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanIntervalSetting(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "", want: defaultScanInterval},
+		{value: "30s", want: 30 * time.Second},
+		{value: "10m", want: 10 * time.Minute},
+		{value: "0s", wantErr: true},
+		{value: "-1m", wantErr: true},
+		{value: "often", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("DEMO_MODE", "true")
+			t.Setenv("OPTIMKUBE_SCAN_INTERVAL", tt.value)
+			co, err := NewCostOptimizer()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCostOptimizer error %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && co.scanInterval != tt.want {
+				t.Errorf("interval %v, want %v", co.scanInterval, tt.want)
+			}
+		})
+	}
+}
+
+// TestStartMonitoringScansRepeatedly checks that monitoring scans on boot and
+// then on every interval
+func TestStartMonitoringScansRepeatedly(t *testing.T) {
+	co, _ := newTestOptimizer(t, testNode("node-1", "4", "16Gi"))
+	co.scanInterval = 50 * time.Millisecond
+	co.scanJitter = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		co.StartMonitoring(ctx)
+		close(stopped)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	deadline := time.Now().Add(200 * time.Millisecond)
+	for len(co.history.all()) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d scans within 200ms, want at least 2", len(co.history.all()))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestStartMonitoringStopsOnCancel checks that the monitor loop returns
+// promptly once its context is cancelled, even mid-wait
+func TestStartMonitoringStopsOnCancel(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+	}{
+		{name: "waiting for the next scan", interval: time.Hour},
+		{name: "short interval", interval: 10 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t, testNode("node-1", "4", "16Gi"))
+			co.scanInterval = tt.interval
+			ctx, cancel := context.WithCancel(context.Background())
+			stopped := make(chan struct{})
+			go func() {
+				co.StartMonitoring(ctx)
+				close(stopped)
+			}()
+
+			for len(co.history.all()) == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			cancel()
+			select {
+			case <-stopped:
+			case <-time.After(time.Second):
+				t.Fatal("StartMonitoring still running a second after cancel")
+			}
+		})
+	}
+}
+
+func TestNodeInstanceType(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     string
+		labels   map[string]string
+		want     string
+		wantCost float64
+	}{
+		{name: "stable label", node: "ip-10-0-1-12", labels: map[string]string{corev1.LabelInstanceTypeStable: "m5.xlarge"}, want: "m5.xlarge", wantCost: 0.192},
+		{name: "beta label", node: "ip-10-0-1-13", labels: map[string]string{corev1.LabelInstanceType: "m5.xlarge"}, want: "m5.xlarge", wantCost: 0.192},
+		{name: "stable label wins", node: "ip-10-0-1-14", labels: map[string]string{corev1.LabelInstanceTypeStable: "m5.xlarge", corev1.LabelInstanceType: "t3.large"}, want: "m5.xlarge", wantCost: 0.192},
+		{name: "name heuristic", node: "worker-t3.large-1", want: "t3.large", wantCost: 0.0832},
+		{name: "unknown", node: "ip-10-0-1-15", want: "default", wantCost: 0.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := testNode(tt.node, "4", "16Gi")
+			node.Labels = tt.labels
+			co, _ := newTestOptimizer(t, node)
+			if got := co.pricing.instanceType(node); got != tt.want {
+				t.Errorf("instanceType = %q, want %q", got, tt.want)
+			}
+			if got := co.nodeHourlyCost(node); got != tt.wantCost {
+				t.Errorf("nodeHourlyCost = %v, want %v", got, tt.wantCost)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// promMetrics are the gauges served at /metrics. They're set from every cost
+// summary, so a scrape reports the same numbers as the REST API did for the
+// latest scan.
+type promMetrics struct {
+	registry *prometheus.Registry
+
+	nodeCPUUtilization    *prometheus.GaugeVec
+	nodeMemoryUtilization *prometheus.GaugeVec
+	nodeMonthlyCost       *prometheus.GaugeVec
+	namespaceMonthlyCost  *prometheus.GaugeVec
+	totalMonthlyCost      prometheus.Gauge
+	potentialSavings      prometheus.Gauge
+}
+
+func newPromMetrics() *promMetrics {
+	m := &promMetrics{
+		registry: prometheus.NewRegistry(),
+		nodeCPUUtilization: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "optimkube_node_cpu_utilization",
+			Help: "Node CPU usage as a percentage of capacity.",
+		}, []string{"node"}),
+		nodeMemoryUtilization: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "optimkube_node_memory_utilization",
+			Help: "Node memory usage as a percentage of capacity.",
+		}, []string{"node"}),
+		nodeMonthlyCost: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "optimkube_node_monthly_cost",
+			Help: "Estimated monthly cost of a node.",
+		}, []string{"node", "instance_type"}),
+		namespaceMonthlyCost: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "optimkube_namespace_monthly_cost",
+			Help: "Estimated monthly cost of the pods in a namespace.",
+		}, []string{"namespace"}),
+		totalMonthlyCost: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "optimkube_total_monthly_cost",
+			Help: "Estimated monthly cost of the cluster.",
+		}),
+		potentialSavings: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "optimkube_potential_savings_total",
+			Help: "Monthly savings of all active recommendations.",
+		}),
+	}
+	m.registry.MustRegister(
+		m.nodeCPUUtilization,
+		m.nodeMemoryUtilization,
+		m.nodeMonthlyCost,
+		m.namespaceMonthlyCost,
+		m.totalMonthlyCost,
+		m.potentialSavings,
+	)
+	return m
+}
+
+// update replaces every gauge with the values of a cost summary and the node
+// metrics it was built from. Series for nodes and namespaces that are gone
+// are dropped.
+func (m *promMetrics) update(nodes []NodeMetrics, summary ClusterCostSummary) {
+	m.nodeCPUUtilization.Reset()
+	m.nodeMemoryUtilization.Reset()
+	m.nodeMonthlyCost.Reset()
+	for _, node := range nodes {
+		m.nodeCPUUtilization.WithLabelValues(node.Name).Set(node.CPUUtilization)
+		m.nodeMemoryUtilization.WithLabelValues(node.Name).Set(node.MemoryUtilization)
+		m.nodeMonthlyCost.WithLabelValues(node.Name, node.InstanceType).Set(node.EstimatedCost)
+	}
+
+	m.namespaceMonthlyCost.Reset()
+	for namespace, cost := range summary.NamespaceCosts {
+		m.namespaceMonthlyCost.WithLabelValues(namespace).Set(cost)
+	}
+
+	m.totalMonthlyCost.Set(summary.TotalMonthlyCost)
+	m.potentialSavings.Set(summary.PotentialSavings)
+}
+
+func (m *promMetrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
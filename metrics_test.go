@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// TestMetricsEndpoint checks that /metrics reports the latest cost summary,
+// labelled as the REST API reports it
+func TestMetricsEndpoint(t *testing.T) {
+	node := testNode("ip-10-0-1-12", "4", "16Gi")
+	node.Labels = map[string]string{corev1.LabelInstanceTypeStable: "m5.xlarge"}
+	co, _ := newTestOptimizer(t,
+		node, testNodeMetrics("ip-10-0-1-12", "1", "4Gi"),
+		testPod("shop", "web", "ip-10-0-1-12", "1", "2Gi"), testPodMetrics("shop", "web", "500m", "1Gi"),
+	)
+	summary := co.generateCostSummary(context.Background())
+	body := serve(co.metrics.handler(), http.MethodGet, "/metrics", nil).Body.String()
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "node cost", want: fmt.Sprintf(`optimkube_node_monthly_cost{instance_type="m5.xlarge",node="ip-10-0-1-12"} %g`, 0.192*24*30)},
+		{name: "node cpu", want: `optimkube_node_cpu_utilization{node="ip-10-0-1-12"} 25`},
+		{name: "namespace cost", want: fmt.Sprintf(`optimkube_namespace_monthly_cost{namespace="shop"} %g`, summary.NamespaceCosts["shop"])},
+		{name: "total cost", want: fmt.Sprintf("optimkube_total_monthly_cost %g", summary.TotalMonthlyCost)},
+		{name: "savings", want: "optimkube_potential_savings_total "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(body, tt.want) {
+				t.Errorf("/metrics has no %q:\n%s", tt.want, body)
+			}
+		})
+	}
+}
+
+// TestMetricsDropGoneNodes checks that a node removed between scans loses its
+// series
+func TestMetricsDropGoneNodes(t *testing.T) {
+	m := newPromMetrics()
+	m.update([]NodeMetrics{{Name: "node-1", InstanceType: "default"}, {Name: "node-2", InstanceType: "default"}}, ClusterCostSummary{})
+	m.update([]NodeMetrics{{Name: "node-1", InstanceType: "default"}}, ClusterCostSummary{})
+
+	body := serve(m.handler(), http.MethodGet, "/metrics", nil).Body.String()
+	if !strings.Contains(body, `node="node-1"`) || strings.Contains(body, `node="node-2"`) {
+		t.Errorf("/metrics should have node-1 and not node-2:\n%s", body)
+	}
+}
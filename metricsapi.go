@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// metricsAPIFallback describes what runs in place of usage-based analysis
+// while the metrics API is missing
+const metricsAPIFallback = "request-based analysis: costs from requests, no usage-based rightsizing or waste"
+
+// metricsAPIMissing reports whether a metrics list failed because the metrics
+// API isn't served at all: metrics-server isn't installed (not found), or its
+// APIService is registered but nothing answers it (service unavailable)
+func metricsAPIMissing(err error) bool {
+	return apierrors.IsNotFound(err) || apierrors.IsServiceUnavailable(err)
+}
+
+// metricsAPIState remembers whether the metrics API was missing at the last
+// metrics list, so the change is logged once instead of by every analyzer on
+// every scan
+type metricsAPIState struct {
+	mu      sync.Mutex
+	missing error // the error of the list that found it missing, nil while it answers
+}
+
+// observeMetricsList records the outcome of a metrics list. Errors other than
+// a missing API leave the state as is.
+func (s *metricsAPIState) observeMetricsList(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case err == nil:
+		if s.missing != nil {
+			slog.Info("Metrics API is available again, resuming usage-based analysis")
+		}
+		s.missing = nil
+	case metricsAPIMissing(err):
+		if s.missing == nil {
+			slog.Error("Metrics API is not available; install metrics-server for usage-based recommendations",
+				"fallback", metricsAPIFallback, "error", err)
+		}
+		s.missing = err
+	}
+}
+
+// err returns why the metrics API is missing, or nil while it answers
+func (s *metricsAPIState) err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.missing
+}
+
+// checkMetricsAPI lists one node's metrics at startup, so a missing
+// metrics-server is reported before the first scan
+func (co *CostOptimizer) checkMetricsAPI(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+	_, err := co.metricsClient.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{Limit: 1})
+	co.metricsAPI.observeMetricsList(err)
+}
+
+// warnUsageFailed logs a failed usage lookup, unless the metrics API is
+// missing, which observeMetricsList has already reported
+func warnUsageFailed(msg string, err error) {
+	if !metricsAPIMissing(err) {
+		slog.Warn(msg, "error", err)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+var (
+	metricsNotFound    = apierrors.NewNotFound(schema.GroupResource{Group: "metrics.k8s.io", Resource: "nodes"}, "")
+	metricsUnavailable = apierrors.NewServiceUnavailable("the server is currently unable to handle the request")
+)
+
+// failMetrics makes every metrics list fail with err
+func failMetrics(co *CostOptimizer, err error) {
+	fake := &co.metricsClient.(*metricsfake.Clientset).Fake
+	failWith(fake, "list", "nodes", err)
+	failWith(fake, "list", "pods", err)
+}
+
+func TestMetricsAPIFallback(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantFallback bool
+	}{
+		{name: "metrics-server not installed", err: metricsNotFound, wantFallback: true},
+		{name: "metrics-server not answering", err: metricsUnavailable, wantFallback: true},
+		{name: "other failure", err: apierrors.NewInternalError(errors.New("etcd timeout"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bestEffort := testPod("shop", "worker", "node-1", "1", "1Gi")
+			bestEffort.Spec.Containers[0].Resources.Requests = nil
+			bestEffort.Status.QOSClass = corev1.PodQOSBestEffort
+			co, _ := newTestOptimizer(t,
+				testNode("node-1", "4", "16Gi"),
+				testPod("shop", "web", "node-1", "1", "2Gi"), bestEffort,
+			)
+			failMetrics(co, tt.err)
+
+			co.analyzeAndGenerateRecommendations(context.Background())
+			var governance []string
+			for _, rec := range co.activeRecommendations() {
+				if rec.Type == "resource_governance" {
+					governance = append(governance, rec.Resource)
+				}
+			}
+			if tt.wantFallback != (len(governance) == 1 && governance[0] == "shop/worker") {
+				t.Errorf("governance recommendations %v, want the best-effort pod flagged %v", governance, tt.wantFallback)
+			}
+
+			summary := co.generateCostSummary(context.Background())
+			if summary.UsageUnavailable != tt.wantFallback {
+				t.Errorf("usage unavailable %v, want %v", summary.UsageUnavailable, tt.wantFallback)
+			}
+			if !tt.wantFallback {
+				return
+			}
+			nodeCost := co.nodeHourlyCost(testNode("node-1", "4", "16Gi")) * 24 * 30
+			if summary.NodeCount != 1 || summary.PodCount != 2 || summary.TotalMonthlyCost != nodeCost {
+				t.Errorf("summary of %d nodes, %d pods costing %v, want 1 node, 2 pods costing %v",
+					summary.NodeCount, summary.PodCount, summary.TotalMonthlyCost, nodeCost)
+			}
+			// web requests a quarter of the CPU and an eighth of the memory
+			if want := nodeCost * (1.0/4 + 1.0/8) / 2; summary.NamespaceCosts["shop"] < want-1e-9 {
+				t.Errorf("shop costs %v, want at least web's requests %v", summary.NamespaceCosts["shop"], want)
+			}
+			if summary.WastedResources != 0 || len(summary.NamespaceUsedCost) != 0 {
+				t.Errorf("waste %v and used costs %v without usage", summary.WastedResources, summary.NamespaceUsedCost)
+			}
+		})
+	}
+}
+
+func TestMetricsAPIMissing(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "not found", err: metricsNotFound, want: true},
+		{name: "service unavailable", err: metricsUnavailable, want: true},
+		{name: "forbidden", err: apierrors.NewForbidden(schema.GroupResource{Group: "metrics.k8s.io", Resource: "nodes"}, "", errors.New("rbac"))},
+		{name: "timeout", err: context.DeadlineExceeded},
+		{name: "no error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := metricsAPIMissing(tt.err); got != tt.want {
+				t.Errorf("metricsAPIMissing = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMetricsAPIReportedOnce checks that a missing metrics API is logged when
+// it goes missing and when it's back, not on every list
+func TestMetricsAPIReportedOnce(t *testing.T) {
+	logs := captureLogs(t, slog.LevelInfo)
+	var state metricsAPIState
+	for _, err := range []error{nil, metricsNotFound, metricsNotFound, errors.New("timeout"), metricsUnavailable, nil, nil} {
+		state.observeMetricsList(err)
+	}
+
+	var messages []string
+	for _, record := range logRecords(t, logs) {
+		messages = append(messages, record["level"].(string)+" "+record["msg"].(string))
+	}
+	want := []string{
+		"ERROR Metrics API is not available; install metrics-server for usage-based recommendations",
+		"INFO Metrics API is available again, resuming usage-based analysis",
+	}
+	if strings.Join(messages, "\n") != strings.Join(want, "\n") {
+		t.Errorf("logged\n%s\nwant\n%s", strings.Join(messages, "\n"), strings.Join(want, "\n"))
+	}
+	if state.err() != nil {
+		t.Errorf("state still missing: %v", state.err())
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// podsPerNode counts the running pods on each node that would be rescheduled
+// by a drain. DaemonSet pods aren't moved, so they're not counted. It returns
+// nil when no reschedule cost is configured, since the counts aren't needed.
+func (co *CostOptimizer) podsPerNode(ctx context.Context) map[string]int {
+	if co.reschedulePodCost <= 0 {
+		return nil
+	}
+
+	pods, err := co.listPods(ctx, "")
+	if err != nil {
+		slog.Warn("Failed to list pods for migration cost, reporting gross savings", "error", err)
+		return nil
+	}
+
+	counts := make(map[string]int)
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == "" || pod.Status.Phase != corev1.PodRunning || ownedByDaemonSet(&pod) {
+			continue
+		}
+		counts[pod.Spec.NodeName]++
+	}
+	return counts
+}
+
+func ownedByDaemonSet(pod *corev1.Pod) bool {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
+
+// fewestPods sums the pod counts of the n least-loaded nodes, which are the
+// ones a scale-in would drain
+func fewestPods(counts []int, n int) int {
+	sorted := append([]int(nil), counts...)
+	sort.Ints(sorted)
+	var total int
+	for i := 0; i < n && i < len(sorted); i++ {
+		total += sorted[i]
+	}
+	return total
+}
+
+// applyMigrationCost charges the one-time cost of rescheduling pods against a
+// consolidation recommendation. Savings becomes the net figure for the first
+// month, with the gross monthly savings kept alongside.
+func (co *CostOptimizer) applyMigrationCost(rec *Recommendation, pods int) {
+	if co.reschedulePodCost <= 0 {
+		return
+	}
+	rec.GrossSavings = rec.Savings
+	rec.MigrationCost = float64(pods) * co.reschedulePodCost
+	rec.Savings -= rec.MigrationCost
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// podsOn returns count small running pods scheduled on node
+func podsOn(node string, count int) []runtime.Object {
+	var objects []runtime.Object
+	for i := 0; i < count; i++ {
+		objects = append(objects, testPod("shop", fmt.Sprintf("%s-pod-%d", node, i), node, "100m", "128Mi"))
+	}
+	return objects
+}
+
+func TestConsolidationNetSavings(t *testing.T) {
+	daemon := testPod("kube-system", "node-exporter", "node-1", "10m", "32Mi")
+	daemon.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "node-exporter"}}
+	pending := testPod("shop", "queued", "node-1", "100m", "128Mi")
+	pending.Status.Phase = corev1.PodPending
+
+	// node-2 has room for node-1's pods, so the drain is feasible, and runs
+	// more of them, so node-1 is the one to remove
+	idleNode := append([]runtime.Object{testNode("node-1", "8", "32Gi"), testNodeMetrics("node-1", "500m", "2Gi"), testNode("node-2", "8", "32Gi"), daemon, pending}, podsOn("node-1", 4)...)
+	idleNode = append(idleNode, podsOn("node-2", 6)...)
+	for _, obj := range idleNode {
+		if pod, ok := obj.(*corev1.Pod); ok && pod.Namespace == "shop" {
+			controlled(pod)
+		}
+	}
+	group := testNodeGroup("eks.amazonaws.com/nodegroup", "workers", 3, "400m", "1Gi")
+	group = append(group, podsOn("workers-1", 1)...)
+	group = append(group, podsOn("workers-2", 2)...)
+	group = append(group, podsOn("workers-3", 5)...)
+
+	tests := []struct {
+		name          string
+		objects       []runtime.Object
+		recType       string
+		podCost       float64
+		wantMigration float64
+	}{
+		{name: "idle node", objects: idleNode, recType: "node_consolidation", podCost: 5, wantMigration: 20},
+		{name: "idle node without reschedule cost", objects: idleNode, recType: "node_consolidation"},
+		// Shrinking 3 to 1 drains the two nodes with the fewest pods
+		{name: "node group scale-in", objects: group, recType: "node_group_rightsizing", podCost: 5, wantMigration: 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t, tt.objects...)
+			co.reschedulePodCost = tt.podCost
+
+			var found []Recommendation
+			for _, rec := range append(co.analyzeNodes(context.Background()), co.analyzeConsolidation(context.Background())...) {
+				if rec.Type == tt.recType {
+					found = append(found, rec)
+				}
+			}
+			if len(found) != 1 {
+				t.Fatalf("got %d %s recommendations, want 1", len(found), tt.recType)
+			}
+			rec := found[0]
+
+			if tt.podCost == 0 {
+				if rec.GrossSavings != 0 || rec.MigrationCost != 0 {
+					t.Errorf("gross %v and migration %v reported without a reschedule cost", rec.GrossSavings, rec.MigrationCost)
+				}
+				return
+			}
+			if rec.MigrationCost != tt.wantMigration {
+				t.Errorf("MigrationCost = %v, want %v", rec.MigrationCost, tt.wantMigration)
+			}
+			if rec.GrossSavings <= 0 || math.Abs(rec.GrossSavings-rec.MigrationCost-rec.Savings) > 1e-9 {
+				t.Errorf("Savings %v is not gross %v net of migration %v", rec.Savings, rec.GrossSavings, rec.MigrationCost)
+			}
+		})
+	}
+}
+
+func TestFewestPods(t *testing.T) {
+	tests := []struct {
+		counts []int
+		n      int
+		want   int
+	}{
+		{counts: []int{5, 1, 2}, n: 2, want: 3},
+		{counts: []int{5, 1, 2}, n: 0, want: 0},
+		{counts: []int{5, 1, 2}, n: 5, want: 8},
+	}
+
+	for _, tt := range tests {
+		if got := fewestPods(tt.counts, tt.n); got != tt.want {
+			t.Errorf("fewestPods(%v, %d) = %d, want %d", tt.counts, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// defaultCostPrecision is the number of decimal places monetary values are
+// serialized with
+const defaultCostPrecision = 2
+
+// costPrecision applies to every marshaled monetary field. It is set once at
+// startup, before anything is served.
+var costPrecision = defaultCostPrecision
+
+// roundMoney rounds a monetary value to costPrecision decimal places, so
+// floating point noise like 23.999999999996 isn't exposed
+func roundMoney(value float64) float64 {
+	scale := math.Pow(10, float64(costPrecision))
+	return math.Round(value*scale) / scale
+}
+
+func roundMoneyMap(values map[string]float64) map[string]float64 {
+	if values == nil {
+		return nil
+	}
+	rounded := make(map[string]float64, len(values))
+	for key, value := range values {
+		rounded[key] = roundMoney(value)
+	}
+	return rounded
+}
+
+// The marshalers below round monetary fields at serialization time only;
+// calculations keep full precision.
+
+func (n NodeMetrics) MarshalJSON() ([]byte, error) {
+	type plain NodeMetrics
+	n.EstimatedCost = roundMoney(n.EstimatedCost)
+	return json.Marshal(plain(n))
+}
+
+func (p PodMetrics) MarshalJSON() ([]byte, error) {
+	type plain PodMetrics
+	p.EstimatedCost = roundMoney(p.EstimatedCost)
+	return json.Marshal(plain(p))
+}
+
+func (r Recommendation) MarshalJSON() ([]byte, error) {
+	type plain Recommendation
+	r.Savings = roundMoney(r.Savings)
+	r.GrossSavings = roundMoney(r.GrossSavings)
+	r.MigrationCost = roundMoney(r.MigrationCost)
+	r.SavingsLow = roundMoney(r.SavingsLow)
+	r.SavingsHigh = roundMoney(r.SavingsHigh)
+	return json.Marshal(plain(r))
+}
+
+func (s ClusterCostSummary) MarshalJSON() ([]byte, error) {
+	type plain ClusterCostSummary
+	s.TotalMonthlyCost = roundMoney(s.TotalMonthlyCost)
+	s.ComputeCost = roundMoney(s.ComputeCost)
+	s.StorageCost = roundMoney(s.StorageCost)
+	s.StorageCostByClass = roundMoneyMap(s.StorageCostByClass)
+	s.EphemeralStorageCost = roundMoney(s.EphemeralStorageCost)
+	s.NetworkCost = roundMoney(s.NetworkCost)
+	s.WastedResources = roundMoney(s.WastedResources)
+	s.PotentialSavings = roundMoney(s.PotentialSavings)
+	s.ProjectedCostIncrease = roundMoney(s.ProjectedCostIncrease)
+	s.UnallocatedCost = roundMoney(s.UnallocatedCost)
+	s.NamespaceCosts = roundMoneyMap(s.NamespaceCosts)
+	s.NamespaceUsedCost = roundMoneyMap(s.NamespaceUsedCost)
+	s.NamespaceIdleCost = roundMoneyMap(s.NamespaceIdleCost)
+	s.CostByRelease = roundMoneyMap(s.CostByRelease)
+	s.LabelCosts = roundMoneyMap(s.LabelCosts)
+	return json.Marshal(plain(s))
+}
+
+func (p CostSummaryPoint) MarshalJSON() ([]byte, error) {
+	type plain CostSummaryPoint
+	p.TotalMonthlyCost = roundMoney(p.TotalMonthlyCost)
+	p.ComputeCost = roundMoney(p.ComputeCost)
+	p.StorageCost = roundMoney(p.StorageCost)
+	p.WastedResources = roundMoney(p.WastedResources)
+	p.PotentialSavings = roundMoney(p.PotentialSavings)
+	return json.Marshal(plain(p))
+}
+
+func (t CostTrend) MarshalJSON() ([]byte, error) {
+	type plain CostTrend
+	t.TotalMonthlyCost = t.TotalMonthlyCost.rounded()
+	t.ComputeCost = t.ComputeCost.rounded()
+	t.StorageCost = t.StorageCost.rounded()
+	t.WastedResources = t.WastedResources.rounded()
+	t.PotentialSavings = t.PotentialSavings.rounded()
+	return json.Marshal(plain(t))
+}
+
+// rounded rounds a monetary delta, and its percentage to two places
+func (d CostDelta) rounded() CostDelta {
+	d.Start = roundMoney(d.Start)
+	d.End = roundMoney(d.End)
+	d.Change = roundMoney(d.Change)
+	if d.ChangePercent != nil {
+		percent := math.Round(*d.ChangePercent*100) / 100
+		d.ChangePercent = &percent
+	}
+	return d
+}
+
+func (w WorkloadEfficiency) MarshalJSON() ([]byte, error) {
+	type plain WorkloadEfficiency
+	w.MonthlyCost = roundMoney(w.MonthlyCost)
+	w.CostPerMillionRequests = roundMoney(w.CostPerMillionRequests)
+	return json.Marshal(plain(w))
+}
+
+func (c ClusterStatus) MarshalJSON() ([]byte, error) {
+	type plain ClusterStatus
+	c.TotalMonthlyCost = roundMoney(c.TotalMonthlyCost)
+	return json.Marshal(plain(c))
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"path"
+)
+
+// defaultExcludedNamespaces hold cluster components nobody can act on. Setting
+// excluded_namespaces in the config file replaces this list entirely.
+var defaultExcludedNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// excludedNamespaceBucket collects the cost of excluded namespaces in the
+// summary so totals still add up. Parentheses can't occur in namespace names.
+const excludedNamespaceBucket = "(excluded)"
+
+// validateNamespacePatterns checks that every entry is an exact name or a
+// valid glob such as "vendor-*"
+func validateNamespacePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid namespace pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// namespaceExcluded reports whether a namespace matches the denylist
+func (co *CostOptimizer) namespaceExcluded(namespace string) bool {
+	for _, pattern := range co.excludedNamespaces {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// costBucket is the namespace a pod's cost is attributed to in the summary
+func (co *CostOptimizer) costBucket(namespace string) string {
+	if co.namespaceExcluded(namespace) {
+		return excludedNamespaceBucket
+	}
+	return namespace
+}
+
+// dropExcludedNamespaces removes recommendations for resources in denylisted
+// or dropped namespaces. Cluster-scoped recommendations have no namespace and
+// are kept.
+func (co *CostOptimizer) dropExcludedNamespaces(recommendations []Recommendation) []Recommendation {
+	kept := recommendations[:0]
+	for _, rec := range recommendations {
+		if rec.Namespace != "" && (co.namespaceExcluded(rec.Namespace) || co.namespaceDropped(rec.Namespace)) {
+			continue
+		}
+		kept = append(kept, rec)
+	}
+	return kept
+}
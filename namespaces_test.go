@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestExcludedNamespacesProduceNoRecommendations(t *testing.T) {
+	tests := []struct {
+		name     string
+		excluded []string
+		want     map[string]bool // namespaces expected to have recommendations
+	}{
+		{name: "defaults", excluded: defaultExcludedNamespaces, want: map[string]bool{"shop": true, "vendor-db": true}},
+		{name: "exact and glob", excluded: []string{"kube-system", "vendor-*"}, want: map[string]bool{"shop": true}},
+		{name: "empty list", excluded: []string{}, want: map[string]bool{"shop": true, "vendor-db": true, "kube-system": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Every pod requests far more CPU than it uses
+			objects := []runtime.Object{testNode("node-1", "8", "32Gi"), testNodeMetrics("node-1", "1", "8Gi")}
+			for _, namespace := range []string{"shop", "vendor-db", "kube-system"} {
+				objects = append(objects,
+					testPod(namespace, "app", "node-1", "2", "1Gi"),
+					testPodMetrics(namespace, "app", "100m", "1Gi"))
+			}
+			co, _ := newTestOptimizer(t, objects...)
+			co.excludedNamespaces = tt.excluded
+
+			co.analyzeAndGenerateRecommendations(context.Background())
+
+			got := make(map[string]bool)
+			for _, rec := range co.recommendations {
+				if rec.Namespace != "" {
+					got[rec.Namespace] = true
+				}
+			}
+			for namespace := range got {
+				if !tt.want[namespace] {
+					t.Errorf("got recommendations for excluded namespace %s", namespace)
+				}
+			}
+			for namespace := range tt.want {
+				if !got[namespace] {
+					t.Errorf("no recommendations for namespace %s", namespace)
+				}
+			}
+		})
+	}
+}
+
+func TestExcludedNamespaceCostBucket(t *testing.T) {
+	co, _ := newTestOptimizer(t,
+		testNode("node-1", "4", "16Gi"), testNodeMetrics("node-1", "1", "4Gi"),
+		testPod("shop", "api", "node-1", "1", "2Gi"), testPodMetrics("shop", "api", "500m", "1Gi"),
+		testPod("kube-system", "dns", "node-1", "1", "2Gi"), testPodMetrics("kube-system", "dns", "500m", "1Gi"),
+	)
+
+	summary := co.generateCostSummary(context.Background())
+	if _, ok := summary.NamespaceCosts["kube-system"]; ok {
+		t.Error("kube-system has its own cost bucket, want it folded into the excluded bucket")
+	}
+	if summary.NamespaceCosts[excludedNamespaceBucket] == 0 || summary.NamespaceCosts["shop"] == 0 {
+		t.Errorf("NamespaceCosts = %v, want both shop and %s costed", summary.NamespaceCosts, excludedNamespaceBucket)
+	}
+}
+
+func TestLoadFileConfigExcludedNamespaces(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    *[]string
+		wantErr bool
+	}{
+		{name: "unset keeps defaults", config: `{}`},
+		{name: "empty list", config: `{"excluded_namespaces": []}`, want: &[]string{}},
+		{name: "globs", config: `{"excluded_namespaces": ["kube-*", "istio-system"]}`, want: &[]string{"kube-*", "istio-system"}},
+		{name: "bad glob", config: `{"excluded_namespaces": ["vendor-["]}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(tt.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := loadFileConfig(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("loadFileConfig succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (cfg.ExcludedNamespaces == nil) != (tt.want == nil) {
+				t.Fatalf("ExcludedNamespaces = %v, want %v", cfg.ExcludedNamespaces, tt.want)
+			}
+			if tt.want != nil && len(*cfg.ExcludedNamespaces) != len(*tt.want) {
+				t.Errorf("ExcludedNamespaces = %v, want %v", *cfg.ExcludedNamespaces, *tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// nodeGroupLabels identify the cloud node group (ASG/MIG/node pool) a node was
+// launched from, in the order they're checked
+var nodeGroupLabels = []string{
+	"eks.amazonaws.com/nodegroup",
+	"cloud.google.com/gke-nodepool",
+	"agentpool",
+}
+
+// nodeGroupTargetUtilization is the utilization a right-sized group should run at,
+// leaving headroom for spikes and rolling updates
+const nodeGroupTargetUtilization = 0.7
+
+// nodeGroupUsage aggregates capacity and usage across the nodes of one group
+type nodeGroupUsage struct {
+	Name           string
+	Nodes          int
+	CPUCapacity    int64 // millicores
+	CPUUsage       int64 // millicores
+	MemoryCapacity int64 // bytes
+	MemoryUsage    int64 // bytes
+	HourlyCost     float64
+	NodeNames      []string
+	Pods           []int     // reschedulable pods on each node
+	Utilization    []float64 // each node's higher of CPU and memory utilization, percent
+}
+
+// nodeGroupName returns the node group a node belongs to, or "" when it isn't
+// managed by a recognized group
+func nodeGroupName(node *corev1.Node) string {
+	for _, label := range nodeGroupLabels {
+		if group := node.Labels[label]; group != "" {
+			return group
+		}
+	}
+	return ""
+}
+
+func (g *nodeGroupUsage) add(name string, capacity, usage corev1.ResourceList, hourlyCost float64, pods int) {
+	g.Nodes++
+	g.NodeNames = append(g.NodeNames, name)
+	g.Pods = append(g.Pods, pods)
+	g.CPUCapacity += capacity.Cpu().MilliValue()
+	g.CPUUsage += usage.Cpu().MilliValue()
+	g.MemoryCapacity += capacity.Memory().Value()
+	g.MemoryUsage += usage.Memory().Value()
+	g.HourlyCost += hourlyCost
+}
+
+// requiredNodes estimates how many of the group's nodes would carry its current
+// load at the target utilization, assuming the nodes are uniformly sized
+func (g *nodeGroupUsage) requiredNodes() int {
+	if g.CPUCapacity == 0 || g.MemoryCapacity == 0 {
+		return g.Nodes
+	}
+	load := math.Max(float64(g.CPUUsage)/float64(g.CPUCapacity), float64(g.MemoryUsage)/float64(g.MemoryCapacity))
+	required := int(math.Ceil(load * float64(g.Nodes) / nodeGroupTargetUtilization))
+	if required < 1 {
+		required = 1
+	}
+	return required
+}
+
+// drainCandidates returns the n nodes with the fewest reschedulable pods,
+// which are the ones a scale-in would drain
+func (g *nodeGroupUsage) drainCandidates(n int) []string {
+	order := make([]int, len(g.NodeNames))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return g.Pods[order[a]] < g.Pods[order[b]] })
+
+	names := make([]string, 0, n)
+	for i := 0; i < n && i < len(order); i++ {
+		names = append(names, g.NodeNames[order[i]])
+	}
+	return names
+}
+
+// nodeGroupRecommendations suggests shrinking node groups whose aggregate usage
+// fits on fewer nodes. Groups scale as a unit, so this replaces per-node
+// underutilization findings for grouped nodes. A shrink whose drained pods
+// can't be placed on the remaining nodes is held back and recorded in blocked.
+func (co *CostOptimizer) nodeGroupRecommendations(groups map[string]*nodeGroupUsage, snapshot *schedulingSnapshot, blocked map[string][]string) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		group := groups[name]
+		required := group.requiredNodes()
+		if required >= group.Nodes {
+			continue
+		}
+		if unplaced := snapshot.simulateDrain(group.drainCandidates(group.Nodes - required)); len(unplaced) > 0 {
+			slog.Info("Not recommending shrinking node group: pods can't be placed on the rest", "node_group", group.Name, "nodes", required, "unplaced_pods", len(unplaced))
+			blocked["nodegroup/"+group.Name] = unplaced
+			continue
+		}
+
+		cpuUtil := float64(group.CPUUsage) / float64(group.CPUCapacity) * 100
+		memoryUtil := float64(group.MemoryUsage) / float64(group.MemoryCapacity) * 100
+		perNodeHourly := group.HourlyCost / float64(group.Nodes)
+
+		rec := Recommendation{
+			Type:        "node_group_rightsizing",
+			Category:    CategoryScale,
+			Resource:    group.Name,
+			Description: fmt.Sprintf("Node group %s is underutilized across %d nodes (CPU: %.1f%%, Memory: %.1f%%)", group.Name, group.Nodes, cpuUtil, memoryUtil),
+			Impact:      fmt.Sprintf("Reduce node group %s size from %d to %d", group.Name, group.Nodes, required),
+			ActionHint: &ActionHint{
+				Verb:         "scale",
+				Target:       hintTarget("nodegroup", "", group.Name),
+				Field:        "size",
+				CurrentValue: strconv.Itoa(group.Nodes),
+				NewValue:     strconv.Itoa(required),
+			},
+			Savings:   perNodeHourly * float64(group.Nodes-required) * 24 * 30,
+			Priority:  "medium",
+			Timestamp: time.Now(),
+		}
+		co.applyMigrationCost(&rec, fewestPods(group.Pods, group.Nodes-required))
+		recommendations = append(recommendations, rec)
+	}
+
+	return recommendations
+}
+
+// NodeGroupCost is the blended cost of a node group that may mix on-demand
+// and spot instances. Rates are per node per hour and left unrounded, since
+// hourly prices routinely need more than cent precision.
+type NodeGroupCost struct {
+	Nodes              int     `json:"nodes"`
+	SpotNodes          int     `json:"spot_nodes"`
+	OnDemandHourlyRate float64 `json:"on_demand_hourly_rate"` // average list price
+	BlendedHourlyRate  float64 `json:"blended_hourly_rate"`   // average after spot discounts
+	MonthlyCost        float64 `json:"monthly_cost"`
+}
+
+func (c NodeGroupCost) MarshalJSON() ([]byte, error) {
+	type plain NodeGroupCost
+	c.MonthlyCost = roundMoney(c.MonthlyCost)
+	return json.Marshal(plain(c))
+}
+
+// effectiveNodeHourlyCost is what a node actually costs per hour. A configured
+// cost expression already sees the spot flag, so only table prices are
+// discounted here.
+func (co *CostOptimizer) effectiveNodeHourlyCost(node *corev1.Node) (listPrice, effective float64) {
+	instanceType := co.pricing.instanceType(node)
+	if cost, ok := co.modelNodeCost(node, instanceType); ok {
+		return cost, cost
+	}
+	listPrice = co.nodeHourlyCost(node)
+	if isSpotNode(node) {
+		return listPrice, listPrice * co.spotPriceFactor
+	}
+	return listPrice, listPrice
+}
+
+// nodeGroupCosts blends each node group's on-demand and spot instances into
+// one effective per-node rate, weighted by instance count
+func (co *CostOptimizer) nodeGroupCosts(nodes []corev1.Node) map[string]NodeGroupCost {
+	costs := make(map[string]NodeGroupCost)
+	for i := range nodes {
+		node := &nodes[i]
+		group := nodeGroupName(node)
+		if group == "" {
+			continue
+		}
+
+		listPrice, effective := co.effectiveNodeHourlyCost(node)
+		cost := costs[group]
+		cost.Nodes++
+		if isSpotNode(node) {
+			cost.SpotNodes++
+		}
+		cost.OnDemandHourlyRate += listPrice
+		cost.BlendedHourlyRate += effective
+		costs[group] = cost
+	}
+
+	for group, cost := range costs {
+		cost.MonthlyCost = cost.BlendedHourlyRate * 24 * 30
+		cost.OnDemandHourlyRate /= float64(cost.Nodes)
+		cost.BlendedHourlyRate /= float64(cost.Nodes)
+		costs[group] = cost
+	}
+	return costs
+}
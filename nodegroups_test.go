@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// testNodeGroup is count nodes of 4 CPUs and 16Gi in group, under label,
+// each using cpu and memory
+func testNodeGroup(label, group string, count int, cpu, memory string) []runtime.Object {
+	var objects []runtime.Object
+	for i := 1; i <= count; i++ {
+		name := fmt.Sprintf("%s-%d", group, i)
+		node := testNode(name, "4", "16Gi")
+		node.Labels = map[string]string{label: group}
+		objects = append(objects, node, testNodeMetrics(name, cpu, memory))
+	}
+	return objects
+}
+
+func TestNodeGroupRecommendations(t *testing.T) {
+	tests := []struct {
+		name        string
+		nodes       []runtime.Object
+		wantImpact  string
+		wantRemoved int
+	}{
+		{
+			name:        "idle EKS node group",
+			nodes:       testNodeGroup("eks.amazonaws.com/nodegroup", "workers", 5, "400m", "1Gi"),
+			wantImpact:  "Reduce node group workers size from 5 to 1",
+			wantRemoved: 4,
+		},
+		{
+			name:        "half-used GKE node pool",
+			nodes:       testNodeGroup("cloud.google.com/gke-nodepool", "pool", 4, "2", "4Gi"),
+			wantImpact:  "Reduce node group pool size from 4 to 3",
+			wantRemoved: 1,
+		},
+		{
+			name:  "busy AKS agent pool",
+			nodes: testNodeGroup("agentpool", "busy", 3, "3", "8Gi"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t, tt.nodes...)
+
+			var groups []Recommendation
+			for _, rec := range co.analyzeNodes(context.Background()) {
+				switch rec.Type {
+				case "node_group_rightsizing":
+					groups = append(groups, rec)
+				case "node_optimization":
+					t.Errorf("grouped node %s got a per-node recommendation", rec.Resource)
+				}
+			}
+			if tt.wantImpact == "" {
+				if len(groups) != 0 {
+					t.Errorf("got %+v, want no node group recommendation", groups)
+				}
+				return
+			}
+			if len(groups) != 1 {
+				t.Fatalf("got %d node group recommendations, want 1", len(groups))
+			}
+			perNode := co.nodeHourlyCost(testNode("any", "4", "16Gi")) * 24 * 30
+			if rec := groups[0]; rec.Impact != tt.wantImpact || math.Abs(rec.Savings-perNode*float64(tt.wantRemoved)) > 1e-9 {
+				t.Errorf("got %q saving %v, want %q saving %v", rec.Impact, rec.Savings, tt.wantImpact, perNode*float64(tt.wantRemoved))
+			}
+		})
+	}
+}
+
+// TestUngroupedIdleNode keeps per-node findings for nodes outside any group
+func TestUngroupedIdleNode(t *testing.T) {
+	co, _ := newTestOptimizer(t, testNode("standalone", "4", "16Gi"), testNodeMetrics("standalone", "100m", "1Gi"))
+
+	recommendations := co.analyzeNodes(context.Background())
+	if len(recommendations) != 1 || recommendations[0].Type != "node_optimization" || recommendations[0].Resource != "standalone" {
+		t.Errorf("got %+v, want one node_optimization for standalone", recommendations)
+	}
+}
+
+func TestNodeGroupCosts(t *testing.T) {
+	groupNode := func(name, group string, spot bool) *corev1.Node {
+		node := testNode(name, "4", "16Gi")
+		node.Labels = map[string]string{"eks.amazonaws.com/nodegroup": group}
+		if spot {
+			node.Labels["eks.amazonaws.com/capacityType"] = "SPOT"
+		}
+		return node
+	}
+
+	tests := []struct {
+		name       string
+		nodes      []*corev1.Node
+		expression string
+		want       map[string]NodeGroupCost
+	}{
+		{
+			name:  "on-demand only",
+			nodes: []*corev1.Node{groupNode("a-1", "a", false), groupNode("a-2", "a", false)},
+			want:  map[string]NodeGroupCost{"a": {Nodes: 2, OnDemandHourlyRate: 1, BlendedHourlyRate: 1, MonthlyCost: 1440}},
+		},
+		{
+			name: "mixed on-demand and spot",
+			nodes: []*corev1.Node{
+				groupNode("a-1", "a", false), groupNode("a-2", "a", false),
+				groupNode("a-3", "a", true), groupNode("a-4", "a", true),
+			},
+			// Two nodes at 1.00 and two at 0.30 average to 0.65
+			want: map[string]NodeGroupCost{"a": {Nodes: 4, SpotNodes: 2, OnDemandHourlyRate: 1, BlendedHourlyRate: 0.65, MonthlyCost: 1872}},
+		},
+		{
+			name:  "groups blended separately, ungrouped nodes left out",
+			nodes: []*corev1.Node{groupNode("a-1", "a", false), groupNode("b-1", "b", true), testNode("loose", "4", "16Gi")},
+			want: map[string]NodeGroupCost{
+				"a": {Nodes: 1, OnDemandHourlyRate: 1, BlendedHourlyRate: 1, MonthlyCost: 720},
+				"b": {Nodes: 1, SpotNodes: 1, OnDemandHourlyRate: 1, BlendedHourlyRate: 0.3, MonthlyCost: 216},
+			},
+		},
+		{
+			name:       "cost expression prices spot itself",
+			nodes:      []*corev1.Node{groupNode("a-1", "a", false), groupNode("a-2", "a", true)},
+			expression: "spot ? tableCost * 0.5 : tableCost",
+			want:       map[string]NodeGroupCost{"a": {Nodes: 2, SpotNodes: 1, OnDemandHourlyRate: 0.75, BlendedHourlyRate: 0.75, MonthlyCost: 1080}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t)
+			co.costCalculator.NodeCostPerHour = map[string]float64{"default": 1}
+			if tt.expression != "" {
+				model, err := compileCostModel(tt.expression)
+				if err != nil {
+					t.Fatal(err)
+				}
+				co.costModel = model
+			}
+			nodes := make([]corev1.Node, 0, len(tt.nodes))
+			for _, node := range tt.nodes {
+				nodes = append(nodes, *node)
+			}
+
+			got := co.nodeGroupCosts(nodes)
+			if len(got) != len(tt.want) {
+				t.Fatalf("costs for %d groups, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for group, want := range tt.want {
+				cost := got[group]
+				if cost.Nodes != want.Nodes || cost.SpotNodes != want.SpotNodes ||
+					math.Abs(cost.OnDemandHourlyRate-want.OnDemandHourlyRate) > 1e-9 ||
+					math.Abs(cost.BlendedHourlyRate-want.BlendedHourlyRate) > 1e-9 ||
+					math.Abs(cost.MonthlyCost-want.MonthlyCost) > 1e-6 {
+					t.Errorf("group %s costs %+v, want %+v", group, cost, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCostSummaryNodeGroupCosts(t *testing.T) {
+	objects := testNodeGroup("cloud.google.com/gke-nodepool", "pool", 2, "1", "1Gi")
+	spot := testNode("pool-spot", "4", "16Gi")
+	spot.Labels = map[string]string{"cloud.google.com/gke-nodepool": "pool", "cloud.google.com/gke-spot": "true"}
+	objects = append(objects, spot, testNodeMetrics("pool-spot", "1", "1Gi"))
+	co, _ := newTestOptimizer(t, objects...)
+
+	summary := co.generateCostSummary(context.Background())
+	pool, ok := summary.NodeGroupCosts["pool"]
+	if !ok || pool.Nodes != 3 || pool.SpotNodes != 1 {
+		t.Fatalf("node group costs %+v, want pool with 3 nodes, 1 spot", summary.NodeGroupCosts)
+	}
+	if want := pool.OnDemandHourlyRate * (2 + defaultSpotPriceFactor) / 3; math.Abs(pool.BlendedHourlyRate-want) > 1e-9 {
+		t.Errorf("blended rate %v, want %v", pool.BlendedHourlyRate, want)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Notification is a message pushed to operators outside of the API
+type Notification struct {
+	Title    string
+	Text     string
+	Priority string
+}
+
+// Notifier delivers notifications to an external channel
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// webhookNotifier posts notifications in the Slack incoming-webhook format,
+// which most chat tools and webhook relays also accept.
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func newWebhookNotifier(url string) *webhookNotifier {
+	return &webhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	payload, err := json.Marshal(map[string]string{
+		"text": fmt.Sprintf("*%s*\n%s", notification.Title, notification.Text),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// notify sends a notification if a notifier is configured, logging failures
+// rather than interrupting the caller.
+func (co *CostOptimizer) notify(ctx context.Context, notification Notification) {
+	if co.notifier == nil {
+		return
+	}
+	if err := co.notifier.Notify(ctx, notification); err != nil {
+		slog.Error("Failed to send notification", "title", notification.Title, "error", err)
+	}
+}
+
+// selfNotifyingTypes are recommendation types whose analyzers notify as they
+// raise them, so they're left out of the new-recommendation notifications
+var selfNotifyingTypes = map[string]bool{
+	"budget_breach": true,
+	"cost_spike":    true,
+}
+
+// notifiableKeys returns the IDs of the high-priority recommendations that
+// are announced through the notifier
+func notifiableKeys(recommendations []Recommendation) map[string]bool {
+	keys := make(map[string]bool)
+	for _, rec := range recommendations {
+		if rec.Priority == "high" && !selfNotifyingTypes[rec.Type] {
+			keys[rec.ID] = true
+		}
+	}
+	return keys
+}
+
+// notifyNewRecommendations sends one notification for each high-priority
+// recommendation that wasn't present in the previous scan. Like exported
+// logs, a finding that clears and later returns is announced again.
+func (co *CostOptimizer) notifyNewRecommendations(ctx context.Context, recommendations []Recommendation) {
+	if co.notifier == nil {
+		return
+	}
+
+	current := notifiableKeys(recommendations)
+	announced := make(map[string]bool)
+	for _, rec := range recommendations {
+		key := rec.ID
+		if !current[key] || co.notifiedRecommendations[key] || announced[key] {
+			continue
+		}
+		// A failed delivery is logged and not retried, so an unreachable
+		// webhook doesn't resend everything once it recovers
+		announced[key] = true
+		co.notify(ctx, Notification{
+			Title:    fmt.Sprintf("New high-priority recommendation: %s %s", rec.Type, rec.Resource),
+			Text:     fmt.Sprintf("%s\n%s (estimated savings $%.2f/month)", rec.Description, rec.Impact, rec.Savings),
+			Priority: rec.Priority,
+		})
+	}
+	co.notifiedRecommendations = current
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "delivered", status: http.StatusOK},
+		{name: "rejected", status: http.StatusInternalServerError, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload map[string]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Content-Type"); got != "application/json" {
+					t.Errorf("Content-Type = %q, want application/json", got)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+					t.Errorf("decode payload: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			err := newWebhookNotifier(server.URL).Notify(context.Background(), Notification{Title: "Budget exceeded: team", Text: "over by $10"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Notify error = %v, want error %v", err, tt.wantErr)
+			}
+			if want := "*Budget exceeded: team*\nover by $10"; payload["text"] != want {
+				t.Errorf("text = %q, want %q", payload["text"], want)
+			}
+		})
+	}
+}
+
+func TestNotifyNewRecommendations(t *testing.T) {
+	idle := Recommendation{Type: "idle_node", Resource: "node-1", Priority: "high"}
+	rightsize := Recommendation{Type: "rightsizing", Resource: "shop/web", Priority: "medium"}
+	breach := Recommendation{Type: "budget_breach", Resource: "team", Priority: "high"}
+	drainHint := Recommendation{Type: "idle_node", Resource: "node-1", Priority: "high",
+		ActionHint: &ActionHint{Verb: "drain", Target: "node-1"}}
+	cordonHint := Recommendation{Type: "idle_node", Resource: "node-1", Priority: "high",
+		ActionHint: &ActionHint{Verb: "patch", Target: "node-1", Field: "spec.unschedulable"}}
+	tests := []struct {
+		name  string
+		scans [][]Recommendation
+		want  []string
+	}{
+		{
+			name:  "new high-priority recommendation",
+			scans: [][]Recommendation{{rightsize}, {rightsize, idle}},
+			want:  []string{"New high-priority recommendation: idle_node node-1"},
+		},
+		{
+			name:  "sent once while it persists",
+			scans: [][]Recommendation{{idle}, {idle}, {idle, rightsize}},
+			want:  []string{"New high-priority recommendation: idle_node node-1"},
+		},
+		{
+			name:  "sent again when it returns",
+			scans: [][]Recommendation{{idle}, {}, {idle}},
+			want:  []string{"New high-priority recommendation: idle_node node-1", "New high-priority recommendation: idle_node node-1"},
+		},
+		{
+			name:  "two findings on one resource",
+			scans: [][]Recommendation{{drainHint}, {drainHint, cordonHint}},
+			want:  []string{"New high-priority recommendation: idle_node node-1", "New high-priority recommendation: idle_node node-1"},
+		},
+		{name: "lower priority", scans: [][]Recommendation{{rightsize}}},
+		{name: "analyzer notifies itself", scans: [][]Recommendation{{breach}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t)
+			notifier := &recordingNotifier{}
+			co.notifier = notifier
+			for _, scan := range tt.scans {
+				scan = append([]Recommendation(nil), scan...)
+				stampIDs(scan)
+				co.notifyNewRecommendations(context.Background(), scan)
+			}
+			var titles []string
+			for _, notification := range notifier.sent {
+				titles = append(titles, notification.Title)
+			}
+			if !reflect.DeepEqual(titles, tt.want) {
+				t.Errorf("notified %q, want %q", titles, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Severity numbers from the OpenTelemetry log data model
+const (
+	otlpSeverityInfo = 9
+	otlpSeverityWarn = 13
+)
+
+// LogExporter ships recommendation log records to an OpenTelemetry collector
+type LogExporter interface {
+	ExportLogs(ctx context.Context, records []Recommendation) error
+}
+
+// otlpLogExporter sends OTLP/HTTP JSON log records. It speaks the wire format
+// directly so a single POST per scan doesn't pull in the OpenTelemetry SDK.
+type otlpLogExporter struct {
+	endpoint    string
+	headers     map[string]string
+	serviceName string
+	clusterName string
+	client      *http.Client
+}
+
+// newOTLPLogExporterFromEnv honors the standard OTEL_EXPORTER_OTLP_* variables.
+// It returns nil when no endpoint is configured.
+func newOTLPLogExporterFromEnv(clusterName string) (*otlpLogExporter, error) {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT")
+	if endpoint == "" {
+		base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if base == "" {
+			return nil, nil
+		}
+		endpoint = strings.TrimRight(base, "/") + "/v1/logs"
+	}
+
+	headers := make(map[string]string)
+	for _, name := range []string{"OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_LOGS_HEADERS"} {
+		raw := os.Getenv(name)
+		if raw == "" {
+			continue
+		}
+		for _, pair := range strings.Split(raw, ",") {
+			key, value, ok := strings.Cut(pair, "=")
+			if !ok || strings.TrimSpace(key) == "" {
+				return nil, fmt.Errorf("invalid %s: expected key=value pairs separated by commas", name)
+			}
+			headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = "optimkube"
+	}
+
+	return &otlpLogExporter{
+		endpoint:    endpoint,
+		headers:     headers,
+		serviceName: serviceName,
+		clusterName: clusterName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+func stringAttribute(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+func doubleAttribute(key string, value float64) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{DoubleValue: &value}}
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string          `json:"timeUnixNano"`
+	ObservedTimeUnixNano string          `json:"observedTimeUnixNano"`
+	SeverityNumber       int             `json:"severityNumber"`
+	SeverityText         string          `json:"severityText"`
+	Body                 otlpValue       `json:"body"`
+	Attributes           []otlpAttribute `json:"attributes"`
+}
+
+// otlpLogRecordFor maps a recommendation onto a log record with its fields as
+// attributes, so collectors can filter and aggregate without parsing the body
+func otlpLogRecordFor(rec Recommendation, observed time.Time) otlpLogRecord {
+	severity, severityText := otlpSeverityInfo, "INFO"
+	if rec.Priority == "high" {
+		severity, severityText = otlpSeverityWarn, "WARN"
+	}
+
+	attributes := []otlpAttribute{
+		stringAttribute("optimkube.recommendation.type", rec.Type),
+		stringAttribute("optimkube.recommendation.resource", rec.Resource),
+		stringAttribute("optimkube.recommendation.priority", rec.Priority),
+		doubleAttribute("optimkube.recommendation.savings", rec.Savings),
+		stringAttribute("optimkube.recommendation.impact", rec.Impact),
+	}
+	if rec.Namespace != "" {
+		attributes = append(attributes, stringAttribute("k8s.namespace.name", rec.Namespace))
+	}
+
+	body := rec.Description
+	return otlpLogRecord{
+		TimeUnixNano:         strconv.FormatInt(rec.Timestamp.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(observed.UnixNano(), 10),
+		SeverityNumber:       severity,
+		SeverityText:         severityText,
+		Body:                 otlpValue{StringValue: &body},
+		Attributes:           attributes,
+	}
+}
+
+func (e *otlpLogExporter) ExportLogs(ctx context.Context, records []Recommendation) error {
+	observed := time.Now()
+	logRecords := make([]otlpLogRecord, 0, len(records))
+	for _, rec := range records {
+		logRecords = append(logRecords, otlpLogRecordFor(rec, observed))
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"resourceLogs": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": []otlpAttribute{
+					stringAttribute("service.name", e.serviceName),
+					stringAttribute("k8s.cluster.name", e.clusterName),
+				},
+			},
+			"scopeLogs": []interface{}{map[string]interface{}{
+				"scope":      map[string]string{"name": "optimkube"},
+				"logRecords": logRecords,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("collector returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// emitNewRecommendations exports recommendations whose IDs weren't present in
+// the previous scan. A finding that clears and later returns is emitted again.
+func (co *CostOptimizer) emitNewRecommendations(ctx context.Context, recommendations []Recommendation) {
+	if co.logExporter == nil {
+		return
+	}
+
+	seen := make(map[string]bool, len(recommendations))
+	fresh := make([]Recommendation, 0)
+	for _, rec := range recommendations {
+		if !co.emittedRecommendations[rec.ID] && !seen[rec.ID] {
+			fresh = append(fresh, rec)
+		}
+		seen[rec.ID] = true
+	}
+
+	if len(fresh) > 0 {
+		if err := co.logExporter.ExportLogs(ctx, fresh); err != nil {
+			slog.Error("Failed to export recommendation logs", "error", err)
+			return
+		}
+	}
+	co.emittedRecommendations = seen
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// memoryLogExporter captures exported records per call
+type memoryLogExporter struct {
+	mu      sync.Mutex
+	batches [][]Recommendation
+}
+
+func (e *memoryLogExporter) ExportLogs(ctx context.Context, records []Recommendation) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.batches = append(e.batches, append([]Recommendation(nil), records...))
+	return nil
+}
+
+func TestEmitNewRecommendationsAcrossScans(t *testing.T) {
+	// An over-provisioned pod yields recommendations on every scan; node-2
+	// has room for it, so node-1's drain stays feasible throughout. A bare
+	// pod pins node-2, so node-1 is the one consolidation removes.
+	co, clientset := newTestOptimizer(t,
+		testNode("node-1", "8", "32Gi"), testNodeMetrics("node-1", "1", "8Gi"),
+		testNode("node-2", "8", "32Gi"), testPod("shop", "debug", "node-2", "100m", "128Mi"),
+		controlled(testPod("shop", "api", "node-1", "2", "1Gi")), testPodMetrics("shop", "api", "100m", "1Gi"),
+	)
+	exporter := &memoryLogExporter{}
+	co.logExporter = exporter
+	// Each call stands for a scan of the cluster as it is then
+	co.listCache.ttl = 0
+
+	co.analyzeAndGenerateRecommendations(context.Background())
+	if len(exporter.batches) != 1 || len(exporter.batches[0]) == 0 {
+		t.Fatalf("first scan exported %v, want one batch with every recommendation", exporter.batches)
+	}
+
+	co.analyzeAndGenerateRecommendations(context.Background())
+	if len(exporter.batches) != 1 {
+		t.Fatalf("unchanged scan exported %v, want nothing new", exporter.batches[1:])
+	}
+
+	// A finding that clears and later returns is emitted again
+	pods := clientset.CoreV1().Pods("shop")
+	if err := pods.Delete(context.Background(), "api", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	co.analyzeAndGenerateRecommendations(context.Background())
+	if _, err := pods.Create(context.Background(), controlled(testPod("shop", "api", "node-1", "2", "1Gi")), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	co.analyzeAndGenerateRecommendations(context.Background())
+	if len(exporter.batches) != 2 || len(exporter.batches[1]) == 0 {
+		t.Fatalf("exported batches %v, want the returning findings once more", exporter.batches)
+	}
+	for _, rec := range exporter.batches[1] {
+		if rec.Resource != "shop/api" {
+			t.Errorf("re-exported the unchanged finding %s %s", rec.Type, rec.Resource)
+		}
+	}
+}
+
+func TestEmitNewRecommendationsByID(t *testing.T) {
+	replicas := Recommendation{Type: "hpa_tuning", Namespace: "shop", Resource: "shop/api",
+		ActionHint: &ActionHint{Target: "shop/api", Field: "minReplicas", NewValue: "2"}}
+	utilization := replicas
+	utilization.ActionHint = &ActionHint{Target: "shop/api", Field: "targetCPUUtilizationPercentage", NewValue: "70"}
+
+	tests := []struct {
+		name     string
+		previous []Recommendation
+		current  []Recommendation
+		want     int
+	}{
+		{name: "two findings on one resource", current: []Recommendation{replicas, utilization}, want: 2},
+		{name: "second finding appears", previous: []Recommendation{replicas}, current: []Recommendation{replicas, utilization}, want: 1},
+		{name: "unchanged", previous: []Recommendation{replicas, utilization}, current: []Recommendation{replicas, utilization}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t)
+			exporter := &memoryLogExporter{}
+			co.logExporter = exporter
+			previous := append([]Recommendation(nil), tt.previous...)
+			stampIDs(previous)
+			co.emitNewRecommendations(context.Background(), previous)
+			exporter.batches = nil
+
+			current := append([]Recommendation(nil), tt.current...)
+			stampIDs(current)
+			co.emitNewRecommendations(context.Background(), current)
+			got := 0
+			for _, batch := range exporter.batches {
+				got += len(batch)
+			}
+			if got != tt.want {
+				t.Errorf("exported %d recommendations, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOTLPLogExporter(t *testing.T) {
+	var got struct {
+		ResourceLogs []struct {
+			Resource struct {
+				Attributes []otlpAttribute `json:"attributes"`
+			} `json:"resource"`
+			ScopeLogs []struct {
+				LogRecords []otlpLogRecord `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	var authorization string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+	}))
+	defer collector.Close()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", collector.URL+"/")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer secret")
+	exporter, err := newOTLPLogExporterFromEnv("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exporter.endpoint != collector.URL+"/v1/logs" {
+		t.Errorf("endpoint = %s, want the logs path under the base endpoint", exporter.endpoint)
+	}
+
+	rec := Recommendation{Type: "pod_rightsizing", Resource: "shop/api", Namespace: "shop", Priority: "high", Savings: 12.5, Description: "oversized", Timestamp: time.Now()}
+	if err := exporter.ExportLogs(context.Background(), []Recommendation{rec}); err != nil {
+		t.Fatal(err)
+	}
+
+	if authorization != "Bearer secret" {
+		t.Errorf("Authorization = %q, want the configured header", authorization)
+	}
+	if len(got.ResourceLogs) != 1 || len(got.ResourceLogs[0].ScopeLogs) != 1 || len(got.ResourceLogs[0].ScopeLogs[0].LogRecords) != 1 {
+		t.Fatalf("payload = %+v, want one log record", got)
+	}
+	record := got.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if record.SeverityText != "WARN" || *record.Body.StringValue != "oversized" {
+		t.Errorf("record = %s %q, want WARN with the description", record.SeverityText, *record.Body.StringValue)
+	}
+	attributes := make(map[string]otlpValue)
+	for _, attribute := range record.Attributes {
+		attributes[attribute.Key] = attribute.Value
+	}
+	for key, want := range map[string]string{
+		"optimkube.recommendation.type":     "pod_rightsizing",
+		"optimkube.recommendation.resource": "shop/api",
+		"optimkube.recommendation.priority": "high",
+		"k8s.namespace.name":                "shop",
+	} {
+		if value := attributes[key].StringValue; value == nil || *value != want {
+			t.Errorf("attribute %s = %v, want %q", key, value, want)
+		}
+	}
+	if savings := attributes["optimkube.recommendation.savings"].DoubleValue; savings == nil || *savings != 12.5 {
+		t.Errorf("savings attribute = %v, want 12.5", savings)
+	}
+}
+
+func TestOTLPLogExporterFromEnv(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		wantEndpoint string
+		wantErr      bool
+	}{
+		{name: "unconfigured"},
+		{name: "logs endpoint wins", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318", "OTEL_EXPORTER_OTLP_LOGS_ENDPOINT": "http://logs:4318/custom"}, wantEndpoint: "http://logs:4318/custom"},
+		{name: "bad headers", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318", "OTEL_EXPORTER_OTLP_HEADERS": "novalue"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_LOGS_ENDPOINT", "OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_LOGS_HEADERS"} {
+				t.Setenv(name, tt.env[name])
+			}
+			exporter, err := newOTLPLogExporterFromEnv("prod")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.wantEndpoint == "" {
+				if exporter != nil {
+					t.Errorf("exporter = %+v, want none when unconfigured", exporter)
+				}
+				return
+			}
+			if exporter.endpoint != tt.wantEndpoint {
+				t.Errorf("endpoint = %s, want %s", exporter.endpoint, tt.wantEndpoint)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PackingReport compares the node count to the fewest nodes that could hold
+// every pod's requests: the headline "this cluster could run on N% fewer
+// nodes". It is an optimistic estimate that ignores taints, affinity and
+// topology spread.
+type PackingReport struct {
+	CurrentNodes     int     `json:"current_nodes"`
+	IdealNodes       int     `json:"ideal_nodes"`
+	ReductionPercent float64 `json:"reduction_percent"`
+	CurrentCost      float64 `json:"current_monthly_cost"`
+	IdealCost        float64 `json:"ideal_monthly_cost"`
+	MonthlySavings   float64 `json:"monthly_savings"`
+	NodeType         string  `json:"node_type"` // type the added nodes would be
+}
+
+func (p PackingReport) MarshalJSON() ([]byte, error) {
+	type plain PackingReport
+	p.CurrentCost = roundMoney(p.CurrentCost)
+	p.IdealCost = roundMoney(p.IdealCost)
+	p.MonthlySavings = roundMoney(p.MonthlySavings)
+	return json.Marshal(plain(p))
+}
+
+// packingBin is one node of the ideal packing, with what's left of its
+// allocatable capacity
+type packingBin struct {
+	cpu, memory, pods int64
+}
+
+func (b *packingBin) fits(cpu, memory int64) bool {
+	return cpu <= b.cpu && memory <= b.memory && b.pods >= 1
+}
+
+func (b *packingBin) place(cpu, memory int64) {
+	b.cpu -= cpu
+	b.memory -= memory
+	b.pods--
+}
+
+// nodeShape is a node type available for the ideal packing
+type nodeShape struct {
+	name       string
+	allocated  packingBin // allocatable capacity less the per-node DaemonSet load
+	hourlyCost float64
+}
+
+// podMovable reports whether a pod could be rescheduled onto another node.
+// Static pods and pods without a controller would be lost, so they pin
+// their node.
+func podMovable(pod *corev1.Pod) bool {
+	if pod.Annotations[mirrorPodAnnotation] != "" {
+		return false
+	}
+	for _, ref := range pod.OwnerReferences {
+		if ref.Controller != nil && *ref.Controller {
+			return true
+		}
+	}
+	return false
+}
+
+// packingReport packs every movable pod's requests, largest first, onto the
+// nodes pinned by unmovable pods and then onto as few new nodes as possible.
+// Each node type in the cluster is tried as the type of the new nodes and the
+// cheapest result wins. Every node, pinned or new, carries the heaviest
+// per-node DaemonSet load seen in the cluster.
+func (co *CostOptimizer) packingReport(nodes []corev1.Node, pods []corev1.Pod) *PackingReport {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	type podLoad struct{ cpu, memory int64 }
+	daemonCPU := make(map[string]int64)
+	daemonMemory := make(map[string]int64)
+	daemonCount := make(map[string]int64)
+	pinned := make(map[string][]podLoad)
+	movable := make([]podLoad, 0)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		load := podLoad{podRequests(pod, corev1.ResourceCPU), podRequests(pod, corev1.ResourceMemory)}
+		switch {
+		case ownedByDaemonSet(pod):
+			daemonCPU[pod.Spec.NodeName] += load.cpu
+			daemonMemory[pod.Spec.NodeName] += load.memory
+			daemonCount[pod.Spec.NodeName]++
+		case !podMovable(pod):
+			pinned[pod.Spec.NodeName] = append(pinned[pod.Spec.NodeName], load)
+		default:
+			movable = append(movable, load)
+		}
+	}
+	var perNodeDaemonCPU, perNodeDaemonMemory, daemonPods int64
+	for name := range daemonCount {
+		perNodeDaemonCPU = max(perNodeDaemonCPU, daemonCPU[name])
+		perNodeDaemonMemory = max(perNodeDaemonMemory, daemonMemory[name])
+		daemonPods = max(daemonPods, daemonCount[name])
+	}
+
+	capacity := func(node *corev1.Node) packingBin {
+		allocatable := node.Status.Allocatable
+		return packingBin{
+			cpu:    allocatable.Cpu().MilliValue() - perNodeDaemonCPU,
+			memory: allocatable.Memory().Value() - perNodeDaemonMemory,
+			pods:   allocatable.Pods().Value() - daemonPods,
+		}
+	}
+
+	report := &PackingReport{CurrentNodes: len(nodes)}
+	shapes := make(map[string]nodeShape)
+	pinnedBins := make([]packingBin, 0)
+	var pinnedCost float64
+	for i := range nodes {
+		node := &nodes[i]
+		hourlyCost := co.nodeHourlyCost(node)
+		report.CurrentCost += hourlyCost * 24 * 30
+
+		name := co.pricing.instanceType(node)
+		if _, seen := shapes[name]; !seen {
+			shapes[name] = nodeShape{name: name, allocated: capacity(node), hourlyCost: hourlyCost}
+		}
+
+		if loads := pinned[node.Name]; len(loads) > 0 {
+			bin := capacity(node)
+			for _, load := range loads {
+				bin.place(load.cpu, load.memory)
+			}
+			pinnedBins = append(pinnedBins, bin)
+			pinnedCost += hourlyCost * 24 * 30
+		}
+	}
+
+	sort.Slice(movable, func(i, j int) bool {
+		if movable[i].cpu != movable[j].cpu {
+			return movable[i].cpu > movable[j].cpu
+		}
+		return movable[i].memory > movable[j].memory
+	})
+
+	shapeNames := make([]string, 0, len(shapes))
+	for name := range shapes {
+		shapeNames = append(shapeNames, name)
+	}
+	sort.Strings(shapeNames)
+
+	best := math.Inf(1)
+	for _, name := range shapeNames {
+		shape := shapes[name]
+		bins := append([]packingBin(nil), pinnedBins...)
+		added, fits := 0, true
+		for _, load := range movable {
+			placed := false
+			for i := range bins {
+				if bins[i].fits(load.cpu, load.memory) {
+					bins[i].place(load.cpu, load.memory)
+					placed = true
+					break
+				}
+			}
+			if placed {
+				continue
+			}
+			bin := shape.allocated
+			if !bin.fits(load.cpu, load.memory) {
+				// This type is too small for the pod
+				fits = false
+				break
+			}
+			bin.place(load.cpu, load.memory)
+			bins = append(bins, bin)
+			added++
+		}
+		if !fits {
+			continue
+		}
+		if cost := pinnedCost + float64(added)*shape.hourlyCost*24*30; cost < best {
+			best = cost
+			report.IdealNodes = len(pinnedBins) + added
+			report.IdealCost = cost
+			report.NodeType = name
+		}
+	}
+	if math.IsInf(best, 1) {
+		return nil
+	}
+
+	// An empty cluster still needs a node to run on
+	if report.IdealNodes == 0 {
+		report.IdealNodes = 1
+		report.IdealCost = shapes[report.NodeType].hourlyCost * 24 * 30
+	}
+	// The heuristic can come out above a cluster that is already tightly
+	// packed; that is reported as no gap rather than a negative one
+	report.ReductionPercent = max(float64(report.CurrentNodes-report.IdealNodes)/float64(report.CurrentNodes)*100, 0)
+	report.MonthlySavings = max(report.CurrentCost-report.IdealCost, 0)
+	return report
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// controlled gives a pod a ReplicaSet controller, so packing may move it
+func controlled(pod *corev1.Pod) *corev1.Pod {
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: pod.Name + "-rs", Controller: boolPtr(true)}}
+	return pod
+}
+
+// packingCluster is a cluster of 4-core, 16Gi nodes, each running a 100m
+// DaemonSet pod, with a 1-core pod per entry of workers
+func packingCluster(nodes, workers int) ([]corev1.Node, []corev1.Pod) {
+	var nodeList []corev1.Node
+	var podList []corev1.Pod
+	for i := 1; i <= nodes; i++ {
+		name := fmt.Sprintf("node-%d", i)
+		nodeList = append(nodeList, *testNode(name, "4", "16Gi"))
+		daemon := testPod("kube-system", "exporter-"+name, name, "100m", "128Mi")
+		daemon.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "exporter", Controller: boolPtr(true)}}
+		podList = append(podList, *daemon)
+	}
+	for i := 0; i < workers; i++ {
+		node := fmt.Sprintf("node-%d", i%nodes+1)
+		podList = append(podList, *controlled(testPod("shop", fmt.Sprintf("web-%d", i), node, "1", "2Gi")))
+	}
+	return nodeList, podList
+}
+
+func TestPackingReport(t *testing.T) {
+	tests := []struct {
+		name        string
+		nodes       int
+		workers     int
+		pinned      string // request of an unmovable pod on node-1, if set
+		wantIdeal   int
+		wantPercent float64
+	}{
+		// Each node has 3.9 cores after the DaemonSet, room for 3 workers
+		{name: "over-provisioned", nodes: 4, workers: 4, wantIdeal: 2, wantPercent: 50},
+		{name: "unmovable pod pins its node", nodes: 4, workers: 4, pinned: "3", wantIdeal: 3, wantPercent: 25},
+		{name: "tightly packed", nodes: 2, workers: 6, wantIdeal: 2},
+		{name: "empty cluster keeps a node", nodes: 3, wantIdeal: 1, wantPercent: 100 * 2.0 / 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t)
+			co.costCalculator.NodeCostPerHour = map[string]float64{"default": 1}
+			nodes, pods := packingCluster(tt.nodes, tt.workers)
+			if tt.pinned != "" {
+				pods = append(pods, *testPod("shop", "bare", "node-1", tt.pinned, "1Gi"))
+			}
+
+			report := co.packingReport(nodes, pods)
+			if report == nil {
+				t.Fatal("no packing report")
+			}
+			perNode := co.nodeHourlyCost(&nodes[0]) * 24 * 30
+			wantSavings := float64(tt.nodes-tt.wantIdeal) * perNode
+			if report.CurrentNodes != tt.nodes || report.IdealNodes != tt.wantIdeal ||
+				math.Abs(report.ReductionPercent-tt.wantPercent) > 1e-9 || math.Abs(report.MonthlySavings-wantSavings) > 1e-6 {
+				t.Errorf("report %+v, want %d of %d nodes (%.1f%% fewer) saving %.2f",
+					report, tt.wantIdeal, tt.nodes, tt.wantPercent, wantSavings)
+			}
+		})
+	}
+
+	co, _ := newTestOptimizer(t)
+	if report := co.packingReport(nil, nil); report != nil {
+		t.Errorf("report without nodes = %+v, want nil", report)
+	}
+}
+
+func TestPodMovable(t *testing.T) {
+	mirror := testPod("kube-system", "kube-proxy", "node-1", "100m", "64Mi")
+	mirror.Annotations = map[string]string{mirrorPodAnnotation: "hash"}
+	tests := []struct {
+		name string
+		pod  *corev1.Pod
+		want bool
+	}{
+		{name: "controlled", pod: controlled(testPod("shop", "web", "node-1", "1", "1Gi")), want: true},
+		{name: "bare", pod: testPod("shop", "debug", "node-1", "1", "1Gi")},
+		{name: "static", pod: mirror},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := podMovable(tt.pod); got != tt.want {
+				t.Errorf("podMovable = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCostSummaryPacking(t *testing.T) {
+	nodes, pods := packingCluster(4, 4)
+	var objects []runtime.Object
+	for i := range nodes {
+		objects = append(objects, &nodes[i], testNodeMetrics(nodes[i].Name, "1", "4Gi"))
+	}
+	for i := range pods {
+		objects = append(objects, &pods[i])
+	}
+	co, _ := newTestOptimizer(t, objects...)
+	co.costCalculator.NodeCostPerHour = map[string]float64{"default": 1}
+
+	summary := co.generateCostSummary(context.Background())
+	if summary.Packing == nil {
+		t.Fatal("cost summary has no packing report")
+	}
+	if got := summary.Packing; got.IdealNodes != 2 || got.ReductionPercent != 50 || math.Abs(got.MonthlySavings-got.CurrentCost/2) > 1e-6 {
+		t.Errorf("packing %+v, want 2 of 4 nodes saving half the cost", got)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"time"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// coveringPDBs returns the disruption budgets in namespace whose selector
+// matches the given pod template labels
+func coveringPDBs(namespace string, podLabels map[string]string, pdbs []policyv1.PodDisruptionBudget) []*policyv1.PodDisruptionBudget {
+	covering := make([]*policyv1.PodDisruptionBudget, 0)
+	for i := range pdbs {
+		pdb := &pdbs[i]
+		if pdb.Namespace != namespace || pdb.Spec.Selector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || selector.Empty() || !selector.Matches(labels.Set(podLabels)) {
+			continue
+		}
+		covering = append(covering, pdb)
+	}
+	return covering
+}
+
+// pdbReplicaFloor returns the highest minAvailable among the budgets covering
+// a workload that runs replicas, with percentages taken of replicas, and the
+// name of that budget. A scale-down below the floor would leave the budget
+// unsatisfiable. The floor is 0 when no budget sets minAvailable.
+func pdbReplicaFloor(covering []*policyv1.PodDisruptionBudget, replicas int32) (floor int32, name string) {
+	for _, pdb := range covering {
+		if pdb.Spec.MinAvailable == nil {
+			continue
+		}
+		minAvailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MinAvailable, int(replicas), true)
+		if err == nil && int32(minAvailable) > floor {
+			floor, name = int32(minAvailable), pdb.Name
+		}
+	}
+	return floor, name
+}
+
+// listPDBs lists the disruption budgets in namespace, or in every namespace
+// when it is empty. A failure is logged and treated as no budgets.
+func (co *CostOptimizer) listPDBs(ctx context.Context, namespace string) []policyv1.PodDisruptionBudget {
+	pdbs, err := co.clientset.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		slog.Warn("Failed to list PodDisruptionBudgets, scale-downs are not checked against them", "error", err)
+		return nil
+	}
+	return pdbs.Items
+}
+
+// productionNamespace reports whether PDBs are expected in a namespace
+func (co *CostOptimizer) productionNamespace(namespace string) bool {
+	for _, pattern := range co.productionNamespaces {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzeMissingPDBs flags multi-replica Deployments and StatefulSets in
+// production namespaces that no PodDisruptionBudget covers. The finding saves
+// nothing itself, but evictions for consolidation, scale-down or spot moves
+// can take such a workload fully offline, so those actions aren't safe until
+// a PDB exists.
+func (co *CostOptimizer) analyzeMissingPDBs(ctx context.Context) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	if co.demoMode || co.clientset == nil || co.analyzerDisabled("pdb") {
+		return recommendations
+	}
+
+	pdbs, err := co.clientset.PolicyV1().PodDisruptionBudgets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		co.analyzerListFailed("pdb", "poddisruptionbudgets", err)
+		return recommendations
+	}
+
+	type workload struct {
+		kind, namespace, name string
+		replicas              int32
+		podLabels             map[string]string
+		objectLabels          map[string]string
+	}
+	workloads := make([]workload, 0)
+
+	deployments, err := co.clientset.AppsV1().Deployments("").List(ctx, co.workloadListOptions())
+	if err != nil {
+		co.analyzerListFailed("pdb", "deployments", err)
+		return recommendations
+	}
+	deployments.Items = inScope(co, deployments.Items)
+	for _, d := range deployments.Items {
+		if d.Spec.Replicas != nil {
+			workloads = append(workloads, workload{"Deployment", d.Namespace, d.Name, *d.Spec.Replicas, d.Spec.Template.Labels, d.Labels})
+		}
+	}
+
+	statefulSets, err := co.clientset.AppsV1().StatefulSets("").List(ctx, co.workloadListOptions())
+	if err != nil {
+		co.analyzerListFailed("pdb", "statefulsets", err)
+		return recommendations
+	}
+	statefulSets.Items = inScope(co, statefulSets.Items)
+	for _, s := range statefulSets.Items {
+		if s.Spec.Replicas != nil {
+			workloads = append(workloads, workload{"StatefulSet", s.Namespace, s.Name, *s.Spec.Replicas, s.Spec.Template.Labels, s.Labels})
+		}
+	}
+
+	for _, w := range workloads {
+		if w.replicas < 2 || !co.productionNamespace(w.namespace) {
+			continue
+		}
+		if len(coveringPDBs(w.namespace, w.podLabels, pdbs.Items)) > 0 {
+			continue
+		}
+
+		recommendations = append(recommendations, Recommendation{
+			Type:        "pod_disruption_budget",
+			Category:    CategoryConfigure,
+			Resource:    fmt.Sprintf("%s/%s", w.namespace, w.name),
+			Namespace:   w.namespace,
+			Release:     helmRelease(w.objectLabels),
+			labels:      w.objectLabels,
+			Description: fmt.Sprintf("%s %s has %d replicas and no PodDisruptionBudget; node consolidation, scale-down and spot moves could evict every replica at once", w.kind, w.name, w.replicas),
+			Impact:      "Create a PodDisruptionBudget (e.g. maxUnavailable: 1) before acting on cost recommendations that drain nodes",
+			ActionHint:  &ActionHint{Verb: "create", Target: hintTarget("poddisruptionbudget", w.namespace, w.name)},
+			Priority:    "medium",
+			Timestamp:   time.Now(),
+		})
+	}
+
+	return recommendations
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// testStatefulSet is a statefulset with the given replicas selecting pods by
+// an app label
+func testStatefulSet(namespace, name string, replicas int32) *appsv1.StatefulSet {
+	deployment := testDeployment(namespace, name, replicas)
+	return &appsv1.StatefulSet{
+		ObjectMeta: deployment.ObjectMeta,
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			Selector: deployment.Spec.Selector,
+			Template: deployment.Spec.Template,
+		},
+	}
+}
+
+func TestAnalyzeMissingPDBs(t *testing.T) {
+	web := testDeployment("shop", "web", 3)
+	otherPDB := testPDB(web, 1)
+	otherPDB.Namespace = "billing"
+	emptySelector := testPDB(web, 1)
+	emptySelector.Spec.Selector = &metav1.LabelSelector{}
+
+	tests := []struct {
+		name       string
+		objects    []runtime.Object
+		production []string
+		want       []string
+	}{
+		{name: "multi-replica deployment without PDB", objects: []runtime.Object{web}, want: []string{"shop/web"}},
+		{name: "covered by a PDB", objects: []runtime.Object{web, testPDB(web, 2)}},
+		{name: "PDB in another namespace", objects: []runtime.Object{web, otherPDB}, want: []string{"shop/web"}},
+		{name: "PDB with empty selector", objects: []runtime.Object{web, emptySelector}, want: []string{"shop/web"}},
+		{name: "single replica", objects: []runtime.Object{testDeployment("shop", "cron", 1)}},
+		{name: "statefulset without PDB", objects: []runtime.Object{testStatefulSet("shop", "db", 3)}, want: []string{"shop/db"}},
+		{name: "outside production namespaces", objects: []runtime.Object{web}, production: []string{"prod-*"}},
+		{name: "inside production namespaces", objects: []runtime.Object{testDeployment("prod-eu", "web", 2)}, production: []string{"prod-*"}, want: []string{"prod-eu/web"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t, tt.objects...)
+			if tt.production != nil {
+				co.productionNamespaces = tt.production
+			}
+
+			recommendations := co.analyzeMissingPDBs(context.Background())
+			if len(recommendations) != len(tt.want) {
+				t.Fatalf("got %d recommendations, want %v: %+v", len(recommendations), tt.want, recommendations)
+			}
+			for i, rec := range recommendations {
+				if rec.Type != "pod_disruption_budget" || rec.Resource != tt.want[i] {
+					t.Errorf("recommendation %d = %s %s, want pod_disruption_budget %s", i, rec.Type, rec.Resource, tt.want[i])
+				}
+				if rec.Savings != 0 {
+					t.Errorf("Savings = %v, want the finding to be informational", rec.Savings)
+				}
+			}
+		})
+	}
+}
+
+func TestCoveringPDBs(t *testing.T) {
+	web := testDeployment("shop", "web", 3)
+	pdbs := []policyv1.PodDisruptionBudget{*testPDB(web, 1), *testPDB(testDeployment("shop", "api", 2), 1)}
+
+	covering := coveringPDBs("shop", web.Spec.Template.Labels, pdbs)
+	if len(covering) != 1 || covering[0].Name != "web" {
+		t.Errorf("coveringPDBs = %v, want only the web PDB", covering)
+	}
+}
+
+// TestScaleDownSuggestionsRespectPDBs checks that a deployment of 5 replicas
+// whose budget needs 4 available is only told to scale down to 4
+func TestScaleDownSuggestionsRespectPDBs(t *testing.T) {
+	tests := []struct {
+		name         string
+		minAvailable int // 0 for no budget
+		wantRec      bool
+		wantMin      string // minReplicas the hint sets, if the budget forces one
+		wantSavings  float64
+		wantPriority string
+	}{
+		{name: "no budget", wantRec: true, wantSavings: 25, wantPriority: "medium"},
+		{name: "budget below the hpa floor", minAvailable: 1, wantRec: true, wantSavings: 25, wantPriority: "medium"},
+		{name: "budget needs 4 of 5", minAvailable: 4, wantRec: true, wantMin: "4", wantSavings: 25.0 / 4, wantPriority: "low"},
+		{name: "budget needs every replica", minAvailable: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := testDeployment("shop", "web", 5)
+			objects := []runtime.Object{deployment}
+			if tt.minAvailable > 0 {
+				objects = append(objects, testPDB(deployment, tt.minAvailable))
+			}
+			co, _ := newTestOptimizer(t, objects...)
+
+			var rec *Recommendation
+			for _, r := range co.analyzeDeployments(context.Background()) {
+				if r.Type == "horizontal_scaling" {
+					rec = &r
+				}
+			}
+			if (rec != nil) != tt.wantRec {
+				t.Fatalf("horizontal_scaling recommendation %+v, want one %v", rec, tt.wantRec)
+			}
+			if rec == nil {
+				return
+			}
+			if rec.ActionHint.NewValue != tt.wantMin || math.Abs(rec.Savings-tt.wantSavings) > 1e-9 || rec.Priority != tt.wantPriority {
+				t.Errorf("minReplicas %s, savings %v, priority %s; want %s, %v, %s",
+					rec.ActionHint.NewValue, rec.Savings, rec.Priority, tt.wantMin, tt.wantSavings, tt.wantPriority)
+			}
+			if tt.wantPriority == "low" && !strings.Contains(rec.Impact, "PodDisruptionBudget web requires 4 available") {
+				t.Errorf("impact %q doesn't name the budget", rec.Impact)
+			}
+		})
+	}
+}
+
+func TestScaleDeploymentRespectsPDB(t *testing.T) {
+	tests := []struct {
+		name     string
+		replicas int32
+		wantErr  string
+		want     int32
+	}{
+		{name: "down to the floor", replicas: 4, want: 4},
+		{name: "below the floor", replicas: 2, wantErr: "would violate PodDisruptionBudget web", want: 5},
+		{name: "scale up", replicas: 6, want: 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := testDeployment("shop", "web", 5)
+			deployment.Spec.Replicas = &deployment.Status.Replicas
+			co, client := newTestOptimizer(t, deployment, testPDB(deployment, 4))
+
+			_, err := co.scaleDeployment(context.Background(), "shop", "web", tt.replicas, false)
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("error %v, want one mentioning %q", err, tt.wantErr)
+			}
+			scaled, err := client.AppsV1().Deployments("shop").Get(context.Background(), "web", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *scaled.Spec.Replicas != tt.want {
+				t.Errorf("replicas %d, want %d", *scaled.Spec.Replicas, tt.want)
+			}
+		})
+	}
+}
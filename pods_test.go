@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+func TestOverProvisioned(t *testing.T) {
+	thresholds := defaultThresholds()
+	tests := []struct {
+		usage, request int64
+		want           bool
+	}{
+		{usage: 0, request: 1, want: true},
+		{usage: 1, request: 1},
+		{usage: 1, request: 3, want: true},
+		{usage: 2, request: 3},
+		{usage: 49, request: 100, want: true},
+		{usage: 50, request: 100},
+		{usage: 51, request: 100},
+		{usage: 0, request: 0},
+	}
+
+	for _, tt := range tests {
+		if got := thresholds.overProvisioned(tt.usage, tt.request); got != tt.want {
+			t.Errorf("overProvisioned(%d, %d) = %v, want %v", tt.usage, tt.request, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzePodsCPUBoundary(t *testing.T) {
+	tests := []struct {
+		name           string
+		request, usage string
+		want           bool
+	}{
+		{name: "1m request, idle", request: "1m", usage: "0", want: true},
+		{name: "odd request, under half", request: "3m", usage: "1m", want: true},
+		{name: "odd request, over half", request: "3m", usage: "2m"},
+		{name: "exactly half", request: "500m", usage: "250m"},
+		{name: "just under half", request: "500m", usage: "249m", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Memory is fully used, so only CPU can be flagged
+			co, _ := newTestOptimizer(t,
+				testPod("default", "web", "node-1", tt.request, "1Gi"),
+				testPodMetrics("default", "web", tt.usage, "1Gi"))
+			// Millicore rounding keeps tiny suggestions below tiny requests
+			co.rounding = RoundingPolicy{CPUMillis: 1, MemoryBytes: 1}
+
+			var cpu []Recommendation
+			for _, rec := range co.analyzePods(context.Background()) {
+				if strings.Contains(rec.Description, "for CPU") {
+					cpu = append(cpu, rec)
+				}
+			}
+			if got := len(cpu) == 1; got != tt.want {
+				t.Errorf("CPU recommendations %+v, want flagged %v", cpu, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzePodsSkipsYoungPods(t *testing.T) {
+	now := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		started time.Duration // before now; zero leaves the start time unset
+		owner   string
+		want    bool
+	}{
+		{name: "freshly started job pod", started: 5 * time.Second, owner: "Job"},
+		{name: "long-running job pod", started: time.Hour, owner: "Job", want: true},
+		{name: "young deployment pod", started: 9 * time.Minute, owner: "ReplicaSet"},
+		{name: "at the minimum age", started: 10 * time.Minute, owner: "ReplicaSet", want: true},
+		{name: "not started", owner: "Job"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := testPod("batch", "worker", "node-1", "1", "1Gi")
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: tt.owner, Name: "worker", Controller: boolPtr(true)}}
+			pod.Status.StartTime = nil
+			if tt.started != 0 {
+				started := metav1.NewTime(now.Add(-tt.started))
+				pod.Status.StartTime = &started
+			}
+			co, _ := newTestOptimizer(t, pod, testPodMetrics("batch", "worker", "10m", "1Gi"))
+			co.now = func() time.Time { return now }
+
+			recommendations := co.analyzePods(context.Background())
+			if got := len(recommendations) > 0; got != tt.want {
+				t.Errorf("recommendations %+v, want analyzed %v", recommendations, tt.want)
+			}
+		})
+	}
+}
+
+func TestRightsizingMinPodAgeSetting(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "", want: defaultRightsizingMinPodAge},
+		{value: "1h", want: time.Hour},
+		{value: "0s", want: 0},
+		{value: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("DEMO_MODE", "true")
+			t.Setenv("OPTIMKUBE_RIGHTSIZING_MIN_POD_AGE", tt.value)
+			co, err := NewCostOptimizer()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCostOptimizer error %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && co.rightsizingMinPodAge != tt.want {
+				t.Errorf("minimum pod age %v, want %v", co.rightsizingMinPodAge, tt.want)
+			}
+		})
+	}
+}
+
+func TestPodMetricsNamespaceScope(t *testing.T) {
+	co, _ := newTestOptimizer(t,
+		testNode("node-1", "4", "16Gi"),
+		testPod("team-a", "api", "node-1", "500m", "1Gi"), testPodMetrics("team-a", "api", "100m", "512Mi"),
+		testPod("team-a", "worker", "node-1", "500m", "1Gi"), testPodMetrics("team-a", "worker", "100m", "512Mi"),
+		testPod("team-b", "web", "node-1", "500m", "1Gi"), testPodMetrics("team-b", "web", "100m", "512Mi"),
+	)
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		want       []string
+	}{
+		{name: "all namespaces", target: "/api/metrics/pods", wantStatus: http.StatusOK, want: []string{"team-a/api", "team-a/worker", "team-b/web"}},
+		{name: "one namespace", target: "/api/metrics/pods?namespace=team-a", wantStatus: http.StatusOK, want: []string{"team-a/api", "team-a/worker"}},
+		{name: "empty namespace", target: "/api/metrics/pods?namespace=team-c", wantStatus: http.StatusOK, want: []string{}},
+		{name: "invalid namespace", target: "/api/metrics/pods?namespace=Team_A", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(http.HandlerFunc(co.handlePodMetrics), http.MethodGet, tt.target, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var pods []PodMetrics
+			if err := json.Unmarshal(rec.Body.Bytes(), &pods); err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(pods))
+			for _, pod := range pods {
+				got = append(got, pod.Namespace+"/"+pod.Name)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pods %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAnalyzePodsMatchesContainersByName checks that usage is paired with the
+// container of the same name, whatever order metrics-server lists them in
+func TestAnalyzePodsMatchesContainersByName(t *testing.T) {
+	tests := []struct {
+		name    string
+		metrics []string // container names in metrics order
+		want    []string // containers recommended for rightsizing
+	}{
+		{name: "spec order", metrics: []string{"app", "proxy"}, want: []string{"app"}},
+		{name: "reverse order", metrics: []string{"proxy", "app"}, want: []string{"app"}},
+		{name: "sidecar without metrics", metrics: []string{"app"}, want: []string{"app"}},
+		{name: "no matching metrics", metrics: []string{"init"}, want: []string{}},
+	}
+	// app requests 2 cores and uses 100m; proxy requests 100m and uses far more,
+	// so pairing by index would swap which container looks idle
+	usage := map[string]string{"app": "100m", "proxy": "1900m", "init": "10m"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := testPod("shop", "web", "node-1", "2", "1Gi")
+			pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{
+				Name: "proxy",
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("100m"),
+					corev1.ResourceMemory: resource.MustParse("64Mi"),
+				}},
+			})
+			metrics := testPodMetrics("shop", "web", "0", "0")
+			metrics.Containers = nil
+			for _, name := range tt.metrics {
+				metrics.Containers = append(metrics.Containers, metricsv1beta1.ContainerMetrics{
+					Name: name,
+					Usage: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse(usage[name]),
+						corev1.ResourceMemory: resource.MustParse("64Mi"),
+					},
+				})
+			}
+			co, _ := newTestOptimizer(t, pod, metrics)
+
+			got := make([]string, 0)
+			for _, rec := range co.analyzePods(context.Background()) {
+				if rec.Type == "resource_rightsizing" && strings.Contains(rec.Description, "for CPU") {
+					got = append(got, strings.Fields(rec.Description)[1])
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CPU rightsizing for containers %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultPricingConfigMapKey is the ConfigMap entry holding pricing, matching
+// the cost-optimizer-config ConfigMap in spec.yaml
+const defaultPricingConfigMapKey = "config.yaml"
+
+// pricingConfig is the part of the ConfigMap's config that sets prices
+type pricingConfig struct {
+	CostCalculator struct {
+		NodeCosts map[string]float64 `json:"node_costs"`
+	} `json:"cost_calculator"`
+}
+
+// pricingFile is the layout of the file named by OPTIMKUBE_PRICING_FILE
+type pricingFile struct {
+	NodeCosts        map[string]float64 `json:"node_costs"`
+	StorageCostPerGB *float64           `json:"storage_cost_per_gb"`
+	GPUCostPerHour   *float64           `json:"gpu_cost_per_hour"`
+
+	EphemeralStorageCostPerGB *float64 `json:"ephemeral_storage_cost_per_gb"`
+	EgressCostPerGB           *float64 `json:"egress_cost_per_gb"`
+}
+
+// loadPricingFile replaces the calculator's built-in prices with those from a
+// YAML or JSON file. As with the ConfigMap, a missing "default" node price
+// keeps the built-in fallback, and unset storage and GPU prices keep their
+// defaults. Ephemeral storage and egress are only priced when set.
+func (cc *CostCalculator) loadPricingFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read pricing file: %w", err)
+	}
+
+	var file pricingFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return fmt.Errorf("parse pricing file %s: %w", path, err)
+	}
+
+	if len(file.NodeCosts) > 0 {
+		prices := make(map[string]float64, len(file.NodeCosts)+1)
+		for instanceType, price := range file.NodeCosts {
+			if price < 0 {
+				return fmt.Errorf("pricing file %s: node_costs[%s]: negative price %v", path, instanceType, price)
+			}
+			prices[instanceType] = price
+		}
+		if _, ok := prices["default"]; !ok {
+			prices["default"] = cc.NodeCostPerHour["default"]
+		}
+		cc.NodeCostPerHour = prices
+	}
+	if file.StorageCostPerGB != nil {
+		if *file.StorageCostPerGB < 0 {
+			return fmt.Errorf("pricing file %s: storage_cost_per_gb: negative price %v", path, *file.StorageCostPerGB)
+		}
+		cc.StorageCostPerGB = *file.StorageCostPerGB
+	}
+	if file.GPUCostPerHour != nil {
+		if *file.GPUCostPerHour < 0 {
+			return fmt.Errorf("pricing file %s: gpu_cost_per_hour: negative price %v", path, *file.GPUCostPerHour)
+		}
+		cc.GPUCostPerHour = *file.GPUCostPerHour
+	}
+	if file.EphemeralStorageCostPerGB != nil {
+		if *file.EphemeralStorageCostPerGB < 0 {
+			return fmt.Errorf("pricing file %s: ephemeral_storage_cost_per_gb: negative price %v", path, *file.EphemeralStorageCostPerGB)
+		}
+		cc.EphemeralStorageCostPerGB = *file.EphemeralStorageCostPerGB
+	}
+	if file.EgressCostPerGB != nil {
+		if *file.EgressCostPerGB < 0 {
+			return fmt.Errorf("pricing file %s: egress_cost_per_gb: negative price %v", path, *file.EgressCostPerGB)
+		}
+		cc.EgressCostPerGB = *file.EgressCostPerGB
+	}
+	return nil
+}
+
+// nodePrices returns the current instance type prices. The map is replaced
+// rather than modified on updates, so callers may read it without locking.
+func (cc *CostCalculator) nodePrices() map[string]float64 {
+	cc.pricingMu.RLock()
+	defer cc.pricingMu.RUnlock()
+	return cc.NodeCostPerHour
+}
+
+func (cc *CostCalculator) setNodePrices(prices map[string]float64) {
+	cc.pricingMu.Lock()
+	defer cc.pricingMu.Unlock()
+	cc.NodeCostPerHour = prices
+}
+
+// parsePricingConfigMap reads node prices from a ConfigMap entry. A missing
+// "default" price keeps the fallback from defaults, so unknown instance types
+// are still priced.
+func parsePricingConfigMap(configMap *corev1.ConfigMap, key string, defaults map[string]float64) (map[string]float64, error) {
+	raw, ok := configMap.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("key %q not found", key)
+	}
+	var config pricingConfig
+	if err := yaml.Unmarshal([]byte(raw), &config); err != nil {
+		return nil, err
+	}
+	if len(config.CostCalculator.NodeCosts) == 0 {
+		return nil, fmt.Errorf("no cost_calculator.node_costs in %q", key)
+	}
+
+	prices := make(map[string]float64, len(config.CostCalculator.NodeCosts)+1)
+	for instanceType, price := range config.CostCalculator.NodeCosts {
+		if price < 0 {
+			return nil, fmt.Errorf("negative price %v for %s", price, instanceType)
+		}
+		prices[instanceType] = price
+	}
+	if _, ok := prices["default"]; !ok {
+		prices["default"] = defaults["default"]
+	}
+	return prices, nil
+}
+
+// watchPricingConfigMap keeps node prices in sync with a ConfigMap, named as
+// namespace/name or just name in the pod's namespace. Prices revert to the
+// built-in defaults while the ConfigMap is absent or unparsable. The watch
+// runs until stop is closed.
+func (co *CostOptimizer) watchPricingConfigMap(clientset kubernetes.Interface, ref, key string, stop <-chan struct{}) {
+	namespace, name, found := strings.Cut(ref, "/")
+	if !found {
+		name = ref
+		namespace = os.Getenv("POD_NAMESPACE")
+		if namespace == "" {
+			namespace = "kube-system"
+		}
+	}
+	defaults := co.costCalculator.nodePrices()
+
+	apply := func(obj interface{}) {
+		configMap, ok := obj.(*corev1.ConfigMap)
+		if !ok {
+			return
+		}
+		prices, err := parsePricingConfigMap(configMap, key, defaults)
+		if err != nil {
+			slog.Warn("Ignoring pricing ConfigMap, using default prices", "namespace", namespace, "configmap", name, "error", err)
+			co.costCalculator.setNodePrices(defaults)
+			return
+		}
+		co.costCalculator.setNodePrices(prices)
+		slog.Info("Loaded node prices from ConfigMap", "prices", len(prices), "namespace", namespace, "configmap", name)
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = "metadata.name=" + name
+		}))
+	factory.Core().V1().ConfigMaps().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    apply,
+		UpdateFunc: func(_, obj interface{}) { apply(obj) },
+		DeleteFunc: func(interface{}) {
+			slog.Info("Pricing ConfigMap deleted, using default prices", "namespace", namespace, "configmap", name)
+			co.costCalculator.setNodePrices(defaults)
+		},
+	})
+	factory.Start(stop)
+}
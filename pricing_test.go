@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testPricingConfigMap holds config in the default pricing key
+func testPricingConfigMap(config string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "pricing", Namespace: "optimkube"},
+		Data:       map[string]string{defaultPricingConfigMapKey: config},
+	}
+}
+
+// waitForNodeCost polls until instanceType is priced at want, since the
+// informer applies ConfigMap changes asynchronously
+func waitForNodeCost(t *testing.T, co *CostOptimizer, instanceType string, want float64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := co.costCalculator.instanceTypeCost(instanceType)
+		if got == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("instanceTypeCost(%s) = %v, want %v", instanceType, got, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPricingConfigMapUpdates(t *testing.T) {
+	ctx := context.Background()
+	co, clientset := newTestOptimizer(t, testPricingConfigMap("cost_calculator:\n  node_costs:\n    m5.large: 0.2\n"))
+	defaultPrice := co.costCalculator.nodePrices()["default"]
+	builtInPrice := co.costCalculator.nodePrices()["m5.large"]
+
+	stop := make(chan struct{})
+	defer close(stop)
+	co.watchPricingConfigMap(clientset, "optimkube/pricing", defaultPricingConfigMapKey, stop)
+	waitForNodeCost(t, co, "m5.large", 0.2)
+	if got := co.costCalculator.instanceTypeCost("unknown"); got != defaultPrice {
+		t.Errorf("unknown instance type priced at %v, want the default %v", got, defaultPrice)
+	}
+
+	steps := []struct {
+		name   string
+		update func() error
+		want   float64 // m5.large price afterwards
+	}{
+		{
+			name: "price updated",
+			update: func() error {
+				_, err := clientset.CoreV1().ConfigMaps("optimkube").Update(ctx, testPricingConfigMap("cost_calculator:\n  node_costs:\n    m5.large: 0.15\n"), metav1.UpdateOptions{})
+				return err
+			},
+			want: 0.15,
+		},
+		{
+			name: "unparsable config",
+			update: func() error {
+				_, err := clientset.CoreV1().ConfigMaps("optimkube").Update(ctx, testPricingConfigMap("cost_calculator: [\n"), metav1.UpdateOptions{})
+				return err
+			},
+			want: builtInPrice,
+		},
+		{
+			name: "fixed again",
+			update: func() error {
+				_, err := clientset.CoreV1().ConfigMaps("optimkube").Update(ctx, testPricingConfigMap("cost_calculator:\n  node_costs:\n    m5.large: 0.25\n"), metav1.UpdateOptions{})
+				return err
+			},
+			want: 0.25,
+		},
+		{
+			name: "deleted",
+			update: func() error {
+				return clientset.CoreV1().ConfigMaps("optimkube").Delete(ctx, "pricing", metav1.DeleteOptions{})
+			},
+			want: builtInPrice,
+		},
+	}
+
+	// Each step depends on the ConfigMap left by the one before
+	for _, step := range steps {
+		if err := step.update(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		waitForNodeCost(t, co, "m5.large", step.want)
+	}
+}
+
+func TestParsePricingConfigMap(t *testing.T) {
+	defaults := map[string]float64{"default": 0.1, "m5.large": 0.096}
+	tests := []struct {
+		name    string
+		key     string
+		config  string
+		want    map[string]float64
+		wantErr string
+	}{
+		{
+			name:   "prices with default",
+			config: "cost_calculator:\n  node_costs:\n    default: 0.05\n    c5.large: 0.085\n",
+			want:   map[string]float64{"default": 0.05, "c5.large": 0.085},
+		},
+		{
+			name:   "default kept from the built-in prices",
+			config: "cost_calculator:\n  node_costs:\n    c5.large: 0.085\n",
+			want:   map[string]float64{"default": 0.1, "c5.large": 0.085},
+		},
+		{name: "missing key", key: "pricing.yaml", config: "cost_calculator: {}", wantErr: `key "pricing.yaml" not found`},
+		{name: "no node costs", config: "cost_calculator:\n  storage_cost_per_gb: 0.1\n", wantErr: "no cost_calculator.node_costs"},
+		{name: "negative price", config: "cost_calculator:\n  node_costs:\n    c5.large: -1\n", wantErr: "negative price"},
+		{name: "not yaml", config: "cost_calculator: [", wantErr: "yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := tt.key
+			if key == "" {
+				key = defaultPricingConfigMapKey
+			}
+			prices, err := parsePricingConfigMap(testPricingConfigMap(tt.config), key, defaults)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(prices) != len(tt.want) {
+				t.Fatalf("prices %v, want %v", prices, tt.want)
+			}
+			for instanceType, price := range tt.want {
+				if prices[instanceType] != price {
+					t.Errorf("%s priced at %v, want %v", instanceType, prices[instanceType], price)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadPricingFile(t *testing.T) {
+	tests := []struct {
+		name          string
+		file          string
+		wantNode      map[string]float64
+		wantStorage   float64
+		wantGPU       float64
+		wantEphemeral float64
+		wantEgress    float64
+		wantErr       string
+	}{
+		{
+			name:        "yaml",
+			file:        "node_costs:\n  m6i.large: 0.2\nstorage_cost_per_gb: 0.08\ngpu_cost_per_hour: 2.5\n",
+			wantNode:    map[string]float64{"m6i.large": 0.2, "default": 0.1},
+			wantStorage: 0.08,
+			wantGPU:     2.5,
+		},
+		{
+			name:          "ephemeral storage and egress",
+			file:          "ephemeral_storage_cost_per_gb: 0.05\negress_cost_per_gb: 0.09\n",
+			wantNode:      map[string]float64{"default": 0.1},
+			wantStorage:   0.1,
+			wantGPU:       1,
+			wantEphemeral: 0.05,
+			wantEgress:    0.09,
+		},
+		{
+			name:        "json keeps the storage and gpu defaults",
+			file:        `{"node_costs": {"m6i.large": 0.2, "default": 0.3}}`,
+			wantNode:    map[string]float64{"m6i.large": 0.2, "default": 0.3},
+			wantStorage: 0.1,
+			wantGPU:     1,
+		},
+		{name: "unknown field", file: "node_cost:\n  m6i.large: 0.2\n", wantErr: `unknown field "node_cost"`},
+		{name: "malformed", file: "node_costs:\n  m6i.large: [\n", wantErr: "line 2"},
+		{name: "negative node price", file: "node_costs:\n  m6i.large: -1\n", wantErr: "node_costs[m6i.large]: negative price"},
+		{name: "negative storage price", file: "storage_cost_per_gb: -1\n", wantErr: "storage_cost_per_gb: negative price"},
+		{name: "negative gpu price", file: "gpu_cost_per_hour: -1\n", wantErr: "gpu_cost_per_hour: negative price"},
+		{name: "negative ephemeral storage price", file: "ephemeral_storage_cost_per_gb: -1\n", wantErr: "ephemeral_storage_cost_per_gb: negative price"},
+		{name: "negative egress price", file: "egress_cost_per_gb: -0.01\n", wantErr: "egress_cost_per_gb: negative price"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "pricing.yaml")
+			if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			cc := &CostCalculator{NodeCostPerHour: map[string]float64{"default": 0.1}, StorageCostPerGB: 0.1, GPUCostPerHour: 1}
+			err := cc.loadPricingFile(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cc.NodeCostPerHour, tt.wantNode) || cc.StorageCostPerGB != tt.wantStorage || cc.GPUCostPerHour != tt.wantGPU {
+				t.Errorf("prices %v, storage %v, gpu %v; want %v, %v, %v", cc.NodeCostPerHour, cc.StorageCostPerGB, cc.GPUCostPerHour, tt.wantNode, tt.wantStorage, tt.wantGPU)
+			}
+			if cc.EphemeralStorageCostPerGB != tt.wantEphemeral || cc.EgressCostPerGB != tt.wantEgress {
+				t.Errorf("ephemeral storage %v, egress %v; want %v, %v", cc.EphemeralStorageCostPerGB, cc.EgressCostPerGB, tt.wantEphemeral, tt.wantEgress)
+			}
+		})
+	}
+}
+
+// TestPricingFileNodeCost checks that a custom m6i.large price from the
+// pricing file reaches the node's estimated cost
+func TestPricingFileNodeCost(t *testing.T) {
+	t.Setenv("OPTIMKUBE_PRICING_FILE", filepath.Join("testdata", "pricing.yaml"))
+	co, _ := newTestOptimizer(t,
+		testNode("worker-m6i.large-1", "2", "8Gi"), testNodeMetrics("worker-m6i.large-1", "1", "4Gi"))
+
+	nodes := co.getNodeMetrics(context.Background())
+	if len(nodes) != 1 {
+		t.Fatalf("got %d node metrics, want 1", len(nodes))
+	}
+	if got, want := nodes[0].EstimatedCost, 0.2*24*30; nodes[0].InstanceType != "m6i.large" || math.Abs(got-want) > 1e-9 {
+		t.Errorf("node %s costs %v, want m6i.large at %v", nodes[0].InstanceType, got, want)
+	}
+	if co.costCalculator.StorageCostPerGB != 0.08 {
+		t.Errorf("storage price %v, want 0.08", co.costCalculator.StorageCostPerGB)
+	}
+
+	t.Setenv("OPTIMKUBE_PRICING_FILE", filepath.Join("testdata", "missing.yaml"))
+	if _, err := NewCostOptimizer(); err == nil {
+		t.Error("NewCostOptimizer succeeded with a missing pricing file")
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+)
+
+// errProtected is returned when an action targets a protected resource
+var errProtected = errors.New("resource is protected from optimization actions")
+
+// ProtectedSelector matches resources, such as ingress controllers or
+// databases, that must never be acted on automatically. Every field that is
+// set has to match; namespace and name accept globs like "ingress-*".
+type ProtectedSelector struct {
+	Namespace string            `json:"namespace,omitempty"`
+	Name      string            `json:"name,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+func (p *ProtectedSelector) validate() error {
+	if p.Namespace == "" && p.Name == "" && len(p.Labels) == 0 {
+		return errors.New("set at least one of namespace, name or labels")
+	}
+	for _, pattern := range []string{p.Namespace, p.Name} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the selector covers a resource. resource is the
+// namespace/name form recommendations and actions use; labels may be nil when
+// the object's labels aren't known, in which case a label selector can't match.
+func (p *ProtectedSelector) matches(namespace, resource string, labels map[string]string) bool {
+	if p.Namespace != "" {
+		if ok, _ := path.Match(p.Namespace, namespace); !ok {
+			return false
+		}
+	}
+	if p.Name != "" {
+		name := resourceName(resource)
+		if ok, _ := path.Match(p.Name, name); !ok {
+			return false
+		}
+	}
+	for key, value := range p.Labels {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (co *CostOptimizer) resourceProtected(namespace, resource string, labels map[string]string) bool {
+	for i := range co.protectedSelectors {
+		if co.protectedSelectors[i].matches(namespace, resource, labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// markProtected flags recommendations for protected resources. They stay in
+// the list for information, but lose their action hint so automation has
+// nothing to execute, and the resources are remembered so actions on them are
+// refused even when the action alone doesn't reveal the object's labels.
+func (co *CostOptimizer) markProtected(recommendations []Recommendation) {
+	protected := make(map[string]bool)
+	for i := range recommendations {
+		rec := &recommendations[i]
+		if !co.resourceProtected(rec.Namespace, rec.Resource, rec.labels) {
+			continue
+		}
+		rec.Protected = true
+		rec.ActionHint = nil
+		protected[rec.Resource] = true
+	}
+
+	co.protectedMu.Lock()
+	co.protectedResources = protected
+	co.protectedMu.Unlock()
+}
+
+// actionProtected reports whether an action targets a protected resource,
+// either by selector or because the last scan found it protected by label
+func (co *CostOptimizer) actionProtected(action OptimizationAction) bool {
+	if co.resourceProtected(action.Namespace, action.Resource, nil) {
+		return true
+	}
+	co.protectedMu.Lock()
+	defer co.protectedMu.Unlock()
+	return co.protectedResources[action.Resource]
+}
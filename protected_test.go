@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestProtectedSelectorMatches(t *testing.T) {
+	labels := map[string]string{"app.kubernetes.io/component": "database", "tier": "data"}
+	tests := []struct {
+		name     string
+		selector ProtectedSelector
+		resource string
+		labels   map[string]string
+		want     bool
+	}{
+		{name: "namespace glob", selector: ProtectedSelector{Namespace: "ingress-*"}, resource: "ingress-nginx/controller", want: true},
+		{name: "other namespace", selector: ProtectedSelector{Namespace: "ingress-*"}, resource: "shop/controller"},
+		{name: "name glob", selector: ProtectedSelector{Name: "postgres-*"}, resource: "shop/postgres-0", want: true},
+		{name: "cluster-scoped name", selector: ProtectedSelector{Name: "gpu-*"}, resource: "gpu-node-1", want: true},
+		{name: "labels", selector: ProtectedSelector{Labels: map[string]string{"app.kubernetes.io/component": "database"}}, resource: "shop/orders", labels: labels, want: true},
+		{name: "labels unknown", selector: ProtectedSelector{Labels: map[string]string{"app.kubernetes.io/component": "database"}}, resource: "shop/orders"},
+		{name: "every field must match", selector: ProtectedSelector{Namespace: "shop", Labels: map[string]string{"tier": "web"}}, resource: "shop/orders", labels: labels},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace, _, _ := strings.Cut(tt.resource, "/")
+			if !strings.Contains(tt.resource, "/") {
+				namespace = ""
+			}
+			if got := tt.selector.matches(namespace, tt.resource, tt.labels); got != tt.want {
+				t.Errorf("matches(%s) = %v, want %v", tt.resource, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFileConfigProtected(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    int
+		wantErr string
+	}{
+		{name: "selectors", config: `{"protected": [{"namespace": "ingress-*"}, {"labels": {"tier": "data"}}]}`, want: 2},
+		{name: "empty selector", config: `{"protected": [{}]}`, wantErr: "protected[0]: set at least one"},
+		{name: "bad glob", config: `{"protected": [{"name": "db-["}]}`, wantErr: "protected[0]: invalid pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(tt.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := loadFileConfig(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cfg.Protected) != tt.want {
+				t.Errorf("%d selectors, want %d", len(cfg.Protected), tt.want)
+			}
+		})
+	}
+}
+
+func TestProtectedResourcesYieldNoActions(t *testing.T) {
+	// The database is over-provisioned but protected by its label
+	db := testPod("shop", "orders-db", "node-1", "2", "1Gi")
+	db.Labels = map[string]string{"app.kubernetes.io/component": "database"}
+	co, clientset := newTestOptimizer(t,
+		db, testPodMetrics("shop", "orders-db", "100m", "1Gi"),
+		testPod("shop", "web", "node-1", "2", "1Gi"), testPodMetrics("shop", "web", "100m", "1Gi"),
+		testDeployment("ingress-nginx", "controller", 2),
+	)
+	co.protectedSelectors = []ProtectedSelector{
+		{Labels: map[string]string{"app.kubernetes.io/component": "database"}},
+		{Namespace: "ingress-*"},
+	}
+	co.analyzeAndGenerateRecommendations(context.Background())
+
+	protected := make(map[string]bool)
+	for _, rec := range co.recommendations {
+		if rec.Resource != "shop/orders-db" && rec.Resource != "shop/web" {
+			continue
+		}
+		protected[rec.Resource] = rec.Protected
+		if rec.Protected && rec.ActionHint != nil {
+			t.Errorf("protected %s kept its action hint %+v", rec.Resource, rec.ActionHint)
+		}
+	}
+	if want := map[string]bool{"shop/orders-db": true, "shop/web": false}; !reflect.DeepEqual(protected, want) {
+		t.Fatalf("protected recommendations %v, want %v", protected, want)
+	}
+
+	// Actions on protected resources, whether matched by the selector alone
+	// or by the labels the scan saw, are refused
+	co.actions = []OptimizationAction{
+		{ID: "resize-db", Type: "rightsize", Resource: "shop/orders-db", Namespace: "shop", Status: actionStatusPending},
+		{ID: "scale-ingress", Type: "scale_down", Resource: "ingress-nginx/controller", Namespace: "ingress-nginx", Action: "scale", Parameters: map[string]interface{}{"replicas": 1}, Status: actionStatusQueued},
+		{ID: "resize-web", Type: "rightsize", Resource: "shop/web", Namespace: "shop", Status: actionStatusPending},
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/actions", co.handleActions).Methods("GET")
+	router.HandleFunc("/api/actions/{id}/execute", co.handleExecuteAction).Methods("POST")
+
+	tests := []struct {
+		id   string
+		want int
+	}{
+		{id: "resize-db", want: http.StatusForbidden},
+		{id: "resize-web", want: http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		if rec := serve(router, http.MethodPost, "/api/actions/"+tt.id+"/execute", nil); rec.Code != tt.want {
+			t.Errorf("execute %s: status %d, want %d: %s", tt.id, rec.Code, tt.want, rec.Body)
+		}
+	}
+
+	var listed []OptimizationAction
+	if err := json.Unmarshal(serve(router, http.MethodGet, "/api/actions", nil).Body.Bytes(), &listed); err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range listed {
+		if want := action.ID != "resize-web"; action.Protected != want {
+			t.Errorf("action %s protected %v, want %v", action.ID, action.Protected, want)
+		}
+	}
+
+	// An action queued before the resource became protected fails in the worker
+	co.processAction("scale-ingress")
+	if action, _ := co.getAction("scale-ingress"); action.Status != actionStatusFailed || action.Error != errProtected.Error() {
+		t.Errorf("action = %s %q, want failed as protected", action.Status, action.Error)
+	}
+	got, err := clientset.AppsV1().Deployments("ingress-nginx").Get(context.Background(), "controller", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got.Spec.Replicas != 2 {
+		t.Errorf("replicas = %d, want the deployment untouched", *got.Spec.Replicas)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// QuietWindow is a recurring period, such as business hours, during which no
+// mutating actions execute. A window whose end is before its start runs past
+// midnight into the next day.
+type QuietWindow struct {
+	Days     []string `json:"days,omitempty"` // e.g. mon, tue; every day when empty
+	Start    string   `json:"start"`          // HH:MM
+	End      string   `json:"end"`            // HH:MM
+	Timezone string   `json:"timezone,omitempty"`
+
+	days       map[time.Weekday]bool
+	start, end time.Duration
+	location   *time.Location
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func (q *QuietWindow) validate() error {
+	var err error
+	if q.start, err = parseClock(q.Start); err != nil {
+		return fmt.Errorf("start: %w", err)
+	}
+	if q.end, err = parseClock(q.End); err != nil {
+		return fmt.Errorf("end: %w", err)
+	}
+	if q.start == q.end {
+		return errors.New("start and end must differ")
+	}
+
+	q.location = time.UTC
+	if q.Timezone != "" {
+		if q.location, err = time.LoadLocation(q.Timezone); err != nil {
+			return fmt.Errorf("timezone: %w", err)
+		}
+	}
+
+	q.days = make(map[time.Weekday]bool)
+	for _, day := range q.Days {
+		weekday, ok := weekdays[strings.ToLower(day)[:min(3, len(day))]]
+		if !ok {
+			return fmt.Errorf("unknown day %q", day)
+		}
+		q.days[weekday] = true
+	}
+	return nil
+}
+
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM, got %q", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// contains reports whether at falls inside the window. For overnight windows
+// the day filter applies to the day the window starts.
+func (q *QuietWindow) contains(at time.Time) bool {
+	local := at.In(q.location)
+	sinceMidnight := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute + time.Duration(local.Second())*time.Second
+
+	if q.start < q.end {
+		return q.activeOn(local.Weekday()) && sinceMidnight >= q.start && sinceMidnight < q.end
+	}
+	if sinceMidnight >= q.start {
+		return q.activeOn(local.Weekday())
+	}
+	return sinceMidnight < q.end && q.activeOn(local.AddDate(0, 0, -1).Weekday())
+}
+
+func (q *QuietWindow) activeOn(day time.Weekday) bool {
+	return len(q.days) == 0 || q.days[day]
+}
+
+// changeFreeze reads the freeze file named by OPTIMKUBE_CHANGE_FREEZE_FILE.
+// While the file exists mutations are frozen; if it holds an RFC3339 time the
+// freeze lifts at that time, otherwise it lasts until the file is removed.
+func (co *CostOptimizer) changeFreeze(now time.Time) (frozen bool, until time.Time) {
+	if co.freezeFile == "" {
+		return false, time.Time{}
+	}
+	data, err := os.ReadFile(co.freezeFile)
+	if err != nil {
+		return false, time.Time{}
+	}
+	if until, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data))); err == nil {
+		return now.Before(until), until
+	}
+	return true, time.Time{}
+}
+
+// mutationBlocked reports why mutating actions may not run at now, and when
+// they next may. next is zero when that isn't known, e.g. an open-ended freeze.
+func (co *CostOptimizer) mutationBlocked(now time.Time) (reason string, next time.Time) {
+	if frozen, until := co.changeFreeze(now); frozen {
+		return "change freeze in effect", until
+	}
+
+	blocked := func(at time.Time) bool {
+		for i := range co.quietWindows {
+			if co.quietWindows[i].contains(at) {
+				return true
+			}
+		}
+		return false
+	}
+	if !blocked(now) {
+		return "", time.Time{}
+	}
+
+	// Windows are minute-aligned, so stepping by minutes finds the exact end
+	next = now.Truncate(time.Minute)
+	for limit := next.Add(8 * 24 * time.Hour); next.Before(limit); {
+		next = next.Add(time.Minute)
+		if !blocked(next) {
+			return "quiet hours in effect", next
+		}
+	}
+	return "quiet hours in effect", time.Time{}
+}
+
+// writeLocked rejects a mutation with 423 Locked and the next allowed time
+func writeLocked(w http.ResponseWriter, reason string, now, next time.Time) {
+	body := map[string]string{"error": reason}
+	if !next.IsZero() {
+		body["next_allowed_at"] = next.UTC().Format(time.RFC3339)
+		w.Header().Set("Retry-After", fmt.Sprintf("%.0f", next.Sub(now).Seconds()))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusLocked)
+	json.NewEncoder(w).Encode(body)
+}
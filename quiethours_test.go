@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func quietWindow(t *testing.T, days []string, start, end string) QuietWindow {
+	t.Helper()
+	window := QuietWindow{Days: days, Start: start, End: end}
+	if err := window.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	return window
+}
+
+func TestQuietWindowContains(t *testing.T) {
+	weekdays := []string{"mon", "tue", "wed", "thu", "fri"}
+	tests := []struct {
+		name   string
+		window QuietWindow
+		at     time.Time
+		want   bool
+	}{
+		{"business hours", quietWindow(t, weekdays, "09:00", "17:00"), time.Date(2026, 10, 19, 10, 30, 0, 0, time.UTC), true},
+		{"before start", quietWindow(t, weekdays, "09:00", "17:00"), time.Date(2026, 10, 19, 8, 59, 0, 0, time.UTC), false},
+		{"end is exclusive", quietWindow(t, weekdays, "09:00", "17:00"), time.Date(2026, 10, 19, 17, 0, 0, 0, time.UTC), false},
+		{"weekend", quietWindow(t, weekdays, "09:00", "17:00"), time.Date(2026, 10, 17, 10, 30, 0, 0, time.UTC), false},
+		{"every day", quietWindow(t, nil, "09:00", "17:00"), time.Date(2026, 10, 17, 10, 30, 0, 0, time.UTC), true},
+		{"overnight evening", quietWindow(t, []string{"friday"}, "22:00", "06:00"), time.Date(2026, 10, 23, 23, 0, 0, 0, time.UTC), true},
+		{"overnight morning after", quietWindow(t, []string{"friday"}, "22:00", "06:00"), time.Date(2026, 10, 24, 5, 0, 0, 0, time.UTC), true},
+		{"overnight other day", quietWindow(t, []string{"friday"}, "22:00", "06:00"), time.Date(2026, 10, 23, 5, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.contains(tt.at); got != tt.want {
+				t.Errorf("contains(%s) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuietWindowValidate(t *testing.T) {
+	tests := []QuietWindow{
+		{Start: "9am", End: "17:00"},
+		{Start: "09:00", End: "25:00"},
+		{Start: "09:00", End: "09:00"},
+		{Start: "09:00", End: "17:00", Days: []string{"someday"}},
+		{Start: "09:00", End: "17:00", Timezone: "Mars/Olympus"},
+	}
+
+	for _, window := range tests {
+		if err := window.validate(); err == nil {
+			t.Errorf("validate(%+v) succeeded, want an error", window)
+		}
+	}
+}
+
+func TestExecuteActionQuietHours(t *testing.T) {
+	monday := time.Date(2026, 10, 19, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		now        time.Time
+		freeze     string // freeze file contents; no file when empty
+		wantStatus int
+		wantNext   string
+	}{
+		{name: "inside quiet hours", now: monday, wantStatus: http.StatusLocked, wantNext: "2026-10-19T17:00:00Z"},
+		{name: "outside quiet hours", now: monday.Add(-3 * time.Hour), wantStatus: http.StatusAccepted},
+		{name: "open-ended freeze", now: monday.Add(-3 * time.Hour), freeze: "release week", wantStatus: http.StatusLocked},
+		{name: "freeze until later", now: monday.Add(-3 * time.Hour), freeze: "2026-10-20T00:00:00Z", wantStatus: http.StatusLocked, wantNext: "2026-10-20T00:00:00Z"},
+		{name: "lifted freeze", now: monday.Add(-3 * time.Hour), freeze: "2026-10-19T00:00:00Z", wantStatus: http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			optimizer, _ := newTestOptimizer(t)
+			optimizer.now = func() time.Time { return tt.now }
+			optimizer.quietWindows = []QuietWindow{quietWindow(t, []string{"mon", "tue", "wed", "thu", "fri"}, "09:00", "17:00")}
+			if tt.freeze != "" {
+				optimizer.freezeFile = filepath.Join(t.TempDir(), "freeze")
+				if err := os.WriteFile(optimizer.freezeFile, []byte(tt.freeze), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			optimizer.actions = []OptimizationAction{{ID: "scale-web", Type: "scale_down", Parameters: map[string]interface{}{"replicas": 1}, Status: actionStatusPending}}
+
+			router := mux.NewRouter()
+			router.HandleFunc("/api/actions/{id}/execute", optimizer.handleExecuteAction).Methods("POST")
+			rec := serve(router, http.MethodPost, "/api/actions/scale-web/execute", nil)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusLocked {
+				return
+			}
+			var body map[string]string
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body["next_allowed_at"] != tt.wantNext {
+				t.Errorf("next_allowed_at = %q, want %q", body["next_allowed_at"], tt.wantNext)
+			}
+			if (rec.Header().Get("Retry-After") != "") != (tt.wantNext != "") {
+				t.Errorf("Retry-After = %q with next_allowed_at %q", rec.Header().Get("Retry-After"), tt.wantNext)
+			}
+			if action, _ := optimizer.getAction("scale-web"); action.Status != actionStatusPending {
+				t.Errorf("locked action status = %q, want it left pending", action.Status)
+			}
+		})
+	}
+}
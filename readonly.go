@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// errReadOnly is returned for any attempted cluster mutation in read-only mode
+var errReadOnly = errors.New("optimkube is running in read-only mode")
+
+// readOnlyTransport refuses every request that isn't a read, so even a code
+// path that forgets to check the mode can't change the cluster
+type readOnlyTransport struct {
+	next http.RoundTripper
+}
+
+func (t *readOnlyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return t.next.RoundTrip(req)
+	}
+	return nil, fmt.Errorf("%w: refusing %s %s", errReadOnly, req.Method, req.URL.Path)
+}
+
+// mutating guards handlers that change cluster state, answering 403 in
+// read-only mode
+func (co *CostOptimizer) mutating(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if co.readOnly {
+			http.Error(w, errReadOnly.Error(), http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
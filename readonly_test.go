@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestReadOnlyTransportRefusesWrites(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Method)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"web","namespace":"shop"}}`))
+	}))
+	defer server.Close()
+
+	config := &rest.Config{Host: server.URL}
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &readOnlyTransport{next: rt}
+	})
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployments := clientset.AppsV1().Deployments("shop")
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		call    func() error
+		allowed bool
+	}{
+		{name: "get", allowed: true, call: func() error {
+			_, err := deployments.Get(ctx, "web", metav1.GetOptions{})
+			return err
+		}},
+		{name: "patch", call: func() error {
+			_, err := deployments.Patch(ctx, "web", types.MergePatchType, []byte(`{"spec":{"replicas":1}}`), metav1.PatchOptions{})
+			return err
+		}},
+		{name: "update", call: func() error {
+			_, err := deployments.Update(ctx, testDeployment("shop", "web", 1), metav1.UpdateOptions{})
+			return err
+		}},
+		{name: "delete", call: func() error {
+			return deployments.Delete(ctx, "web", metav1.DeleteOptions{})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			seen = nil
+			mu.Unlock()
+
+			err := tt.call()
+			if tt.allowed && err != nil {
+				t.Fatalf("read refused: %v", err)
+			}
+			if !tt.allowed && !errors.Is(err, errReadOnly) {
+				t.Fatalf("error = %v, want errReadOnly", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if reached := len(seen) > 0; reached != tt.allowed {
+				t.Errorf("API server saw %v, want reached %v", seen, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestReadOnlyModeEndpoints(t *testing.T) {
+	tests := []struct {
+		method, target string
+		readOnly       bool
+		want           int
+	}{
+		{method: http.MethodPost, target: "/api/actions/scale-web/execute", readOnly: true, want: http.StatusForbidden},
+		{method: http.MethodGet, target: "/api/actions", readOnly: true, want: http.StatusOK},
+		{method: http.MethodGet, target: "/api/actions/scale-web", readOnly: true, want: http.StatusOK},
+		{method: http.MethodPost, target: "/api/actions/scale-web/execute", want: http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			co, _ := newTestOptimizer(t)
+			co.readOnly = tt.readOnly
+			co.actions = []OptimizationAction{{ID: "scale-web", Type: "scale_down", Parameters: map[string]interface{}{"replicas": 1}, Status: actionStatusPending}}
+
+			router := mux.NewRouter()
+			router.HandleFunc("/api/actions", co.handleActions).Methods("GET")
+			router.HandleFunc("/api/actions/{id}", co.handleGetAction).Methods("GET")
+			router.HandleFunc("/api/actions/{id}/execute", co.mutating(co.handleExecuteAction)).Methods("POST")
+
+			if rec := serve(router, tt.method, tt.target, nil); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestReadOnlyActionWorkerRefusesQueuedActions(t *testing.T) {
+	deployment := testDeployment("shop", "web", 3)
+	co, clientset := newTestOptimizer(t, deployment)
+	co.readOnly = true
+	co.actions = []OptimizationAction{{ID: "scale-web", Type: "scale_down", Resource: "shop/web", Namespace: "shop", Action: "scale", Parameters: map[string]interface{}{"replicas": 1}, Status: actionStatusQueued}}
+
+	co.processAction("scale-web")
+
+	action, _ := co.getAction("scale-web")
+	if action.Status != actionStatusFailed || action.Error != errReadOnly.Error() {
+		t.Errorf("action = %s %q, want failed with the read-only error", action.Status, action.Error)
+	}
+	got, err := clientset.AppsV1().Deployments("shop").Get(context.Background(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got.Spec.Replicas != 3 {
+		t.Errorf("replicas = %d, want the deployment untouched", *got.Spec.Replicas)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Recommendation categories, so automation can branch on the kind of change
+// without parsing descriptions
+const (
+	CategoryScale     = "scale"
+	CategoryRightsize = "rightsize"
+	CategoryDelete    = "delete"
+	CategoryConfigure = "configure"
+)
+
+// ActionHint describes the change a recommendation calls for in machine
+// terms. Target is "kind/namespace/name", or "kind/name" when cluster-scoped;
+// Field is a path within the target object.
+type ActionHint struct {
+	Verb         string `json:"verb"` // patch, scale, create, drain
+	Target       string `json:"target"`
+	Field        string `json:"field,omitempty"`
+	CurrentValue string `json:"current_value,omitempty"`
+	NewValue     string `json:"new_value,omitempty"`
+}
+
+// hintTarget formats an ActionHint target; namespace may be empty
+func hintTarget(kind, namespace, name string) string {
+	if namespace == "" {
+		return kind + "/" + name
+	}
+	return kind + "/" + namespace + "/" + name
+}
+
+// dedupRecommendations drops exact repeats of a finding within one scan: the
+// same type, resource and action hint with the same description. Findings
+// that only share a target are kept; stampIDs numbers them apart.
+func dedupRecommendations(recommendations []Recommendation) []Recommendation {
+	seen := make(map[string]bool, len(recommendations))
+	kept := recommendations[:0]
+	for _, rec := range recommendations {
+		key := rec.Type + "|" + rec.Namespace + "|" + rec.Resource + "|" + rec.Description
+		if rec.ActionHint != nil {
+			key += "|" + rec.ActionHint.Target + "|" + rec.ActionHint.Field + "|" + rec.ActionHint.NewValue
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, rec)
+	}
+	return kept
+}
+
+// stampSeen marks recommendations as seen at now. One that was already
+// present in previous, by ID, keeps the time it was first seen, so a finding
+// that persists across scans (and restarts) shows how long it has been open.
+func stampSeen(recommendations, previous []Recommendation, now time.Time) {
+	firstSeen := make(map[string]time.Time, len(previous))
+	for _, rec := range previous {
+		if !rec.FirstSeen.IsZero() {
+			firstSeen[rec.ID] = rec.FirstSeen
+		}
+	}
+	for i := range recommendations {
+		rec := &recommendations[i]
+		rec.FirstSeen = now
+		if seen, ok := firstSeen[rec.ID]; ok {
+			rec.FirstSeen = seen
+		}
+		rec.LastSeen = now
+	}
+}
+
+// defaultRecommendationTTL bounds how long a recommendation is served after the
+// scan that produced it, so findings don't linger when no new scan replaces them
+const defaultRecommendationTTL = 24 * time.Hour
+
+// stampExpiry sets ExpiresAt on every recommendation from a scan finished at now
+func (co *CostOptimizer) stampExpiry(recommendations []Recommendation, now time.Time) {
+	if co.recommendationTTL <= 0 {
+		return
+	}
+	expiresAt := now.Add(co.recommendationTTL)
+	for i := range recommendations {
+		recommendations[i].ExpiresAt = &expiresAt
+	}
+}
+
+// loadRecommendations restores the last scan's recommendations, so they're
+// served until the first scan after a restart replaces them. Expired ones are
+// filtered on read as usual.
+func (co *CostOptimizer) loadRecommendations() error {
+	if co.store == nil {
+		return nil
+	}
+	recommendations, err := co.store.LoadRecommendations()
+	if err != nil {
+		return err
+	}
+	co.recommendationsMu.Lock()
+	co.recommendations = recommendations
+	co.recommendationsMu.Unlock()
+	// What was announced before the restart isn't announced again
+	co.notifiedRecommendations = notifiableKeys(recommendations)
+	return nil
+}
+
+// saveRecommendations persists a scan's recommendations
+func (co *CostOptimizer) saveRecommendations(recommendations []Recommendation) {
+	if co.store == nil {
+		return
+	}
+	if err := co.store.SaveRecommendations(recommendations); err != nil {
+		slog.Error("Failed to persist recommendations", "error", err)
+	}
+}
+
+// activeRecommendations returns a copy of the current recommendations that
+// haven't expired, safe to use while a scan replaces them
+func (co *CostOptimizer) activeRecommendations() []Recommendation {
+	co.recommendationsMu.RLock()
+	defer co.recommendationsMu.RUnlock()
+	return filterExpired(co.recommendations, co.now())
+}
+
+func filterExpired(recommendations []Recommendation, now time.Time) []Recommendation {
+	active := make([]Recommendation, 0, len(recommendations))
+	for _, rec := range recommendations {
+		if rec.ExpiresAt != nil && !now.Before(*rec.ExpiresAt) {
+			continue
+		}
+		active = append(active, rec)
+	}
+	return active
+}
+
+// priorityRank orders priorities for sorting, most urgent first
+var priorityRank = map[string]int{"high": 0, "medium": 1, "low": 2}
+
+// RecommendationQuery filters, sorts and pages the recommendations list
+type RecommendationQuery struct {
+	Namespace  string  `json:"namespace,omitempty"`
+	Type       string  `json:"type,omitempty"`
+	Priority   string  `json:"priority,omitempty"`
+	MinSavings float64 `json:"min_savings,omitempty"`
+	Sort       string  `json:"sort,omitempty"` // savings or priority
+	Limit      int     `json:"limit,omitempty"`
+	Offset     int     `json:"offset,omitempty"`
+}
+
+// RecommendationPage is one page of recommendations matching a query
+type RecommendationPage struct {
+	Items   []Recommendation    `json:"items"`
+	Total   int                 `json:"total"` // matches across all pages
+	Filters RecommendationQuery `json:"filters"`
+}
+
+// parseRecommendationQuery reads a query from URL parameters, rejecting values
+// that can't be applied
+func parseRecommendationQuery(values url.Values) (RecommendationQuery, error) {
+	query := RecommendationQuery{
+		Namespace: values.Get("namespace"),
+		Type:      values.Get("type"),
+		Priority:  values.Get("priority"),
+		Sort:      values.Get("sort"),
+	}
+	if _, ok := priorityRank[query.Priority]; query.Priority != "" && !ok {
+		return query, fmt.Errorf("invalid priority %q: expected high, medium or low", query.Priority)
+	}
+	if query.Sort != "" && query.Sort != "savings" && query.Sort != "priority" {
+		return query, fmt.Errorf("invalid sort %q: expected savings or priority", query.Sort)
+	}
+	if raw := values.Get("min_savings"); raw != "" {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(value) {
+			return query, fmt.Errorf("invalid min_savings %q: expected a number", raw)
+		}
+		query.MinSavings = value
+	}
+	if raw := values.Get("limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 {
+			return query, fmt.Errorf("invalid limit %q: expected a positive integer", raw)
+		}
+		query.Limit = value
+	}
+	if raw := values.Get("offset"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			return query, fmt.Errorf("invalid offset %q: expected a non-negative integer", raw)
+		}
+		query.Offset = value
+	}
+	return query, nil
+}
+
+// apply filters and sorts recommendations and cuts out the requested page.
+// Without a sort the scan order is kept.
+func (q RecommendationQuery) apply(recommendations []Recommendation) RecommendationPage {
+	items := make([]Recommendation, 0, len(recommendations))
+	for _, rec := range recommendations {
+		if (q.Namespace != "" && rec.Namespace != q.Namespace) ||
+			(q.Type != "" && rec.Type != q.Type) ||
+			(q.Priority != "" && rec.Priority != q.Priority) ||
+			rec.Savings < q.MinSavings {
+			continue
+		}
+		items = append(items, rec)
+	}
+
+	switch q.Sort {
+	case "savings":
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Savings > items[j].Savings })
+	case "priority":
+		sort.SliceStable(items, func(i, j int) bool {
+			if items[i].Priority != items[j].Priority {
+				return priorityRank[items[i].Priority] < priorityRank[items[j].Priority]
+			}
+			return items[i].Savings > items[j].Savings
+		})
+	}
+
+	total := len(items)
+	items = items[min(q.Offset, total):]
+	if q.Limit > 0 && q.Limit < len(items) {
+		items = items[:q.Limit]
+	}
+	return RecommendationPage{Items: items, Total: total, Filters: q}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestExpiredRecommendationsExcluded(t *testing.T) {
+	now := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	expired, live := now.Add(-time.Minute), now.Add(time.Hour)
+	co, _ := newTestOptimizer(t)
+	co.now = func() time.Time { return now }
+	co.recommendations = []Recommendation{
+		{Resource: "default/stale", Savings: 100, ExpiresAt: &expired},
+		{Resource: "default/expiring-now", Savings: 100, ExpiresAt: &now},
+		{Resource: "default/fresh", Savings: 10, ExpiresAt: &live},
+		{Resource: "default/no-expiry", Savings: 1},
+	}
+
+	rec := serve(http.HandlerFunc(co.handleRecommendations), http.MethodGet, "/api/recommendations", nil)
+	var page RecommendationPage
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decode recommendations: %v", err)
+	}
+	served := page.Items
+	if len(served) != 2 || served[0].Resource != "default/fresh" || served[1].Resource != "default/no-expiry" {
+		t.Errorf("served %+v, want only the unexpired recommendations", served)
+	}
+
+	summary := co.generateCostSummary(context.Background())
+	if summary.RecommendationCount != 2 || summary.PotentialSavings != 11 {
+		t.Errorf("summary counts %d recommendations saving %v, want 2 saving 11", summary.RecommendationCount, summary.PotentialSavings)
+	}
+}
+
+func TestRecommendationTTL(t *testing.T) {
+	now := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ttl  string // OPTIMKUBE_RECOMMENDATION_TTL
+		want *time.Time
+	}{
+		{ttl: "", want: timePtr(now.Add(defaultRecommendationTTL))},
+		{ttl: "90m", want: timePtr(now.Add(90 * time.Minute))},
+		{ttl: "0", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("OPTIMKUBE_RECOMMENDATION_TTL", tt.ttl)
+		co, _ := newTestOptimizer(t)
+		recommendations := []Recommendation{{Resource: "default/web"}}
+		co.stampExpiry(recommendations, now)
+
+		got := recommendations[0].ExpiresAt
+		if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+			t.Errorf("TTL %q: expires at %v, want %v", tt.ttl, got, tt.want)
+		}
+	}
+}
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+func TestRightsizingActionHintMatchesDescription(t *testing.T) {
+	tests := []struct {
+		name            string
+		cpu, memory     string // requests
+		cpuUsage        string
+		memoryUsage     string
+		wantDescription string
+		wantHint        ActionHint
+	}{
+		{
+			name: "cpu", cpu: "2", memory: "1Gi", cpuUsage: "100m", memoryUsage: "1Gi",
+			wantDescription: "Container app is over-provisioned for CPU (request: 2000m, usage: 100m, suggested: 120m)",
+			wantHint:        ActionHint{Verb: "patch", Target: "pod/shop/api", Field: "spec.containers[name=app].resources.requests.cpu", CurrentValue: "2000m", NewValue: "120m"},
+		},
+		{
+			name: "memory", cpu: "100m", memory: "4Gi", cpuUsage: "100m", memoryUsage: "1Gi",
+			wantDescription: "Container app is over-provisioned for memory (request: 4Gi, usage: 1Gi, suggested: 1232Mi)",
+			wantHint:        ActionHint{Verb: "patch", Target: "pod/shop/api", Field: "spec.containers[name=app].resources.requests.memory", CurrentValue: "4Gi", NewValue: "1232Mi"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t,
+				testPod("shop", "api", "node-1", tt.cpu, tt.memory),
+				testPodMetrics("shop", "api", tt.cpuUsage, tt.memoryUsage))
+
+			recommendations := co.analyzePods(context.Background())
+			if len(recommendations) != 1 {
+				t.Fatalf("got %d recommendations, want 1: %+v", len(recommendations), recommendations)
+			}
+			rec := recommendations[0]
+			if rec.Description != tt.wantDescription {
+				t.Errorf("Description = %q, want %q", rec.Description, tt.wantDescription)
+			}
+			if rec.Category != CategoryRightsize {
+				t.Errorf("Category = %q, want %q", rec.Category, CategoryRightsize)
+			}
+			if rec.ActionHint == nil || *rec.ActionHint != tt.wantHint {
+				t.Errorf("ActionHint = %+v, want %+v", rec.ActionHint, tt.wantHint)
+			}
+		})
+	}
+}
+
+func TestEveryRecommendationHasCategory(t *testing.T) {
+	web := testDeployment("shop", "web", 3)
+	co, _ := newTestOptimizer(t,
+		testNode("node-1", "8", "32Gi"), testNodeMetrics("node-1", "1", "4Gi"),
+		testPod("shop", "api", "node-1", "2", "4Gi"), testPodMetrics("shop", "api", "100m", "1Gi"),
+		web, testReplica(web, "web-a", "node-1"),
+	)
+
+	co.analyzeAndGenerateRecommendations(context.Background())
+	if len(co.recommendations) == 0 {
+		t.Fatal("scan produced no recommendations")
+	}
+	for _, rec := range co.recommendations {
+		switch rec.Category {
+		case CategoryScale, CategoryRightsize, CategoryDelete, CategoryConfigure:
+		default:
+			t.Errorf("%s %s has category %q", rec.Type, rec.Resource, rec.Category)
+		}
+	}
+}
+
+func TestHintTarget(t *testing.T) {
+	if got := hintTarget("deployment", "shop", "web"); got != "deployment/shop/web" {
+		t.Errorf("namespaced target = %q", got)
+	}
+	if got := hintTarget("node", "", "node-1"); got != "node/node-1" {
+		t.Errorf("cluster-scoped target = %q", got)
+	}
+}
+
+// countingNotifier counts notifications
+type countingNotifier struct{ sent atomic.Int64 }
+
+func (n *countingNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.sent.Add(1)
+	return nil
+}
+
+// slowLogExporter counts exported records, holding each export long enough
+// for scans that aren't serialized to overlap
+type slowLogExporter struct{ exported atomic.Int64 }
+
+func (e *slowLogExporter) ExportLogs(ctx context.Context, records []Recommendation) error {
+	time.Sleep(20 * time.Millisecond)
+	e.exported.Add(int64(len(records)))
+	return nil
+}
+
+// TestConcurrentScansAndReads is meant for go test -race: the monitor loop's
+// scans, scans started by POST /api/optimize, and API reads all overlap
+func TestConcurrentScansAndReads(t *testing.T) {
+	co, _ := newTestOptimizer(t, testNode("node-1", "4", "16Gi"), testDeployment("default", "web", 3))
+	co.notifier = &countingNotifier{}
+	co.logExporter = &slowLogExporter{}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/recommendations", co.handleRecommendations).Methods("GET")
+	router.HandleFunc("/api/optimize", co.handleOptimize).Methods("POST")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			co.analyzeAndGenerateRecommendations(context.Background())
+		}()
+		go func() {
+			defer wg.Done()
+			if rec := serve(router, http.MethodPost, "/api/optimize", nil); rec.Code != http.StatusOK {
+				t.Errorf("POST /api/optimize: status %d", rec.Code)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if rec := serve(router, http.MethodGet, "/api/recommendations", nil); rec.Code != http.StatusOK {
+					t.Errorf("GET /api/recommendations: status %d", rec.Code)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Wait out the scans the optimize requests started in the background
+	co.scanMu.Lock()
+	co.scanMu.Unlock()
+
+	if len(co.activeRecommendations()) == 0 {
+		t.Error("no recommendations after the scans")
+	}
+}
+
+// testRecommendations are five findings of mixed priority and savings, in
+// scan order
+func testRecommendations(now time.Time) []Recommendation {
+	recommendations := []Recommendation{
+		{Resource: "default/a", Priority: "low", Savings: 10},
+		{Resource: "default/b", Priority: "high", Savings: 50},
+		{Resource: "default/c", Priority: "medium", Savings: 30},
+		{Resource: "default/d", Priority: "high", Savings: 20},
+		{Resource: "default/e", Priority: "high", Savings: 40},
+	}
+	for i := range recommendations {
+		recommendations[i].Type = "rightsizing"
+		recommendations[i].Namespace = "default"
+		recommendations[i].Timestamp = now
+	}
+	stampIDs(recommendations)
+	return recommendations
+}
+
+func TestHandleRecommendations(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantItems []string
+		wantTotal int
+	}{
+		{name: "all", wantCode: http.StatusOK, wantItems: []string{"default/a", "default/b", "default/c", "default/d", "default/e"}, wantTotal: 5},
+		{name: "priority", query: "priority=high", wantCode: http.StatusOK, wantItems: []string{"default/b", "default/d", "default/e"}, wantTotal: 3},
+		{name: "priority sorted by savings", query: "priority=high&sort=savings", wantCode: http.StatusOK, wantItems: []string{"default/b", "default/e", "default/d"}, wantTotal: 3},
+		{name: "offset", query: "offset=2", wantCode: http.StatusOK, wantItems: []string{"default/c", "default/d", "default/e"}, wantTotal: 5},
+		{name: "offset and limit", query: "offset=1&limit=2", wantCode: http.StatusOK, wantItems: []string{"default/b", "default/c"}, wantTotal: 5},
+		{name: "offset past the end", query: "offset=10", wantCode: http.StatusOK, wantItems: []string{}, wantTotal: 5},
+		{name: "filtered page", query: "priority=high&offset=1&limit=1", wantCode: http.StatusOK, wantItems: []string{"default/d"}, wantTotal: 3},
+		{name: "unknown priority", query: "priority=urgent", wantCode: http.StatusBadRequest},
+		{name: "negative offset", query: "offset=-1", wantCode: http.StatusBadRequest},
+		{name: "zero limit", query: "limit=0", wantCode: http.StatusBadRequest},
+	}
+
+	co, _ := newTestOptimizer(t)
+	co.recommendations = testRecommendations(co.now())
+	router := co.newRouter()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(router, http.MethodGet, "/api/recommendations?"+tt.query, nil)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var page RecommendationPage
+			if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+				t.Fatalf("decode page: %v", err)
+			}
+			got := make([]string, len(page.Items))
+			for i, item := range page.Items {
+				got[i] = item.Resource
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.wantItems) {
+				t.Errorf("items = %v, want %v", got, tt.wantItems)
+			}
+			if page.Total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", page.Total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+// recommendationIDs maps resource and analyzer to recommendation ID
+func recommendationIDs(co *CostOptimizer) map[string]string {
+	co.recommendationsMu.RLock()
+	defer co.recommendationsMu.RUnlock()
+	ids := make(map[string]string, len(co.recommendations))
+	for _, rec := range co.recommendations {
+		ids[rec.Resource+" "+rec.analyzer] = rec.ID
+	}
+	return ids
+}
+
+func TestScansKeepRecommendationIDs(t *testing.T) {
+	co, _ := newTestOptimizer(t, testNode("node-1", "4", "16Gi"), testDeployment("default", "web", 3))
+	first := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := first
+	co.now = func() time.Time { return now }
+
+	co.analyzeAndGenerateRecommendations(context.Background())
+	before := recommendationIDs(co)
+	if len(before) == 0 {
+		t.Fatal("no recommendations from the first scan")
+	}
+
+	now = first.Add(time.Hour)
+	co.analyzeAndGenerateRecommendations(context.Background())
+	if after := recommendationIDs(co); fmt.Sprint(after) != fmt.Sprint(before) {
+		t.Errorf("IDs = %v after a second scan, want %v", after, before)
+	}
+	for _, rec := range co.activeRecommendations() {
+		if !rec.FirstSeen.Equal(first) || !rec.LastSeen.Equal(now) {
+			t.Errorf("%s: first seen %v, last seen %v, want %v and %v", rec.Resource, rec.FirstSeen, rec.LastSeen, first, now)
+		}
+	}
+}
+
+func TestDedupRecommendations(t *testing.T) {
+	rightsize := Recommendation{Type: "rightsizing", Namespace: "default", Resource: "default/web", Description: "Lower CPU requests"}
+	otherDescription := rightsize
+	otherDescription.Description = "Lower memory requests"
+	hinted := rightsize
+	hinted.ActionHint = &ActionHint{Target: "default/web", Field: "replicas", NewValue: "1"}
+	otherValue := hinted
+	otherValue.ActionHint = &ActionHint{Target: "default/web", Field: "replicas", NewValue: "2"}
+
+	tests := []struct {
+		name string
+		in   []Recommendation
+		want int
+	}{
+		{name: "exact repeat", in: []Recommendation{rightsize, rightsize}, want: 1},
+		{name: "same target, other finding", in: []Recommendation{rightsize, otherDescription}, want: 2},
+		{name: "repeated hint", in: []Recommendation{hinted, hinted, rightsize}, want: 2},
+		{name: "same field, other value", in: []Recommendation{hinted, otherValue}, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dedupRecommendations(tt.in)
+			if len(got) != tt.want {
+				t.Fatalf("kept %d recommendations, want %d", len(got), tt.want)
+			}
+			stampIDs(got)
+			ids := make(map[string]bool, len(got))
+			for _, rec := range got {
+				if ids[rec.ID] {
+					t.Errorf("ID %s stamped twice", rec.ID)
+				}
+				ids[rec.ID] = true
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// recommendationIDNamespace seeds the name-based UUIDs of recommendations, so
+// the same finding keeps its ID from one scan to the next
+var recommendationIDNamespace = uuid.MustParse("5f0c6a52-2f4e-4d0b-9a51-7d3f3c1f6e2a")
+
+// stampIDs gives every recommendation a stable ID derived from what it targets.
+// Findings that would collide are numbered in order.
+func stampIDs(recommendations []Recommendation) {
+	stampNewIDs(nil, recommendations)
+}
+
+// stampNewIDs stamps recommendations added alongside kept ones, whose IDs are
+// left as they are. A new finding whose ID is taken is numbered past it, the
+// way a collision within one scan is.
+func stampNewIDs(kept, added []Recommendation) {
+	taken := make(map[string]bool, len(kept)+len(added))
+	for _, rec := range kept {
+		taken[rec.ID] = true
+	}
+	for i := range added {
+		rec := &added[i]
+		key := rec.Type + "|" + rec.Namespace + "|" + rec.Resource
+		if rec.ActionHint != nil {
+			key += "|" + rec.ActionHint.Target + "|" + rec.ActionHint.Field
+		}
+		for n := 1; ; n++ {
+			numbered := key
+			if n > 1 {
+				numbered += "#" + strconv.Itoa(n)
+			}
+			if id := uuid.NewSHA1(recommendationIDNamespace, []byte(numbered)).String(); !taken[id] {
+				rec.ID = id
+				taken[id] = true
+				break
+			}
+		}
+	}
+}
+
+// Remediation manifest formats
+const (
+	manifestStrategicMerge = "strategic-merge-patch"
+	manifestJSONPatch      = "json-patch"
+	manifestDelete         = "delete"
+	manifestCommand        = "command"
+	manifestApply          = "apply"
+)
+
+// RemediationManifest is the change that applies a recommendation, in a form
+// that can be committed to a GitOps repository or applied with Command
+type RemediationManifest struct {
+	ID         string `json:"id"`
+	Format     string `json:"format"`
+	APIVersion string `json:"api_version,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	Manifest   string `json:"manifest,omitempty"` // YAML
+	Command    string `json:"command"`
+
+	// For patches, the patch body as JSON and a command that applies it
+	// inline, with nothing to save first
+	Patch         string `json:"patch,omitempty"`
+	InlineCommand string `json:"inline_command,omitempty"`
+}
+
+// hintKinds maps ActionHint target kinds to their API group version and kind
+var hintKinds = map[string][2]string{
+	"pod":                     {"v1", "Pod"},
+	"node":                    {"v1", "Node"},
+	"persistentvolume":        {"v1", "PersistentVolume"},
+	"limitrange":              {"v1", "LimitRange"},
+	"deployment":              {"apps/v1", "Deployment"},
+	"statefulset":             {"apps/v1", "StatefulSet"},
+	"daemonset":               {"apps/v1", "DaemonSet"},
+	"job":                     {"batch/v1", "Job"},
+	"ingress":                 {"networking.k8s.io/v1", "Ingress"},
+	"horizontalpodautoscaler": {"autoscaling/v2", "HorizontalPodAutoscaler"},
+	"poddisruptionbudget":     {"policy/v1", "PodDisruptionBudget"},
+}
+
+// fieldSegment matches one step of an ActionHint field path: a name with an
+// optional [key=value], [index] or [mapKey] selector
+var fieldSegment = regexp.MustCompile(`^([A-Za-z]+)(?:\[([^\]]+)\])?$`)
+
+// splitField splits a field path on the dots outside brackets, since map keys
+// such as annotation names contain dots of their own
+func splitField(field string) []string {
+	segments := make([]string, 0)
+	depth, start := 0, 0
+	for i, c := range field {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '.':
+			if depth == 0 {
+				segments = append(segments, field[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(segments, field[start:])
+}
+
+// strategicMergePatch builds a patch setting field to value. It fails on
+// wildcard and positional selectors, which a merge patch can't address.
+func strategicMergePatch(field, value string) (map[string]interface{}, error) {
+	segments := splitField(field)
+	var build func(int) (interface{}, error)
+	build = func(i int) (interface{}, error) {
+		if i == len(segments) {
+			return value, nil
+		}
+		match := fieldSegment.FindStringSubmatch(segments[i])
+		if match == nil {
+			return nil, fmt.Errorf("unsupported field segment %q", segments[i])
+		}
+		name, selector := match[1], match[2]
+
+		inner, err := build(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case selector == "":
+			return map[string]interface{}{name: inner}, nil
+		case strings.Contains(selector, "="):
+			// Only lists merged by name keep their other elements; any other
+			// list would be replaced wholesale by the patch
+			key, keyValue, _ := strings.Cut(selector, "=")
+			if key != "name" {
+				return nil, fmt.Errorf("list %q isn't merged by name", segments[i])
+			}
+			element, ok := inner.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("list selector %q must be followed by a field", segments[i])
+			}
+			element[key] = keyValue
+			return map[string]interface{}{name: []interface{}{element}}, nil
+		case selector == "*":
+			return nil, fmt.Errorf("wildcard %q has no single target", segments[i])
+		default:
+			if _, err := strconv.Atoi(selector); err == nil {
+				return nil, fmt.Errorf("positional %q needs a JSON patch", segments[i])
+			}
+			return map[string]interface{}{name: map[string]interface{}{selector: inner}}, nil
+		}
+	}
+
+	patch, err := build(0)
+	if err != nil {
+		return nil, err
+	}
+	return patch.(map[string]interface{}), nil
+}
+
+// jsonPointer converts a field path of plain names and indexes, such as
+// spec.rules[0].http.paths[1], into a JSON patch path
+func jsonPointer(field string) (string, error) {
+	var pointer strings.Builder
+	for _, segment := range splitField(field) {
+		match := fieldSegment.FindStringSubmatch(segment)
+		if match == nil {
+			return "", fmt.Errorf("unsupported field segment %q", segment)
+		}
+		pointer.WriteString("/" + match[1])
+		if match[2] == "" {
+			continue
+		}
+		if _, err := strconv.Atoi(match[2]); err != nil {
+			return "", fmt.Errorf("%q is not positional", segment)
+		}
+		pointer.WriteString("/" + match[2])
+	}
+	return pointer.String(), nil
+}
+
+// remediationManifest renders the change for a recommendation's action hint
+func (co *CostOptimizer) remediationManifest(ctx context.Context, rec Recommendation) (*RemediationManifest, error) {
+	hint := rec.ActionHint
+	if hint == nil {
+		return nil, fmt.Errorf("recommendation has no action to render")
+	}
+	parts := strings.Split(hint.Target, "/")
+	kind, namespace, name := parts[0], "", parts[len(parts)-1]
+	if len(parts) == 3 {
+		namespace = parts[1]
+	}
+	field := hint.Field
+
+	manifest := &RemediationManifest{ID: rec.ID, Namespace: namespace, Name: name}
+	namespaceFlag := ""
+	if namespace != "" {
+		namespaceFlag = " -n " + namespace
+	}
+
+	if hint.Verb == "drain" && kind == "node" {
+		manifest.Format = manifestCommand
+		manifest.Kind = "Node"
+		manifest.Command = fmt.Sprintf("kubectl drain %s --ignore-daemonsets --delete-emptydir-data", name)
+		return manifest, nil
+	}
+
+	// Pod requests are changed in the pod template of the owning workload. A
+	// pod's own requests can't be changed, so a pod whose owner can't be found
+	// gets no manifest.
+	if kind == "pod" && hint.Verb == "patch" && strings.HasPrefix(field, "spec.containers") {
+		owner := co.podOwner(ctx, namespace, name)
+		ownerKind, ownerName, _ := strings.Cut(owner, "/")
+		if _, ok := hintKinds[ownerKind]; !ok || ownerKind == "pod" {
+			return nil, fmt.Errorf("no workload owning pod %s/%s to patch", namespace, name)
+		}
+		kind, name = ownerKind, ownerName
+		field = "spec.template." + field
+	}
+
+	groupKind, ok := hintKinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("no manifest for %s targets", kind)
+	}
+	manifest.APIVersion, manifest.Kind = groupKind[0], groupKind[1]
+
+	var document interface{}
+	switch {
+	case hint.Verb == "create" && kind == "horizontalpodautoscaler":
+		hpa, err := co.hpaManifest(ctx, namespace, name, hint)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Format = manifestApply
+		document = hpa
+		manifest.Command = "kubectl apply -f manifest.yaml"
+
+	case hint.Verb == "delete" && field == "":
+		manifest.Format = manifestDelete
+		document = map[string]interface{}{
+			"apiVersion": manifest.APIVersion,
+			"kind":       manifest.Kind,
+			"metadata":   objectMeta(namespace, name),
+		}
+		manifest.Command = fmt.Sprintf("kubectl delete %s %s%s", kind, name, namespaceFlag)
+
+	case hint.Verb == "delete":
+		pointer, err := jsonPointer(field)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Format = manifestJSONPatch
+		document = []map[string]string{{"op": "remove", "path": pointer}}
+		manifest.Command = fmt.Sprintf("kubectl patch %s %s%s --type json --patch-file patch.yaml", kind, name, namespaceFlag)
+		if err := manifest.inlinePatch(document, "json", kind, name, namespaceFlag); err != nil {
+			return nil, err
+		}
+
+	case hint.Verb == "patch" && hint.NewValue != "":
+		patch, err := strategicMergePatch(field, hint.NewValue)
+		if err != nil {
+			return nil, err
+		}
+		// The inline patch is the change alone; the manifest also names its object
+		if err := manifest.inlinePatch(patch, "strategic", kind, name, namespaceFlag); err != nil {
+			return nil, err
+		}
+		patch["apiVersion"] = manifest.APIVersion
+		patch["kind"] = manifest.Kind
+		meta, _ := patch["metadata"].(map[string]interface{})
+		if meta == nil {
+			meta = make(map[string]interface{})
+		}
+		for key, value := range objectMeta(namespace, name) {
+			meta[key] = value
+		}
+		patch["metadata"] = meta
+		manifest.Format = manifestStrategicMerge
+		document = patch
+		manifest.Command = fmt.Sprintf("kubectl patch %s %s%s --type strategic --patch-file patch.yaml", kind, name, namespaceFlag)
+
+	default:
+		return nil, fmt.Errorf("no manifest for a %s of %s without a concrete value", hint.Verb, field)
+	}
+
+	rendered, err := yaml.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("render manifest: %w", err)
+	}
+	manifest.Name = name
+	manifest.Manifest = string(rendered)
+	return manifest, nil
+}
+
+// inlinePatch sets the manifest's JSON patch body and the kubectl command that
+// applies it as an argument
+func (m *RemediationManifest) inlinePatch(patch interface{}, patchType, kind, name, namespaceFlag string) error {
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("render patch: %w", err)
+	}
+	m.Patch = string(body)
+	m.InlineCommand = fmt.Sprintf("kubectl patch %s %s%s --type %s -p %s", kind, name, namespaceFlag, patchType, shellQuote(m.Patch))
+	return nil
+}
+
+// shellQuote single-quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func objectMeta(namespace, name string) map[string]interface{} {
+	meta := map[string]interface{}{"name": name}
+	if namespace != "" {
+		meta["namespace"] = namespace
+	}
+	return meta
+}
+
+// podOwner looks up the workload owning a pod as kind/name, or "" when the pod
+// can't be read
+func (co *CostOptimizer) podOwner(ctx context.Context, namespace, name string) string {
+	if co.demoMode || co.clientset == nil {
+		return ""
+	}
+	pod, err := co.clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return ""
+	}
+	return podWorkload(pod)
+}
+
+func (co *CostOptimizer) handleRecommendationManifest(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	for _, rec := range co.activeRecommendations() {
+		if rec.ID != id {
+			continue
+		}
+		manifest, err := co.remediationManifest(r.Context(), rec)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(manifest)
+		return
+	}
+	http.Error(w, "recommendation not found", http.StatusNotFound)
+}
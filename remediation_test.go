@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+)
+
+func TestStampIDs(t *testing.T) {
+	scan := func() []Recommendation {
+		return []Recommendation{
+			{Type: "resource_rightsizing", Namespace: "shop", Resource: "shop/web", ActionHint: &ActionHint{Target: "pod/shop/web", Field: containerRequestField("app", "cpu")}},
+			{Type: "resource_rightsizing", Namespace: "shop", Resource: "shop/web", ActionHint: &ActionHint{Target: "pod/shop/web", Field: containerRequestField("app", "memory")}},
+			{Type: "networking_cleanup", Namespace: "shop", Resource: "shop/web"},
+			{Type: "networking_cleanup", Namespace: "shop", Resource: "shop/web"},
+		}
+	}
+
+	first, second := scan(), scan()
+	stampIDs(first)
+	stampIDs(second)
+
+	ids := make(map[string]bool)
+	for i := range first {
+		if first[i].ID == "" || first[i].ID != second[i].ID {
+			t.Errorf("recommendation %d: IDs %q and %q across scans, want one stable ID", i, first[i].ID, second[i].ID)
+		}
+		ids[first[i].ID] = true
+	}
+	if len(ids) != len(first) {
+		t.Errorf("%d distinct IDs for %d recommendations", len(ids), len(first))
+	}
+}
+
+func TestStrategicMergePatch(t *testing.T) {
+	tests := []struct {
+		field   string
+		want    string // YAML
+		wantErr bool
+	}{
+		{field: "spec.replicas", want: "spec:\n  replicas: \"3\"\n"},
+		{field: "spec.containers[name=app].resources.requests.cpu", want: "spec:\n  containers:\n  - name: app\n    resources:\n      requests:\n        cpu: \"3\"\n"},
+		{field: "metadata.annotations[example.com/retention]", want: "metadata:\n  annotations:\n    example.com/retention: \"3\"\n"},
+		{field: "spec.limits[type=Container].max", wantErr: true},
+		{field: "spec.containers[*].resources", wantErr: true},
+		{field: "spec.rules[0].host", wantErr: true},
+		{field: "spec.containers[name=app]", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			patch, err := strategicMergePatch(tt.field, "3")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("patch %v, want an error", patch)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := yaml.Marshal(patch); string(got) != tt.want {
+				t.Errorf("patch\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPointer(t *testing.T) {
+	tests := []struct {
+		field   string
+		want    string
+		wantErr bool
+	}{
+		{field: "spec.defaultBackend", want: "/spec/defaultBackend"},
+		{field: "spec.rules[1].http.paths[0]", want: "/spec/rules/1/http/paths/0"},
+		{field: "spec.containers[name=app]", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := jsonPointer(tt.field)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("jsonPointer(%s) = %q, %v; want %q, error %v", tt.field, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRecommendationManifest(t *testing.T) {
+	web := testDeployment("shop", "web", 2)
+	replica := testReplica(web, "web-7d9f8-a", "node-1")
+	replica.Labels = map[string]string{"app": "web", "pod-template-hash": "7d9f8"}
+	replica.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-7d9f8", Controller: boolPtr(true)}}
+	co, _ := newTestOptimizer(t, web, replica, testPod("batch", "job", "node-1", "1", "1Gi"))
+
+	co.recommendations = []Recommendation{
+		{ID: "rightsize", ActionHint: &ActionHint{Verb: "patch", Target: hintTarget("pod", "shop", replica.Name), Field: containerRequestField("app", "cpu"), NewValue: "150m"}},
+		{ID: "bare-pod", ActionHint: &ActionHint{Verb: "patch", Target: hintTarget("pod", "batch", "job"), Field: containerRequestField("app", "memory"), NewValue: "512Mi"}},
+		{ID: "cleanup-rule", ActionHint: &ActionHint{Verb: "delete", Target: hintTarget("ingress", "shop", "web"), Field: "spec.rules[0].http.paths[1]"}},
+		{ID: "cleanup-pod", ActionHint: &ActionHint{Verb: "delete", Target: hintTarget("pod", "batch", "job")}},
+		{ID: "drain", ActionHint: &ActionHint{Verb: "drain", Target: hintTarget("node", "", "node-1")}},
+		{ID: "no-value", ActionHint: &ActionHint{Verb: "patch", Target: hintTarget("deployment", "shop", "web"), Field: "spec.template.spec.affinity"}},
+		{ID: "informational"},
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/recommendations/{id}/manifest", co.handleRecommendationManifest)
+
+	tests := []struct {
+		id       string
+		wantCode int
+		want     RemediationManifest
+		wantDoc  string // the manifest YAML
+	}{
+		{
+			id:       "rightsize",
+			wantCode: http.StatusOK,
+			want: RemediationManifest{Format: manifestStrategicMerge, APIVersion: "apps/v1", Kind: "Deployment", Namespace: "shop", Name: "web",
+				Command:       "kubectl patch deployment web -n shop --type strategic --patch-file patch.yaml",
+				Patch:         `{"spec":{"template":{"spec":{"containers":[{"name":"app","resources":{"requests":{"cpu":"150m"}}}]}}}}`,
+				InlineCommand: `kubectl patch deployment web -n shop --type strategic -p '{"spec":{"template":{"spec":{"containers":[{"name":"app","resources":{"requests":{"cpu":"150m"}}}]}}}}'`},
+			wantDoc: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  namespace: shop\nspec:\n  template:\n    spec:\n      containers:\n      - name: app\n        resources:\n          requests:\n            cpu: 150m\n",
+		},
+		// A bare pod's requests can't be changed in place
+		{id: "bare-pod", wantCode: http.StatusUnprocessableEntity},
+		{
+			id:       "cleanup-rule",
+			wantCode: http.StatusOK,
+			want: RemediationManifest{Format: manifestJSONPatch, APIVersion: "networking.k8s.io/v1", Kind: "Ingress", Namespace: "shop", Name: "web",
+				Command:       "kubectl patch ingress web -n shop --type json --patch-file patch.yaml",
+				Patch:         `[{"op":"remove","path":"/spec/rules/0/http/paths/1"}]`,
+				InlineCommand: `kubectl patch ingress web -n shop --type json -p '[{"op":"remove","path":"/spec/rules/0/http/paths/1"}]'`},
+			wantDoc: "- op: remove\n  path: /spec/rules/0/http/paths/1\n",
+		},
+		{
+			id:       "cleanup-pod",
+			wantCode: http.StatusOK,
+			want:     RemediationManifest{Format: manifestDelete, APIVersion: "v1", Kind: "Pod", Namespace: "batch", Name: "job", Command: "kubectl delete pod job -n batch"},
+			wantDoc:  "apiVersion: v1\nkind: Pod\nmetadata:\n  name: job\n  namespace: batch\n",
+		},
+		{
+			id:       "drain",
+			wantCode: http.StatusOK,
+			want:     RemediationManifest{Format: manifestCommand, Kind: "Node", Name: "node-1", Command: "kubectl drain node-1 --ignore-daemonsets --delete-emptydir-data"},
+		},
+		{id: "no-value", wantCode: http.StatusUnprocessableEntity},
+		{id: "informational", wantCode: http.StatusUnprocessableEntity},
+		{id: "unknown", wantCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			rec := serve(router, http.MethodGet, "/api/recommendations/"+tt.id+"/manifest", nil)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var got RemediationManifest
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			doc := got.Manifest
+			got.Manifest = ""
+			tt.want.ID = tt.id
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("manifest %+v, want %+v", got, tt.want)
+			}
+			if doc != tt.wantDoc {
+				t.Errorf("manifest YAML\n%s\nwant\n%s", doc, tt.wantDoc)
+			}
+		})
+	}
+}
+
+// TestScanRecommendationManifest renders a manifest for a recommendation as a
+// scan produced it
+func TestScanRecommendationManifest(t *testing.T) {
+	api := testDeployment("shop", "api", 1)
+	api.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU] = resource.MustParse("2")
+	replica := rolledOut(api, "7d9f8", "a")
+	co, _ := newTestOptimizer(t, api, replica, testPodMetrics("shop", "api-7d9f8-a", "100m", "128Mi"))
+	co.analyzeAndGenerateRecommendations(context.Background())
+
+	var rightsizing *Recommendation
+	for i, rec := range co.recommendations {
+		if rec.Type == "resource_rightsizing" {
+			rightsizing = &co.recommendations[i]
+		}
+	}
+	if rightsizing == nil || rightsizing.ID == "" {
+		t.Fatalf("recommendations %+v, want a rightsizing with an ID", co.recommendations)
+	}
+	manifest, err := co.remediationManifest(context.Background(), *rightsizing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Kind != "Deployment" || manifest.Name != "api" || manifest.Format != manifestStrategicMerge || manifest.Patch == "" {
+		t.Errorf("manifest %+v, want a strategic merge patch of deployment api", manifest)
+	}
+}
+
+// controlledBy gives a pod a controller of kind
+func controlledBy(pod *corev1.Pod, kind, name string) *corev1.Pod {
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: kind, Name: name, Controller: boolPtr(true)}}
+	return pod
+}
+
+// rolledOut is a replica of deployment from its ReplicaSet of the given
+// pod-template-hash
+func rolledOut(deployment *appsv1.Deployment, hash, suffix string) *corev1.Pod {
+	pod := controlledBy(testReplica(deployment, deployment.Name+"-"+hash+"-"+suffix, "node-1"), "ReplicaSet", deployment.Name+"-"+hash)
+	pod.Labels = map[string]string{"pod-template-hash": hash}
+	for key, value := range deployment.Spec.Template.Labels {
+		pod.Labels[key] = value
+	}
+	return pod
+}
+
+// TestRightsizingPatchApplies applies the inline patch of a rightsizing to
+// the owning workload and checks that only the named container's request
+// changes
+func TestRightsizingPatchApplies(t *testing.T) {
+	sidecar := corev1.Container{Name: "proxy", Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("50m"),
+		corev1.ResourceMemory: resource.MustParse("64Mi"),
+	}}}
+	web := testDeployment("shop", "web", 2)
+	web.Spec.Template.Spec.Containers = append(web.Spec.Template.Spec.Containers, sidecar)
+	db := testStatefulSet("shop", "db", 1)
+	db.Spec.Template.Spec.Containers = append([]corev1.Container{sidecar}, db.Spec.Template.Spec.Containers...)
+
+	tests := []struct {
+		name      string
+		pod       *corev1.Pod
+		resource  string
+		value     string
+		workload  func(co *CostOptimizer) (corev1.PodSpec, error)
+		wantKind  string
+		wantApp   corev1.ResourceList
+		wantProxy corev1.ResourceList
+	}{
+		{
+			name:     "deployment cpu",
+			pod:      rolledOut(web, "7d9f8", "a"),
+			resource: "cpu",
+			value:    "150m",
+			workload: func(co *CostOptimizer) (corev1.PodSpec, error) {
+				d, err := co.clientset.AppsV1().Deployments("shop").Get(context.Background(), "web", metav1.GetOptions{})
+				if err != nil {
+					return corev1.PodSpec{}, err
+				}
+				return d.Spec.Template.Spec, nil
+			},
+			wantKind: "Deployment",
+			wantApp:  corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("150m"), corev1.ResourceMemory: resource.MustParse("128Mi")},
+		},
+		{
+			name:     "statefulset memory",
+			pod:      controlledBy(testReplica(web, "db-0", "node-1"), "StatefulSet", "db"),
+			resource: "memory",
+			value:    "512Mi",
+			workload: func(co *CostOptimizer) (corev1.PodSpec, error) {
+				s, err := co.clientset.AppsV1().StatefulSets("shop").Get(context.Background(), "db", metav1.GetOptions{})
+				if err != nil {
+					return corev1.PodSpec{}, err
+				}
+				return s.Spec.Template.Spec, nil
+			},
+			wantKind: "StatefulSet",
+			wantApp:  corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m"), corev1.ResourceMemory: resource.MustParse("512Mi")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, client := newTestOptimizer(t, web, db, tt.pod)
+			manifest, err := co.remediationManifest(context.Background(), Recommendation{
+				Type:       "resource_rightsizing",
+				ActionHint: &ActionHint{Verb: "patch", Target: hintTarget("pod", "shop", tt.pod.Name), Field: containerRequestField("app", corev1.ResourceName(tt.resource)), NewValue: tt.value},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if manifest.Kind != tt.wantKind {
+				t.Fatalf("patch of %s %s, want the owning %s", manifest.Kind, manifest.Name, tt.wantKind)
+			}
+
+			gvr := appsv1.SchemeGroupVersion.WithResource(strings.ToLower(manifest.Kind) + "s")
+			action := k8stesting.NewPatchAction(gvr, manifest.Namespace, manifest.Name, types.StrategicMergePatchType, []byte(manifest.Patch))
+			if _, err := client.Invokes(action, nil); err != nil {
+				t.Fatalf("apply patch %s: %v", manifest.Patch, err)
+			}
+
+			spec, err := tt.workload(co)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(spec.Containers) != 2 {
+				t.Fatalf("containers %+v, want app and proxy still there", spec.Containers)
+			}
+			for _, container := range spec.Containers {
+				want := sidecar.Resources.Requests
+				if container.Name == "app" {
+					want = tt.wantApp
+				}
+				for name, quantity := range want {
+					got := container.Resources.Requests[name]
+					if got.Cmp(quantity) != 0 {
+						t.Errorf("container %s requests %s %s, want %s", container.Name, name, got.String(), quantity.String())
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Reservation is reserved instance or committed-use coverage for an instance
+// type: up to Count nodes of the type bill at DiscountPercent off on-demand
+type Reservation struct {
+	InstanceType    string  `json:"instance_type"`
+	Count           int     `json:"count"`
+	DiscountPercent float64 `json:"discount_percent"`
+}
+
+func (r *Reservation) validate() error {
+	if r.InstanceType == "" {
+		return errors.New("instance_type is required")
+	}
+	if r.Count < 1 {
+		return fmt.Errorf("count %d must be at least 1", r.Count)
+	}
+	if r.DiscountPercent <= 0 || r.DiscountPercent > 100 {
+		return fmt.Errorf("discount_percent %v must be in (0, 100]", r.DiscountPercent)
+	}
+	return nil
+}
+
+// reservedNodes assigns each reservation to the oldest on-demand nodes of its
+// instance type, and returns the discount percent of every covered node. Spot
+// nodes are never covered, and a node cost expression is used as-is.
+func (co *CostOptimizer) reservedNodes(nodes []corev1.Node) map[string]float64 {
+	reserved := make(map[string]float64)
+	if len(co.reservations) == 0 || co.costModel != nil {
+		return reserved
+	}
+
+	byType := make(map[string][]*corev1.Node)
+	for i := range nodes {
+		node := &nodes[i]
+		if isSpotNode(node) {
+			continue
+		}
+		instanceType := co.pricing.instanceType(node)
+		byType[instanceType] = append(byType[instanceType], node)
+	}
+
+	for _, reservation := range co.reservations {
+		candidates := byType[reservation.InstanceType]
+		sort.Slice(candidates, func(i, j int) bool {
+			if !candidates[i].CreationTimestamp.Equal(&candidates[j].CreationTimestamp) {
+				return candidates[i].CreationTimestamp.Before(&candidates[j].CreationTimestamp)
+			}
+			return candidates[i].Name < candidates[j].Name
+		})
+		for _, node := range candidates[:min(reservation.Count, len(candidates))] {
+			reserved[node.Name] = reservation.DiscountPercent
+		}
+	}
+	return reserved
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// reservationNode is an m5.xlarge node created age days ago
+func reservationNode(name string, age int) *corev1.Node {
+	node := testNode(name, "4", "16Gi")
+	node.Labels = map[string]string{corev1.LabelInstanceTypeStable: "m5.xlarge"}
+	node.CreationTimestamp = metav1.NewTime(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -age))
+	return node
+}
+
+func TestReservedNodeMetrics(t *testing.T) {
+	spot := reservationNode("node-spot", 90)
+	spot.Labels["node.kubernetes.io/capacity-type"] = "spot"
+	tests := []struct {
+		name         string
+		reservations []Reservation
+		nodes        []*corev1.Node
+		want         map[string]float64 // node -> discount percent
+	}{
+		{
+			name:         "partial coverage goes to the oldest nodes",
+			reservations: []Reservation{{InstanceType: "m5.xlarge", Count: 2, DiscountPercent: 40}},
+			nodes:        []*corev1.Node{reservationNode("node-a", 10), reservationNode("node-b", 30), reservationNode("node-c", 20)},
+			want:         map[string]float64{"node-b": 40, "node-c": 40},
+		},
+		{
+			name:         "coverage beyond the fleet",
+			reservations: []Reservation{{InstanceType: "m5.xlarge", Count: 5, DiscountPercent: 30}},
+			nodes:        []*corev1.Node{reservationNode("node-a", 10)},
+			want:         map[string]float64{"node-a": 30},
+		},
+		{
+			name:         "spot nodes are never covered",
+			reservations: []Reservation{{InstanceType: "m5.xlarge", Count: 1, DiscountPercent: 40}},
+			nodes:        []*corev1.Node{spot, reservationNode("node-a", 10)},
+			want:         map[string]float64{"node-a": 40},
+		},
+		{
+			name:         "other instance type",
+			reservations: []Reservation{{InstanceType: "c5.large", Count: 1, DiscountPercent: 40}},
+			nodes:        []*corev1.Node{reservationNode("node-a", 10)},
+			want:         map[string]float64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objects []runtime.Object
+			for _, node := range tt.nodes {
+				objects = append(objects, node, testNodeMetrics(node.Name, "1", "4Gi"))
+			}
+			co, _ := newTestOptimizer(t, objects...)
+			co.reservations = tt.reservations
+
+			got := make(map[string]float64)
+			for _, m := range co.getNodeMetrics(context.Background()) {
+				discount := tt.want[m.Name]
+				if m.Reserved != (discount > 0) {
+					t.Errorf("node %s reserved %v, want %v", m.Name, m.Reserved, discount > 0)
+				}
+				if m.Reserved {
+					got[m.Name] = discount
+				}
+				full := 0.192 * 24 * 30
+				if m.CapacityType == capacitySpot {
+					full *= co.spotPriceFactor
+				}
+				if want := full * (1 - discount/100); math.Abs(m.EstimatedCost-want) > 1e-9 {
+					t.Errorf("node %s costs %v, want %v", m.Name, m.EstimatedCost, want)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reserved nodes %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFileConfigReservations(t *testing.T) {
+	tests := []struct {
+		name         string
+		reservations string
+		wantErr      string
+	}{
+		{name: "valid", reservations: `[{"instance_type": "m5.xlarge", "count": 3, "discount_percent": 40}]`},
+		{name: "missing type", reservations: `[{"count": 3, "discount_percent": 40}]`, wantErr: "reservations[0]: instance_type is required"},
+		{name: "no nodes", reservations: `[{"instance_type": "m5.xlarge", "count": 0, "discount_percent": 40}]`, wantErr: "count 0"},
+		{name: "discount over 100", reservations: `[{"instance_type": "m5.xlarge", "count": 1, "discount_percent": 120}]`, wantErr: "discount_percent 120"},
+		{name: "duplicate type", reservations: `[{"instance_type": "m5.xlarge", "count": 1, "discount_percent": 40}, {"instance_type": "m5.xlarge", "count": 2, "discount_percent": 30}]`, wantErr: `reservations[1]: duplicate instance_type "m5.xlarge"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(fmt.Sprintf(`{"reservations": %s}`, tt.reservations)), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadFileConfig(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
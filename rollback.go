@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// errActionDrifted means the resource changed after the action executed, so
+// restoring the recorded state would undo someone else's change too
+var errActionDrifted = errors.New("resource changed since the action executed")
+
+// rollbackAction restores the state an executed action replaced and
+// describes the change that makes
+func (co *CostOptimizer) rollbackAction(ctx context.Context, action OptimizationAction, dryRun bool) (*ActionChange, error) {
+	switch action.Type {
+	case "scale_down":
+		applied := action.Change
+		name := resourceName(action.Resource)
+		if !co.demoMode && co.clientset != nil {
+			scale, err := co.clientset.AppsV1().Deployments(action.Namespace).GetScale(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("get scale of deployment %s/%s: %w", action.Namespace, name, err)
+			}
+			if scale.Spec.Replicas != applied.NewReplicas {
+				return nil, fmt.Errorf("%w: deployment %s/%s has %d replicas, not the %d the action set", errActionDrifted, action.Namespace, name, scale.Spec.Replicas, applied.NewReplicas)
+			}
+		}
+		return co.scaleDeployment(ctx, action.Namespace, name, *applied.CurrentReplicas, dryRun)
+	}
+	return nil, fmt.Errorf("unsupported action type %q", action.Type)
+}
+
+// handleRollbackAction undoes an executed action. Unlike execution it runs
+// synchronously, since it's the way out of a problem the action caused.
+func (co *CostOptimizer) handleRollbackAction(w http.ResponseWriter, r *http.Request) {
+	actionID := mux.Vars(r)["id"]
+
+	co.actionsMu.Lock()
+	var action *OptimizationAction
+	for i := range co.actions {
+		if co.actions[i].ID == actionID {
+			action = &co.actions[i]
+			break
+		}
+	}
+	if action == nil {
+		co.actionsMu.Unlock()
+		http.Error(w, "action not found", http.StatusNotFound)
+		return
+	}
+	if action.Status != actionStatusExecuted {
+		co.actionsMu.Unlock()
+		http.Error(w, fmt.Sprintf("only executed actions can be rolled back, action is %s", action.Status), http.StatusConflict)
+		return
+	}
+	if action.Change == nil || action.Change.CurrentReplicas == nil {
+		co.actionsMu.Unlock()
+		http.Error(w, "action has no recorded previous state to restore", http.StatusConflict)
+		return
+	}
+	dryRun := co.dryRun
+	if raw := r.URL.Query().Get("dry_run"); raw != "" {
+		requested, err := strconv.ParseBool(raw)
+		if err != nil {
+			co.actionsMu.Unlock()
+			http.Error(w, fmt.Sprintf("invalid dry_run %q", raw), http.StatusBadRequest)
+			return
+		}
+		dryRun = dryRun || requested
+	}
+	snapshot := *action
+	if dryRun {
+		co.actionsMu.Unlock()
+		change, err := co.rollbackAction(r.Context(), snapshot, true)
+		if err != nil {
+			http.Error(w, err.Error(), rollbackErrorStatus(err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"dry_run":   true,
+			"action_id": actionID,
+			"change":    change,
+		})
+		return
+	}
+	now := co.now()
+	if reason, next := co.mutationBlocked(now); reason != "" {
+		co.actionsMu.Unlock()
+		writeLocked(w, reason, now, next)
+		return
+	}
+	// Keeps a second rollback or an execute out until this one ends. Unlike
+	// running, the worker doesn't resume it after a restart.
+	action.Status = actionStatusRollingBack
+	co.saveActionsLocked()
+	co.actionsMu.Unlock()
+
+	// The rollback must finish even if the client goes away
+	change, err := co.rollbackAction(context.WithoutCancel(r.Context()), snapshot, false)
+	updated, _ := co.updateAction(actionID, func(a *OptimizationAction) {
+		if err != nil {
+			a.Status = actionStatusExecuted
+			a.Error = "rollback failed: " + err.Error()
+			return
+		}
+		rolledBackAt := co.now()
+		a.Status = actionStatusRolledBack
+		a.RolledBackAt = &rolledBackAt
+		a.Error = ""
+	})
+	if err != nil {
+		slog.Error("Rollback of optimization action failed", "action_id", actionID, "error", err)
+		http.Error(w, err.Error(), rollbackErrorStatus(err))
+		return
+	}
+	slog.Info("Rolled back optimization action", "action_id", actionID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    updated.Status,
+		"action_id": actionID,
+		"change":    change,
+	})
+}
+
+func rollbackErrorStatus(err error) int {
+	if errors.Is(err, errActionDrifted) {
+		return http.StatusConflict
+	}
+	return http.StatusUnprocessableEntity
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRollbackActionRestoresReplicas(t *testing.T) {
+	co, client := newTestOptimizer(t, testDeployment("default", "web", 3))
+	action := testScaleAction("web", 1)
+	co.actions = []OptimizationAction{action}
+	router := co.newRouter()
+
+	if rec := serve(router, http.MethodPost, "/api/actions/"+action.ID+"/execute", nil); rec.Code != http.StatusAccepted {
+		t.Fatalf("execute: status %d, want %d: %s", rec.Code, http.StatusAccepted, rec.Body)
+	}
+	// Run the queued action here rather than on a worker, so it's done
+	// before the rollback
+	co.processAction(<-co.actionQueue)
+	if executed, _ := co.getAction(action.ID); executed.Status != actionStatusExecuted {
+		t.Fatalf("status = %q (error %q), want %q", executed.Status, executed.Error, actionStatusExecuted)
+	}
+	if got := deploymentReplicas(t, client, "default", "web"); got != 1 {
+		t.Fatalf("replicas after execute = %d, want 1", got)
+	}
+
+	rec := serve(router, http.MethodPost, "/api/actions/"+action.ID+"/rollback", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rollback: status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if got := deploymentReplicas(t, client, "default", "web"); got != 3 {
+		t.Errorf("replicas after rollback = %d, want 3", got)
+	}
+	rolledBack, _ := co.getAction(action.ID)
+	if rolledBack.Status != actionStatusRolledBack || rolledBack.RolledBackAt == nil {
+		t.Errorf("status = %q, rolled_back_at = %v, want rolled back with a time", rolledBack.Status, rolledBack.RolledBackAt)
+	}
+
+	// A second rollback has nothing left to undo
+	if rec := serve(router, http.MethodPost, "/api/actions/"+action.ID+"/rollback", nil); rec.Code != http.StatusConflict {
+		t.Errorf("second rollback: status %d, want %d", rec.Code, http.StatusConflict)
+	}
+}
+
+func TestRollbackActionConflicts(t *testing.T) {
+	previous := int32(3)
+	executed := testScaleAction("web", 1)
+	executed.Status = actionStatusExecuted
+	executed.Change = &ActionChange{CurrentReplicas: &previous, NewReplicas: 1}
+
+	pending := testScaleAction("web", 1)
+
+	noPrevious := executed
+	noPrevious.Change = &ActionChange{NewReplicas: 1}
+
+	tests := []struct {
+		name     string
+		action   OptimizationAction
+		replicas int32 // of the deployment when the rollback runs
+	}{
+		{name: "not executed", action: pending, replicas: 3},
+		{name: "no previous state", action: noPrevious, replicas: 1},
+		{name: "drifted", action: executed, replicas: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, client := newTestOptimizer(t, testDeployment("default", "web", tt.replicas))
+			co.actions = []OptimizationAction{tt.action}
+
+			rec := serve(co.newRouter(), http.MethodPost, "/api/actions/"+tt.action.ID+"/rollback", nil)
+			if rec.Code != http.StatusConflict {
+				t.Errorf("status %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body)
+			}
+			if got := deploymentReplicas(t, client, "default", "web"); got != tt.replicas {
+				t.Errorf("replicas = %d, want %d", got, tt.replicas)
+			}
+			if got, _ := co.getAction(tt.action.ID); got.Status != tt.action.Status {
+				t.Errorf("status = %q, want it left %q", got.Status, tt.action.Status)
+			}
+			for _, call := range client.Actions() {
+				if call.GetVerb() == "update" {
+					t.Errorf("conflicting rollback updated %s", call.GetResource().Resource)
+				}
+			}
+		})
+	}
+}
+
+func TestRollbackActionDryRun(t *testing.T) {
+	previous := int32(3)
+	action := testScaleAction("web", 1)
+	action.Status = actionStatusExecuted
+	action.Change = &ActionChange{CurrentReplicas: &previous, NewReplicas: 1}
+
+	co, client := newTestOptimizer(t, testDeployment("default", "web", 1))
+	co.actions = []OptimizationAction{action}
+
+	rec := serve(co.newRouter(), http.MethodPost, "/api/actions/"+action.ID+"/rollback?dry_run=true", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if got := deploymentReplicas(t, client, "default", "web"); got != 1 {
+		t.Errorf("replicas = %d, want 1", got)
+	}
+	for _, call := range client.Actions() {
+		if call.GetVerb() == "update" {
+			t.Errorf("dry-run rollback updated %s", call.GetResource().Resource)
+		}
+	}
+	if got, _ := co.getAction(action.ID); got.Status != actionStatusExecuted {
+		t.Errorf("status = %q, want it left %q", got.Status, actionStatusExecuted)
+	}
+}
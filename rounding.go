@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// defaultSuggestedHeadroom is the margin over observed usage kept in suggested
+// requests
+const defaultSuggestedHeadroom = 1.2
+
+// Default rounding increments and floors for suggested requests
+var (
+	defaultCPURounding      = resource.MustParse("10m")
+	defaultMemoryRounding   = resource.MustParse("16Mi")
+	defaultMinCPURequest    = resource.MustParse("10m")
+	defaultMinMemoryRequest = resource.MustParse("32Mi")
+)
+
+// RoundingPolicy rounds suggested requests up to clean increments so teams get
+// values like 150m or 512Mi instead of 137m or 1.37Gi. Rounding is always up,
+// never below usage plus headroom, so it can't cause under-provisioning.
+// Suggestions below the floors are raised to them, since tiny requests cause
+// scheduling churn and CPU starvation.
+type RoundingPolicy struct {
+	CPUMillis   int64 // CPU increment in millicores
+	MemoryBytes int64 // memory increment in bytes
+
+	MinCPUMillis   int64
+	MinMemoryBytes int64
+
+	Headroom float64 // multiplier applied to usage before rounding
+}
+
+func newRoundingPolicy(cpu, memory, minCPU, minMemory resource.Quantity, headroom float64) (RoundingPolicy, error) {
+	policy := RoundingPolicy{
+		CPUMillis:      cpu.MilliValue(),
+		MemoryBytes:    memory.Value(),
+		MinCPUMillis:   minCPU.MilliValue(),
+		MinMemoryBytes: minMemory.Value(),
+		Headroom:       headroom,
+	}
+	if headroom < 1 {
+		return RoundingPolicy{}, fmt.Errorf("invalid headroom %v: must be at least 1", headroom)
+	}
+	if policy.CPUMillis < 1 {
+		return RoundingPolicy{}, fmt.Errorf("invalid CPU rounding %s: must be at least 1m", cpu.String())
+	}
+	if policy.MemoryBytes < 1 {
+		return RoundingPolicy{}, fmt.Errorf("invalid memory rounding %s: must be at least 1 byte", memory.String())
+	}
+	return policy, nil
+}
+
+// roundUp returns the smallest multiple of increment that is at least value
+func roundUp(value, increment int64) int64 {
+	if increment <= 1 || value%increment == 0 {
+		return value
+	}
+	return (value/increment + 1) * increment
+}
+
+// withHeadroom adds the suggestion margin to observed usage, rounding up
+func (p RoundingPolicy) withHeadroom(usage float64) int64 {
+	return int64(math.Ceil(usage * p.Headroom))
+}
+
+// cpuRequest suggests a CPU request for the observed usage in millicores,
+// reporting whether it was raised to the floor
+func (p RoundingPolicy) cpuRequest(usageMillis float64) (*resource.Quantity, bool) {
+	millis := roundUp(p.withHeadroom(usageMillis), p.CPUMillis)
+	clamped := millis < p.MinCPUMillis
+	if clamped {
+		millis = p.MinCPUMillis
+	}
+	return resource.NewMilliQuantity(millis, resource.DecimalSI), clamped
+}
+
+// memoryRequest suggests a memory request for the observed usage in bytes,
+// reporting whether it was raised to the floor
+func (p RoundingPolicy) memoryRequest(usageBytes float64) (*resource.Quantity, bool) {
+	bytes := roundUp(p.withHeadroom(usageBytes), p.MemoryBytes)
+	clamped := bytes < p.MinMemoryBytes
+	if clamped {
+		bytes = p.MinMemoryBytes
+	}
+	return resource.NewQuantity(bytes, resource.BinarySI), clamped
+}
+
+// clampNote explains a suggestion that was raised to its floor
+func clampNote(clamped bool, floor *resource.Quantity) string {
+	if !clamped {
+		return ""
+	}
+	return fmt.Sprintf(" (raised to the %s minimum request)", floor.String())
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestRoundingPolicy(t *testing.T) {
+	policy, err := newRoundingPolicy(resource.MustParse("50m"), resource.MustParse("128Mi"), resource.Quantity{}, resource.Quantity{}, defaultSuggestedHeadroom)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cpuTests := []struct {
+		usage float64 // millicores
+		want  string
+	}{
+		{usage: 100, want: "150m"},   // 120m with headroom rounds up, not down to 100m
+		{usage: 125, want: "150m"},   // exactly 150m with headroom stays
+		{usage: 126, want: "200m"},   // 151.2m rounds up a whole increment
+		{usage: 0, want: "0"},        // idle stays at zero
+		{usage: 1000, want: "1200m"}, // already a multiple
+	}
+	for _, tt := range cpuTests {
+		got, _ := policy.cpuRequest(tt.usage)
+		if got.String() != tt.want {
+			t.Errorf("cpuRequest(%vm) = %s, want %s", tt.usage, got.String(), tt.want)
+		}
+		if float64(got.MilliValue()) < tt.usage*defaultSuggestedHeadroom {
+			t.Errorf("cpuRequest(%vm) = %s is below usage plus headroom", tt.usage, got.String())
+		}
+	}
+
+	const mi = 1 << 20
+	memoryTests := []struct {
+		usage float64 // bytes
+		want  string
+	}{
+		{usage: 100 * mi, want: "128Mi"},
+		{usage: 1130 * mi, want: "1408Mi"}, // 1356Mi with headroom
+		{usage: 1.25 * 1024 * mi, want: "1536Mi"},
+	}
+	for _, tt := range memoryTests {
+		got, _ := policy.memoryRequest(tt.usage)
+		if got.String() != tt.want {
+			t.Errorf("memoryRequest(%.0fMi) = %s, want %s", tt.usage/mi, got.String(), tt.want)
+		}
+		if float64(got.Value()) < tt.usage*defaultSuggestedHeadroom {
+			t.Errorf("memoryRequest(%.0fMi) = %s is below usage plus headroom", tt.usage/mi, got.String())
+		}
+	}
+}
+
+func TestRequestFloors(t *testing.T) {
+	policy, err := newRoundingPolicy(resource.MustParse("10m"), resource.MustParse("16Mi"), resource.MustParse("50m"), resource.MustParse("64Mi"), defaultSuggestedHeadroom)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const mi = 1 << 20
+	tests := []struct {
+		name        string
+		cpu         float64 // millicores
+		memory      float64 // bytes
+		wantCPU     string
+		wantMemory  string
+		wantClamped bool
+	}{
+		{name: "far below the floors", cpu: 4, memory: 10 * mi, wantCPU: "50m", wantMemory: "64Mi", wantClamped: true},
+		{name: "idle", wantCPU: "50m", wantMemory: "64Mi", wantClamped: true},
+		{name: "at the floors", cpu: 40, memory: 50 * mi, wantCPU: "50m", wantMemory: "64Mi"},
+		{name: "above the floors", cpu: 100, memory: 100 * mi, wantCPU: "120m", wantMemory: "128Mi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu, cpuClamped := policy.cpuRequest(tt.cpu)
+			if cpu.String() != tt.wantCPU || cpuClamped != tt.wantClamped {
+				t.Errorf("cpuRequest(%vm) = %s, clamped %v; want %s, clamped %v", tt.cpu, cpu.String(), cpuClamped, tt.wantCPU, tt.wantClamped)
+			}
+			memory, memoryClamped := policy.memoryRequest(tt.memory)
+			if memory.String() != tt.wantMemory || memoryClamped != tt.wantClamped {
+				t.Errorf("memoryRequest(%.0fMi) = %s, clamped %v; want %s, clamped %v", tt.memory/mi, memory.String(), memoryClamped, tt.wantMemory, tt.wantClamped)
+			}
+		})
+	}
+}
+
+func TestRoundUp(t *testing.T) {
+	tests := []struct{ value, increment, want int64 }{
+		{value: 137, increment: 50, want: 150},
+		{value: 150, increment: 50, want: 150},
+		{value: 151, increment: 50, want: 200},
+		{value: 137, increment: 1, want: 137},
+	}
+	for _, tt := range tests {
+		if got := roundUp(tt.value, tt.increment); got != tt.want {
+			t.Errorf("roundUp(%d, %d) = %d, want %d", tt.value, tt.increment, got, tt.want)
+		}
+	}
+}
+
+func TestRoundedSuggestionInRecommendation(t *testing.T) {
+	tests := []struct {
+		name     string
+		cpu      string // OPTIMKUBE_CPU_ROUNDING
+		minCPU   string // OPTIMKUBE_MIN_CPU_REQUEST
+		request  string
+		usage    string
+		want     string // suggested CPU request; no recommendation when empty
+		wantNote string // in the Impact when the suggestion was clamped
+		wantErr  string
+	}{
+		{name: "default increment", request: "2", usage: "137m", want: "170m"},
+		{name: "idle raised to the default floor", request: "2", usage: "0", want: "10m", wantNote: "(raised to the 10m minimum request)"},
+		{name: "raised to a configured floor", minCPU: "50m", request: "2", usage: "4m", want: "50m", wantNote: "(raised to the 50m minimum request)"},
+		{name: "floor reaches the request", minCPU: "500m", request: "500m", usage: "4m"},
+		{name: "bad floor", minCPU: "tiny", wantErr: "OPTIMKUBE_MIN_CPU_REQUEST"},
+		{name: "50m increment", cpu: "50m", request: "2", usage: "137m", want: "200m"},
+		{name: "rounding reaches the request", cpu: "500m", request: "500m", usage: "100m"},
+		{name: "zero increment", cpu: "0", wantErr: "invalid CPU rounding"},
+		{name: "not a quantity", cpu: "fifty", wantErr: "OPTIMKUBE_CPU_ROUNDING"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OPTIMKUBE_CPU_ROUNDING", tt.cpu)
+			t.Setenv("OPTIMKUBE_MIN_CPU_REQUEST", tt.minCPU)
+			if tt.wantErr != "" {
+				t.Setenv("DEMO_MODE", "true")
+				if _, err := NewCostOptimizer(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("NewCostOptimizer error = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			// Memory is fully used, so only CPU can be flagged
+			co, _ := newTestOptimizer(t,
+				testPod("shop", "api", "node-1", tt.request, "1Gi"),
+				testPodMetrics("shop", "api", tt.usage, "1Gi"))
+
+			recommendations := co.analyzePods(context.Background())
+			if tt.want == "" {
+				if len(recommendations) != 0 {
+					t.Errorf("got %+v, want no recommendation", recommendations)
+				}
+				return
+			}
+			if len(recommendations) != 1 || recommendations[0].SuggestedCPURequest != tt.want {
+				t.Fatalf("recommendations %+v, want one suggesting %s", recommendations, tt.want)
+			}
+			if hint := recommendations[0].ActionHint; hint == nil || hint.NewValue != tt.want {
+				t.Errorf("ActionHint = %+v, want new value %s", hint, tt.want)
+			}
+			if impact := recommendations[0].Impact; tt.wantNote == "" && strings.Contains(impact, "raised") || !strings.Contains(impact, tt.wantNote) {
+				t.Errorf("Impact = %q, want note %q", impact, tt.wantNote)
+			}
+		})
+	}
+}
+
+// TestSuggestedHeadroom checks that a container using 100m of a 1000m request
+// is told to set usage plus headroom, shown in the description
+func TestSuggestedHeadroom(t *testing.T) {
+	tests := []struct {
+		headroom string
+		want     string
+		wantErr  bool
+	}{
+		{headroom: "", want: "120m"},
+		{headroom: "1.5", want: "150m"},
+		{headroom: "1", want: "100m"},
+		{headroom: "0.8", wantErr: true},
+		{headroom: "lots", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.headroom, func(t *testing.T) {
+			t.Setenv("OPTIMKUBE_SUGGESTED_HEADROOM", tt.headroom)
+			if tt.wantErr {
+				t.Setenv("DEMO_MODE", "true")
+				if _, err := NewCostOptimizer(); err == nil {
+					t.Fatal("NewCostOptimizer accepted the headroom")
+				}
+				return
+			}
+			co, _ := newTestOptimizer(t,
+				testPod("shop", "web", "node-1", "1000m", "1Gi"), testPodMetrics("shop", "web", "100m", "1Gi"))
+
+			var rec *Recommendation
+			for _, r := range co.analyzePods(context.Background()) {
+				if r.SuggestedCPURequest != "" {
+					rec = &r
+				}
+			}
+			if rec == nil {
+				t.Fatal("no CPU rightsizing recommendation")
+			}
+			if rec.SuggestedCPURequest != tt.want {
+				t.Errorf("suggested %s, want %s", rec.SuggestedCPURequest, tt.want)
+			}
+			if want := "(request: 1000m, usage: 100m, suggested: " + tt.want + ")"; !strings.Contains(rec.Description, want) {
+				t.Errorf("description %q, want it to contain %q", rec.Description, want)
+			}
+		})
+	}
+}
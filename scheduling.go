@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// mirrorPodAnnotation marks static pods, which a drain can't move
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// schedulingSnapshot is the node and pod state a drain simulation places pods
+// against. It checks nodeSelector, taints and tolerations, required node
+// affinity and remaining requested capacity; pod affinity and topology spread
+// are not simulated.
+type schedulingSnapshot struct {
+	nodes []*corev1.Node
+	pods  map[string][]*corev1.Pod // by node name
+
+	// requested CPU millicores, memory bytes and pod count per node
+	cpu    map[string]int64
+	memory map[string]int64
+	count  map[string]int64
+}
+
+// loadSchedulingSnapshot lists the cluster's pods against nodes. It returns
+// nil when pods can't be listed, and consolidation is then left ungated.
+func (co *CostOptimizer) loadSchedulingSnapshot(ctx context.Context, nodes []corev1.Node) *schedulingSnapshot {
+	pods, err := co.listPods(ctx, "")
+	if err != nil {
+		slog.Warn("Failed to list pods, skipping drain feasibility checks", "error", err)
+		return nil
+	}
+
+	snapshot := &schedulingSnapshot{
+		pods:   make(map[string][]*corev1.Pod),
+		cpu:    make(map[string]int64),
+		memory: make(map[string]int64),
+		count:  make(map[string]int64),
+	}
+	for i := range nodes {
+		snapshot.nodes = append(snapshot.nodes, &nodes[i])
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		snapshot.pods[pod.Spec.NodeName] = append(snapshot.pods[pod.Spec.NodeName], pod)
+		snapshot.cpu[pod.Spec.NodeName] += podRequests(pod, corev1.ResourceCPU)
+		snapshot.memory[pod.Spec.NodeName] += podRequests(pod, corev1.ResourceMemory)
+		snapshot.count[pod.Spec.NodeName]++
+	}
+	return snapshot
+}
+
+// simulateDrain places the pods of the drained nodes on the remaining nodes,
+// largest requests first, and returns the namespace/name of every pod that
+// fits nowhere. DaemonSet and static pods aren't moved by a drain and are
+// skipped. A nil snapshot can't simulate anything and reports no pods.
+func (s *schedulingSnapshot) simulateDrain(drained []string) []string {
+	if s == nil {
+		return nil
+	}
+
+	draining := make(map[string]bool, len(drained))
+	for _, name := range drained {
+		draining[name] = true
+	}
+
+	moving := make([]*corev1.Pod, 0)
+	for _, name := range drained {
+		for _, pod := range s.pods[name] {
+			if ownedByDaemonSet(pod) || pod.Annotations[mirrorPodAnnotation] != "" {
+				continue
+			}
+			moving = append(moving, pod)
+		}
+	}
+	sort.SliceStable(moving, func(i, j int) bool {
+		return podRequests(moving[i], corev1.ResourceCPU) > podRequests(moving[j], corev1.ResourceCPU)
+	})
+
+	// Placements in this simulation only; the snapshot itself is left as is
+	cpu := make(map[string]int64, len(s.cpu))
+	memory := make(map[string]int64, len(s.memory))
+	count := make(map[string]int64, len(s.count))
+	for name := range s.count {
+		cpu[name], memory[name], count[name] = s.cpu[name], s.memory[name], s.count[name]
+	}
+
+	unplaced := make([]string, 0)
+	for _, pod := range moving {
+		podCPU := podRequests(pod, corev1.ResourceCPU)
+		podMemory := podRequests(pod, corev1.ResourceMemory)
+
+		placed := false
+		for _, node := range s.nodes {
+			if draining[node.Name] || node.Spec.Unschedulable || !podFitsNode(pod, node) {
+				continue
+			}
+			allocatable := node.Status.Allocatable
+			if cpu[node.Name]+podCPU > allocatable.Cpu().MilliValue() ||
+				memory[node.Name]+podMemory > allocatable.Memory().Value() ||
+				count[node.Name]+1 > allocatable.Pods().Value() {
+				continue
+			}
+			cpu[node.Name] += podCPU
+			memory[node.Name] += podMemory
+			count[node.Name]++
+			placed = true
+			break
+		}
+		if !placed {
+			unplaced = append(unplaced, pod.Namespace+"/"+pod.Name)
+		}
+	}
+	return unplaced
+}
+
+// podFitsNode checks the pod's nodeSelector, its tolerations against the
+// node's NoSchedule and NoExecute taints, and its required node affinity
+func podFitsNode(pod *corev1.Pod, node *corev1.Node) bool {
+	for key, value := range pod.Spec.NodeSelector {
+		if node.Labels[key] != value {
+			return false
+		}
+	}
+
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := false
+		for _, toleration := range pod.Spec.Tolerations {
+			if toleration.ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+
+	affinity := pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+	// Terms are ORed; the requirements within a term are ANDed
+	for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		if nodeMatchesTerm(node, term) {
+			return true
+		}
+	}
+	return false
+}
+
+func nodeMatchesTerm(node *corev1.Node, term corev1.NodeSelectorTerm) bool {
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return false
+	}
+	for _, requirement := range term.MatchExpressions {
+		value, ok := node.Labels[requirement.Key]
+		if !requirementMatches(requirement, value, ok) {
+			return false
+		}
+	}
+	for _, requirement := range term.MatchFields {
+		if requirement.Key != metav1.ObjectNameField || !requirementMatches(requirement, node.Name, true) {
+			return false
+		}
+	}
+	return true
+}
+
+// requirementMatches evaluates one node selector requirement against a label
+// value, where present reports whether the label exists at all
+func requirementMatches(requirement corev1.NodeSelectorRequirement, value string, present bool) bool {
+	switch requirement.Operator {
+	case corev1.NodeSelectorOpIn:
+		return present && slices.Contains(requirement.Values, value)
+	case corev1.NodeSelectorOpNotIn:
+		return !present || !slices.Contains(requirement.Values, value)
+	case corev1.NodeSelectorOpExists:
+		return present
+	case corev1.NodeSelectorOpDoesNotExist:
+		return !present
+	case corev1.NodeSelectorOpGt, corev1.NodeSelectorOpLt:
+		if !present || len(requirement.Values) != 1 {
+			return false
+		}
+		actual, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false
+		}
+		bound, err := strconv.ParseInt(requirement.Values[0], 10, 64)
+		if err != nil {
+			return false
+		}
+		if requirement.Operator == corev1.NodeSelectorOpGt {
+			return actual > bound
+		}
+		return actual < bound
+	}
+	return false
+}
+
+// recordBlockedConsolidations replaces the consolidations the last scan held
+// back, keyed by node or node group, with the pods that couldn't be placed
+func (co *CostOptimizer) recordBlockedConsolidations(blocked map[string][]string) {
+	co.diagMu.Lock()
+	defer co.diagMu.Unlock()
+	co.blockedConsolidations = blocked
+}
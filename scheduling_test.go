@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// withTaint adds a taint to node
+func withTaint(node *corev1.Node, key, value string, effect corev1.TaintEffect) *corev1.Node {
+	node.Spec.Taints = append(node.Spec.Taints, corev1.Taint{Key: key, Value: value, Effect: effect})
+	return node
+}
+
+// requiredAffinity requires the node to match one of terms
+func requiredAffinity(terms ...corev1.NodeSelectorTerm) *corev1.Affinity {
+	return &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: terms},
+	}}
+}
+
+func expression(key string, operator corev1.NodeSelectorOperator, values ...string) corev1.NodeSelectorTerm {
+	return corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: key, Operator: operator, Values: values}}}
+}
+
+func TestPodFitsNode(t *testing.T) {
+	node := testNode("node-1", "4", "16Gi")
+	node.Labels = map[string]string{"zone": "a", "generation": "5"}
+	gpuNode := withTaint(testNode("gpu-1", "4", "16Gi"), "nvidia.com/gpu", "present", corev1.TaintEffectNoSchedule)
+	softNode := withTaint(testNode("soft-1", "4", "16Gi"), "spot", "", corev1.TaintEffectPreferNoSchedule)
+
+	tests := []struct {
+		name string
+		node *corev1.Node
+		pod  func(*corev1.Pod)
+		want bool
+	}{
+		{name: "no constraints", node: node, pod: func(*corev1.Pod) {}, want: true},
+		{name: "matching nodeSelector", node: node, pod: func(p *corev1.Pod) { p.Spec.NodeSelector = map[string]string{"zone": "a"} }, want: true},
+		{name: "mismatched nodeSelector", node: node, pod: func(p *corev1.Pod) { p.Spec.NodeSelector = map[string]string{"zone": "b"} }},
+		{name: "untolerated taint", node: gpuNode, pod: func(*corev1.Pod) {}},
+		{
+			name: "tolerated taint",
+			node: gpuNode,
+			pod: func(p *corev1.Pod) {
+				p.Spec.Tolerations = []corev1.Toleration{{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}}
+			},
+			want: true,
+		},
+		{name: "soft taint", node: softNode, pod: func(*corev1.Pod) {}, want: true},
+		{name: "affinity In", node: node, pod: func(p *corev1.Pod) {
+			p.Spec.Affinity = requiredAffinity(expression("zone", corev1.NodeSelectorOpIn, "a", "b"))
+		}, want: true},
+		{name: "affinity NotIn", node: node, pod: func(p *corev1.Pod) {
+			p.Spec.Affinity = requiredAffinity(expression("zone", corev1.NodeSelectorOpNotIn, "a"))
+		}},
+		{name: "affinity DoesNotExist", node: node, pod: func(p *corev1.Pod) {
+			p.Spec.Affinity = requiredAffinity(expression("gpu", corev1.NodeSelectorOpDoesNotExist))
+		}, want: true},
+		{name: "affinity Gt", node: node, pod: func(p *corev1.Pod) {
+			p.Spec.Affinity = requiredAffinity(expression("generation", corev1.NodeSelectorOpGt, "4"))
+		}, want: true},
+		{name: "affinity Lt", node: node, pod: func(p *corev1.Pod) {
+			p.Spec.Affinity = requiredAffinity(expression("generation", corev1.NodeSelectorOpLt, "4"))
+		}},
+		{
+			name: "any term matches",
+			node: node,
+			pod: func(p *corev1.Pod) {
+				p.Spec.Affinity = requiredAffinity(expression("zone", corev1.NodeSelectorOpIn, "b"), expression("zone", corev1.NodeSelectorOpExists))
+			},
+			want: true,
+		},
+		{
+			name: "node name field",
+			node: node,
+			pod: func(p *corev1.Pod) {
+				p.Spec.Affinity = requiredAffinity(corev1.NodeSelectorTerm{MatchFields: []corev1.NodeSelectorRequirement{{Key: metav1.ObjectNameField, Operator: corev1.NodeSelectorOpIn, Values: []string{"node-2"}}}})
+			},
+		},
+		{name: "empty term", node: node, pod: func(p *corev1.Pod) { p.Spec.Affinity = requiredAffinity(corev1.NodeSelectorTerm{}) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := testPod("shop", "web", "node-0", "100m", "128Mi")
+			tt.pod(pod)
+			if got := podFitsNode(pod, tt.node); got != tt.want {
+				t.Errorf("podFitsNode = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimulateDrain(t *testing.T) {
+	daemon := testPod("kube-system", "node-exporter", "node-1", "4", "16Gi")
+	daemon.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "node-exporter", Controller: boolPtr(true)}}
+	static := testPod("kube-system", "etcd", "node-1", "4", "16Gi")
+	static.Annotations = map[string]string{mirrorPodAnnotation: "abc"}
+	cordoned := testNode("node-2", "4", "16Gi")
+	cordoned.Spec.Unschedulable = true
+
+	tests := []struct {
+		name  string
+		nodes []*corev1.Node
+		pods  []*corev1.Pod
+		want  []string
+	}{
+		{
+			name:  "fits on the other node",
+			nodes: []*corev1.Node{testNode("node-1", "4", "16Gi"), testNode("node-2", "4", "16Gi")},
+			pods:  []*corev1.Pod{testPod("shop", "web", "node-1", "1", "1Gi"), testPod("shop", "api", "node-2", "2", "1Gi")},
+			want:  []string{},
+		},
+		{
+			// The larger pod is placed first and takes the remaining room
+			name:  "no room left",
+			nodes: []*corev1.Node{testNode("node-1", "4", "16Gi"), testNode("node-2", "4", "16Gi")},
+			pods: []*corev1.Pod{
+				testPod("shop", "web", "node-1", "1", "1Gi"), testPod("shop", "db", "node-1", "2", "1Gi"),
+				testPod("shop", "api", "node-2", "2", "1Gi"),
+			},
+			want: []string{"shop/web"},
+		},
+		{
+			name:  "daemon and static pods stay",
+			nodes: []*corev1.Node{testNode("node-1", "4", "16Gi"), testNode("node-2", "1", "1Gi")},
+			pods:  []*corev1.Pod{daemon, static},
+			want:  []string{},
+		},
+		{
+			name:  "cordoned node takes nothing",
+			nodes: []*corev1.Node{testNode("node-1", "4", "16Gi"), cordoned},
+			pods:  []*corev1.Pod{testPod("shop", "web", "node-1", "100m", "128Mi")},
+			want:  []string{"shop/web"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := make([]runtime.Object, 0)
+			nodes := make([]corev1.Node, 0, len(tt.nodes))
+			for _, node := range tt.nodes {
+				nodes = append(nodes, *node)
+			}
+			for _, pod := range tt.pods {
+				objects = append(objects, pod)
+			}
+			co, _ := newTestOptimizer(t, objects...)
+
+			snapshot := co.loadSchedulingSnapshot(context.Background(), nodes)
+			if got := snapshot.simulateDrain([]string{"node-1"}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unplaced %v, want %v", got, tt.want)
+			}
+			// The simulation leaves the snapshot's requests untouched
+			if got := snapshot.simulateDrain([]string{"node-1"}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("second simulation unplaced %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var unknown *schedulingSnapshot
+	if got := unknown.simulateDrain([]string{"node-1"}); got != nil {
+		t.Errorf("nil snapshot reported %v", got)
+	}
+}
+
+func TestTaintedDrainSuppressed(t *testing.T) {
+	tests := []struct {
+		name        string
+		spare       *corev1.Node
+		wantBlocked []string
+	}{
+		{name: "untainted spare node", spare: testNode("node-2", "8", "32Gi")},
+		{name: "tainted spare node", spare: withTaint(testNode("node-2", "8", "32Gi"), "dedicated", "gpu", corev1.TaintEffectNoSchedule), wantBlocked: []string{"shop/web"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t,
+				testNode("node-1", "8", "32Gi"), testNodeMetrics("node-1", "500m", "2Gi"), tt.spare,
+				testPod("shop", "web", "node-1", "500m", "1Gi"))
+
+			var drains []Recommendation
+			for _, rec := range co.analyzeNodes(context.Background()) {
+				if rec.Type == "node_optimization" && rec.Resource == "node-1" {
+					drains = append(drains, rec)
+				}
+			}
+			if got := len(drains) == 1; got != (tt.wantBlocked == nil) {
+				t.Errorf("drain recommendations %+v, want recommended %v", drains, tt.wantBlocked == nil)
+			}
+
+			blocked := co.diagnostics().BlockedConsolidations
+			if tt.wantBlocked == nil {
+				if len(blocked) != 0 {
+					t.Errorf("blocked consolidations %v, want none", blocked)
+				}
+				return
+			}
+			if got := blocked["node/node-1"]; !reflect.DeepEqual(got, tt.wantBlocked) {
+				t.Errorf("node-1 blocked by %v, want %v", got, tt.wantBlocked)
+			}
+		})
+	}
+}
+
+func TestNodeGroupShrinkSuppressedByTaints(t *testing.T) {
+	// Three idle nodes would shrink to one, but the survivor is tainted
+	objects := testNodeGroup("eks.amazonaws.com/nodegroup", "workers", 3, "100m", "1Gi")
+	workers3 := objects[4].(*corev1.Node)
+	withTaint(workers3, "dedicated", "batch", corev1.TaintEffectNoExecute)
+	objects = append(objects, podsOn("workers-1", 1)...)
+	objects = append(objects, podsOn("workers-2", 1)...)
+	objects = append(objects, podsOn("workers-3", 3)...)
+	co, _ := newTestOptimizer(t, objects...)
+
+	for _, rec := range co.analyzeNodes(context.Background()) {
+		if rec.Type == "node_group_rightsizing" {
+			t.Errorf("recommended %q despite the taint", rec.Impact)
+		}
+	}
+	want := []string{"shop/workers-1-pod-0", "shop/workers-2-pod-0"}
+	if got := co.diagnostics().BlockedConsolidations["nodegroup/workers"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("workers blocked by %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// loadAnalysisScope reads OPTIMKUBE_LABEL_SELECTOR, which limits the pods and
+// workloads analyzed to those whose labels match, and OPTIMKUBE_NAMESPACE_EXCLUDE,
+// a comma-separated list of namespace names or globs left out of every result.
+// Unlike excluded_namespaces in the config file, whose cost is still reported
+// under "(excluded)", these namespaces don't appear in the cost summary at all.
+func (co *CostOptimizer) loadAnalysisScope() error {
+	if selector := os.Getenv("OPTIMKUBE_LABEL_SELECTOR"); selector != "" {
+		if _, err := labels.Parse(selector); err != nil {
+			return fmt.Errorf("invalid OPTIMKUBE_LABEL_SELECTOR %q: %w", selector, err)
+		}
+		co.workloadSelector = selector
+	}
+
+	if raw := os.Getenv("OPTIMKUBE_NAMESPACE_EXCLUDE"); raw != "" {
+		patterns := make([]string, 0)
+		for _, pattern := range strings.Split(raw, ",") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				patterns = append(patterns, pattern)
+			}
+		}
+		if err := validateNamespacePatterns(patterns); err != nil {
+			return fmt.Errorf("invalid OPTIMKUBE_NAMESPACE_EXCLUDE: %w", err)
+		}
+		co.droppedNamespaces = patterns
+	}
+	return nil
+}
+
+// workloadListOptions lists pods, pod metrics and workload controllers in
+// scope of OPTIMKUBE_LABEL_SELECTOR
+func (co *CostOptimizer) workloadListOptions() metav1.ListOptions {
+	return metav1.ListOptions{LabelSelector: co.workloadSelector}
+}
+
+// namespaceDropped reports whether a namespace matches OPTIMKUBE_NAMESPACE_EXCLUDE
+func (co *CostOptimizer) namespaceDropped(namespace string) bool {
+	for _, pattern := range co.droppedNamespaces {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// inScope removes the items of a list that are in dropped namespaces. The
+// list's items are filtered in place.
+func inScope[T any, PT interface {
+	*T
+	GetNamespace() string
+}](co *CostOptimizer, items []T) []T {
+	if len(co.droppedNamespaces) == 0 {
+		return items
+	}
+	kept := items[:0]
+	for i := range items {
+		if !co.namespaceDropped(PT(&items[i]).GetNamespace()) {
+			kept = append(kept, items[i])
+		}
+	}
+	return kept
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// labelledPod is an over-provisioned pod with the given labels, and its usage
+func labelledPod(namespace, name string, labels map[string]string) []runtime.Object {
+	pod := testPod(namespace, name, "node-1", "2", "4Gi")
+	pod.Labels = labels
+	metrics := testPodMetrics(namespace, name, "100m", "256Mi")
+	metrics.Labels = labels
+	return []runtime.Object{pod, metrics}
+}
+
+func TestAnalysisScope(t *testing.T) {
+	tests := []struct {
+		name           string
+		selector       string
+		exclude        string
+		wantNamespaces []string // with recommendations and a cost line
+		wantResources  []string // recommended pods
+	}{
+		{
+			name:           "everything",
+			wantNamespaces: []string{"batch", "monitoring", "shop"},
+			wantResources:  []string{"batch/etl", "monitoring/prometheus", "shop/web"},
+		},
+		{
+			name:           "excluded namespaces",
+			exclude:        "mon*, batch",
+			wantNamespaces: []string{"shop"},
+			wantResources:  []string{"shop/web"},
+		},
+		{
+			name:           "label selector",
+			selector:       "tier=batch",
+			wantNamespaces: []string{"batch"},
+			wantResources:  []string{"batch/etl"},
+		},
+		{
+			name:     "selector and exclusion together",
+			selector: "tier=batch",
+			exclude:  "batch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OPTIMKUBE_LABEL_SELECTOR", tt.selector)
+			t.Setenv("OPTIMKUBE_NAMESPACE_EXCLUDE", tt.exclude)
+			objects := []runtime.Object{testNode("node-1", "16", "64Gi"), testNodeMetrics("node-1", "4", "16Gi")}
+			objects = append(objects, labelledPod("shop", "web", map[string]string{"tier": "web"})...)
+			objects = append(objects, labelledPod("batch", "etl", map[string]string{"tier": "batch"})...)
+			objects = append(objects, labelledPod("monitoring", "prometheus", map[string]string{"app": "prometheus"})...)
+			co, _ := newTestOptimizer(t, objects...)
+
+			co.analyzeAndGenerateRecommendations(context.Background())
+			recommended := make(map[string]bool)
+			namespaces := make(map[string]bool)
+			for _, rec := range co.activeRecommendations() {
+				if rec.Namespace == "" {
+					continue
+				}
+				namespaces[rec.Namespace] = true
+				if rec.Type == "resource_rightsizing" {
+					recommended[rec.Resource] = true
+				}
+			}
+			if !sameKeys(recommended, tt.wantResources) {
+				t.Errorf("rightsized %v, want %v", recommended, tt.wantResources)
+			}
+			if !sameKeys(namespaces, tt.wantNamespaces) {
+				t.Errorf("recommendations in %v, want only %v", namespaces, tt.wantNamespaces)
+			}
+
+			summary := co.generateCostSummary(context.Background())
+			costed := make(map[string]bool)
+			for namespace := range summary.NamespaceCosts {
+				costed[namespace] = true
+			}
+			if !sameKeys(costed, tt.wantNamespaces) {
+				t.Errorf("cost lines for %v, want %v", summary.NamespaceCosts, tt.wantNamespaces)
+			}
+			if summary.PodCount != len(tt.wantResources) {
+				t.Errorf("%d pods, want %d", summary.PodCount, len(tt.wantResources))
+			}
+		})
+	}
+}
+
+// sameKeys reports whether set holds exactly want
+func sameKeys(set map[string]bool, want []string) bool {
+	if len(set) != len(want) {
+		return false
+	}
+	for _, key := range want {
+		if !set[key] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDropExcludedNamespacesDropped(t *testing.T) {
+	t.Setenv("OPTIMKUBE_NAMESPACE_EXCLUDE", "batch")
+	co, _ := newTestOptimizer(t)
+	recommendations := co.dropExcludedNamespaces([]Recommendation{
+		{Type: "resource_rightsizing", Resource: "batch/etl", Namespace: "batch"},
+		{Type: "resource_rightsizing", Resource: "shop/web", Namespace: "shop"},
+		{Type: "node_consolidation", Resource: "node-1"},
+	})
+	if len(recommendations) != 2 || recommendations[0].Resource != "shop/web" || recommendations[1].Resource != "node-1" {
+		t.Errorf("kept %+v, want shop/web and the cluster-scoped node-1", recommendations)
+	}
+}
+
+func TestAnalysisScopeSetting(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		exclude  string
+		wantErr  bool
+	}{
+		{name: "unset"},
+		{name: "set-based selector", selector: "tier in (batch, web),!canary"},
+		{name: "invalid selector", selector: "tier in (batch", wantErr: true},
+		{name: "invalid pattern", exclude: "kube-[", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DEMO_MODE", "true")
+			t.Setenv("OPTIMKUBE_LABEL_SELECTOR", tt.selector)
+			t.Setenv("OPTIMKUBE_NAMESPACE_EXCLUDE", tt.exclude)
+			co, err := NewCostOptimizer()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCostOptimizer error %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && co.workloadListOptions().LabelSelector != tt.selector {
+				t.Errorf("selector %q, want %q", co.workloadListOptions().LabelSelector, tt.selector)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Defaults for cost spike detection. A spike must exceed both thresholds, so
+// small namespaces doubling from pennies don't page anyone.
+const (
+	defaultCostSpikePercent     = 50.0
+	defaultCostSpikeMinIncrease = 100.0 // monthly
+)
+
+// scanCosts is the projected monthly pod cost seen by one scan
+type scanCosts struct {
+	Total      float64
+	Namespaces map[string]float64
+	Workloads  map[string]map[string]float64 // namespace -> workload -> cost
+}
+
+func newScanCosts(pods []PodMetrics) *scanCosts {
+	costs := &scanCosts{
+		Namespaces: make(map[string]float64),
+		Workloads:  make(map[string]map[string]float64),
+	}
+	for _, pod := range pods {
+		costs.Total += pod.EstimatedCost
+		costs.Namespaces[pod.Namespace] += pod.EstimatedCost
+		if costs.Workloads[pod.Namespace] == nil {
+			costs.Workloads[pod.Namespace] = make(map[string]float64)
+		}
+		costs.Workloads[pod.Namespace][pod.Workload] += pod.EstimatedCost
+	}
+	return costs
+}
+
+// isCostSpike reports whether a move from previous to current exceeds both the
+// percentage and the absolute threshold
+func (co *CostOptimizer) isCostSpike(previous, current float64) bool {
+	increase := current - previous
+	if increase <= co.costSpikeMinIncrease {
+		return false
+	}
+	return previous == 0 || increase/previous*100 > co.costSpikePercent
+}
+
+// largestIncrease returns the key whose cost grew the most between two scans
+func largestIncrease(previous, current map[string]float64) (string, float64) {
+	keys := make([]string, 0, len(current))
+	for key := range current {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var driver string
+	var largest float64
+	for _, key := range keys {
+		if increase := current[key] - previous[key]; increase > largest {
+			driver, largest = key, increase
+		}
+	}
+	return driver, largest
+}
+
+// detectCostSpikes compares this scan's projected cost, in total and per
+// namespace, with the previous scan's and flags sudden increases along with
+// the namespace or workload driving them. Each spike is also pushed to the
+// notifier, since it's relative to the last scan and won't repeat.
+func (co *CostOptimizer) detectCostSpikes(ctx context.Context) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	if co.demoMode || co.clientset == nil || co.metricsClient == nil {
+		return recommendations
+	}
+
+	current := newScanCosts(co.getPodMetrics(ctx, ""))
+
+	co.spikeMu.Lock()
+	previous := co.previousCosts
+	co.previousCosts = current
+	co.spikeMu.Unlock()
+
+	if previous == nil {
+		return recommendations
+	}
+
+	if co.isCostSpike(previous.Total, current.Total) {
+		driver, increase := largestIncrease(previous.Namespaces, current.Namespaces)
+		recommendations = append(recommendations, co.costSpikeRecommendation(ctx, "cluster", "",
+			fmt.Sprintf("Projected cluster cost jumped from $%.2f to $%.2f/month since the last scan, driven mostly by namespace %s (+$%.2f)",
+				previous.Total, current.Total, driver, increase)))
+	}
+
+	namespaces := make([]string, 0, len(current.Namespaces))
+	for namespace := range current.Namespaces {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	for _, namespace := range namespaces {
+		before, after := previous.Namespaces[namespace], current.Namespaces[namespace]
+		if !co.isCostSpike(before, after) {
+			continue
+		}
+		driver, increase := largestIncrease(previous.Workloads[namespace], current.Workloads[namespace])
+		recommendations = append(recommendations, co.costSpikeRecommendation(ctx, namespace, namespace,
+			fmt.Sprintf("Projected cost of namespace %s jumped from $%.2f to $%.2f/month since the last scan, driven mostly by %s (+$%.2f)",
+				namespace, before, after, driver, increase)))
+	}
+
+	return recommendations
+}
+
+func (co *CostOptimizer) costSpikeRecommendation(ctx context.Context, resource, namespace, description string) Recommendation {
+	co.notify(ctx, Notification{
+		Title:    fmt.Sprintf("Cost spike: %s", resource),
+		Text:     description,
+		Priority: "high",
+	})
+	return Recommendation{
+		Type:        "cost_spike",
+		Category:    CategoryConfigure,
+		Resource:    resource,
+		Namespace:   namespace,
+		Description: description,
+		Impact:      "Check for runaway scaling or unexpectedly large resource requests",
+		Priority:    "high",
+		Timestamp:   time.Now(),
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+func TestDetectCostSpikes(t *testing.T) {
+	ctx := context.Background()
+	co, clientset := newTestOptimizer(t,
+		testNode("node-1", "32", "128Gi"), testNodeMetrics("node-1", "4", "16Gi"),
+		testPod("shop", "api", "node-1", "1", "2Gi"), testPodMetrics("shop", "api", "500m", "1Gi"),
+		testPod("batch", "etl-0", "node-1", "1", "2Gi"), testPodMetrics("batch", "etl-0", "500m", "1Gi"),
+	)
+	co.costSpikeMinIncrease = 1
+	// Each call stands for a scan of the cluster as it is then
+	co.listCache.ttl = 0
+	notifier := &recordingNotifier{}
+	co.notifier = notifier
+
+	if recommendations := co.detectCostSpikes(ctx); len(recommendations) != 0 {
+		t.Fatalf("first scan flagged %+v, want a baseline only", recommendations)
+	}
+	if recommendations := co.detectCostSpikes(ctx); len(recommendations) != 0 {
+		t.Fatalf("unchanged scan flagged %+v", recommendations)
+	}
+
+	// The etl deployment scales out with large pods
+	metrics := co.metricsClient.(*metricsfake.Clientset)
+	for _, name := range []string{"etl-7d9f8-a", "etl-7d9f8-b", "etl-7d9f8-c"} {
+		pod := testPod("batch", name, "node-1", "4", "16Gi")
+		pod.Labels = map[string]string{"pod-template-hash": "7d9f8"}
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "etl-7d9f8", Controller: boolPtr(true)}}
+		if _, err := clientset.CoreV1().Pods("batch").Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := metrics.Tracker().Create(metricsv1beta1.SchemeGroupVersion.WithResource("pods"), testPodMetrics("batch", name, "2", "8Gi"), "batch"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recommendations := co.detectCostSpikes(ctx)
+	resources := make([]string, 0, len(recommendations))
+	for _, rec := range recommendations {
+		resources = append(resources, rec.Resource)
+		if rec.Type != "cost_spike" || rec.Priority != "high" {
+			t.Errorf("%s: %s %s, want a high-priority cost_spike", rec.Resource, rec.Type, rec.Priority)
+		}
+	}
+	if want := []string{"cluster", "batch"}; !reflect.DeepEqual(resources, want) {
+		t.Fatalf("spikes for %v, want %v", resources, want)
+	}
+	if !strings.Contains(recommendations[0].Description, "driven mostly by namespace batch") {
+		t.Errorf("cluster spike %q doesn't name the namespace", recommendations[0].Description)
+	}
+	if !strings.Contains(recommendations[1].Description, "driven mostly by deployment/etl") {
+		t.Errorf("namespace spike %q doesn't name the workload", recommendations[1].Description)
+	}
+	if titles := notifier.titles(); !reflect.DeepEqual(titles, []string{"Cost spike: cluster", "Cost spike: batch"}) {
+		t.Errorf("notifications %v, want one per spike", titles)
+	}
+}
+
+func TestIsCostSpike(t *testing.T) {
+	co := &CostOptimizer{costSpikePercent: 50, costSpikeMinIncrease: 100}
+	tests := []struct {
+		name              string
+		previous, current float64
+		want              bool
+	}{
+		{name: "both thresholds exceeded", previous: 1000, current: 1600, want: true},
+		{name: "large percentage, small amount", previous: 10, current: 60},
+		{name: "large amount, small percentage", previous: 10000, current: 10500},
+		{name: "new namespace", previous: 0, current: 500, want: true},
+		{name: "decrease", previous: 1000, current: 100},
+	}
+
+	for _, tt := range tests {
+		if got := co.isCostSpike(tt.previous, tt.current); got != tt.want {
+			t.Errorf("%s: isCostSpike(%v, %v) = %v, want %v", tt.name, tt.previous, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestPodWorkload(t *testing.T) {
+	controller := boolPtr(true)
+	tests := []struct {
+		name   string
+		labels map[string]string
+		owners []metav1.OwnerReference
+		want   string
+	}{
+		{name: "bare pod", want: "pod/web"},
+		{name: "deployment replica", labels: map[string]string{"pod-template-hash": "5c7f9"},
+			owners: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-5c7f9", Controller: controller}}, want: "deployment/web"},
+		{name: "standalone replicaset", owners: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-rs", Controller: controller}}, want: "replicaset/web-rs"},
+		{name: "statefulset", owners: []metav1.OwnerReference{{Kind: "StatefulSet", Name: "db", Controller: controller}}, want: "statefulset/db"},
+		{name: "non-controller owner", owners: []metav1.OwnerReference{{Kind: "ConfigMap", Name: "cfg"}}, want: "pod/web"},
+	}
+
+	for _, tt := range tests {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Labels: tt.labels, OwnerReferences: tt.owners}}
+		if got := podWorkload(pod); got != tt.want {
+			t.Errorf("%s: podWorkload = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func boolPtr(b bool) *bool { return &b }
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// defaultSpotPriceFactor is the fraction of the on-demand rate a spot node costs
+const defaultSpotPriceFactor = 0.3
+
+// spotLabels maps capacity-type labels used by the major providers and
+// autoscalers to the value that marks a node as spot/preemptible.
+var spotLabels = map[string]string{
+	"node.kubernetes.io/capacity-type":      "spot",
+	"karpenter.sh/capacity-type":            "spot",
+	"eks.amazonaws.com/capacityType":        "SPOT",
+	"cloud.google.com/gke-preemptible":      "true",
+	"cloud.google.com/gke-spot":             "true",
+	"kubernetes.azure.com/scalesetpriority": "spot",
+}
+
+// Capacity types reported in NodeMetrics
+const (
+	capacitySpot     = "spot"
+	capacityOnDemand = "on-demand"
+)
+
+func nodeCapacityType(node *corev1.Node) string {
+	if isSpotNode(node) {
+		return capacitySpot
+	}
+	return capacityOnDemand
+}
+
+func isSpotNode(node *corev1.Node) bool {
+	for key, value := range spotLabels {
+		if strings.EqualFold(node.Labels[key], value) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCapacityTypeConstraint reports whether a pod spec pins itself to a
+// capacity type through a nodeSelector or required node affinity. Workloads
+// that do this made an explicit placement choice we shouldn't second-guess.
+func hasCapacityTypeConstraint(spec *corev1.PodSpec) bool {
+	for key := range spec.NodeSelector {
+		if _, ok := spotLabels[key]; ok {
+			return true
+		}
+	}
+
+	if spec.Affinity == nil || spec.Affinity.NodeAffinity == nil || spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return false
+	}
+	for _, term := range spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, expr := range term.MatchExpressions {
+			if _, ok := spotLabels[expr.Key]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// analyzeSpotMigration recommends moving interruption-tolerant deployments
+// from on-demand nodes onto an existing spot pool.
+func (co *CostOptimizer) analyzeSpotMigration(ctx context.Context) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	if co.demoMode || co.clientset == nil || co.analyzerDisabled("spot") {
+		return recommendations
+	}
+
+	nodes, err := co.listNodes(ctx)
+	if err != nil {
+		co.analyzerListFailed("spot", "nodes", err)
+		return recommendations
+	}
+
+	nodesByName := make(map[string]*corev1.Node, len(nodes.Items))
+	hasSpotPool := false
+	for i := range nodes.Items {
+		nodesByName[nodes.Items[i].Name] = &nodes.Items[i]
+		if isSpotNode(&nodes.Items[i]) {
+			hasSpotPool = true
+		}
+	}
+	if !hasSpotPool {
+		return recommendations
+	}
+
+	deployments, err := co.clientset.AppsV1().Deployments("").List(ctx, co.workloadListOptions())
+	if err != nil {
+		co.analyzerListFailed("spot", "deployments", err)
+		return recommendations
+	}
+	deployments.Items = inScope(co, deployments.Items)
+
+	pods, err := co.listPods(ctx, "")
+	if err != nil {
+		co.analyzerListFailed("spot", "pods", err)
+		return recommendations
+	}
+
+	pdbs, err := co.clientset.PolicyV1().PodDisruptionBudgets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		co.analyzerListFailed("spot", "poddisruptionbudgets", err)
+		return recommendations
+	}
+
+	for _, deployment := range deployments.Items {
+		if !spotMigrationCandidate(&deployment, pdbs.Items) {
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+		if err != nil {
+			continue
+		}
+
+		var onDemandPods int
+		var savings float64
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if pod.Namespace != deployment.Namespace || pod.Status.Phase != corev1.PodRunning || !selector.Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+			node, ok := nodesByName[pod.Spec.NodeName]
+			if !ok || isSpotNode(node) {
+				continue
+			}
+			onDemandPods++
+			savings += podShareOfNode(pod, node) * co.nodeHourlyCost(node) * 24 * 30 * (1 - co.spotPriceFactor)
+		}
+		if onDemandPods == 0 {
+			continue
+		}
+
+		recommendations = append(recommendations, Recommendation{
+			Type:        "spot_migration",
+			Category:    CategoryConfigure,
+			Resource:    fmt.Sprintf("%s/%s", deployment.Namespace, deployment.Name),
+			Namespace:   deployment.Namespace,
+			Release:     helmRelease(deployment.Labels),
+			labels:      deployment.Labels,
+			Description: fmt.Sprintf("Deployment %s runs %d of %d replicas on on-demand nodes and has no constraints preventing spot scheduling", deployment.Name, onDemandPods, *deployment.Spec.Replicas),
+			Impact:      "Add a nodeSelector or preferred affinity for the spot pool (and tolerations for its taints) to move replicas onto spot capacity",
+			ActionHint:  &ActionHint{Verb: "patch", Target: hintTarget("deployment", deployment.Namespace, deployment.Name), Field: "spec.template.spec.affinity"},
+			Savings:     savings,
+			Priority:    "medium",
+			Timestamp:   time.Now(),
+		})
+	}
+
+	return recommendations
+}
+
+// spotMigrationCandidate reports whether a deployment can tolerate spot
+// interruptions: several replicas, no local state, no explicit capacity-type
+// pinning, and a disruption budget (if any) that allows evictions.
+func spotMigrationCandidate(deployment *appsv1.Deployment, pdbs []policyv1.PodDisruptionBudget) bool {
+	if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas < 2 {
+		return false
+	}
+
+	spec := &deployment.Spec.Template.Spec
+	if hasCapacityTypeConstraint(spec) {
+		return false
+	}
+	for _, volume := range spec.Volumes {
+		if volume.PersistentVolumeClaim != nil {
+			return false
+		}
+	}
+
+	replicas := *deployment.Spec.Replicas
+	for _, pdb := range coveringPDBs(deployment.Namespace, deployment.Spec.Template.Labels, pdbs) {
+		if pdbAllowedDisruptions(pdb, replicas) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pdbAllowedDisruptions computes how many of replicas a PDB lets be evicted at
+// once when all of them are healthy.
+func pdbAllowedDisruptions(pdb *policyv1.PodDisruptionBudget, replicas int32) int32 {
+	allowed := replicas
+	if pdb.Spec.MaxUnavailable != nil {
+		if maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MaxUnavailable, int(replicas), false); err == nil && int32(maxUnavailable) < allowed {
+			allowed = int32(maxUnavailable)
+		}
+	}
+	if pdb.Spec.MinAvailable != nil {
+		if minAvailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MinAvailable, int(replicas), true); err == nil && replicas-int32(minAvailable) < allowed {
+			allowed = replicas - int32(minAvailable)
+		}
+	}
+	if allowed < 0 {
+		allowed = 0
+	}
+	return allowed
+}
+
+// podShareOfNode is the fraction of a node's allocatable capacity reserved by
+// a pod's requests, taking the larger of the CPU and memory shares.
+func podShareOfNode(pod *corev1.Pod, node *corev1.Node) float64 {
+	allocCPU := node.Status.Allocatable[corev1.ResourceCPU]
+	allocMem := node.Status.Allocatable[corev1.ResourceMemory]
+
+	var cpuMilli, memBytes int64
+	for _, container := range pod.Spec.Containers {
+		if request, ok := container.Resources.Requests[corev1.ResourceCPU]; ok {
+			cpuMilli += request.MilliValue()
+		}
+		if request, ok := container.Resources.Requests[corev1.ResourceMemory]; ok {
+			memBytes += request.Value()
+		}
+	}
+
+	var share float64
+	if allocCPU.MilliValue() > 0 {
+		share = float64(cpuMilli) / float64(allocCPU.MilliValue())
+	}
+	if allocMem.Value() > 0 {
+		if memShare := float64(memBytes) / float64(allocMem.Value()); memShare > share {
+			share = memShare
+		}
+	}
+	if share > 1 {
+		share = 1
+	}
+	return share
+}
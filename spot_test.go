@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// testSpotNode is a node labelled as spot capacity
+func testSpotNode(name string) *corev1.Node {
+	node := testNode(name, "4", "16Gi")
+	node.Labels = map[string]string{"karpenter.sh/capacity-type": "spot"}
+	return node
+}
+
+// testPDB covers the pods of deployment with a minAvailable budget
+func testPDB(deployment *appsv1.Deployment, minAvailable int) *policyv1.PodDisruptionBudget {
+	min := intstr.FromInt(minAvailable)
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: deployment.Name, Namespace: deployment.Namespace},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable: &min,
+			Selector:     deployment.Spec.Selector,
+		},
+	}
+}
+
+func TestAnalyzeSpotMigration(t *testing.T) {
+	web := testDeployment("default", "web", 2)
+
+	pinned := testDeployment("default", "web", 2)
+	pinned.Spec.Template.Spec.NodeSelector = map[string]string{"karpenter.sh/capacity-type": "on-demand"}
+
+	stateful := testDeployment("default", "web", 2)
+	stateful.Spec.Template.Spec.Volumes = []corev1.Volume{{
+		Name:         "data",
+		VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "data"}},
+	}}
+
+	tests := []struct {
+		name       string
+		deployment *appsv1.Deployment
+		extra      []runtime.Object
+		noSpotPool bool
+		want       bool
+	}{
+		{name: "tolerant on on-demand", deployment: web, want: true},
+		{name: "PDB allows an eviction", deployment: web, extra: []runtime.Object{testPDB(web, 1)}, want: true},
+		{name: "no spot pool", deployment: web, noSpotPool: true},
+		{name: "pinned to on-demand", deployment: pinned},
+		{name: "persistent volume", deployment: stateful},
+		{name: "PDB allows no eviction", deployment: web, extra: []runtime.Object{testPDB(web, 2)}},
+		{name: "single replica", deployment: testDeployment("default", "web", 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			onDemand := testNode("on-demand-1", "4", "16Gi")
+			objects := append([]runtime.Object{
+				onDemand, tt.deployment,
+				testReplica(tt.deployment, "web-1", "on-demand-1"),
+				testReplica(tt.deployment, "web-2", "on-demand-1"),
+			}, tt.extra...)
+			if !tt.noSpotPool {
+				objects = append(objects, testSpotNode("spot-1"))
+			}
+			co, _ := newTestOptimizer(t, objects...)
+
+			recommendations := co.analyzeSpotMigration(context.Background())
+			if !tt.want {
+				if len(recommendations) != 0 {
+					t.Errorf("got %+v, want no recommendation", recommendations)
+				}
+				return
+			}
+			if len(recommendations) != 1 {
+				t.Fatalf("got %d recommendations, want 1", len(recommendations))
+			}
+			rec := recommendations[0]
+			// Each replica reserves 100m of 4 CPUs, saving the spot discount on that share
+			want := 2 * 0.1 / 4 * co.nodeHourlyCost(onDemand) * 24 * 30 * (1 - defaultSpotPriceFactor)
+			if rec.Type != "spot_migration" || rec.Resource != "default/web" || math.Abs(rec.Savings-want) > 1e-9 {
+				t.Errorf("got %s for %s saving %v, want spot_migration for default/web saving %v", rec.Type, rec.Resource, rec.Savings, want)
+			}
+		})
+	}
+}
+
+func TestPDBAllowedDisruptions(t *testing.T) {
+	percent := func(s string) *intstr.IntOrString { v := intstr.FromString(s); return &v }
+	count := func(n int) *intstr.IntOrString { v := intstr.FromInt(n); return &v }
+	tests := []struct {
+		name string
+		spec policyv1.PodDisruptionBudgetSpec
+		want int32
+	}{
+		{name: "no limits", want: 4},
+		{name: "minAvailable count", spec: policyv1.PodDisruptionBudgetSpec{MinAvailable: count(3)}, want: 1},
+		{name: "minAvailable percent rounds up", spec: policyv1.PodDisruptionBudgetSpec{MinAvailable: percent("60%")}, want: 1},
+		{name: "maxUnavailable", spec: policyv1.PodDisruptionBudgetSpec{MaxUnavailable: count(2)}, want: 2},
+		{name: "minAvailable above replicas", spec: policyv1.PodDisruptionBudgetSpec{MinAvailable: count(6)}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pdbAllowedDisruptions(&policyv1.PodDisruptionBudget{Spec: tt.spec}, 4); got != tt.want {
+				t.Errorf("pdbAllowedDisruptions = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSpotNodeMetrics checks that a spot node is priced at the spot factor of
+// an on-demand node of the same type, for each cloud's spot label
+func TestSpotNodeMetrics(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		factor string
+		want   float64 // fraction of the on-demand cost
+	}{
+		{name: "eks spot", labels: map[string]string{"node.kubernetes.io/capacity-type": "spot"}, want: defaultSpotPriceFactor},
+		{name: "gke preemptible", labels: map[string]string{"cloud.google.com/gke-preemptible": "true"}, want: defaultSpotPriceFactor},
+		{name: "aks spot", labels: map[string]string{"kubernetes.azure.com/scalesetpriority": "spot"}, want: defaultSpotPriceFactor},
+		{name: "configured factor", labels: map[string]string{"node.kubernetes.io/capacity-type": "spot"}, factor: "0.5", want: 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OPTIMKUBE_SPOT_PRICE_FACTOR", tt.factor)
+			onDemand := testNode("on-demand-m5.xlarge", "4", "16Gi")
+			spot := testNode("spot-m5.xlarge", "4", "16Gi")
+			spot.Labels = tt.labels
+			co, _ := newTestOptimizer(t,
+				onDemand, testNodeMetrics(onDemand.Name, "1", "4Gi"),
+				spot, testNodeMetrics(spot.Name, "1", "4Gi"))
+
+			byName := make(map[string]NodeMetrics)
+			for _, m := range co.getNodeMetrics(context.Background()) {
+				byName[m.Name] = m
+			}
+			full, discounted := byName[onDemand.Name], byName[spot.Name]
+			if full.CapacityType != capacityOnDemand || discounted.CapacityType != capacitySpot {
+				t.Errorf("capacity types %q and %q, want on-demand and spot", full.CapacityType, discounted.CapacityType)
+			}
+			if want := 0.192 * 24 * 30; math.Abs(full.EstimatedCost-want) > 1e-9 {
+				t.Errorf("on-demand cost %v, want %v", full.EstimatedCost, want)
+			}
+			if want := full.EstimatedCost * tt.want; math.Abs(discounted.EstimatedCost-want) > 1e-9 {
+				t.Errorf("spot cost %v, want %v", discounted.EstimatedCost, want)
+			}
+		})
+	}
+}
+
+func TestSpotPriceFactorSetting(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{value: "", want: defaultSpotPriceFactor},
+		{value: "0.4", want: 0.4},
+		{value: "1", want: 1},
+		{value: "0", wantErr: true},
+		{value: "1.5", wantErr: true},
+		{value: "cheap", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("DEMO_MODE", "true")
+			t.Setenv("OPTIMKUBE_SPOT_PRICE_FACTOR", tt.value)
+			co, err := NewCostOptimizer()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCostOptimizer error %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && co.spotPriceFactor != tt.want {
+				t.Errorf("factor %v, want %v", co.spotPriceFactor, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+)
+
+// analyzeStatefulSets flags StatefulSets whose pods run without requests or
+// limits. Their pods are rightsized one by one by the pod analyzer, since
+// replicas of a StatefulSet often carry different load, such as a primary and
+// its followers, and the remediation of those findings patches the
+// StatefulSet's template. Replica counts are never suggested: a StatefulSet's
+// replicas hold state, and none is ever recommended below one.
+func (co *CostOptimizer) analyzeStatefulSets(ctx context.Context) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	if co.demoMode || co.clientset == nil || co.analyzerDisabled("statefulsets") {
+		return recommendations
+	}
+
+	statefulSets, err := co.clientset.AppsV1().StatefulSets("").List(ctx, co.workloadListOptions())
+	if err != nil {
+		co.analyzerListFailed("statefulsets", "statefulsets", err)
+		return recommendations
+	}
+	statefulSets.Items = inScope(co, statefulSets.Items)
+
+	for i := range statefulSets.Items {
+		statefulSet := &statefulSets.Items[i]
+		if rec := missingResourcesRecommendation("StatefulSet", statefulSet.Namespace, statefulSet.Name, statefulSet.Labels, &statefulSet.Spec.Template.Spec); rec != nil {
+			recommendations = append(recommendations, *rec)
+		}
+	}
+
+	return recommendations
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestAnalyzeStatefulSets(t *testing.T) {
+	bare := testStatefulSet("data", "postgres", 3)
+	bare.Spec.Template.Spec.Containers[0].Resources = corev1.ResourceRequirements{}
+	limitsOnly := testStatefulSet("data", "postgres", 3)
+	limitsOnly.Spec.Template.Spec.Containers[0].Resources = corev1.ResourceRequirements{Limits: limitsOnly.Spec.Template.Spec.Containers[0].Resources.Requests}
+	tests := []struct {
+		name        string
+		statefulSet *appsv1.StatefulSet
+		want        bool
+	}{
+		{name: "missing requests", statefulSet: bare, want: true},
+		{name: "limits only", statefulSet: limitsOnly},
+		{name: "with requests", statefulSet: testStatefulSet("data", "postgres", 3)},
+		{name: "single replica without requests", statefulSet: func() *appsv1.StatefulSet {
+			s := bare.DeepCopy()
+			*s.Spec.Replicas = 1
+			return s
+		}(), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t, tt.statefulSet)
+
+			recommendations := co.analyzeStatefulSets(context.Background())
+			if !tt.want {
+				if len(recommendations) != 0 {
+					t.Errorf("got %+v, want no recommendation", recommendations)
+				}
+				return
+			}
+			if len(recommendations) != 1 {
+				t.Fatalf("got %d recommendations, want 1", len(recommendations))
+			}
+			rec := recommendations[0]
+			if rec.Type != "resource_governance" || rec.Resource != "data/postgres" || rec.Description != "StatefulSet postgres lacks resource requests/limits" {
+				t.Errorf("recommendation %+v, want resource_governance for data/postgres", rec)
+			}
+			if hint := rec.ActionHint; hint == nil || hint.Target != "statefulset/data/postgres" || hint.Field != "spec.template.spec.containers[*].resources" {
+				t.Errorf("hint %+v, want the StatefulSet's container resources", hint)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Defaults for replicated storage and snapshot pricing
+const (
+	defaultReplicationCostFactor      = 1.0 // each extra copy costs a full volume
+	defaultSnapshotCostFactor         = 0.1 // incremental snapshots cost a fraction of the volume
+	defaultSnapshotRetentionThreshold = 30
+)
+
+// Annotations on a PersistentVolume or its StorageClass that describe copies
+// the provisioner's parameters don't reveal
+const (
+	annotationReplicas          = "optimkube.io/replicas"
+	annotationSnapshotRetention = "optimkube.io/snapshot-retention"
+)
+
+// volumeStorage is the billable footprint of one persistent volume
+type volumeStorage struct {
+	Name      string
+	Claim     string // namespace/name of the bound claim, if any
+	Namespace string
+	Class     string // StorageClass name, if any
+	SizeGB    float64
+	Replicas  int // billed copies, including the primary
+	Snapshots int // retained snapshots
+}
+
+// listVolumeStorage reads every PersistentVolume with the replication and
+// snapshot settings from its annotations or StorageClass
+func (co *CostOptimizer) listVolumeStorage(ctx context.Context) ([]volumeStorage, error) {
+	pvs, err := co.clientset.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	classes := make(map[string]*storagev1.StorageClass)
+	if storageClasses, err := co.clientset.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{}); err != nil {
+		slog.Warn("Failed to list storage classes, pricing volumes from annotations only", "error", err)
+	} else {
+		for i := range storageClasses.Items {
+			classes[storageClasses.Items[i].Name] = &storageClasses.Items[i]
+		}
+	}
+
+	volumes := make([]volumeStorage, 0, len(pvs.Items))
+	for i := range pvs.Items {
+		pv := &pvs.Items[i]
+		storage := pv.Spec.Capacity[corev1.ResourceStorage]
+		class := classes[pv.Spec.StorageClassName]
+
+		volume := volumeStorage{
+			Name:      pv.Name,
+			Class:     pv.Spec.StorageClassName,
+			SizeGB:    float64(storage.Value()) / (1024 * 1024 * 1024),
+			Replicas:  volumeReplicas(pv, class),
+			Snapshots: annotatedInt(pv, class, annotationSnapshotRetention, 0),
+		}
+		if ref := pv.Spec.ClaimRef; ref != nil {
+			volume.Claim = ref.Namespace + "/" + ref.Name
+			volume.Namespace = ref.Namespace
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes, nil
+}
+
+// volumeReplicas returns how many copies of a volume are billed. An explicit
+// annotation wins; otherwise known cross-zone/region provisioner settings are
+// recognized (GCE regional PDs, Azure geo-redundant disks and files).
+func volumeReplicas(pv *corev1.PersistentVolume, class *storagev1.StorageClass) int {
+	if replicas := annotatedInt(pv, class, annotationReplicas, 0); replicas > 0 {
+		return replicas
+	}
+	if class != nil {
+		if strings.EqualFold(class.Parameters["replication-type"], "regional-pd") {
+			return 2
+		}
+		sku := strings.ToUpper(class.Parameters["skuName"] + class.Parameters["skuname"])
+		if strings.Contains(sku, "GRS") {
+			return 2
+		}
+	}
+	return 1
+}
+
+// annotatedInt reads an integer annotation from the volume, falling back to
+// its StorageClass and then def
+func annotatedInt(pv *corev1.PersistentVolume, class *storagev1.StorageClass, key string, def int) int {
+	sources := []map[string]string{pv.Annotations}
+	if class != nil {
+		sources = append(sources, class.Annotations)
+	}
+	for _, annotations := range sources {
+		if raw, ok := annotations[key]; ok {
+			if value, err := strconv.Atoi(raw); err == nil && value >= 0 {
+				return value
+			}
+			slog.Warn("Ignoring invalid volume annotation", "annotation", key, "value", raw, "volume", pv.Name)
+		}
+	}
+	return def
+}
+
+// replicationMonthlyCost is the cost of a volume's copies beyond the primary
+func (cc *CostCalculator) replicationMonthlyCost(volume volumeStorage) float64 {
+	if volume.Replicas <= 1 {
+		return 0
+	}
+	return volume.SizeGB * cc.StorageCostPerGB * float64(volume.Replicas-1) * cc.ReplicationCostFactor
+}
+
+// snapshotMonthlyCost estimates the cost of retaining n snapshots of a volume
+func (cc *CostCalculator) snapshotMonthlyCost(volume volumeStorage, n int) float64 {
+	return volume.SizeGB * cc.StorageCostPerGB * float64(n) * cc.SnapshotCostFactor
+}
+
+// volumeMonthlyCost prices a volume's capacity, with its extra copies and
+// retained snapshots on top
+func (co *CostOptimizer) volumeMonthlyCost(volume volumeStorage) float64 {
+	return volume.SizeGB*co.pricing.StorageGBMonthlyCost() +
+		co.costCalculator.replicationMonthlyCost(volume) +
+		co.costCalculator.snapshotMonthlyCost(volume, volume.Snapshots)
+}
+
+// demoStorageMonthlyCost stands in for volume pricing in demo mode
+const demoStorageMonthlyCost = 100.0
+
+// storageCosts prices every persistent volume, totalled and by StorageClass.
+// A bound volume's cost is also attributed to its claim's namespace; volumes
+// without capacity are skipped.
+func (co *CostOptimizer) storageCosts(volumes []volumeStorage) (total float64, byClass, byNamespace map[string]float64) {
+	byClass = make(map[string]float64)
+	byNamespace = make(map[string]float64)
+	for _, volume := range volumes {
+		if volume.SizeGB <= 0 {
+			continue
+		}
+		cost := co.volumeMonthlyCost(volume)
+		total += cost
+		if volume.Class != "" {
+			byClass[volume.Class] += cost
+		}
+		if volume.Namespace != "" {
+			byNamespace[volume.Namespace] += cost
+		}
+	}
+	return total, byClass, byNamespace
+}
+
+// analyzeSnapshotRetention flags volumes that keep more snapshots than the
+// configured threshold, with the cost of the excess as potential savings
+func (co *CostOptimizer) analyzeSnapshotRetention(ctx context.Context) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	if co.demoMode || co.clientset == nil || co.analyzerDisabled("storage") {
+		return recommendations
+	}
+
+	volumes, err := co.listVolumeStorage(ctx)
+	if err != nil {
+		co.analyzerListFailed("storage", "persistentvolumes", err)
+		return recommendations
+	}
+
+	for _, volume := range volumes {
+		excess := volume.Snapshots - co.snapshotRetentionThreshold
+		if excess <= 0 {
+			continue
+		}
+
+		resource := volume.Name
+		if volume.Claim != "" {
+			resource = volume.Claim
+		}
+		recommendations = append(recommendations, Recommendation{
+			Type:        "snapshot_retention",
+			Category:    CategoryConfigure,
+			Resource:    resource,
+			Namespace:   volume.Namespace,
+			Description: fmt.Sprintf("Volume %s (%.0f GB, %d copies) retains %d snapshots, above the %d threshold", volume.Name, volume.SizeGB, volume.Replicas, volume.Snapshots, co.snapshotRetentionThreshold),
+			Impact:      fmt.Sprintf("Reduce snapshot retention to %d or fewer", co.snapshotRetentionThreshold),
+			ActionHint: &ActionHint{
+				Verb:         "patch",
+				Target:       hintTarget("persistentvolume", "", volume.Name),
+				Field:        "metadata.annotations[" + annotationSnapshotRetention + "]",
+				CurrentValue: strconv.Itoa(volume.Snapshots),
+				NewValue:     strconv.Itoa(co.snapshotRetentionThreshold),
+			},
+			Savings:   co.costCalculator.snapshotMonthlyCost(volume, excess),
+			Priority:  "low",
+			Timestamp: time.Now(),
+		})
+	}
+
+	return recommendations
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// testVolume is a PersistentVolume of class bound to namespace/claim
+func testVolume(name, size, class, namespace, claim string, annotations map[string]string) *corev1.PersistentVolume {
+	return &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+		Spec: corev1.PersistentVolumeSpec{
+			Capacity:         corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)},
+			StorageClassName: class,
+			ClaimRef:         &corev1.ObjectReference{Namespace: namespace, Name: claim},
+		},
+	}
+}
+
+func TestVolumeReplicas(t *testing.T) {
+	tests := []struct {
+		name  string
+		pv    *corev1.PersistentVolume
+		class *storagev1.StorageClass
+		want  int
+	}{
+		{name: "plain", pv: testVolume("pv", "10Gi", "", "", "", nil), want: 1},
+		{name: "annotated volume", pv: testVolume("pv", "10Gi", "", "", "", map[string]string{annotationReplicas: "3"}), want: 3},
+		{name: "annotated class", pv: testVolume("pv", "10Gi", "replicated", "", "", nil),
+			class: &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "replicated", Annotations: map[string]string{annotationReplicas: "2"}}}, want: 2},
+		{name: "GCE regional PD", pv: testVolume("pv", "10Gi", "regional", "", "", nil),
+			class: &storagev1.StorageClass{Parameters: map[string]string{"replication-type": "regional-pd"}}, want: 2},
+		{name: "Azure GRS", pv: testVolume("pv", "10Gi", "geo", "", "", nil),
+			class: &storagev1.StorageClass{Parameters: map[string]string{"skuName": "Standard_GRS"}}, want: 2},
+		{name: "invalid annotation", pv: testVolume("pv", "10Gi", "", "", "", map[string]string{annotationReplicas: "two"}), want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := volumeReplicas(tt.pv, tt.class); got != tt.want {
+				t.Errorf("volumeReplicas = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplicatedVolumeStorageCost(t *testing.T) {
+	plain, _ := newTestOptimizer(t)
+	base := plain.generateCostSummary(context.Background()).StorageCost
+
+	// 100 GB replicated to two extra copies with 20 snapshots retained
+	co, _ := newTestOptimizer(t, testVolume("pv-db", "100Gi", "standard", "shop", "data-db-0", map[string]string{
+		annotationReplicas:          "3",
+		annotationSnapshotRetention: "20",
+	}))
+	co.costCalculator.ReplicationCostFactor = 0.5
+
+	got := co.generateCostSummary(context.Background()).StorageCost - base
+	want := 100*0.10 + 100*0.10*2*0.5 + 100*0.10*20*defaultSnapshotCostFactor
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("storage cost = %v, want %v", got, want)
+	}
+}
+
+func TestCostSummaryStorageCost(t *testing.T) {
+	unbound := testVolume("pv-spare", "20Gi", "standard", "", "", nil)
+	unbound.Spec.ClaimRef = nil
+	pending := testVolume("pv-pending", "0", "standard", "shop", "pending", nil)
+	tests := []struct {
+		name          string
+		volumes       []runtime.Object
+		want          float64
+		wantClass     map[string]float64
+		wantNamespace map[string]float64
+	}{
+		{
+			name: "10Gi and 50Gi claims",
+			volumes: []runtime.Object{
+				testVolume("pv-db", "50Gi", "gp3", "shop", "data-db-0", nil),
+				testVolume("pv-cache", "10Gi", "", "cache", "data", nil),
+			},
+			want:          6,
+			wantClass:     map[string]float64{"gp3": 5},
+			wantNamespace: map[string]float64{"shop": 5, "cache": 1},
+		},
+		{
+			name:          "unbound volume has no namespace",
+			volumes:       []runtime.Object{unbound},
+			want:          2,
+			wantClass:     map[string]float64{"standard": 2},
+			wantNamespace: map[string]float64{},
+		},
+		{
+			name:          "volume without capacity is skipped",
+			volumes:       []runtime.Object{pending},
+			wantClass:     map[string]float64{},
+			wantNamespace: map[string]float64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t, tt.volumes...)
+			co.costCalculator.StorageCostPerGB = 0.10
+
+			summary := co.generateCostSummary(context.Background())
+			if math.Abs(summary.StorageCost-tt.want) > 1e-9 {
+				t.Errorf("storage cost %v, want %v", summary.StorageCost, tt.want)
+			}
+			if !reflect.DeepEqual(summary.StorageCostByClass, tt.wantClass) {
+				t.Errorf("storage cost by class %v, want %v", summary.StorageCostByClass, tt.wantClass)
+			}
+			for namespace, want := range tt.wantNamespace {
+				if got := summary.NamespaceCosts[namespace]; math.Abs(got-want) > 1e-9 {
+					t.Errorf("namespace %s costs %v, want %v", namespace, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzeSnapshotRetention(t *testing.T) {
+	co, _ := newTestOptimizer(t,
+		testVolume("pv-db", "100Gi", "", "shop", "data-db-0", map[string]string{annotationSnapshotRetention: "50"}),
+		testVolume("pv-cache", "100Gi", "", "shop", "data-cache-0", map[string]string{annotationSnapshotRetention: "30"}),
+		testVolume("pv-logs", "100Gi", "", "shop", "logs", nil),
+	)
+
+	recommendations := co.analyzeSnapshotRetention(context.Background())
+	if len(recommendations) != 1 {
+		t.Fatalf("got %d recommendations, want only the volume above the threshold: %+v", len(recommendations), recommendations)
+	}
+	rec := recommendations[0]
+	if rec.Type != "snapshot_retention" || rec.Resource != "shop/data-db-0" || rec.Namespace != "shop" {
+		t.Errorf("recommendation = %s %s in %s, want snapshot_retention shop/data-db-0", rec.Type, rec.Resource, rec.Namespace)
+	}
+	if want := 100 * 0.10 * 20 * defaultSnapshotCostFactor; math.Abs(rec.Savings-want) > 1e-9 {
+		t.Errorf("Savings = %v, want the cost of 20 excess snapshots, %v", rec.Savings, want)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// stateStore persists optimizer state so it survives restarts
+type stateStore interface {
+	SaveRecommendations(recommendations []Recommendation) error
+	LoadRecommendations() ([]Recommendation, error)
+	SaveActions(actions []OptimizationAction) error
+	LoadActions() ([]OptimizationAction, error)
+	SaveHistory(snapshots []storedSummary) error
+	LoadHistory() ([]storedSummary, error)
+}
+
+// fileStore keeps state as JSON documents in a directory
+type fileStore struct {
+	dir string
+}
+
+func newFileStore(dir string) (*fileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create state dir %s: %w", dir, err)
+	}
+	return &fileStore{dir: dir}, nil
+}
+
+func (s *fileStore) SaveRecommendations(recommendations []Recommendation) error {
+	stored := make([]storedRecommendation, len(recommendations))
+	for i := range recommendations {
+		stored[i] = newStoredRecommendation(recommendations[i])
+	}
+	return s.writeJSON("recommendations.json", stored)
+}
+
+func (s *fileStore) LoadRecommendations() ([]Recommendation, error) {
+	var stored []storedRecommendation
+	if err := s.readJSON("recommendations.json", &stored); err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, nil
+	}
+	recommendations := make([]Recommendation, len(stored))
+	for i := range stored {
+		recommendations[i] = stored[i].recommendation()
+	}
+	return recommendations, nil
+}
+
+func (s *fileStore) SaveActions(actions []OptimizationAction) error {
+	return s.writeJSON("actions.json", actions)
+}
+
+func (s *fileStore) LoadActions() ([]OptimizationAction, error) {
+	var actions []OptimizationAction
+	if err := s.readJSON("actions.json", &actions); err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
+
+func (s *fileStore) SaveHistory(snapshots []storedSummary) error {
+	return s.writeJSON("history.json", snapshots)
+}
+
+func (s *fileStore) LoadHistory() ([]storedSummary, error) {
+	var snapshots []storedSummary
+	if err := s.readJSON("history.json", &snapshots); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// The stored types below have the JSON fields of the types they store,
+// without the MarshalJSON methods that round money for the API, so persisted
+// values keep full precision across restarts.
+
+type (
+	plainRecommendation Recommendation
+	plainCostSummary    ClusterCostSummary
+	plainNodeGroupCost  NodeGroupCost
+	plainPackingReport  PackingReport
+)
+
+// storedRecommendation is a Recommendation with the analyzer and labels the
+// API leaves out, which incremental analysis and protected selectors match on
+type storedRecommendation struct {
+	plainRecommendation
+	Analyzer string            `json:"analyzer,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+func newStoredRecommendation(rec Recommendation) storedRecommendation {
+	return storedRecommendation{plainRecommendation: plainRecommendation(rec), Analyzer: rec.analyzer, Labels: rec.labels}
+}
+
+func (s storedRecommendation) recommendation() Recommendation {
+	rec := Recommendation(s.plainRecommendation)
+	rec.analyzer = s.Analyzer
+	rec.labels = s.Labels
+	return rec
+}
+
+// storedCostSummary is a ClusterCostSummary down to its nested node group
+// costs and packing report, whose fields shadow the rounding ones embedded
+type storedCostSummary struct {
+	plainCostSummary
+	NodeGroupCosts map[string]plainNodeGroupCost `json:"node_group_costs,omitempty"`
+	Packing        *plainPackingReport           `json:"packing,omitempty"`
+}
+
+func newStoredCostSummary(summary ClusterCostSummary) storedCostSummary {
+	stored := storedCostSummary{plainCostSummary: plainCostSummary(summary)}
+	if summary.NodeGroupCosts != nil {
+		stored.NodeGroupCosts = make(map[string]plainNodeGroupCost, len(summary.NodeGroupCosts))
+		for group, cost := range summary.NodeGroupCosts {
+			stored.NodeGroupCosts[group] = plainNodeGroupCost(cost)
+		}
+	}
+	if summary.Packing != nil {
+		packing := plainPackingReport(*summary.Packing)
+		stored.Packing = &packing
+	}
+	return stored
+}
+
+func (s storedCostSummary) summary() ClusterCostSummary {
+	summary := ClusterCostSummary(s.plainCostSummary)
+	summary.NodeGroupCosts = nil
+	if s.NodeGroupCosts != nil {
+		summary.NodeGroupCosts = make(map[string]NodeGroupCost, len(s.NodeGroupCosts))
+		for group, cost := range s.NodeGroupCosts {
+			summary.NodeGroupCosts[group] = NodeGroupCost(cost)
+		}
+	}
+	summary.Packing = nil
+	if s.Packing != nil {
+		packing := PackingReport(*s.Packing)
+		summary.Packing = &packing
+	}
+	return summary
+}
+
+// writeJSON replaces the named document atomically so a crash mid-write
+// never leaves a truncated file behind.
+func (s *fileStore) writeJSON(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", name, err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, name)); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+// readJSON decodes the named document into v, leaving v untouched when the
+// document has not been written yet.
+func (s *fileStore) readJSON(name string, v interface{}) error {
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decode %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestStateSurvivesRestart checks that a second optimizer on the same state
+// dir reloads the recommendations and actions the first one saved
+func TestStateSurvivesRestart(t *testing.T) {
+	t.Setenv("OPTIMKUBE_STATE_DIR", t.TempDir())
+	co, _ := newTestOptimizer(t,
+		testNode("node-1", "4", "16Gi"), testNodeMetrics("node-1", "1", "4Gi"),
+		testPod("shop", "web", "node-1", "2", "1Gi"), testPodMetrics("shop", "web", "100m", "1Gi"),
+	)
+	co.analyzeAndGenerateRecommendations(context.Background())
+	if len(co.recommendations) == 0 {
+		t.Fatal("scan made no recommendations")
+	}
+	co.actionsMu.Lock()
+	co.actions = []OptimizationAction{
+		{ID: "scale-web", Type: "scale_down", Resource: "shop/web", Namespace: "shop", Parameters: map[string]interface{}{"replicas": 1}, Status: actionStatusQueued},
+		{ID: "resize-db", Type: "rightsize", Resource: "shop/db", Namespace: "shop", Status: actionStatusPending},
+	}
+	co.saveActionsLocked()
+	co.actionsMu.Unlock()
+
+	restarted, err := NewCostOptimizer()
+	if err != nil {
+		t.Fatalf("NewCostOptimizer: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{name: "recommendations", got: restarted.recommendations, want: co.recommendations},
+		{name: "actions", got: restarted.actions, want: co.actions},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := json.Marshal(tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("reloaded %s\n%s\nwant\n%s", tt.name, got, want)
+			}
+		})
+	}
+}
+
+func TestFileStoreBeforeFirstSave(t *testing.T) {
+	store, err := newFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	recommendations, err := store.LoadRecommendations()
+	if err != nil || len(recommendations) != 0 {
+		t.Errorf("LoadRecommendations = %v, %v; want nothing", recommendations, err)
+	}
+	actions, err := store.LoadActions()
+	if err != nil || len(actions) != 0 {
+		t.Errorf("LoadActions = %v, %v; want nothing", actions, err)
+	}
+}
+
+func TestFileStoreKeepsFullPrecision(t *testing.T) {
+	store, err := newFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("newFileStore: %v", err)
+	}
+	at := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	recommendations := []Recommendation{{ID: "rec-1", Savings: 12.345678, SavingsLow: 1.005, SavingsHigh: 20.0049, Timestamp: at}}
+	if err := store.SaveRecommendations(recommendations); err != nil {
+		t.Fatalf("SaveRecommendations: %v", err)
+	}
+	loaded, err := store.LoadRecommendations()
+	if err != nil {
+		t.Fatalf("LoadRecommendations: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, recommendations) {
+		t.Errorf("recommendations = %+v, want %+v", loaded, recommendations)
+	}
+
+	summary := ClusterCostSummary{
+		TotalMonthlyCost: 1234.56789,
+		NamespaceCosts:   map[string]float64{"default": 0.001234},
+		NodeGroupCosts:   map[string]NodeGroupCost{"pool-a": {Nodes: 2, MonthlyCost: 99.999}},
+		Packing:          &PackingReport{CurrentCost: 10.0051, IdealCost: 5.0049},
+		LastUpdated:      at,
+	}
+	if err := store.SaveHistory([]storedSummary{{Summary: newStoredCostSummary(summary)}}); err != nil {
+		t.Fatalf("SaveHistory: %v", err)
+	}
+	history, err := store.LoadHistory()
+	if err != nil {
+		t.Fatalf("LoadHistory: %v", err)
+	}
+	if len(history) != 1 {
+		t.Fatalf("got %d history entries, want 1", len(history))
+	}
+	if got := history[0].Summary.summary(); !reflect.DeepEqual(got, summary) {
+		t.Errorf("summary = %+v, want %+v", got, summary)
+	}
+}
+
+func TestRecommendationsAPIRoundsMoney(t *testing.T) {
+	co, _ := newTestOptimizer(t)
+	co.recommendations = []Recommendation{{ID: "rec-1", Type: "rightsizing", Savings: 12.345678, Timestamp: co.now()}}
+
+	rec := serve(co.newRouter(), http.MethodGet, "/api/recommendations", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var response struct {
+		Items []struct {
+			Savings json.Number `json:"potential_savings"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(response.Items) != 1 || response.Items[0].Savings != "12.35" {
+		t.Errorf("items = %+v, want potential_savings 12.35", response.Items)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// streamKeepAlive is how often an idle stream gets a comment line, so proxies
+// and load balancers don't close it between scans
+const streamKeepAlive = 30 * time.Second
+
+// ScanEvent is pushed to stream subscribers after every completed scan
+type ScanEvent struct {
+	Summary             ClusterCostSummary `json:"summary"`
+	RecommendationCount int                `json:"recommendation_count"`
+}
+
+// streamBroker fans scan events out to every /api/stream subscriber. Each
+// subscriber holds only the newest event: a slow client skips stale ones
+// instead of holding up the scan.
+type streamBroker struct {
+	mu          sync.Mutex
+	subscribers map[chan ScanEvent]struct{}
+	closed      bool
+}
+
+func newStreamBroker() *streamBroker {
+	return &streamBroker{subscribers: make(map[chan ScanEvent]struct{})}
+}
+
+// subscribe registers a subscriber. Its channel is closed when the broker
+// shuts down; unsubscribe must be called once the subscriber is done.
+func (b *streamBroker) subscribe() (events <-chan ScanEvent, unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan ScanEvent, 1)
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	b.subscribers[ch] = struct{}{}
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// publish hands an event to every subscriber without blocking, replacing any
+// event a subscriber hasn't read yet
+func (b *streamBroker) publish(event ScanEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- event
+	}
+}
+
+// close ends every stream, so server shutdown isn't held up by open ones
+func (b *streamBroker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// handleStream serves scan events as Server-Sent Events. A subscriber first
+// gets the latest recorded summary, if any, then one event per scan.
+func (co *CostOptimizer) handleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported by this connection", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := co.stream.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	if summary, ok := co.history.latest(); ok {
+		if writeScanEvent(w, ScanEvent{Summary: summary, RecommendationCount: summary.RecommendationCount}) != nil {
+			return
+		}
+		flusher.Flush()
+	}
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if writeScanEvent(w, event) != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeScanEvent(w http.ResponseWriter, event ScanEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: scan\ndata: %s\n\n", data)
+	return err
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readScanEvent reads lines from an event stream until a scan event's data
+func readScanEvent(t *testing.T, lines *bufio.Scanner) ScanEvent {
+	t.Helper()
+	for lines.Scan() {
+		data, ok := strings.CutPrefix(lines.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event ScanEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("decode event %q: %v", data, err)
+		}
+		return event
+	}
+	t.Fatalf("stream ended without an event: %v", lines.Err())
+	return ScanEvent{}
+}
+
+func TestStreamScanEvents(t *testing.T) {
+	co, _ := newTestOptimizer(t,
+		testNode("node-1", "4", "16Gi"), testNodeMetrics("node-1", "1", "4Gi"),
+		testPod("shop", "web", "node-1", "2", "1Gi"), testPodMetrics("shop", "web", "100m", "1Gi"),
+	)
+	server := httptest.NewServer(http.HandlerFunc(co.handleStream))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var streams []*bufio.Scanner
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("content type %q, want text/event-stream", ct)
+		}
+		streams = append(streams, bufio.NewScanner(resp.Body))
+	}
+	waitFor(t, "both subscribers", func() bool {
+		co.stream.mu.Lock()
+		defer co.stream.mu.Unlock()
+		return len(co.stream.subscribers) == 2
+	})
+
+	co.analyzeAndGenerateRecommendations(context.Background())
+	want := len(co.activeRecommendations())
+	for i, stream := range streams {
+		event := readScanEvent(t, stream)
+		if event.RecommendationCount != want || event.Summary.NodeCount != 1 || event.Summary.PodCount != 1 {
+			t.Errorf("subscriber %d got %d recommendations over %d nodes and %d pods, want %d over 1 and 1",
+				i, event.RecommendationCount, event.Summary.NodeCount, event.Summary.PodCount, want)
+		}
+	}
+
+	// A disconnected subscriber is dropped
+	cancel()
+	waitFor(t, "the subscribers to leave", func() bool {
+		co.stream.mu.Lock()
+		defer co.stream.mu.Unlock()
+		return len(co.stream.subscribers) == 0
+	})
+}
+
+func TestStreamStartsWithLatestSummary(t *testing.T) {
+	co, _ := newTestOptimizer(t)
+	co.history.add(ClusterCostSummary{TotalMonthlyCost: 420, RecommendationCount: 3, LastUpdated: time.Now()})
+	server := httptest.NewServer(http.HandlerFunc(co.handleStream))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if event := readScanEvent(t, bufio.NewScanner(resp.Body)); event.Summary.TotalMonthlyCost != 420 || event.RecommendationCount != 3 {
+		t.Errorf("first event %+v, want the latest summary", event)
+	}
+}
+
+func TestStreamBroker(t *testing.T) {
+	tests := []struct {
+		name      string
+		publish   []float64 // total cost of each event
+		close     bool
+		wantEvent float64 // total cost of the event read; none when 0
+	}{
+		{name: "one event", publish: []float64{100}, wantEvent: 100},
+		{name: "a slow subscriber gets the newest", publish: []float64{100, 200, 300}, wantEvent: 300},
+		{name: "nothing published"},
+		{name: "closed", close: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := newStreamBroker()
+			events, unsubscribe := broker.subscribe()
+			defer unsubscribe()
+			for _, cost := range tt.publish {
+				broker.publish(ScanEvent{Summary: ClusterCostSummary{TotalMonthlyCost: cost}})
+			}
+			if tt.close {
+				broker.close()
+			}
+
+			select {
+			case event, ok := <-events:
+				if tt.close {
+					if ok {
+						t.Errorf("got %+v from a closed broker", event)
+					}
+					return
+				}
+				if !ok || event.Summary.TotalMonthlyCost != tt.wantEvent {
+					t.Errorf("got %+v (open %v), want an event costing %v", event, ok, tt.wantEvent)
+				}
+			default:
+				if tt.wantEvent != 0 || tt.close {
+					t.Error("no event waiting")
+				}
+			}
+		})
+	}
+
+	broker := newStreamBroker()
+	broker.close()
+	if _, ok := <-mustSubscribe(broker); ok {
+		t.Error("subscribing to a closed broker got an open stream")
+	}
+}
+
+func mustSubscribe(b *streamBroker) <-chan ScanEvent {
+	events, _ := b.subscribe()
+	return events
+}
+
+// TestStreamAuth subscribes through the authenticated router the way a
+// browser's EventSource does, with the token in the query string
+func TestStreamAuth(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		authorization string
+		want          int
+	}{
+		{name: "query token", query: "?access_token=secret", want: http.StatusOK},
+		{name: "header token", authorization: "Bearer secret", want: http.StatusOK},
+		{name: "wrong query token", query: "?access_token=wrong", want: http.StatusUnauthorized},
+		{name: "empty query token", query: "?access_token=", want: http.StatusUnauthorized},
+		{name: "no token", want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OPTIMKUBE_API_TOKEN", "secret")
+			co, _ := newTestOptimizer(t)
+			server := httptest.NewServer(co.newRouter())
+			defer server.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/stream"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status %d, want %d", resp.StatusCode, tt.want)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+
+			waitFor(t, "the subscriber", func() bool {
+				co.stream.mu.Lock()
+				defer co.stream.mu.Unlock()
+				return len(co.stream.subscribers) == 1
+			})
+			co.stream.publish(ScanEvent{RecommendationCount: 3})
+			if event := readScanEvent(t, bufio.NewScanner(resp.Body)); event.RecommendationCount != 3 {
+				t.Errorf("got %d recommendations, want the published 3", event.RecommendationCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestPodUsedFraction(t *testing.T) {
+	tests := []struct {
+		name string
+		pod  PodMetrics
+		want float64
+	}{
+		{name: "half used", pod: PodMetrics{CPUUsage: 0.5, CPURequest: 1, MemoryUsage: 1, MemoryRequest: 2}, want: 0.5},
+		{name: "over its requests", pod: PodMetrics{CPUUsage: 2, CPURequest: 1, MemoryUsage: 3, MemoryRequest: 2}, want: 1},
+		{name: "CPU request only", pod: PodMetrics{CPUUsage: 0.25, CPURequest: 1, MemoryUsage: 1}, want: 0.25},
+		{name: "no requests", pod: PodMetrics{CPUUsage: 0.5, MemoryUsage: 1}, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := podUsedFraction(tt.pod); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("podUsedFraction = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNamespaceIdleCost splits a namespace whose pods use half their requests
+// into equal used and idle costs
+func TestNamespaceIdleCost(t *testing.T) {
+	co, _ := newTestOptimizer(t,
+		testNode("node-1", "4", "16Gi"), testNodeMetrics("node-1", "1", "4Gi"),
+		testPod("team", "api", "node-1", "1", "2Gi"), testPodMetrics("team", "api", "500m", "1Gi"),
+		testPod("team", "worker", "node-1", "1", "2Gi"), testPodMetrics("team", "worker", "500m", "1Gi"),
+	)
+
+	summary := co.generateCostSummary(context.Background())
+	cost := summary.NamespaceCosts["team"]
+	if cost == 0 {
+		t.Fatal("no cost attributed to the team namespace")
+	}
+	if used, idle := summary.NamespaceUsedCost["team"], summary.NamespaceIdleCost["team"]; math.Abs(used-cost/2) > 1e-9 || math.Abs(idle-cost/2) > 1e-9 {
+		t.Errorf("used %v and idle %v, want half of %v each", used, idle, cost)
+	}
+}
+
+func TestCostSummarySavingsAndIncreases(t *testing.T) {
+	tests := []struct {
+		name         string
+		savings      []float64
+		wantSavings  float64
+		wantIncrease float64
+	}{
+		{name: "savings only", savings: []float64{120, 30.5}, wantSavings: 150.5},
+		{name: "increases only", savings: []float64{-50, -25}, wantIncrease: 75},
+		{name: "mixed", savings: []float64{200, -50, 40, -50, 0}, wantSavings: 240, wantIncrease: 100},
+		{name: "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t, testNode("node-1", "4", "16Gi"), testNodeMetrics("node-1", "1", "4Gi"))
+			for i, savings := range tt.savings {
+				co.recommendations = append(co.recommendations, Recommendation{
+					ID:      fmt.Sprintf("rec-%d", i),
+					Type:    "node_optimization",
+					Savings: savings,
+				})
+			}
+
+			summary := co.generateCostSummary(context.Background())
+			if summary.PotentialSavings != tt.wantSavings || summary.ProjectedCostIncrease != tt.wantIncrease {
+				t.Errorf("savings %v and increase %v, want %v and %v",
+					summary.PotentialSavings, summary.ProjectedCostIncrease, tt.wantSavings, tt.wantIncrease)
+			}
+			if summary.RecommendationCount != len(tt.savings) {
+				t.Errorf("%d recommendations, want %d", summary.RecommendationCount, len(tt.savings))
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// SuppressionRule drops matching recommendations during generation, such as
+// rightsizing in CI namespaces or cleanups worth too little to act on. Every
+// matcher that is set has to match; type and namespace accept globs.
+type SuppressionRule struct {
+	Name         string  `json:"name"`
+	Type         string  `json:"type,omitempty"`
+	Namespace    string  `json:"namespace,omitempty"`
+	Selector     string  `json:"selector,omitempty"`
+	SavingsBelow float64 `json:"savings_below,omitempty"`
+
+	selector labels.Selector
+}
+
+func (r *SuppressionRule) validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	if r.Type == "" && r.Namespace == "" && r.Selector == "" && r.SavingsBelow == 0 {
+		return errors.New("set at least one of type, namespace, selector or savings_below")
+	}
+	for _, pattern := range []string{r.Type, r.Namespace} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	if r.SavingsBelow < 0 {
+		return errors.New("savings_below must not be negative")
+	}
+	if r.Selector != "" {
+		selector, err := labels.Parse(r.Selector)
+		if err != nil {
+			return fmt.Errorf("invalid selector: %w", err)
+		}
+		r.selector = selector
+	}
+	return nil
+}
+
+// matches reports whether the rule covers a recommendation. A selector only
+// matches recommendations whose analyzer recorded the object's labels.
+func (r *SuppressionRule) matches(rec Recommendation) bool {
+	if r.Type != "" {
+		if ok, _ := path.Match(r.Type, rec.Type); !ok {
+			return false
+		}
+	}
+	if r.Namespace != "" {
+		if ok, _ := path.Match(r.Namespace, rec.Namespace); !ok {
+			return false
+		}
+	}
+	if r.selector != nil && (rec.labels == nil || !r.selector.Matches(labels.Set(rec.labels))) {
+		return false
+	}
+	if r.SavingsBelow > 0 && rec.Savings >= r.SavingsBelow {
+		return false
+	}
+	return true
+}
+
+// suppressRecommendations drops recommendations matched by a suppression rule,
+// counting the drops per rule; the first matching rule gets the count
+func (co *CostOptimizer) suppressRecommendations(recommendations []Recommendation) ([]Recommendation, map[string]int) {
+	suppressed := make(map[string]int)
+	if len(co.suppressionRules) == 0 {
+		return recommendations, suppressed
+	}
+
+	kept := recommendations[:0]
+	for _, rec := range recommendations {
+		matched := false
+		for i := range co.suppressionRules {
+			if co.suppressionRules[i].matches(rec) {
+				suppressed[co.suppressionRules[i].Name]++
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			kept = append(kept, rec)
+		}
+	}
+	return kept, suppressed
+}
+
+// recordSuppressed keeps the last scan's suppression counts for diagnostics
+func (co *CostOptimizer) recordSuppressed(suppressed map[string]int) {
+	co.diagMu.Lock()
+	defer co.diagMu.Unlock()
+	co.suppressedRecommendations = suppressed
+}
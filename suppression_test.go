@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSuppressionRuleMatches(t *testing.T) {
+	rightsizing := Recommendation{Type: "resource_rightsizing", Namespace: "ci-build", Savings: 12, labels: map[string]string{"team": "infra"}}
+	cleanup := Recommendation{Type: "storage_cleanup", Namespace: "shop", Savings: 3}
+	tests := []struct {
+		name string
+		rule SuppressionRule
+		rec  Recommendation
+		want bool
+	}{
+		{name: "namespace prefix", rule: SuppressionRule{Name: "ci", Namespace: "ci-*"}, rec: rightsizing, want: true},
+		{name: "other namespace", rule: SuppressionRule{Name: "ci", Namespace: "ci-*"}, rec: cleanup},
+		{name: "type glob", rule: SuppressionRule{Name: "sizing", Type: "resource_*"}, rec: rightsizing, want: true},
+		{name: "small cleanup", rule: SuppressionRule{Name: "small", Type: "storage_cleanup", SavingsBelow: 5}, rec: cleanup, want: true},
+		{name: "cleanup worth keeping", rule: SuppressionRule{Name: "small", Type: "storage_cleanup", SavingsBelow: 2}, rec: cleanup},
+		{name: "selector", rule: SuppressionRule{Name: "infra", Selector: "team=infra"}, rec: rightsizing, want: true},
+		{name: "selector without labels", rule: SuppressionRule{Name: "infra", Selector: "team=infra"}, rec: cleanup},
+		{name: "every matcher must match", rule: SuppressionRule{Name: "ci", Namespace: "ci-*", Type: "storage_cleanup"}, rec: rightsizing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := tt.rule
+			if err := rule.validate(); err != nil {
+				t.Fatal(err)
+			}
+			if got := rule.matches(tt.rec); got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFileConfigSuppress(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    int
+		wantErr string
+	}{
+		{name: "rules", config: `{"suppress": [{"name": "ci", "namespace": "ci-*"}, {"name": "small", "type": "storage_cleanup", "savings_below": 5}]}`, want: 2},
+		{name: "missing name", config: `{"suppress": [{"namespace": "ci-*"}]}`, wantErr: "suppress[0]: name is required"},
+		{name: "no matchers", config: `{"suppress": [{"name": "all"}]}`, wantErr: "suppress[0]: set at least one"},
+		{name: "duplicate name", config: `{"suppress": [{"name": "ci", "namespace": "ci-*"}, {"name": "ci", "type": "x"}]}`, wantErr: `suppress[1]: duplicate name "ci"`},
+		{name: "bad selector", config: `{"suppress": [{"name": "bad", "selector": "team in ("}]}`, wantErr: "suppress[0]: invalid selector"},
+		{name: "negative savings", config: `{"suppress": [{"name": "neg", "savings_below": -1}]}`, wantErr: "suppress[0]: savings_below"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(tt.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := loadFileConfig(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cfg.Suppress) != tt.want {
+				t.Errorf("%d rules, want %d", len(cfg.Suppress), tt.want)
+			}
+		})
+	}
+}
+
+// TestSuppressedRecommendations checks that a scan drops rightsizing in CI
+// namespaces and counts the drop in diagnostics
+func TestSuppressedRecommendations(t *testing.T) {
+	co, _ := newTestOptimizer(t,
+		testPod("ci-build", "runner", "node-1", "2", "1Gi"), testPodMetrics("ci-build", "runner", "100m", "1Gi"),
+		testPod("shop", "web", "node-1", "2", "1Gi"), testPodMetrics("shop", "web", "100m", "1Gi"),
+	)
+	co.suppressionRules = []SuppressionRule{{Name: "ci", Namespace: "ci-*", Type: "resource_rightsizing"}}
+	if err := co.suppressionRules[0].validate(); err != nil {
+		t.Fatal(err)
+	}
+	co.analyzeAndGenerateRecommendations(context.Background())
+
+	rightsized := make(map[string]bool)
+	for _, rec := range co.recommendations {
+		if rec.Type == "resource_rightsizing" {
+			rightsized[rec.Resource] = true
+		}
+	}
+	if want := map[string]bool{"shop/web": true}; !reflect.DeepEqual(rightsized, want) {
+		t.Errorf("rightsized %v, want %v", rightsized, want)
+	}
+
+	var diag Diagnostics
+	rec := serve(http.HandlerFunc(co.handleDiagnostics), http.MethodGet, "/api/diagnostics", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &diag); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"ci": 1}; !reflect.DeepEqual(diag.SuppressedRecommendations, want) {
+		t.Errorf("suppressed %v, want %v", diag.SuppressedRecommendations, want)
+	}
+}
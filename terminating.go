@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// defaultTerminatingThreshold is how long past its deletion deadline a pod may
+// linger before it's reported as stuck
+const defaultTerminatingThreshold = 15 * time.Minute
+
+// analyzeStuckTerminating flags pods whose deletion deadline passed long ago.
+// They keep their requests reserved and stop their node from being drained,
+// which blocks consolidation. The deletionTimestamp already includes the
+// grace period, so the threshold counts from when the pod should have gone.
+func (co *CostOptimizer) analyzeStuckTerminating(ctx context.Context) []Recommendation {
+	recommendations := make([]Recommendation, 0)
+
+	if co.demoMode || co.clientset == nil || co.analyzerDisabled("terminating") {
+		return recommendations
+	}
+
+	pods, err := co.listPods(ctx, "")
+	if err != nil {
+		co.analyzerListFailed("terminating", "pods", err)
+		return recommendations
+	}
+
+	var nodeReady map[string]bool
+	nodesListed := false
+	now := co.now()
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp == nil {
+			continue
+		}
+		stuckFor := now.Sub(pod.DeletionTimestamp.Time)
+		if stuckFor < co.terminatingThreshold {
+			continue
+		}
+
+		if !nodesListed {
+			nodeReady = co.listNodeReadiness(ctx)
+			nodesListed = true
+		}
+
+		reasons := make([]string, 0)
+		if len(pod.Finalizers) > 0 {
+			reasons = append(reasons, "finalizers "+strings.Join(pod.Finalizers, ", "))
+		}
+		node := pod.Spec.NodeName
+		if ready, exists := nodeReady[node]; node != "" && nodeReady != nil {
+			if !exists {
+				reasons = append(reasons, fmt.Sprintf("node %s no longer exists", node))
+			} else if !ready {
+				reasons = append(reasons, fmt.Sprintf("node %s is not ready", node))
+			}
+		}
+		blocking := "unknown cause"
+		if len(reasons) > 0 {
+			blocking = strings.Join(reasons, "; ")
+		}
+		if node == "" {
+			node = "(unscheduled)"
+		}
+
+		cpu := resource.NewMilliQuantity(podRequests(pod, corev1.ResourceCPU), resource.DecimalSI)
+		memory := resource.NewQuantity(podRequests(pod, corev1.ResourceMemory), resource.BinarySI)
+
+		recommendations = append(recommendations, Recommendation{
+			Type:        "workload_health",
+			Category:    CategoryDelete,
+			Resource:    fmt.Sprintf("%s/%s", pod.Namespace, pod.Name),
+			Namespace:   pod.Namespace,
+			Release:     helmRelease(pod.Labels),
+			labels:      pod.Labels,
+			Description: fmt.Sprintf("Pod %s on node %s has been stuck terminating for %s (%s), holding %s CPU and %s memory and blocking the node's consolidation", pod.Name, node, stuckFor.Round(time.Minute), blocking, cpu.String(), memory.String()),
+			Impact:      "Resolve the blocking finalizer or node, or force-delete the pod once it is confirmed gone",
+			ActionHint:  &ActionHint{Verb: "delete", Target: hintTarget("pod", pod.Namespace, pod.Name)},
+			Savings:     co.estimatePodCost(*cpu, *memory),
+			Priority:    "medium",
+			Timestamp:   time.Now(),
+		})
+	}
+
+	return recommendations
+}
+
+// listNodeReadiness maps each node to whether its Ready condition is true. It
+// returns nil when nodes can't be listed.
+func (co *CostOptimizer) listNodeReadiness(ctx context.Context) map[string]bool {
+	nodes, err := co.listNodes(ctx)
+	if err != nil {
+		slog.Warn("Failed to list nodes for stuck pod analysis", "error", err)
+		return nil
+	}
+	ready := make(map[string]bool, len(nodes.Items))
+	for _, node := range nodes.Items {
+		ready[node.Name] = false
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeReady {
+				ready[node.Name] = condition.Status == corev1.ConditionTrue
+			}
+		}
+	}
+	return ready
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// withReadiness sets a node's Ready condition
+func withReadiness(node *corev1.Node, ready corev1.ConditionStatus) *corev1.Node {
+	node.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}}
+	return node
+}
+
+func TestAnalyzeStuckTerminating(t *testing.T) {
+	now := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		deletedAgo time.Duration // zero leaves the pod undeleted
+		finalizers []string
+		node       *corev1.Node
+		want       string // in the description; no recommendation when empty
+	}{
+		{name: "running pod", node: withReadiness(testNode("node-1", "4", "16Gi"), corev1.ConditionTrue)},
+		{name: "recently deleted", deletedAgo: 5 * time.Minute, node: withReadiness(testNode("node-1", "4", "16Gi"), corev1.ConditionTrue)},
+		{
+			name:       "held by a finalizer",
+			deletedAgo: 3 * time.Hour,
+			finalizers: []string{"example.com/cleanup"},
+			node:       withReadiness(testNode("node-1", "4", "16Gi"), corev1.ConditionTrue),
+			want:       "Pod web on node node-1 has been stuck terminating for 3h0m0s (finalizers example.com/cleanup), holding 500m CPU and 1Gi memory",
+		},
+		{
+			name:       "node not ready",
+			deletedAgo: time.Hour,
+			node:       withReadiness(testNode("node-1", "4", "16Gi"), corev1.ConditionUnknown),
+			want:       "(node node-1 is not ready)",
+		},
+		{name: "node gone", deletedAgo: time.Hour, want: "(node node-1 no longer exists)"},
+		{
+			name:       "unknown cause",
+			deletedAgo: 20 * time.Minute,
+			node:       withReadiness(testNode("node-1", "4", "16Gi"), corev1.ConditionTrue),
+			want:       "for 20m0s (unknown cause)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := testPod("shop", "web", "node-1", "500m", "1Gi")
+			pod.Finalizers = tt.finalizers
+			if tt.deletedAgo != 0 {
+				deleted := metav1.NewTime(now.Add(-tt.deletedAgo))
+				pod.DeletionTimestamp = &deleted
+			}
+			objects := []runtime.Object{pod}
+			if tt.node != nil {
+				objects = append(objects, tt.node)
+			}
+			co, _ := newTestOptimizer(t, objects...)
+			co.now = func() time.Time { return now }
+
+			recommendations := co.analyzeStuckTerminating(context.Background())
+			if tt.want == "" {
+				if len(recommendations) != 0 {
+					t.Errorf("got %+v, want no recommendation", recommendations)
+				}
+				return
+			}
+			if len(recommendations) != 1 {
+				t.Fatalf("got %d recommendations, want 1", len(recommendations))
+			}
+			rec := recommendations[0]
+			if rec.Type != "workload_health" || rec.Resource != "shop/web" || rec.Savings <= 0 {
+				t.Errorf("recommendation %+v, want workload_health for shop/web with savings", rec)
+			}
+			if !strings.Contains(rec.Description, tt.want) {
+				t.Errorf("Description = %q, want it to contain %q", rec.Description, tt.want)
+			}
+		})
+	}
+}
+
+func TestTerminatingThresholdSetting(t *testing.T) {
+	now := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	deleted := metav1.NewTime(now.Add(-30 * time.Minute))
+	pod := testPod("shop", "web", "node-1", "500m", "1Gi")
+	pod.DeletionTimestamp = &deleted
+
+	tests := []struct {
+		threshold string
+		want      int
+	}{
+		{threshold: "", want: 1},
+		{threshold: "1h", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.threshold, func(t *testing.T) {
+			t.Setenv("OPTIMKUBE_TERMINATING_THRESHOLD", tt.threshold)
+			co, _ := newTestOptimizer(t, pod.DeepCopy())
+			co.now = func() time.Time { return now }
+
+			if got := co.analyzeStuckTerminating(context.Background()); len(got) != tt.want {
+				t.Errorf("%d recommendations, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import "fmt"
+
+// Thresholds are the utilization cutoffs the analyzers and the cost summary
+// judge resources by. Utilizations are percentages; the ratio and the waste
+// factor are fractions.
+type Thresholds struct {
+	// A node below both is underutilized and suggested for draining
+	NodeUnderutilizedCPU    float64 `json:"node_underutilized_cpu"`
+	NodeUnderutilizedMemory float64 `json:"node_underutilized_memory"`
+
+	// A node above this for CPU or memory is overutilized
+	NodeOverutilized float64 `json:"node_overutilized"`
+
+	// A container using less than this fraction of its request is
+	// over-provisioned
+	PodOverProvisionedRatio float64 `json:"pod_overprovisioned_ratio"`
+
+	// A node below this for CPU or memory counts WasteFactor of its cost as
+	// wasted in the cost summary
+	WasteUtilization float64 `json:"waste_utilization"`
+	WasteFactor      float64 `json:"waste_factor"`
+}
+
+func defaultThresholds() Thresholds {
+	return Thresholds{
+		NodeUnderutilizedCPU:    20,
+		NodeUnderutilizedMemory: 30,
+		NodeOverutilized:        90,
+		PodOverProvisionedRatio: 0.5,
+		WasteUtilization:        50,
+		WasteFactor:             0.3,
+	}
+}
+
+// thresholdField describes one threshold for validation and env overrides
+type thresholdField struct {
+	name     string // config file key
+	env      string
+	value    *float64
+	min, max float64
+}
+
+func (t *Thresholds) fields() []thresholdField {
+	return []thresholdField{
+		{"node_underutilized_cpu", "OPTIMKUBE_NODE_UNDERUTILIZED_CPU", &t.NodeUnderutilizedCPU, 0, 100},
+		{"node_underutilized_memory", "OPTIMKUBE_NODE_UNDERUTILIZED_MEMORY", &t.NodeUnderutilizedMemory, 0, 100},
+		{"node_overutilized", "OPTIMKUBE_NODE_OVERUTILIZED", &t.NodeOverutilized, 0, 100},
+		{"pod_overprovisioned_ratio", "OPTIMKUBE_POD_OVERPROVISIONED_RATIO", &t.PodOverProvisionedRatio, 0, 1},
+		{"waste_utilization", "OPTIMKUBE_WASTE_UTILIZATION", &t.WasteUtilization, 0, 100},
+		{"waste_factor", "OPTIMKUBE_WASTE_FACTOR", &t.WasteFactor, 0, 1},
+	}
+}
+
+func (t *Thresholds) validate() error {
+	for _, field := range t.fields() {
+		if *field.value < field.min || *field.value > field.max {
+			return fmt.Errorf("%s %v must be between %v and %v", field.name, *field.value, field.min, field.max)
+		}
+	}
+	return nil
+}
+
+// applyEnv overrides thresholds with any that are set in the environment,
+// which take precedence over the config file
+func (t *Thresholds) applyEnv() error {
+	for _, field := range t.fields() {
+		value, err := envFloat(field.env, *field.value)
+		if err != nil {
+			return err
+		}
+		if value < field.min || value > field.max {
+			return fmt.Errorf("invalid %s %v: must be between %v and %v", field.env, value, field.min, field.max)
+		}
+		*field.value = value
+	}
+	return nil
+}
+
+// overProvisioned reports whether usage is strictly below the configured
+// fraction of request. The comparison is exact for integer quantities: at the
+// default of one half, with a 1m request 0m usage is over-provisioned, and
+// with a 3m request 1m is while 2m is not. Usage of exactly half the request
+// is not over-provisioned.
+func (t *Thresholds) overProvisioned(usage, request int64) bool {
+	return request > 0 && float64(usage) < t.PodOverProvisionedRatio*float64(request)
+}
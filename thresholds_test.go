@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestUnderutilizedThresholdFlagsNodes(t *testing.T) {
+	tests := []struct {
+		name string
+		cpu  float64
+		want []string
+	}{
+		{name: "default", cpu: 20, want: []string{"node-idle", "node-quiet"}},
+		{name: "lowered", cpu: 10, want: []string{"node-idle"}},
+		{name: "raised", cpu: 50, want: []string{"node-busy", "node-idle", "node-quiet"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t,
+				testNode("node-idle", "4", "16Gi"), testNodeMetrics("node-idle", "200m", "1Gi"),
+				testNode("node-quiet", "4", "16Gi"), testNodeMetrics("node-quiet", "600m", "2Gi"),
+				testNode("node-busy", "4", "16Gi"), testNodeMetrics("node-busy", "1600m", "2Gi"),
+			)
+			co.thresholds.NodeUnderutilizedCPU = tt.cpu
+
+			var flagged []string
+			for _, rec := range co.analyzeNodes(context.Background()) {
+				if rec.Type == "node_optimization" {
+					flagged = append(flagged, rec.Resource)
+				}
+			}
+			sort.Strings(flagged)
+			if !reflect.DeepEqual(flagged, tt.want) {
+				t.Errorf("flagged %v, want %v", flagged, tt.want)
+			}
+		})
+	}
+}
+
+func TestPodOverProvisionedRatio(t *testing.T) {
+	tests := []struct {
+		ratio          float64
+		usage, request int64
+		want           bool
+	}{
+		{ratio: 0.5, usage: 300, request: 1000, want: true},
+		{ratio: 0.25, usage: 300, request: 1000},
+		{ratio: 0.25, usage: 249, request: 1000, want: true},
+		{ratio: 0, usage: 0, request: 1000},
+	}
+	for _, tt := range tests {
+		thresholds := Thresholds{PodOverProvisionedRatio: tt.ratio}
+		if got := thresholds.overProvisioned(tt.usage, tt.request); got != tt.want {
+			t.Errorf("ratio %v: overProvisioned(%d, %d) = %v, want %v", tt.ratio, tt.usage, tt.request, got, tt.want)
+		}
+	}
+}
+
+func TestCostSummaryWasteThresholds(t *testing.T) {
+	tests := []struct {
+		name        string
+		utilization float64
+		factor      float64
+		want        float64 // fraction of the node's cost counted as waste
+	}{
+		{name: "default", utilization: 50, factor: 0.3, want: 0.3},
+		{name: "lower cutoff", utilization: 10, factor: 0.3},
+		{name: "higher factor", utilization: 50, factor: 0.6, want: 0.6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 25% CPU and memory
+			co, _ := newTestOptimizer(t, testNode("node-1", "4", "16Gi"), testNodeMetrics("node-1", "1", "4Gi"))
+			co.thresholds.WasteUtilization = tt.utilization
+			co.thresholds.WasteFactor = tt.factor
+
+			summary := co.generateCostSummary(context.Background())
+			if want := summary.TotalMonthlyCost * tt.want; fmt.Sprintf("%.6f", summary.WastedResources) != fmt.Sprintf("%.6f", want) {
+				t.Errorf("wasted %v of %v, want %v", summary.WastedResources, summary.TotalMonthlyCost, want)
+			}
+		})
+	}
+}
+
+func TestThresholdsConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string // thresholds in the config file
+		env     map[string]string
+		want    func(*Thresholds)
+		wantErr string
+	}{
+		{name: "defaults", want: func(*Thresholds) {}},
+		{name: "file", file: `{"node_underutilized_cpu": 10, "waste_factor": 0.2}`, want: func(t *Thresholds) {
+			t.NodeUnderutilizedCPU, t.WasteFactor = 10, 0.2
+		}},
+		{
+			name: "env over file",
+			file: `{"node_underutilized_cpu": 10}`,
+			env:  map[string]string{"OPTIMKUBE_NODE_UNDERUTILIZED_CPU": "5", "OPTIMKUBE_NODE_OVERUTILIZED": "80"},
+			want: func(t *Thresholds) { t.NodeUnderutilizedCPU, t.NodeOverutilized = 5, 80 },
+		},
+		{name: "file out of range", file: `{"waste_factor": 1.5}`, wantErr: "waste_factor 1.5 must be between 0 and 1"},
+		{name: "env out of range", env: map[string]string{"OPTIMKUBE_NODE_OVERUTILIZED": "120"}, wantErr: "invalid OPTIMKUBE_NODE_OVERUTILIZED 120"},
+		{name: "unknown key", file: `{"node_idle": 5}`, wantErr: "node_idle"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DEMO_MODE", "true")
+			if tt.file != "" {
+				path := filepath.Join(t.TempDir(), "config.json")
+				if err := os.WriteFile(path, []byte(fmt.Sprintf(`{"thresholds": %s}`, tt.file)), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				t.Setenv("OPTIMKUBE_CONFIG_FILE", path)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			co, err := NewCostOptimizer()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := defaultThresholds()
+			tt.want(&want)
+			if co.thresholds != want {
+				t.Errorf("thresholds %+v, want %+v", co.thresholds, want)
+			}
+		})
+	}
+}
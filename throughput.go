@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ThroughputQuery maps a workload to a PromQL query returning its request rate
+// in requests per second.
+type ThroughputQuery struct {
+	Name string `json:"name"`
+	workloadScope
+	Query string `json:"query"`
+}
+
+func (q *ThroughputQuery) validate() error {
+	if q.Name == "" {
+		return errors.New("name is required")
+	}
+	if err := q.workloadScope.validate(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(q.Query) == "" {
+		return errors.New("query is required")
+	}
+	return nil
+}
+
+// WorkloadEfficiency relates a workload's allocated cost to the traffic it serves
+type WorkloadEfficiency struct {
+	Name                   string  `json:"name"`
+	Namespace              string  `json:"namespace,omitempty"`
+	MonthlyCost            float64 `json:"monthly_cost"`
+	RequestsPerSecond      float64 `json:"requests_per_second"`
+	CostPerMillionRequests float64 `json:"cost_per_million_requests,omitempty"`
+}
+
+// metricsSource evaluates instant queries against a custom metrics backend
+type metricsSource interface {
+	Query(ctx context.Context, query string) (float64, error)
+}
+
+// prometheusSource queries the Prometheus HTTP API
+type prometheusSource struct {
+	url    string
+	client *http.Client
+}
+
+func newPrometheusSource(baseURL string) *prometheusSource {
+	return &prometheusSource{
+		url:    strings.TrimRight(baseURL, "/"),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Query runs an instant query and sums the resulting vector, so queries that
+// return one series per pod or route still yield a single workload figure.
+func (p *prometheusSource) Query(ctx context.Context, query string) (float64, error) {
+	endpoint := p.url + "/api/v1/query?query=" + url.QueryEscape(query)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Value [2]interface{} `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("decode response (status %d): %w", resp.StatusCode, err)
+	}
+	if body.Status != "success" {
+		return 0, fmt.Errorf("query failed: %s", body.Error)
+	}
+	if body.Data.ResultType != "vector" {
+		return 0, fmt.Errorf("unsupported result type %q: expected vector", body.Data.ResultType)
+	}
+
+	var total float64
+	for _, sample := range body.Data.Result {
+		raw, ok := sample.Value[1].(string)
+		if !ok {
+			return 0, errors.New("malformed sample value")
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return 0, fmt.Errorf("malformed sample value %q", raw)
+		}
+		total += value
+	}
+	return total, nil
+}
+
+// costPerMillionRequests spreads a monthly cost over a month of traffic at the
+// given request rate. It returns 0 when there is no traffic to divide by.
+func costPerMillionRequests(monthlyCost, requestsPerSecond float64) float64 {
+	if requestsPerSecond <= 0 {
+		return 0
+	}
+	monthlyRequests := requestsPerSecond * 60 * 60 * 24 * 30
+	return monthlyCost / monthlyRequests * 1e6
+}
+
+// workloadEfficiency computes cost per million requests for every configured
+// throughput query. Workloads whose query fails are logged and skipped.
+func (co *CostOptimizer) workloadEfficiency(ctx context.Context) []WorkloadEfficiency {
+	results := make([]WorkloadEfficiency, 0, len(co.throughputQueries))
+	if co.metricsSource == nil || len(co.throughputQueries) == 0 {
+		return results
+	}
+
+	pods := co.getPodMetrics(ctx, "")
+	for i := range co.throughputQueries {
+		query := &co.throughputQueries[i]
+
+		rate, err := co.metricsSource.Query(ctx, query.Query)
+		if err != nil {
+			slog.Warn("Failed to query throughput", "workload", query.Name, "error", err)
+			continue
+		}
+
+		var monthlyCost float64
+		for _, pod := range pods {
+			if query.matches(pod) {
+				monthlyCost += pod.EstimatedCost
+			}
+		}
+
+		results = append(results, WorkloadEfficiency{
+			Name:                   query.Name,
+			Namespace:              query.Namespace,
+			MonthlyCost:            monthlyCost,
+			RequestsPerSecond:      rate,
+			CostPerMillionRequests: costPerMillionRequests(monthlyCost, rate),
+		})
+	}
+
+	return results
+}
+
+func (co *CostOptimizer) handleWorkloadMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(co.workloadEfficiency(r.Context()))
+}
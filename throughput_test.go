@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeMetricsSource answers queries from a map, failing unknown ones
+type fakeMetricsSource map[string]float64
+
+func (f fakeMetricsSource) Query(ctx context.Context, query string) (float64, error) {
+	value, ok := f[query]
+	if !ok {
+		return 0, errors.New("unknown query")
+	}
+	return value, nil
+}
+
+func TestPrometheusSourceQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     float64
+		wantErr  string
+	}{
+		{
+			name:     "sums the vector",
+			response: `{"status":"success","data":{"resultType":"vector","result":[{"value":[1700000000,"12.5"]},{"value":[1700000000,"7.5"]}]}}`,
+			want:     20,
+		},
+		{
+			name:     "empty vector",
+			response: `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+		},
+		{
+			name:     "query error",
+			response: `{"status":"error","error":"parse error"}`,
+			wantErr:  "parse error",
+		},
+		{
+			name:     "range result",
+			response: `{"status":"success","data":{"resultType":"matrix","result":[]}}`,
+			wantErr:  "unsupported result type",
+		},
+		{
+			name:     "malformed value",
+			response: `{"status":"success","data":{"resultType":"vector","result":[{"value":[1700000000,"fast"]}]}}`,
+			wantErr:  "malformed sample value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := `sum(rate(http_requests_total{app="web"}[5m]))`
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v1/query" || r.URL.Query().Get("query") != query {
+					t.Errorf("requested %s, want the instant query endpoint with the query", r.URL)
+				}
+				fmt.Fprint(w, tt.response)
+			}))
+			defer server.Close()
+
+			got, err := newPrometheusSource(server.URL+"/").Query(context.Background(), query)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Query error = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Query = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkloadEfficiency(t *testing.T) {
+	co, _ := newTestOptimizer(t,
+		testNode("node-1", "8", "32Gi"), testNodeMetrics("node-1", "1", "4Gi"),
+		testPod("shop", "web", "node-1", "1", "2Gi"), testPodMetrics("shop", "web", "500m", "1Gi"),
+	)
+	co.metricsSource = fakeMetricsSource{"web_rps": 10, "idle_rps": 0}
+	co.throughputQueries = []ThroughputQuery{
+		{Name: "web", workloadScope: workloadScope{Namespace: "shop"}, Query: "web_rps"},
+		{Name: "idle", workloadScope: workloadScope{Namespace: "shop"}, Query: "idle_rps"},
+		{Name: "broken", workloadScope: workloadScope{Namespace: "shop"}, Query: "missing"},
+	}
+	// web requests an eighth of the node's CPU and a sixteenth of its memory
+	cost := co.nodeHourlyCost(testNode("node-1", "8", "32Gi")) * 24 * 30 * (1.0/8 + 1.0/16) / 2
+
+	rec := serve(http.HandlerFunc(co.handleWorkloadMetrics), http.MethodGet, "/api/metrics/workloads", nil)
+	var workloads []WorkloadEfficiency
+	if err := json.Unmarshal(rec.Body.Bytes(), &workloads); err != nil {
+		t.Fatalf("decode workloads: %v", err)
+	}
+	if len(workloads) != 2 {
+		t.Fatalf("got %+v, want the two workloads whose query succeeded", workloads)
+	}
+
+	// 10 requests per second is 25.92 million requests a month
+	web := workloads[0]
+	if web.Name != "web" || web.MonthlyCost != roundMoney(cost) || web.CostPerMillionRequests != roundMoney(cost/25.92) {
+		t.Errorf("web = %+v, want cost %v at %v per million requests", web, roundMoney(cost), roundMoney(cost/25.92))
+	}
+	if idle := workloads[1]; idle.Name != "idle" || idle.CostPerMillionRequests != 0 {
+		t.Errorf("idle = %+v, want no cost per request without traffic", idle)
+	}
+}
+
+func TestThroughputQueriesRequirePrometheus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	config := "throughput:\n- name: web\n  namespace: shop\n  query: sum(rate(http_requests_total[5m]))\n"
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DEMO_MODE", "true")
+	t.Setenv("OPTIMKUBE_CONFIG_FILE", path)
+
+	if _, err := NewCostOptimizer(); err == nil || !strings.Contains(err.Error(), "OPTIMKUBE_PROMETHEUS_URL") {
+		t.Errorf("NewCostOptimizer error = %v, want one asking for OPTIMKUBE_PROMETHEUS_URL", err)
+	}
+	t.Setenv("OPTIMKUBE_PROMETHEUS_URL", "http://prometheus:9090")
+	if _, err := NewCostOptimizer(); err != nil {
+		t.Errorf("NewCostOptimizer: %v", err)
+	}
+}
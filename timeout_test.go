@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// hangingServer accepts requests and never answers them until the test ends
+func hangingServer(t *testing.T) *httptest.Server {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	t.Cleanup(func() {
+		close(done)
+		server.Close()
+	})
+	return server
+}
+
+// TestAPITimeout points the optimizer at an API server that never responds
+// and checks that analysis gives up after the timeout instead of blocking
+func TestAPITimeout(t *testing.T) {
+	server := hangingServer(t)
+	config := &rest.Config{Host: server.URL}
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &timeoutTransport{next: rt, timeout: 50 * time.Millisecond}
+	})
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	co, _ := newTestOptimizer(t)
+	co.clientset = clientset
+
+	tests := []struct {
+		name string
+		call func(ctx context.Context) int
+	}{
+		{name: "analyzeNodes", call: func(ctx context.Context) int { return len(co.analyzeNodes(ctx)) }},
+		{name: "analyzePods", call: func(ctx context.Context) int { return len(co.analyzePods(ctx)) }},
+		{name: "analyzeDeployments", call: func(ctx context.Context) int { return len(co.analyzeDeployments(ctx)) }},
+		{name: "getNodeMetrics", call: func(ctx context.Context) int { return len(co.getNodeMetrics(ctx)) }},
+		{name: "getPodMetrics", call: func(ctx context.Context) int { return len(co.getPodMetrics(ctx, "")) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done := make(chan int)
+			go func() { done <- tt.call(context.Background()) }()
+			select {
+			case n := <-done:
+				if n != 0 {
+					t.Errorf("got %d results from a hung API server, want none", n)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("call still blocked long after the API timeout")
+			}
+		})
+	}
+}
+
+func TestTimeoutTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		// Watches stream for as long as the client reads
+		if r.URL.Query().Get("watch") == "true" {
+			time.Sleep(100 * time.Millisecond)
+			io.WriteString(w, "event")
+			return
+		}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: &timeoutTransport{next: http.DefaultTransport, timeout: 50 * time.Millisecond}}
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+	}{
+		{name: "slow body times out", wantErr: true},
+		{name: "watch is exempt", query: "?watch=true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Get(server.URL + "/api/v1/pods" + tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			_, err = io.ReadAll(resp.Body)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("reading the body: %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAPITimeoutSetting(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{value: ""},
+		{value: "5s"},
+		{value: "0s", wantErr: true},
+		{value: "forever", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("DEMO_MODE", "true")
+			t.Setenv("OPTIMKUBE_API_TIMEOUT", tt.value)
+			if _, err := NewCostOptimizer(); (err != nil) != tt.wantErr {
+				t.Errorf("NewCostOptimizer error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// CostSummaryPoint is one recorded scan in the cost history, without the
+// per-namespace breakdowns of the full summary
+type CostSummaryPoint struct {
+	Timestamp        time.Time `json:"timestamp"`
+	TotalMonthlyCost float64   `json:"total_monthly_cost"`
+	ComputeCost      float64   `json:"compute_cost"`
+	StorageCost      float64   `json:"storage_cost"`
+	WastedResources  float64   `json:"wasted_resources"`
+	PotentialSavings float64   `json:"potential_savings"`
+	NodeCount        int       `json:"node_count"`
+	PodCount         int       `json:"pod_count"`
+}
+
+// CostDelta compares a value at the start and end of the trend window
+type CostDelta struct {
+	Start  float64 `json:"start"`
+	End    float64 `json:"end"`
+	Change float64 `json:"change"`
+	// Relative change in percent, omitted when the start is zero
+	ChangePercent *float64 `json:"change_percent,omitempty"`
+}
+
+// CostTrend summarizes how cost and waste moved across the history window
+type CostTrend struct {
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Snapshots int       `json:"snapshots"`
+
+	TotalMonthlyCost CostDelta `json:"total_monthly_cost"`
+	ComputeCost      CostDelta `json:"compute_cost"`
+	StorageCost      CostDelta `json:"storage_cost"`
+	WastedResources  CostDelta `json:"wasted_resources"`
+	PotentialSavings CostDelta `json:"potential_savings"`
+	NodeCount        CostDelta `json:"node_count"`
+	PodCount         CostDelta `json:"pod_count"`
+}
+
+func costSummaryPoint(summary ClusterCostSummary) CostSummaryPoint {
+	return CostSummaryPoint{
+		Timestamp:        summary.LastUpdated,
+		TotalMonthlyCost: summary.TotalMonthlyCost,
+		ComputeCost:      summary.ComputeCost,
+		StorageCost:      summary.StorageCost,
+		WastedResources:  summary.WastedResources,
+		PotentialSavings: summary.PotentialSavings,
+		NodeCount:        summary.NodeCount,
+		PodCount:         summary.PodCount,
+	}
+}
+
+func costDelta(start, end float64) CostDelta {
+	delta := CostDelta{Start: start, End: end, Change: end - start}
+	if start != 0 {
+		percent := (end - start) / start * 100
+		delta.ChangePercent = &percent
+	}
+	return delta
+}
+
+// costTrend compares the first and last of the summaries, oldest first. It
+// needs at least two.
+func costTrend(snapshots []ClusterCostSummary) (CostTrend, bool) {
+	if len(snapshots) < 2 {
+		return CostTrend{}, false
+	}
+	first, last := snapshots[0], snapshots[len(snapshots)-1]
+	return CostTrend{
+		From:             first.LastUpdated,
+		To:               last.LastUpdated,
+		Snapshots:        len(snapshots),
+		TotalMonthlyCost: costDelta(first.TotalMonthlyCost, last.TotalMonthlyCost),
+		ComputeCost:      costDelta(first.ComputeCost, last.ComputeCost),
+		StorageCost:      costDelta(first.StorageCost, last.StorageCost),
+		WastedResources:  costDelta(first.WastedResources, last.WastedResources),
+		PotentialSavings: costDelta(first.PotentialSavings, last.PotentialSavings),
+		NodeCount:        costDelta(float64(first.NodeCount), float64(last.NodeCount)),
+		PodCount:         costDelta(float64(first.PodCount), float64(last.PodCount)),
+	}, true
+}
+
+// historyWindow returns the recorded summaries, limited by ?window= to those
+// within that duration of the newest. On an invalid window it writes the
+// error response and returns false.
+func (co *CostOptimizer) historyWindow(w http.ResponseWriter, r *http.Request) ([]ClusterCostSummary, bool) {
+	snapshots := co.history.all()
+	raw := r.URL.Query().Get("window")
+	if raw == "" || len(snapshots) == 0 {
+		return snapshots, true
+	}
+	window, err := time.ParseDuration(raw)
+	if err != nil || window <= 0 {
+		http.Error(w, fmt.Sprintf("invalid window %q: expected a duration such as 6h", raw), http.StatusBadRequest)
+		return nil, false
+	}
+	since := snapshots[len(snapshots)-1].LastUpdated.Add(-window)
+	for i, snapshot := range snapshots {
+		if !snapshot.LastUpdated.Before(since) {
+			return snapshots[i:], true
+		}
+	}
+	return nil, true
+}
+
+func (co *CostOptimizer) handleCostHistory(w http.ResponseWriter, r *http.Request) {
+	snapshots, ok := co.historyWindow(w, r)
+	if !ok {
+		return
+	}
+
+	points := make([]CostSummaryPoint, len(snapshots))
+	for i, snapshot := range snapshots {
+		points[i] = costSummaryPoint(snapshot)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(points)
+}
+
+func (co *CostOptimizer) handleCostTrend(w http.ResponseWriter, r *http.Request) {
+	snapshots, ok := co.historyWindow(w, r)
+	if !ok {
+		return
+	}
+
+	trend, ok := costTrend(snapshots)
+	if !ok {
+		http.Error(w, fmt.Sprintf("a trend needs at least 2 recorded scans, have %d", len(snapshots)), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trend)
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// recordScans adds a summary every 5 minutes from start, with the given total
+// and wasted costs and a node per 100 of total
+func recordScans(co *CostOptimizer, start time.Time, totals, wasted []float64) {
+	for i := range totals {
+		co.history.add(ClusterCostSummary{
+			LastUpdated:      start.Add(time.Duration(i) * 5 * time.Minute),
+			TotalMonthlyCost: totals[i],
+			ComputeCost:      totals[i],
+			WastedResources:  wasted[i],
+			NodeCount:        int(totals[i] / 100),
+		})
+	}
+}
+
+func TestCostTrend(t *testing.T) {
+	start := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		window        string
+		wantFrom      time.Time
+		wantSnapshots int
+		wantTotal     CostDelta
+		wantWasted    CostDelta
+		wantNodes     CostDelta
+	}{
+		{
+			name:          "whole history",
+			wantFrom:      start,
+			wantSnapshots: 4,
+			wantTotal:     CostDelta{Start: 400, End: 500, Change: 100, ChangePercent: floatPtr(25)},
+			wantWasted:    CostDelta{Start: 0, End: 30, Change: 30},
+			wantNodes:     CostDelta{Start: 4, End: 5, Change: 1, ChangePercent: floatPtr(25)},
+		},
+		{
+			name:          "last ten minutes",
+			window:        "10m",
+			wantFrom:      start.Add(5 * time.Minute),
+			wantSnapshots: 3,
+			wantTotal:     CostDelta{Start: 600, End: 500, Change: -100, ChangePercent: floatPtr(-100.0 / 6)},
+			wantWasted:    CostDelta{Start: 60, End: 30, Change: -30, ChangePercent: floatPtr(-50)},
+			wantNodes:     CostDelta{Start: 6, End: 5, Change: -1, ChangePercent: floatPtr(-100.0 / 6)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t)
+			recordScans(co, start, []float64{400, 600, 450, 500}, []float64{0, 60, 45, 30})
+
+			target := "/api/cost-summary/trend"
+			if tt.window != "" {
+				target += "?window=" + tt.window
+			}
+			rec := serve(http.HandlerFunc(co.handleCostTrend), http.MethodGet, target, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body)
+			}
+			var trend CostTrend
+			if err := json.Unmarshal(rec.Body.Bytes(), &trend); err != nil {
+				t.Fatalf("decode trend: %v", err)
+			}
+			if !trend.From.Equal(tt.wantFrom) || !trend.To.Equal(start.Add(15*time.Minute)) || trend.Snapshots != tt.wantSnapshots {
+				t.Errorf("trend over %d scans from %v to %v, want %d from %v", trend.Snapshots, trend.From, trend.To, tt.wantSnapshots, tt.wantFrom)
+			}
+			for _, delta := range []struct {
+				name      string
+				got, want CostDelta
+			}{
+				{"total", trend.TotalMonthlyCost, tt.wantTotal},
+				{"wasted", trend.WastedResources, tt.wantWasted},
+				{"nodes", trend.NodeCount, tt.wantNodes},
+			} {
+				if !equalDelta(delta.got, delta.want) {
+					t.Errorf("%s %+v (percent %v), want %+v (percent %v)", delta.name, delta.got, deref(delta.got.ChangePercent), delta.want, deref(delta.want.ChangePercent))
+				}
+			}
+		})
+	}
+}
+
+func floatPtr(f float64) *float64 { return &f }
+
+func deref(f *float64) interface{} {
+	if f == nil {
+		return nil
+	}
+	return *f
+}
+
+// equalDelta compares deltas, allowing for percentages served rounded
+func equalDelta(a, b CostDelta) bool {
+	if a.Start != b.Start || a.End != b.End || a.Change != b.Change || (a.ChangePercent == nil) != (b.ChangePercent == nil) {
+		return false
+	}
+	return a.ChangePercent == nil || math.Abs(*a.ChangePercent-*b.ChangePercent) < 0.01
+}
+
+func TestCostHistoryEndpoints(t *testing.T) {
+	start := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		scans      int
+		query      string
+		wantCode   int
+		wantPoints int
+		wantTrend  int
+	}{
+		{name: "history", scans: 3, wantCode: http.StatusOK, wantPoints: 3, wantTrend: http.StatusOK},
+		{name: "window", scans: 3, query: "?window=5m", wantCode: http.StatusOK, wantPoints: 2, wantTrend: http.StatusOK},
+		{name: "one scan", scans: 1, wantCode: http.StatusOK, wantPoints: 1, wantTrend: http.StatusNotFound},
+		{name: "no scans", wantCode: http.StatusOK, wantTrend: http.StatusNotFound},
+		{name: "invalid window", scans: 3, query: "?window=daily", wantCode: http.StatusBadRequest, wantTrend: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co, _ := newTestOptimizer(t)
+			totals := make([]float64, tt.scans)
+			for i := range totals {
+				totals[i] = 100 * float64(i+1)
+			}
+			recordScans(co, start, totals, totals)
+
+			rec := serve(http.HandlerFunc(co.handleCostHistory), http.MethodGet, "/api/cost-summary/history"+tt.query, nil)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("history status %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if rec.Code == http.StatusOK {
+				var points []CostSummaryPoint
+				if err := json.Unmarshal(rec.Body.Bytes(), &points); err != nil {
+					t.Fatalf("decode history: %v", err)
+				}
+				if len(points) != tt.wantPoints {
+					t.Errorf("%d points, want %d", len(points), tt.wantPoints)
+				}
+				for i, point := range points {
+					if i > 0 && !point.Timestamp.After(points[i-1].Timestamp) {
+						t.Errorf("points %v out of order", points)
+					}
+				}
+			}
+
+			if rec := serve(http.HandlerFunc(co.handleCostTrend), http.MethodGet, "/api/cost-summary/trend"+tt.query, nil); rec.Code != tt.wantTrend {
+				t.Errorf("trend status %d, want %d: %s", rec.Code, tt.wantTrend, rec.Body)
+			}
+		})
+	}
+}
+
+// TestHistorySurvivesRestart checks that a second optimizer on the same state
+// dir serves the trend of the scans the first one recorded
+func TestHistorySurvivesRestart(t *testing.T) {
+	t.Setenv("OPTIMKUBE_STATE_DIR", t.TempDir())
+	t.Setenv("OPTIMKUBE_HISTORY_SIZE", "3")
+	co, _ := newTestOptimizer(t,
+		testNode("node-1", "4", "16Gi"), testNodeMetrics("node-1", "1", "4Gi"),
+		testPod("shop", "web", "node-1", "1", "1Gi"), testPodMetrics("shop", "web", "100m", "1Gi"),
+	)
+	for i := 0; i < 4; i++ {
+		co.recordSummary(co.generateCostSummary(context.Background()))
+	}
+
+	restarted, err := NewCostOptimizer()
+	if err != nil {
+		t.Fatalf("NewCostOptimizer: %v", err)
+	}
+	got, want := restarted.history.all(), co.history.all()
+	if len(got) != 3 || len(want) != 3 {
+		t.Fatalf("restored %d summaries of %d, want the 3 that fit", len(got), len(want))
+	}
+	for i := range want {
+		gotJSON, err := json.Marshal(storedSummary{Summary: newStoredCostSummary(got[i]), WorkloadCosts: got[i].WorkloadCosts})
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantJSON, err := json.Marshal(storedSummary{Summary: newStoredCostSummary(want[i]), WorkloadCosts: want[i].WorkloadCosts})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got[i].WorkloadCosts) == 0 || string(gotJSON) != string(wantJSON) {
+			t.Errorf("summary %d restored as\n%s\nwant\n%s", i, gotJSON, wantJSON)
+		}
+	}
+}
+
+func TestHistorySizeSetting(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "", want: defaultHistorySize},
+		{value: "12", want: 12},
+		{value: "0", wantErr: true},
+		{value: "day", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("DEMO_MODE", "true")
+			t.Setenv("OPTIMKUBE_HISTORY_SIZE", tt.value)
+			co, err := NewCostOptimizer()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCostOptimizer error %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && co.history.capacity != tt.want {
+				t.Errorf("history holds %d, want %d", co.history.capacity, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// defaultUsageHistorySize keeps about a day of samples at the 5 minute interval
+const defaultUsageHistorySize = 288
+
+// minBandSamples is how many samples a container needs before its usage
+// spread is trusted for a savings band
+const minBandSamples = 3
+
+// usageHistory keeps a rolling window of raw CPU and memory samples per
+// container, for percentiles across scans
+type usageHistory struct {
+	capacity int
+
+	mu       sync.Mutex
+	samples  map[string][][2]float64
+	observed map[string]bool // keys seen since the last sweep
+}
+
+func newUsageHistory(capacity int) *usageHistory {
+	return &usageHistory{
+		capacity: capacity,
+		samples:  make(map[string][][2]float64),
+		observed: make(map[string]bool),
+	}
+}
+
+// observe records a sample for key, dropping the oldest once the window is full
+func (h *usageHistory) observe(key string, cpu, memory float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.observed[key] = true
+	samples := append(h.samples[key], [2]float64{cpu, memory})
+	if len(samples) > h.capacity {
+		samples = samples[len(samples)-h.capacity:]
+	}
+	h.samples[key] = samples
+}
+
+// percentile returns the nearest-rank percentile p (0-100) of the CPU and
+// memory samples for key, along with how many samples there are
+func (h *usageHistory) percentile(key string, p float64) (cpu, memory float64, n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	samples := h.samples[key]
+	if len(samples) == 0 {
+		return 0, 0, 0
+	}
+	cpus := make([]float64, len(samples))
+	memories := make([]float64, len(samples))
+	for i, sample := range samples {
+		cpus[i], memories[i] = sample[0], sample[1]
+	}
+	sort.Float64s(cpus)
+	sort.Float64s(memories)
+
+	rank := int(math.Ceil(p / 100 * float64(len(samples))))
+	if rank < 1 {
+		rank = 1
+	}
+	return cpus[rank-1], memories[rank-1], len(samples)
+}
+
+// sweep forgets keys not observed since the previous sweep
+func (h *usageHistory) sweep() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for key := range h.samples {
+		if !h.observed[key] {
+			delete(h.samples, key)
+		}
+	}
+	h.observed = make(map[string]bool)
+}
+
+// rightsizingSavingsBand estimates the monthly savings range of rightsizing a
+// container's request from its usage spread: sizing for p95 usage gives the
+// low end and sizing for p50 the high end. The excess is priced by price.
+// ok is false until the container has enough samples.
+func (co *CostOptimizer) rightsizingSavingsBand(key string, resourceName corev1.ResourceName, request resource.Quantity, price func(cpu, memory resource.Quantity) float64) (low, high float64, ok bool) {
+	cpu95, memory95, n := co.usageHistory.percentile(key, 95)
+	if n < minBandSamples {
+		return 0, 0, false
+	}
+	cpu50, memory50, _ := co.usageHistory.percentile(key, 50)
+
+	savings := func(cpuUsage, memoryUsage float64) float64 {
+		var suggested *resource.Quantity
+		if resourceName == corev1.ResourceCPU {
+			suggested, _ = co.rounding.cpuRequest(cpuUsage)
+		} else {
+			suggested, _ = co.rounding.memoryRequest(memoryUsage)
+		}
+		excess := request.DeepCopy()
+		excess.Sub(*suggested)
+		if excess.Sign() <= 0 {
+			return 0
+		}
+		if resourceName == corev1.ResourceCPU {
+			return price(excess, resource.Quantity{})
+		}
+		return price(resource.Quantity{}, excess)
+	}
+	return savings(cpu95, memory95), savings(cpu50, memory50), true
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+func TestUsageHistoryPercentile(t *testing.T) {
+	history := newUsageHistory(5)
+	for _, cpu := range []float64{900, 100, 500, 300, 200, 400, 800} {
+		history.observe("shop/api/app", cpu, cpu*1000)
+	}
+
+	// The window keeps the last five samples: 500 300 200 400 800
+	tests := []struct {
+		p       float64
+		wantCPU float64
+	}{
+		{p: 0, wantCPU: 200},
+		{p: 50, wantCPU: 400},
+		{p: 95, wantCPU: 800},
+		{p: 100, wantCPU: 800},
+	}
+	for _, tt := range tests {
+		cpu, memory, n := history.percentile("shop/api/app", tt.p)
+		if cpu != tt.wantCPU || memory != tt.wantCPU*1000 || n != 5 {
+			t.Errorf("p%v = %v CPU, %v memory over %d samples; want %v, %v over 5", tt.p, cpu, memory, n, tt.wantCPU, tt.wantCPU*1000)
+		}
+	}
+
+	if _, _, n := history.percentile("shop/web/app", 50); n != 0 {
+		t.Errorf("unknown container has %d samples", n)
+	}
+
+	// A container not observed between sweeps is forgotten
+	history.sweep()
+	history.observe("shop/web/app", 10, 10)
+	history.sweep()
+	if _, _, n := history.percentile("shop/api/app", 50); n != 0 {
+		t.Errorf("swept container still has %d samples", n)
+	}
+	if _, _, n := history.percentile("shop/web/app", 50); n != 1 {
+		t.Errorf("observed container has %d samples, want 1", n)
+	}
+}
+
+func TestRightsizingSavingsBand(t *testing.T) {
+	ctx := context.Background()
+	// Memory is fully used, so only CPU is flagged
+	co, _ := newTestOptimizer(t,
+		testPod("shop", "api", "node-1", "2", "1Gi"),
+		testPodMetrics("shop", "api", "100m", "1Gi"))
+	metrics := co.metricsClient.(*metricsfake.Clientset)
+	// Each call stands for a scan of the cluster as it is then
+	co.listCache.ttl = 0
+
+	scans := []struct {
+		cpu      string
+		wantBand bool
+	}{
+		{cpu: "100m"},
+		{cpu: "400m"},
+		{cpu: "200m", wantBand: true},
+		{cpu: "800m", wantBand: true},
+		{cpu: "250m", wantBand: true},
+	}
+
+	var rec Recommendation
+	for i, scan := range scans {
+		if err := metrics.Tracker().Update(metricsv1beta1.SchemeGroupVersion.WithResource("pods"), testPodMetrics("shop", "api", scan.cpu, "1Gi"), "shop"); err != nil {
+			t.Fatal(err)
+		}
+		recommendations := co.analyzePods(ctx)
+		if len(recommendations) != 1 {
+			t.Fatalf("scan %d: %d recommendations, want one for CPU", i, len(recommendations))
+		}
+		rec = recommendations[0]
+		if hasBand := rec.SavingsHigh > 0; hasBand != scan.wantBand {
+			t.Errorf("scan %d: band %v-%v, want a band %v", i, rec.SavingsLow, rec.SavingsHigh, scan.wantBand)
+		}
+	}
+
+	// Five samples: p50 is 250m, sized at 300m; p95 is 800m, sized at 960m
+	wantLow := co.estimatePodCost(*resource.NewMilliQuantity(2000-960, resource.DecimalSI), resource.Quantity{})
+	wantHigh := co.estimatePodCost(*resource.NewMilliQuantity(2000-300, resource.DecimalSI), resource.Quantity{})
+	if rec.SavingsLow != wantLow || rec.SavingsHigh != wantHigh {
+		t.Errorf("band %v-%v, want %v-%v", rec.SavingsLow, rec.SavingsHigh, wantLow, wantHigh)
+	}
+	if rec.SavingsLow > rec.Savings || rec.Savings > rec.SavingsHigh {
+		t.Errorf("point estimate %v outside its band %v-%v", rec.Savings, rec.SavingsLow, rec.SavingsHigh)
+	}
+}
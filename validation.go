@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// routeVarValidators checks path variables by name before any handler sees them
+var routeVarValidators = map[string]func(string) error{
+	"id":        validateActionID,
+	"name":      validateResourceName,
+	"namespace": validateNamespace,
+}
+
+// validateRouteVars rejects requests whose path variables don't match their
+// expected format with 400, so malformed input never reaches lookups or logs.
+func validateRouteVars(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for key, value := range mux.Vars(r) {
+			validate, ok := routeVarValidators[key]
+			if !ok {
+				continue
+			}
+			if err := validate(value); err != nil {
+				http.Error(w, fmt.Sprintf("invalid %s: %v", key, err), http.StatusBadRequest)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validateActionID accepts only the canonical 36-character UUID form
+func validateActionID(id string) error {
+	if len(id) != 36 {
+		return errors.New("must be a UUID")
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return errors.New("must be a UUID")
+	}
+	return nil
+}
+
+func validateResourceName(name string) error {
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func validateNamespace(namespace string) error {
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestValidateRouteVars(t *testing.T) {
+	co, _ := newTestOptimizer(t)
+	router := mux.NewRouter()
+	router.Use(validateRouteVars)
+	router.HandleFunc("/api/actions/{id}/execute", co.handleExecuteAction).Methods("POST")
+	router.HandleFunc("/api/workloads/{namespace}/{name}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}).Methods("GET")
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		want   int
+	}{
+		{name: "unknown action", method: http.MethodPost, target: "/api/actions/" + uuid.NewString() + "/execute", want: http.StatusNotFound},
+		{name: "numeric action ID", method: http.MethodPost, target: "/api/actions/1/execute", want: http.StatusBadRequest},
+		{name: "UUID without dashes", method: http.MethodPost, target: "/api/actions/0b9f6c4e7a1d4c3e9f2a5b8c7d6e1f00/execute", want: http.StatusBadRequest},
+		{name: "forged log line", method: http.MethodPost, target: "/api/actions/x%0Aexecuted%20all/execute", want: http.StatusBadRequest},
+		{name: "valid workload", method: http.MethodGet, target: "/api/workloads/default/web.v1", want: http.StatusOK},
+		{name: "uppercase name", method: http.MethodGet, target: "/api/workloads/default/Web", want: http.StatusBadRequest},
+		{name: "underscore in name", method: http.MethodGet, target: "/api/workloads/default/my_app", want: http.StatusBadRequest},
+		{name: "dotted namespace", method: http.MethodGet, target: "/api/workloads/kube.system/web", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve(router, tt.method, tt.target, nil); rec.Code != tt.want {
+				t.Errorf("%s %s: status %d, want %d: %s", tt.method, tt.target, rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
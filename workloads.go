@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// podWorkload names the workload that owns a pod as kind/name. ReplicaSets
+// created by a Deployment are reported as the Deployment, and bare pods as
+// themselves.
+func podWorkload(pod *corev1.Pod) string {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Controller == nil || !*ref.Controller {
+			continue
+		}
+		if ref.Kind == "ReplicaSet" {
+			if hash := pod.Labels["pod-template-hash"]; hash != "" && strings.HasSuffix(ref.Name, "-"+hash) {
+				return "deployment/" + strings.TrimSuffix(ref.Name, "-"+hash)
+			}
+		}
+		return strings.ToLower(ref.Kind) + "/" + ref.Name
+	}
+	return "pod/" + pod.Name
+}
+
+// missingResourcesRecommendation flags a workload of the given kind, such as
+// "StatefulSet", whose pod template sets no requests or limits on any
+// container. It returns nil when some container has them.
+func missingResourcesRecommendation(kind, namespace, name string, labels map[string]string, spec *corev1.PodSpec) *Recommendation {
+	for _, container := range spec.Containers {
+		if container.Resources.Requests != nil || container.Resources.Limits != nil {
+			return nil
+		}
+	}
+	return &Recommendation{
+		Type:        "resource_governance",
+		Category:    CategoryConfigure,
+		Resource:    fmt.Sprintf("%s/%s", namespace, name),
+		Namespace:   namespace,
+		Release:     helmRelease(labels),
+		labels:      labels,
+		Description: fmt.Sprintf("%s %s lacks resource requests/limits", kind, name),
+		Impact:      "Add resource requests and limits for better scheduling and cost control",
+		ActionHint:  &ActionHint{Verb: "patch", Target: hintTarget(strings.ToLower(kind), namespace, name), Field: "spec.template.spec.containers[*].resources"},
+		Savings:     20.0, // Estimated monthly savings through better resource management
+		Priority:    "medium",
+		Timestamp:   time.Now(),
+	}
+}
+
+// WorkloadCostPoint is a workload's projected monthly cost at one scan
+type WorkloadCostPoint struct {
+	Timestamp   time.Time `json:"timestamp"`
+	MonthlyCost float64   `json:"monthly_cost"`
+}
+
+func (p WorkloadCostPoint) MarshalJSON() ([]byte, error) {
+	type plain WorkloadCostPoint
+	p.MonthlyCost = roundMoney(p.MonthlyCost)
+	return json.Marshal(plain(p))
+}
+
+// WorkloadCostHistory is one workload's cost across the recorded scans
+type WorkloadCostHistory struct {
+	Namespace string              `json:"namespace"`
+	Kind      string              `json:"kind"`
+	Name      string              `json:"name"`
+	Points    []WorkloadCostPoint `json:"points"`
+}
+
+// workloadHistories collects the cost series of every workload in namespace
+// named name, optionally restricted to one kind. Scans where a workload had
+// no running pods are omitted from its series.
+func workloadHistories(summaries []ClusterCostSummary, namespace, kind, name string) []WorkloadCostHistory {
+	byKey := make(map[string]*WorkloadCostHistory)
+	keys := make([]string, 0)
+	for _, summary := range summaries {
+		for key, cost := range summary.WorkloadCosts {
+			parts := strings.SplitN(key, "/", 3)
+			if len(parts) != 3 || parts[0] != namespace || parts[2] != name || (kind != "" && parts[1] != kind) {
+				continue
+			}
+			history, ok := byKey[key]
+			if !ok {
+				history = &WorkloadCostHistory{Namespace: parts[0], Kind: parts[1], Name: parts[2], Points: make([]WorkloadCostPoint, 0)}
+				byKey[key] = history
+				keys = append(keys, key)
+			}
+			history.Points = append(history.Points, WorkloadCostPoint{Timestamp: summary.LastUpdated, MonthlyCost: cost})
+		}
+	}
+
+	sort.Strings(keys)
+	histories := make([]WorkloadCostHistory, 0, len(keys))
+	for _, key := range keys {
+		histories = append(histories, *byKey[key])
+	}
+	return histories
+}
+
+// handleWorkloadHistory serves a workload's cost trend from the summary
+// history. Names can repeat across kinds, so every matching kind is returned
+// unless ?kind= narrows it down.
+func (co *CostOptimizer) handleWorkloadHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	kind := strings.ToLower(r.URL.Query().Get("kind"))
+
+	histories := workloadHistories(co.history.all(), vars["namespace"], kind, vars["name"])
+	if len(histories) == 0 {
+		http.Error(w, fmt.Sprintf("no cost history for workload %s/%s", vars["namespace"], vars["name"]), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(histories)
+}
+
+// defaultRightsizingMinPodAge is how long a pod must have been running before
+// its usage is compared with its requests
+const defaultRightsizingMinPodAge = 10 * time.Minute
+
+// tooYoungForRightsizing reports whether a pod started too recently for its
+// usage to be representative. This is what keeps short-lived Job pods out of
+// rightsizing; Job pods that keep running past the age, such as queue
+// workers, are analyzed like any other pod.
+func (co *CostOptimizer) tooYoungForRightsizing(pod *corev1.Pod) bool {
+	if pod.Status.StartTime == nil {
+		return true
+	}
+	return co.now().Sub(pod.Status.StartTime.Time) < co.rightsizingMinPodAge
+}
+
+// Ways of combining per-replica usage into one workload-level value
+const (
+	replicaAggregationAvg = "avg"
+	replicaAggregationMax = "max"
+	replicaAggregationP95 = "p95"
+)
+
+// defaultReplicaAggregation sizes workload suggestions for the busier replicas
+// rather than the average one, to avoid under-provisioning
+const defaultReplicaAggregation = replicaAggregationP95
+
+func validReplicaAggregation(mode string) bool {
+	switch mode {
+	case replicaAggregationAvg, replicaAggregationMax, replicaAggregationP95:
+		return true
+	}
+	return false
+}
+
+// aggregateReplicas combines per-replica values. p95 uses the nearest-rank
+// method, so with fewer than 20 replicas it equals the max.
+func aggregateReplicas(values []int64, mode string) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	switch mode {
+	case replicaAggregationAvg:
+		var total int64
+		for _, value := range values {
+			total += value
+		}
+		return total / int64(len(values))
+	case replicaAggregationMax:
+		return slices.Max(values)
+	default:
+		sorted := slices.Clone(values)
+		slices.Sort(sorted)
+		rank := int(math.Ceil(0.95 * float64(len(sorted))))
+		return sorted[rank-1]
+	}
+}